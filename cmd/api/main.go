@@ -12,11 +12,15 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/config"
 	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/geocode"
 	"github.com/jwald3/waybill/internal/handler"
 	"github.com/jwald3/waybill/internal/logger"
+	"github.com/jwald3/waybill/internal/mailer"
 	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/repository"
 	"github.com/jwald3/waybill/internal/service"
+	"github.com/jwald3/waybill/internal/signing"
 	"go.uber.org/zap"
 )
 
@@ -26,15 +30,52 @@ func main() {
 	log := logger.New(cfg.App.LogLevel)
 	defer log.Sync()
 
-	db, err := database.NewMongoConnection(*cfg)
+	// the in-memory backend leaves db nil; services only reach for it to open transactions
+	var db *database.MongoDB
+	if cfg.UsesInMemoryDatabase() {
+		if cfg.IsProduction() {
+			log.Fatal("the in-memory database is for development only and can't be used in production")
+		}
+		log.Warn("using in-memory database, data will not persist between restarts and failed changes are not rolled back")
+	} else {
+		mongoDB, err := database.NewMongoConnection(*cfg)
+		if err != nil {
+			log.Fatal("failed to connect to database", zap.Error(err))
+		}
+		defer mongoDB.Close()
+		db = mongoDB
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = repository.EnsureIndexes(ctx, db)
+		if err == nil {
+			err = repository.Migrate(ctx, db)
+		}
+		cancel()
+		if err != nil {
+			log.Fatal("failed to prepare database", zap.Error(err))
+		}
+	}
+
+	keys, err := loadSigningKeys(cfg, log)
+	if err != nil {
+		log.Fatal("failed to load signing keys", zap.Error(err))
+	}
+
+	mail, err := loadMailer(cfg, log)
 	if err != nil {
-		log.Fatal("failed to connect to database", zap.Error(err))
+		log.Fatal("failed to set up mailer", zap.Error(err))
 	}
-	defer db.Close()
 
-	handlers := initializeHandlers(db, cfg)
+	geocoder, err := geocode.New(cfg)
+	if err != nil {
+		log.Fatal("failed to set up geocoder", zap.Error(err))
+	}
+
+	svcs := initializeServices(db, cfg, keys, mail, geocoder, log)
+	handlers := initializeHandlers(svcs, keys)
 
 	router := mux.NewRouter()
+	router.Use(middleware.ClientIP(cfg.Server.TrustedProxies))
 	router.Use(middleware.Logging(log))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.CORS())
@@ -45,20 +86,47 @@ func main() {
 	})
 
 	router.HandleFunc("/health", handler.HealthCheck).Methods(http.MethodGet)
+	router.HandleFunc("/.well-known/jwks.json", handlers.jwks.Get).Methods(http.MethodGet)
+
+	limiter := middleware.NewRateLimiter(cfg)
 
 	v1 := router.PathPrefix("/api/v1").Subrouter()
-	registerAuthRoutes(v1, handlers.auth)
 
-	protected := v1.NewRoute().Subrouter()
-	protected.Use(middleware.Auth([]byte(cfg.Auth.JWTKey)))
+	// signing in is limited per IP, and more tightly, since it's where credential stuffing happens
+	public := v1.NewRoute().Subrouter()
+	if cfg.RateLimit.Enabled {
+		public.Use(limiter.Limit("auth", cfg.RateLimit.Auth))
+	}
+	registerAuthRoutes(public, handlers.auth)
+
+	authenticated := v1.NewRoute().Subrouter()
+	authenticated.Use(middleware.Auth(keys, svcs.auth, svcs.apiKey))
+	if cfg.RateLimit.Enabled {
+		authenticated.Use(limiter.Limit("api", cfg.RateLimit.Default))
+	}
+	registerSessionRoutes(authenticated, handlers.auth)
+	registerOrganizationRoutes(authenticated, handlers.organization)
+
+	// everything below acts on a single organization's data
+	protected := authenticated.NewRoute().Subrouter()
+	protected.Use(middleware.Membership(svcs.organization))
 
 	registerDriverRoutes(protected, handlers.driver)
+	registerHOSRoutes(protected, handlers.hos)
 	registerFacilityRoutes(protected, handlers.facility)
 	registerFuelLogRoutes(protected, handlers.fuelLog)
+	registerFuelAnalyticsRoutes(protected, handlers.fuelAnalytics)
 	registerIncidentReportRoutes(protected, handlers.incidentReport)
 	registerMaintenanceLogRoutes(protected, handlers.maintenanceLog)
+	registerMaintenanceScheduleRoutes(protected, handlers.maintenance)
+	registerWorkOrderRoutes(protected, handlers.workOrder)
 	registerTripRoutes(protected, handlers.trip)
 	registerTruckRoutes(protected, handlers.truck)
+	registerOdometerRoutes(protected, handlers.odometer)
+	registerPositionRoutes(protected, handlers.position)
+	registerAuditRoutes(protected, handlers.audit)
+	registerMemberRoutes(protected, handlers.organization)
+	registerAPIKeyRoutes(protected, handlers.apiKey)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
@@ -95,122 +163,428 @@ func main() {
 }
 
 type handlers struct {
+	apiKey         *handler.APIKeyHandler
 	driver         *handler.DriverHandler
+	hos            *handler.HOSHandler
 	facility       *handler.FacilityHandler
 	fuelLog        *handler.FuelLogHandler
+	fuelAnalytics  *handler.FuelAnalyticsHandler
 	incidentReport *handler.IncidentReportHandler
 	maintenanceLog *handler.MaintenanceLogHandler
+	maintenance    *handler.MaintenanceScheduleHandler
+	workOrder      *handler.WorkOrderHandler
+	organization   *handler.OrganizationHandler
 	trip           *handler.TripHandler
 	truck          *handler.TruckHandler
+	odometer       *handler.OdometerHandler
+	position       *handler.PositionHandler
+	audit          *handler.AuditHandler
 	auth           *handler.AuthHandler
+	jwks           *handler.JWKSHandler
+}
+
+type repositories struct {
+	apiKey         repository.APIKeyRepository
+	auditEvent     repository.AuditEventRepository
+	driver         repository.DriverRepository
+	dutyStatus     repository.DutyStatusRepository
+	emailToken     repository.EmailTokenRepository
+	facility       repository.FacilityRepository
+	fuelLog        repository.FuelLogRepository
+	fuelAnalytics  repository.FuelAnalyticsRepository
+	incidentReport repository.IncidentReportRepository
+	loginAttempt   repository.LoginAttemptRepository
+	maintenanceLog repository.MaintenanceLogRepository
+	maintenance    repository.MaintenanceScheduleRepository
+	workOrder      repository.WorkOrderRepository
+	membership     repository.MembershipRepository
+	organization   repository.OrganizationRepository
+	refreshToken   repository.RefreshTokenRepository
+	revokedToken   repository.RevokedTokenRepository
+	trip           repository.TripRepository
+	truck          repository.TruckRepository
+	truckPosition  repository.TruckPositionRepository
+	user           repository.UserRepository
+}
+
+func initializeRepositories(db *database.MongoDB, cfg *config.Config) *repositories {
+	if cfg.UsesInMemoryDatabase() {
+		store := repository.NewMemoryStore()
+
+		return &repositories{
+			apiKey:         repository.NewMemoryAPIKeyRepository(store),
+			auditEvent:     repository.NewMemoryAuditEventRepository(store),
+			driver:         repository.NewMemoryDriverRepository(store),
+			dutyStatus:     repository.NewMemoryDutyStatusRepository(store),
+			emailToken:     repository.NewMemoryEmailTokenRepository(store),
+			facility:       repository.NewMemoryFacilityRepository(store),
+			fuelLog:        repository.NewMemoryFuelLogRepository(store),
+			fuelAnalytics:  repository.NewMemoryFuelAnalyticsRepository(store),
+			incidentReport: repository.NewMemoryIncidentReportRepository(store),
+			loginAttempt:   repository.NewMemoryLoginAttemptRepository(store),
+			maintenanceLog: repository.NewMemoryMaintenanceLogRepository(store),
+			maintenance:    repository.NewMemoryMaintenanceScheduleRepository(store),
+			workOrder:      repository.NewMemoryWorkOrderRepository(store),
+			membership:     repository.NewMemoryMembershipRepository(store),
+			organization:   repository.NewMemoryOrganizationRepository(store),
+			refreshToken:   repository.NewMemoryRefreshTokenRepository(store),
+			revokedToken:   repository.NewMemoryRevokedTokenRepository(store),
+			trip:           repository.NewMemoryTripRepository(store),
+			truck:          repository.NewMemoryTruckRepository(store),
+			truckPosition:  repository.NewMemoryTruckPositionRepository(store),
+			user:           repository.NewMemoryUserRepository(store),
+		}
+	}
+
+	return &repositories{
+		apiKey:         repository.NewAPIKeyRepository(db),
+		auditEvent:     repository.NewAuditEventRepository(db),
+		driver:         repository.NewDriverRepository(db),
+		dutyStatus:     repository.NewDutyStatusRepository(db),
+		emailToken:     repository.NewEmailTokenRepository(db),
+		facility:       repository.NewFacilityRepository(db),
+		fuelLog:        repository.NewFuelLogRepository(db),
+		fuelAnalytics:  repository.NewFuelAnalyticsRepository(db),
+		incidentReport: repository.NewIncidentReportRepository(db),
+		loginAttempt:   repository.NewLoginAttemptRepository(db),
+		maintenanceLog: repository.NewMaintenanceLogRepository(db),
+		maintenance:    repository.NewMaintenanceScheduleRepository(db),
+		workOrder:      repository.NewWorkOrderRepository(db),
+		membership:     repository.NewMembershipRepository(db),
+		organization:   repository.NewOrganizationRepository(db),
+		refreshToken:   repository.NewRefreshTokenRepository(db),
+		revokedToken:   repository.NewRevokedTokenRepository(db),
+		trip:           repository.NewTripRepository(db),
+		truck:          repository.NewTruckRepository(db),
+		truckPosition:  repository.NewTruckPositionRepository(db),
+		user:           repository.NewUserRepository(db),
+	}
+}
+
+// loadSigningKeys reads the configured token signing keys. outside of production a throwaway key is generated
+// when none are configured, which means every token is invalidated by a restart.
+func loadSigningKeys(cfg *config.Config, log *zap.Logger) (*signing.KeySet, error) {
+	if len(cfg.Auth.SigningKeys) > 0 {
+		return signing.Load(cfg.Auth.SigningKeys, cfg.Auth.VerificationKeys)
+	}
+
+	if cfg.IsProduction() {
+		return nil, fmt.Errorf("JWT_SIGNING_KEYS must be set in production")
+	}
+
+	log.Warn("no JWT_SIGNING_KEYS configured, generating a temporary signing key; tokens will not survive a restart")
+
+	return signing.Generate()
+}
+
+// loadMailer sets up the configured mailer. the log and file mailers leave reset links where anyone reading the
+// logs or the disk can use them, so production has to send real email.
+func loadMailer(cfg *config.Config, log *zap.Logger) (mailer.Mailer, error) {
+	if cfg.IsProduction() && cfg.Mail.Driver != mailer.DriverSMTP {
+		return nil, fmt.Errorf("MAIL_DRIVER must be smtp in production")
+	}
+
+	return mailer.New(cfg, log)
+}
+
+type services struct {
+	apiKey         service.APIKeyService
+	audit          service.AuditService
+	driver         service.DriverService
+	hos            service.HOSService
+	facility       service.FacilityService
+	fuelLog        service.FuelLogService
+	fuelAnalytics  service.FuelAnalyticsService
+	incidentReport service.IncidentReportService
+	maintenanceLog service.MaintenanceLogService
+	maintenance    service.MaintenanceScheduleService
+	workOrder      service.WorkOrderService
+	organization   service.OrganizationService
+	trip           service.TripService
+	truck          service.TruckService
+	odometer       service.OdometerService
+	truckPosition  service.TruckPositionService
+	geofence       service.GeofenceService
+	auth           *service.AuthService
+}
+
+func initializeServices(
+	db *database.MongoDB,
+	cfg *config.Config,
+	keys *signing.KeySet,
+	mail mailer.Mailer,
+	geocoder geocode.Geocoder,
+	log *zap.Logger) *services {
+	repos := initializeRepositories(db, cfg)
+
+	auditService := service.NewAuditService(db, repos.auditEvent)
+	loginThrottleService := service.NewLoginThrottleService(repos.loginAttempt, repos.membership, repos.user, auditService, domain.LockoutPolicy{
+		Enabled:                cfg.Auth.Lockout.Enabled,
+		MaxAccountFailures:     cfg.Auth.Lockout.MaxAccountFailures,
+		MaxAccountWideFailures: cfg.Auth.Lockout.MaxAccountWideFailures,
+		MaxIPFailures:          cfg.Auth.Lockout.MaxIPFailures,
+		Duration:               cfg.Auth.Lockout.Duration,
+		FailureWindow:          cfg.Auth.Lockout.FailureWindow,
+		BaseDelay:              cfg.Auth.Lockout.BaseDelay,
+	})
+	organizationService := service.NewOrganizationService(db, repos.organization, repos.membership, repos.user, loginThrottleService)
+	authService := service.NewAuthService(db, repos.user, repos.refreshToken, repos.revokedToken, repos.emailToken, organizationService, loginThrottleService, mail, log, service.AuthConfig{
+		Keys:                     keys,
+		AccessTokenTTL:           cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL:          cfg.Auth.RefreshTokenTTL,
+		EmailVerificationTTL:     cfg.Auth.EmailVerificationTTL,
+		PasswordResetTTL:         cfg.Auth.PasswordResetTTL,
+		RequireEmailVerification: cfg.Auth.RequireEmailVerification,
+		LinkBaseURL:              cfg.Mail.LinkBaseURL,
+		TwoFactorIssuer:          cfg.Auth.TwoFactorIssuer,
+		TwoFactorChallengeTTL:    cfg.Auth.TwoFactorChallengeTTL,
+	})
+	hosService := service.NewHOSService(db, repos.dutyStatus, repos.driver, domain.HOSRules{
+		MaxDriving:    cfg.HOS.MaxDriving,
+		DutyWindow:    cfg.HOS.DutyWindow,
+		ShiftReset:    cfg.HOS.ShiftReset,
+		BreakAfter:    cfg.HOS.BreakAfter,
+		BreakDuration: cfg.HOS.BreakDuration,
+		CycleLimit:    cfg.HOS.CycleLimit,
+		CycleDays:     cfg.HOS.CycleDays,
+		CycleRestart:  cfg.HOS.CycleRestart,
+	})
+	maintenanceService := service.NewMaintenanceScheduleService(db, repos.maintenance, repos.maintenanceLog, repos.truck, auditService, cfg.Maintenance.BlockOverdueDispatch)
+	tripService := service.NewTripService(db, repos.trip, repos.truck, repos.driver, repos.facility, auditService, hosService, maintenanceService, geocoder, domain.RoutePolicy{
+		RoadFactor:         cfg.Routing.RoadFactor,
+		AverageSpeedMPH:    cfg.Routing.AverageSpeedMPH,
+		BreakAfter:         cfg.Routing.BreakAfter,
+		BreakDuration:      cfg.Routing.BreakDuration,
+		MaxDrivingPerShift: cfg.Routing.MaxDrivingPerShift,
+		OffDutyDuration:    cfg.Routing.OffDutyDuration,
+		DeviationThreshold: cfg.Routing.DeviationThreshold,
+	})
+	geofenceService := service.NewGeofenceService(repos.trip, repos.truck, repos.facility, tripService, domain.GeofencePolicy{
+		DefaultRadiusMiles: cfg.Geofencing.DefaultRadiusMiles,
+		Rules: domain.GeofenceRules{
+			BeginOnExit:       cfg.Geofencing.BeginOnExit,
+			ArriveOnEntry:     cfg.Geofencing.ArriveOnEntry,
+			CompleteOnArrival: cfg.Geofencing.CompleteOnArrival,
+		},
+		DepartureWindow: cfg.Geofencing.DepartureWindow,
+	})
+
+	return &services{
+		apiKey:         service.NewAPIKeyService(db, repos.apiKey),
+		audit:          auditService,
+		driver:         service.NewDriverService(db, repos.driver, auditService),
+		hos:            hosService,
+		facility:       service.NewFacilityService(db, repos.facility, auditService, geocoder),
+		fuelLog:        service.NewFuelLogService(db, repos.fuelLog, repos.trip, repos.truck, repos.driver, repos.facility, auditService),
+		fuelAnalytics:  service.NewFuelAnalyticsService(repos.fuelAnalytics),
+		incidentReport: service.NewIncidentReportService(db, repos.incidentReport, auditService),
+		maintenanceLog: service.NewMaintenanceLogService(db, repos.maintenanceLog, repos.maintenance, repos.truck, auditService),
+		maintenance:    maintenanceService,
+		workOrder:      service.NewWorkOrderService(db, repos.workOrder, repos.truck, repos.maintenanceLog, repos.maintenance, auditService),
+		organization:   organizationService,
+		trip:           tripService,
+		truck:          service.NewTruckService(db, repos.truck, repos.driver, repos.trip, auditService),
+		odometer:       service.NewOdometerService(repos.truck, repos.trip, repos.fuelLog),
+		truckPosition:  service.NewTruckPositionService(repos.truckPosition, repos.truck, repos.trip, geofenceService),
+		geofence:       geofenceService,
+		auth:           authService,
+	}
 }
 
-func initializeHandlers(db *database.MongoDB, cfg *config.Config) *handlers {
-	// Initialize repositories
-	driverRepo := repository.NewDriverRepository(db)
-	facilityRepo := repository.NewFacilityRepository(db)
-	fuelLogRepo := repository.NewFuelLogRepository(db)
-	incidentReportRepo := repository.NewIncidentReportRepository(db)
-	maintenanceLogRepo := repository.NewMaintenanceLogRepository(db)
-	tripRepo := repository.NewTripRepository(db)
-	truckRepo := repository.NewTruckRepository(db)
-	userRepo := repository.NewUserRepository(db)
-
-	// Initialize services
-	driverService := service.NewDriverService(db, driverRepo)
-	facilityService := service.NewFacilityService(db, facilityRepo)
-	fuelLogService := service.NewFuelLogService(db, fuelLogRepo)
-	incidentReportService := service.NewIncidentReportService(db, incidentReportRepo)
-	maintenanceLogService := service.NewMaintenanceLogService(db, maintenanceLogRepo)
-	tripService := service.NewTripService(db, tripRepo)
-	truckService := service.NewTruckService(db, truckRepo)
-	authService := service.NewAuthService(db, userRepo, cfg.Auth.JWTKey)
-
-	// Initialize handlers
+func initializeHandlers(svcs *services, keys *signing.KeySet) *handlers {
 	return &handlers{
-		driver:         handler.NewDriverHandler(driverService),
-		facility:       handler.NewFacilityHandler(facilityService),
-		fuelLog:        handler.NewFuelLogHandler(fuelLogService),
-		incidentReport: handler.NewIncidentReportHandler(incidentReportService),
-		maintenanceLog: handler.NewMaintenanceLogHandler(maintenanceLogService),
-		trip:           handler.NewTripHandler(tripService),
-		truck:          handler.NewTruckHandler(truckService),
-		auth:           handler.NewAuthHandler(authService),
+		apiKey:         handler.NewAPIKeyHandler(svcs.apiKey),
+		driver:         handler.NewDriverHandler(svcs.driver),
+		hos:            handler.NewHOSHandler(svcs.hos),
+		facility:       handler.NewFacilityHandler(svcs.facility),
+		fuelLog:        handler.NewFuelLogHandler(svcs.fuelLog),
+		fuelAnalytics:  handler.NewFuelAnalyticsHandler(svcs.fuelAnalytics),
+		incidentReport: handler.NewIncidentReportHandler(svcs.incidentReport),
+		maintenanceLog: handler.NewMaintenanceLogHandler(svcs.maintenanceLog),
+		maintenance:    handler.NewMaintenanceScheduleHandler(svcs.maintenance),
+		workOrder:      handler.NewWorkOrderHandler(svcs.workOrder),
+		organization:   handler.NewOrganizationHandler(svcs.organization),
+		trip:           handler.NewTripHandler(svcs.trip),
+		truck:          handler.NewTruckHandler(svcs.truck),
+		odometer:       handler.NewOdometerHandler(svcs.odometer),
+		position:       handler.NewPositionHandler(svcs.truckPosition, svcs.geofence),
+		audit:          handler.NewAuditHandler(svcs.audit),
+		auth:           handler.NewAuthHandler(svcs.auth),
+		jwks:           handler.NewJWKSHandler(keys),
 	}
 }
 
 func registerDriverRoutes(r *mux.Router, h *handler.DriverHandler) {
-	r.HandleFunc("/drivers", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/drivers", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/drivers/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/drivers/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/drivers/{id}", h.Delete).Methods(http.MethodDelete)
-	r.HandleFunc("/drivers/{id}/employment-status/activate", h.ActivateDriver).Methods(http.MethodPatch)
-	r.HandleFunc("/drivers/{id}/employment-status/suspend", h.SuspendDriver).Methods(http.MethodPatch)
-	r.HandleFunc("/drivers/{id}/employment-status/terminate", h.TerminateDriver).Methods(http.MethodPatch)
+	r.HandleFunc("/drivers", middleware.RequirePermission(domain.PermissionDriversRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/drivers", middleware.RequirePermission(domain.PermissionDriversWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/drivers/expiring", middleware.RequirePermission(domain.PermissionDriversRead, h.ListExpiring)).Methods(http.MethodGet)
+	r.HandleFunc("/drivers/{id}", middleware.RequirePermission(domain.PermissionDriversRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/drivers/{id}", middleware.RequirePermission(domain.PermissionDriversWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/drivers/{id}", middleware.RequirePermission(domain.PermissionDriversWrite, h.Delete)).Methods(http.MethodDelete)
+	r.HandleFunc("/drivers/{id}/employment-status/activate", middleware.RequirePermission(domain.PermissionDriversWrite, h.ActivateDriver)).Methods(http.MethodPatch)
+	r.HandleFunc("/drivers/{id}/employment-status/suspend", middleware.RequirePermission(domain.PermissionDriversWrite, h.SuspendDriver)).Methods(http.MethodPatch)
+	r.HandleFunc("/drivers/{id}/employment-status/terminate", middleware.RequirePermission(domain.PermissionDriversWrite, h.TerminateDriver)).Methods(http.MethodPatch)
+}
+
+func registerHOSRoutes(r *mux.Router, h *handler.HOSHandler) {
+	r.HandleFunc("/drivers/{id}/duty-status", middleware.RequirePermission(domain.PermissionDriversRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/drivers/{id}/duty-status", middleware.RequirePermission(domain.PermissionHOSWrite, h.Record)).Methods(http.MethodPost)
+	r.HandleFunc("/drivers/{id}/hos", middleware.RequirePermission(domain.PermissionDriversRead, h.Summary)).Methods(http.MethodGet)
 }
 
 func registerFacilityRoutes(r *mux.Router, h *handler.FacilityHandler) {
-	r.HandleFunc("/facilities", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/facilities", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/facilities/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/facilities/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/facilities/{id}", h.Delete).Methods(http.MethodDelete)
-	r.HandleFunc("/facilities/{id}/services", h.UpdateAvailableFacilityServices).Methods(http.MethodPatch)
+	r.HandleFunc("/facilities", middleware.RequirePermission(domain.PermissionFacilitiesRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/facilities", middleware.RequirePermission(domain.PermissionFacilitiesWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/facilities/{id}", middleware.RequirePermission(domain.PermissionFacilitiesRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/facilities/{id}", middleware.RequirePermission(domain.PermissionFacilitiesWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/facilities/{id}", middleware.RequirePermission(domain.PermissionFacilitiesWrite, h.Delete)).Methods(http.MethodDelete)
+	r.HandleFunc("/facilities/{id}/services", middleware.RequirePermission(domain.PermissionFacilitiesWrite, h.UpdateAvailableFacilityServices)).Methods(http.MethodPatch)
 }
 
 func registerFuelLogRoutes(r *mux.Router, h *handler.FuelLogHandler) {
-	r.HandleFunc("/fuel-logs", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/fuel-logs", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/fuel-logs/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/fuel-logs/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/fuel-logs/{id}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/fuel-logs", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/fuel-logs", middleware.RequirePermission(domain.PermissionFuelLogsWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/fuel-logs/{id}", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/fuel-logs/{id}", middleware.RequirePermission(domain.PermissionFuelLogsWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/fuel-logs/{id}", middleware.RequirePermission(domain.PermissionFuelLogsWrite, h.Delete)).Methods(http.MethodDelete)
+}
+
+func registerFuelAnalyticsRoutes(r *mux.Router, h *handler.FuelAnalyticsHandler) {
+	r.HandleFunc("/analytics/fuel/trucks", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.Efficiency(domain.FuelGroupingTruck))).Methods(http.MethodGet)
+	r.HandleFunc("/analytics/fuel/drivers", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.Efficiency(domain.FuelGroupingDriver))).Methods(http.MethodGet)
+	r.HandleFunc("/analytics/fuel/fuel-types", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.Efficiency(domain.FuelGroupingFuelType))).Methods(http.MethodGet)
+	r.HandleFunc("/analytics/fuel/trailer-types", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.Efficiency(domain.FuelGroupingTrailerType))).Methods(http.MethodGet)
+	r.HandleFunc("/analytics/fuel/lanes", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.Efficiency(domain.FuelGroupingLane))).Methods(http.MethodGet)
+	r.HandleFunc("/analytics/fuel/outliers", middleware.RequirePermission(domain.PermissionFuelLogsRead, h.Outliers)).Methods(http.MethodGet)
 }
 
 func registerIncidentReportRoutes(r *mux.Router, h *handler.IncidentReportHandler) {
-	r.HandleFunc("/incident-reports", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/incident-reports", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/incident-reports/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/incident-reports/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/incident-reports/{id}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/incident-reports", middleware.RequirePermission(domain.PermissionIncidentReportsRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/incident-reports", middleware.RequirePermission(domain.PermissionIncidentReportsWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/incident-reports/{id}", middleware.RequirePermission(domain.PermissionIncidentReportsRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/incident-reports/{id}", middleware.RequirePermission(domain.PermissionIncidentReportsWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/incident-reports/{id}", middleware.RequirePermission(domain.PermissionIncidentReportsWrite, h.Delete)).Methods(http.MethodDelete)
+}
+
+func registerMaintenanceScheduleRoutes(r *mux.Router, h *handler.MaintenanceScheduleHandler) {
+	r.HandleFunc("/maintenance/due", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.Due)).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance/schedules", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance/schedules", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/maintenance/schedules/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance/schedules/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/maintenance/schedules/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Delete)).Methods(http.MethodDelete)
+}
+
+func registerWorkOrderRoutes(r *mux.Router, h *handler.WorkOrderHandler) {
+	r.HandleFunc("/work-orders", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/work-orders", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/work-orders/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/work-orders/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/work-orders/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Delete)).Methods(http.MethodDelete)
+	r.HandleFunc("/work-orders/{id}/start", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Start)).Methods(http.MethodPatch)
+	r.HandleFunc("/work-orders/{id}/await-parts", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.AwaitParts)).Methods(http.MethodPatch)
+	r.HandleFunc("/work-orders/{id}/complete", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Complete)).Methods(http.MethodPatch)
+	r.HandleFunc("/work-orders/{id}/cancel", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Cancel)).Methods(http.MethodPatch)
 }
 
 func registerMaintenanceLogRoutes(r *mux.Router, h *handler.MaintenanceLogHandler) {
-	r.HandleFunc("/maintenance-logs", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/maintenance-logs", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/maintenance-logs/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/maintenance-logs/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/maintenance-logs/{id}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/maintenance-logs", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance-logs", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/maintenance-logs/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance-logs/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/maintenance-logs/{id}", middleware.RequirePermission(domain.PermissionMaintenanceLogsWrite, h.Delete)).Methods(http.MethodDelete)
 }
 
 func registerTripRoutes(r *mux.Router, h *handler.TripHandler) {
-	r.HandleFunc("/trips", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/trips", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/trips/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/trips/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/trips/{id}", h.Delete).Methods(http.MethodDelete)
-	r.HandleFunc("/trips/{id}/notes", h.AddNote).Methods(http.MethodPost)
-	r.HandleFunc("/trips/{id}/begin", h.BeginTrip).Methods(http.MethodPatch)
-	r.HandleFunc("/trips/{id}/cancel", h.CancelTrip).Methods(http.MethodPatch)
-	r.HandleFunc("/trips/{id}/finish/success", h.FinishTripSuccessfully).Methods(http.MethodPatch)
-	r.HandleFunc("/trips/{id}/finish/failure", h.FinishTripUnsuccessfully).Methods(http.MethodPatch)
+	r.HandleFunc("/trips", middleware.RequirePermission(domain.PermissionTripsRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/trips", middleware.RequirePermission(domain.PermissionTripsWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/trips/conflicts", middleware.RequirePermission(domain.PermissionTripsRead, h.ListConflicts)).Methods(http.MethodGet)
+	r.HandleFunc("/trips/{id}", middleware.RequirePermission(domain.PermissionTripsRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/trips/{id}", middleware.RequirePermission(domain.PermissionTripsWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/trips/{id}", middleware.RequirePermission(domain.PermissionTripsWrite, h.Delete)).Methods(http.MethodDelete)
+	r.HandleFunc("/trips/{id}/notes", middleware.RequirePermission(domain.PermissionTripsDispatch, h.AddNote)).Methods(http.MethodPost)
+	r.HandleFunc("/trips/{id}/begin", middleware.RequirePermission(domain.PermissionTripsDispatch, h.BeginTrip)).Methods(http.MethodPatch)
+	r.HandleFunc("/trips/{id}/cancel", middleware.RequirePermission(domain.PermissionTripsDispatch, h.CancelTrip)).Methods(http.MethodPatch)
+	r.HandleFunc("/trips/{id}/finish/success", middleware.RequirePermission(domain.PermissionTripsDispatch, h.FinishTripSuccessfully)).Methods(http.MethodPatch)
+	r.HandleFunc("/trips/{id}/finish/failure", middleware.RequirePermission(domain.PermissionTripsDispatch, h.FinishTripUnsuccessfully)).Methods(http.MethodPatch)
+	r.HandleFunc("/trips/{id}/stops/{stopId}/arrive", middleware.RequirePermission(domain.PermissionTripsDispatch, h.ArriveAtStop)).Methods(http.MethodPatch)
+	r.HandleFunc("/trips/{id}/stops/{stopId}/depart", middleware.RequirePermission(domain.PermissionTripsDispatch, h.DepartStop)).Methods(http.MethodPatch)
+	r.HandleFunc("/trips/{id}/stops/{stopId}/skip", middleware.RequirePermission(domain.PermissionTripsDispatch, h.SkipStop)).Methods(http.MethodPatch)
 }
 
 func registerTruckRoutes(r *mux.Router, h *handler.TruckHandler) {
-	r.HandleFunc("/trucks", h.List).Methods(http.MethodGet)
-	r.HandleFunc("/trucks", h.Create).Methods(http.MethodPost)
-	r.HandleFunc("/trucks/{id}", h.GetById).Methods(http.MethodGet)
-	r.HandleFunc("/trucks/{id}", h.Update).Methods(http.MethodPut)
-	r.HandleFunc("/trucks/{id}", h.Delete).Methods(http.MethodDelete)
-	r.HandleFunc("/trucks/{id}/status/available", h.MakeTruckAvailable).Methods(http.MethodPatch)
-	r.HandleFunc("/trucks/{id}/status/maintenance", h.SetTruckInMaintenance).Methods(http.MethodPatch)
-	r.HandleFunc("/trucks/{id}/status/in-transit", h.SetTruckInTransit).Methods(http.MethodPatch)
-	r.HandleFunc("/trucks/{id}/status/retire", h.RetireTruck).Methods(http.MethodPatch)
-	r.HandleFunc("/trucks/{id}/mileage", h.UpdateTruckMileage).Methods(http.MethodPatch)
-	r.HandleFunc("/trucks/{id}/maintenance", h.UpdateTruckLastMaintenance).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks", middleware.RequirePermission(domain.PermissionTrucksRead, h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/trucks", middleware.RequirePermission(domain.PermissionTrucksWrite, h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/trucks/{id}", middleware.RequirePermission(domain.PermissionTrucksRead, h.GetById)).Methods(http.MethodGet)
+	r.HandleFunc("/trucks/{id}", middleware.RequirePermission(domain.PermissionTrucksWrite, h.Update)).Methods(http.MethodPut)
+	r.HandleFunc("/trucks/{id}", middleware.RequirePermission(domain.PermissionTrucksWrite, h.Delete)).Methods(http.MethodDelete)
+	r.HandleFunc("/trucks/{id}/status/available", middleware.RequirePermission(domain.PermissionTrucksWrite, h.MakeTruckAvailable)).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks/{id}/status/maintenance", middleware.RequirePermission(domain.PermissionTrucksWrite, h.SetTruckInMaintenance)).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks/{id}/status/in-transit", middleware.RequirePermission(domain.PermissionTrucksWrite, h.SetTruckInTransit)).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks/{id}/status/retire", middleware.RequirePermission(domain.PermissionTrucksWrite, h.RetireTruck)).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks/{id}/mileage", middleware.RequirePermission(domain.PermissionTrucksWrite, h.UpdateTruckMileage)).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks/{id}/mileage/correction", middleware.RequirePermission(domain.PermissionOdometerCorrect, h.CorrectTruckMileage)).Methods(http.MethodPatch)
+	r.HandleFunc("/trucks/{id}/maintenance", middleware.RequirePermission(domain.PermissionTrucksWrite, h.UpdateTruckLastMaintenance)).Methods(http.MethodPatch)
+}
+
+func registerOdometerRoutes(r *mux.Router, h *handler.OdometerHandler) {
+	r.HandleFunc("/odometer/discrepancies", middleware.RequirePermission(domain.PermissionTrucksRead, h.Discrepancies)).Methods(http.MethodGet)
+}
+
+func registerPositionRoutes(r *mux.Router, h *handler.PositionHandler) {
+	r.HandleFunc("/trucks/{id}/positions", middleware.RequirePermission(domain.PermissionPositionsWrite, h.Record)).Methods(http.MethodPost)
+	r.HandleFunc("/trucks/{id}/positions/bulk", middleware.RequirePermission(domain.PermissionPositionsWrite, h.RecordBulk)).Methods(http.MethodPost)
+	r.HandleFunc("/trucks/{id}/location-events", middleware.RequirePermission(domain.PermissionPositionsWrite, h.RecordLocationEvent)).Methods(http.MethodPost)
+	r.HandleFunc("/trips/{id}/track", middleware.RequirePermission(domain.PermissionTripsRead, h.TripTrack)).Methods(http.MethodGet)
+}
+
+func registerAuditRoutes(r *mux.Router, h *handler.AuditHandler) {
+	r.HandleFunc("/drivers/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityDriver))).Methods(http.MethodGet)
+	r.HandleFunc("/facilities/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityFacility))).Methods(http.MethodGet)
+	r.HandleFunc("/fuel-logs/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityFuelLog))).Methods(http.MethodGet)
+	r.HandleFunc("/incident-reports/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityIncidentReport))).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance-logs/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityMaintenanceLog))).Methods(http.MethodGet)
+	r.HandleFunc("/maintenance/schedules/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityMaintenanceSchedule))).Methods(http.MethodGet)
+	r.HandleFunc("/work-orders/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityWorkOrder))).Methods(http.MethodGet)
+	r.HandleFunc("/trips/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityTrip))).Methods(http.MethodGet)
+	r.HandleFunc("/trucks/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityTruck))).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/history", middleware.RequirePermission(domain.PermissionHistoryRead, h.History(domain.AuditEntityUser))).Methods(http.MethodGet)
+}
+
+func registerOrganizationRoutes(r *mux.Router, h *handler.OrganizationHandler) {
+	r.HandleFunc("/organizations", middleware.RequireUserSession(h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/organizations", middleware.RequireUserSession(h.Create)).Methods(http.MethodPost)
+}
+
+func registerMemberRoutes(r *mux.Router, h *handler.OrganizationHandler) {
+	r.HandleFunc("/organization/members", middleware.RequirePermission(domain.PermissionMembersRead, h.ListMembers)).Methods(http.MethodGet)
+	r.HandleFunc("/organization/members", middleware.RequirePermission(domain.PermissionMembersManage, h.AddMember)).Methods(http.MethodPost)
+	r.HandleFunc("/organization/members/{id}", middleware.RequirePermission(domain.PermissionMembersManage, h.UpdateMemberRole)).Methods(http.MethodPatch)
+	r.HandleFunc("/organization/members/{id}", middleware.RequirePermission(domain.PermissionMembersManage, h.RemoveMember)).Methods(http.MethodDelete)
+	r.HandleFunc("/organization/members/{id}/unlock", middleware.RequirePermission(domain.PermissionMembersManage, h.UnlockMember)).Methods(http.MethodPost)
 }
 
 func registerAuthRoutes(r *mux.Router, h *handler.AuthHandler) {
 	r.HandleFunc("/auth/register", h.Register).Methods(http.MethodPost, http.MethodOptions)
 	r.HandleFunc("/auth/login", h.Login).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/auth/refresh", h.Refresh).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/auth/verify-email", h.VerifyEmail).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/auth/resend-verification", h.ResendVerification).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/auth/forgot-password", h.ForgotPassword).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/auth/reset-password", h.ResetPassword).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/auth/2fa/verify", h.VerifyTwoFactor).Methods(http.MethodPost, http.MethodOptions)
+}
+
+func registerSessionRoutes(r *mux.Router, h *handler.AuthHandler) {
+	r.HandleFunc("/auth/logout", middleware.RequireUserSession(h.Logout)).Methods(http.MethodPost)
+	r.HandleFunc("/auth/2fa/enroll", middleware.RequireUserSession(h.EnrollTwoFactor)).Methods(http.MethodPost)
+	r.HandleFunc("/auth/2fa/confirm", middleware.RequireUserSession(h.ConfirmTwoFactor)).Methods(http.MethodPost)
+	r.HandleFunc("/auth/2fa/disable", middleware.RequireUserSession(h.DisableTwoFactor)).Methods(http.MethodPost)
+	r.HandleFunc("/auth/2fa/recovery-codes", middleware.RequireUserSession(h.RegenerateRecoveryCodes)).Methods(http.MethodPost)
+}
+
+func registerAPIKeyRoutes(r *mux.Router, h *handler.APIKeyHandler) {
+	r.HandleFunc("/api-keys", middleware.RequireUserSession(h.List)).Methods(http.MethodGet)
+	r.HandleFunc("/api-keys", middleware.RequireUserSession(h.Create)).Methods(http.MethodPost)
+	r.HandleFunc("/api-keys/{id}", middleware.RequireUserSession(h.Revoke)).Methods(http.MethodDelete)
 }
@@ -8,6 +8,11 @@ import (
 	"time"
 )
 
+const (
+	DatabaseBackendMongo  = "mongo"
+	DatabaseBackendMemory = "memory"
+)
+
 type Config struct {
 	Server struct {
 		Port         string
@@ -15,9 +20,14 @@ type Config struct {
 		ReadTimeout  time.Duration
 		WriteTimeout time.Duration
 		IdleTimeout  time.Duration
+
+		// how many proxies in front of us append to X-Forwarded-For. the client's address is that many entries
+		// from the right; anything to the left of it was sent by the client and can't be trusted.
+		TrustedProxies int
 	}
 
 	Database struct {
+		Backend         string
 		Host            string
 		Port            string
 		User            string
@@ -32,21 +42,130 @@ type Config struct {
 	App struct {
 		Environment string
 		LogLevel    string
-		APIKey      string
 		APIVersion  string
 		CORSOrigins []string
 		DebugMode   bool
 	}
 
 	RateLimit struct {
-		Enabled  bool
-		Requests int
-		Duration time.Duration
+		Enabled     bool
+		Default     RateLimitRule
+		Auth        RateLimitRule
+		Routes      map[string]RateLimitRule
+		MaxClients  int
+		IdleTimeout time.Duration
 	}
 
 	Auth struct {
-		JWTKey string
+		SigningKeys      []KeyFile
+		VerificationKeys []KeyFile
+		AccessTokenTTL   time.Duration
+		RefreshTokenTTL  time.Duration
+		Lockout          Lockout
+
+		// when set, accounts can't log in until their email address has been verified
+		RequireEmailVerification bool
+		EmailVerificationTTL     time.Duration
+		PasswordResetTTL         time.Duration
+
+		// the name authenticator apps show next to a user's codes
+		TwoFactorIssuer       string
+		TwoFactorChallengeTTL time.Duration
 	}
+
+	Mail struct {
+		Driver string
+		From   string
+		Dir    string
+		SMTP   SMTP
+
+		// links in emails point here, at the page that posts the token back to the api
+		LinkBaseURL string
+	}
+
+	Geocoding struct {
+		// a CSV of ZIP code centroids; geocoding is off when it's empty
+		ZipCentroidsFile string
+	}
+
+	Routing Routing
+
+	Geofencing Geofencing
+
+	HOS HOS
+
+	Maintenance struct {
+		// when set, a trip can't be begun with a truck that is overdue on safety-critical maintenance
+		BlockOverdueDispatch bool
+	}
+}
+
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// Lockout controls how failed logins are throttled. every failure against an account from an address makes the
+// next attempt from there wait twice as long as the last, starting from BaseDelay, and after MaxAccountFailures
+// the account is locked for Duration, but only from that address. after MaxAccountWideFailures from any number of
+// addresses it's locked for everyone. addresses are only locked, at MaxIPFailures, since many users can share one.
+// failures older than FailureWindow are forgotten.
+type Lockout struct {
+	Enabled                bool
+	MaxAccountFailures     int
+	MaxAccountWideFailures int
+	MaxIPFailures          int
+	Duration               time.Duration
+	FailureWindow          time.Duration
+	BaseDelay              time.Duration
+}
+
+// Routing holds the assumptions used to estimate trip distances and travel times
+type Routing struct {
+	RoadFactor         float64
+	AverageSpeedMPH    float64
+	BreakAfter         time.Duration
+	BreakDuration      time.Duration
+	MaxDrivingPerShift time.Duration
+	OffDutyDuration    time.Duration
+	DeviationThreshold float64
+}
+
+// Geofencing controls the trip transitions trucks make by crossing facility geofences. facilities without a
+// geofence of their own are fenced at DefaultRadiusMiles around their location.
+type Geofencing struct {
+	DefaultRadiusMiles float64
+	BeginOnExit        bool
+	ArriveOnEntry      bool
+	CompleteOnArrival  bool
+	DepartureWindow    time.Duration
+}
+
+// HOS holds the hours of service limits drivers' duty status logs are checked against. the defaults are the
+// federal rules for property-carrying drivers on the 70 hour, 8 day cycle.
+type HOS struct {
+	MaxDriving    time.Duration
+	DutyWindow    time.Duration
+	ShiftReset    time.Duration
+	BreakAfter    time.Duration
+	BreakDuration time.Duration
+	CycleLimit    time.Duration
+	CycleDays     int
+	CycleRestart  time.Duration
+}
+
+// a RateLimitRule allows Requests per Period, refilling steadily, with up to Requests allowed in a burst
+type RateLimitRule struct {
+	Requests int
+	Period   time.Duration
+}
+
+// a KeyFile points at a PEM encoded key and the id ("kid") tokens signed with it carry
+type KeyFile struct {
+	ID   string
+	Path string
 }
 
 func Load() *Config {
@@ -57,7 +176,13 @@ func Load() *Config {
 	config.Server.ReadTimeout = getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second)
 	config.Server.WriteTimeout = getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second)
 	config.Server.IdleTimeout = getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second)
+	config.Server.TrustedProxies = getIntEnv("TRUSTED_PROXIES", 0)
+	if config.Server.TrustedProxies == 0 && getBoolEnv("TRUST_FORWARDED_FOR", false) {
+		config.Server.TrustedProxies = 1
+	}
 
+	// DB_BACKEND=memory is non-transactional and for development only
+	config.Database.Backend = getEnv("DB_BACKEND", DatabaseBackendMongo)
 	config.Database.Host = getEnv("DB_HOST", "")
 	config.Database.Port = getEnv("DB_PORT", "")
 	config.Database.User = getEnv("DB_USER", "admin")
@@ -65,17 +190,72 @@ func Load() *Config {
 	config.Database.DBName = getEnv("DB_NAME", "myapp")
 
 	config.App.Environment = getEnv("APP_ENV", "development")
-	config.App.APIKey = getEnv("API_KEY", "ABC123")
 	config.App.LogLevel = getEnv("LOG_LEVEL", "info")
 	config.App.APIVersion = getEnv("API_VERSION", "v1")
 	config.App.DebugMode = getBoolEnv("DEBUG_MODE", true)
 	config.App.CORSOrigins = getSliceEnv("CORS_ORIGINS", []string{"https://getwaybill.com"})
 
 	config.RateLimit.Enabled = getBoolEnv("RATE_LIMIT_ENABLED", true)
-	config.RateLimit.Requests = getIntEnv("RATE_LIMIT_REQUESTS", 100)
-	config.RateLimit.Duration = getDurationEnv("RATE_LIMIT_DURATION", time.Hour)
+	config.RateLimit.Default.Requests = getIntEnv("RATE_LIMIT_REQUESTS", 100)
+	config.RateLimit.Default.Period = getDurationEnv("RATE_LIMIT_DURATION", time.Hour)
+	config.RateLimit.Auth.Requests = getIntEnv("RATE_LIMIT_AUTH_REQUESTS", 10)
+	config.RateLimit.Auth.Period = getDurationEnv("RATE_LIMIT_AUTH_DURATION", time.Minute)
+	config.RateLimit.Routes = getRateLimitRulesEnv("RATE_LIMIT_ROUTES")
+	config.RateLimit.MaxClients = getIntEnv("RATE_LIMIT_MAX_CLIENTS", 10000)
+	config.RateLimit.IdleTimeout = getDurationEnv("RATE_LIMIT_IDLE_TIMEOUT", 10*time.Minute)
+
+	config.Auth.SigningKeys = getKeyFilesEnv("JWT_SIGNING_KEYS")
+	config.Auth.VerificationKeys = getKeyFilesEnv("JWT_VERIFICATION_KEYS")
+	config.Auth.AccessTokenTTL = getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	config.Auth.RefreshTokenTTL = getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	config.Auth.Lockout.Enabled = getBoolEnv("LOGIN_LOCKOUT_ENABLED", true)
+	config.Auth.Lockout.MaxAccountFailures = getIntEnv("LOGIN_MAX_ACCOUNT_FAILURES", 5)
+	config.Auth.Lockout.MaxAccountWideFailures = getIntEnv("LOGIN_MAX_ACCOUNT_WIDE_FAILURES", 50)
+	config.Auth.Lockout.MaxIPFailures = getIntEnv("LOGIN_MAX_IP_FAILURES", 50)
+	config.Auth.Lockout.Duration = getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	config.Auth.Lockout.FailureWindow = getDurationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+	config.Auth.Lockout.BaseDelay = getDurationEnv("LOGIN_FAILURE_DELAY", time.Second)
+	config.Auth.RequireEmailVerification = getBoolEnv("REQUIRE_EMAIL_VERIFICATION", false)
+	config.Auth.EmailVerificationTTL = getDurationEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	config.Auth.PasswordResetTTL = getDurationEnv("PASSWORD_RESET_TTL", time.Hour)
+	config.Auth.TwoFactorIssuer = getEnv("TWO_FACTOR_ISSUER", "Waybill")
+	config.Auth.TwoFactorChallengeTTL = getDurationEnv("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute)
+
+	config.Mail.Driver = getEnv("MAIL_DRIVER", "log")
+	config.Mail.From = getEnv("MAIL_FROM", "Waybill <no-reply@getwaybill.com>")
+	config.Mail.Dir = getEnv("MAIL_DIR", "tmp/mail")
+	config.Mail.SMTP.Host = getEnv("SMTP_HOST", "")
+	config.Mail.SMTP.Port = getEnv("SMTP_PORT", "587")
+	config.Mail.SMTP.Username = getEnv("SMTP_USERNAME", "")
+	config.Mail.SMTP.Password = getEnv("SMTP_PASSWORD", "")
+	config.Mail.LinkBaseURL = getEnv("APP_URL", "http://localhost:3000")
+
+	config.Geocoding.ZipCentroidsFile = getEnv("GEOCODER_ZIP_CENTROIDS", "")
 
-	config.Auth.JWTKey = getEnv("JWT_KEY", "your-secret-key-here")
+	config.Routing.RoadFactor = getFloatEnv("ROUTE_ROAD_FACTOR", 1.2)
+	config.Routing.AverageSpeedMPH = getFloatEnv("ROUTE_AVERAGE_SPEED_MPH", 50)
+	config.Routing.BreakAfter = getDurationEnv("ROUTE_BREAK_AFTER", 8*time.Hour)
+	config.Routing.BreakDuration = getDurationEnv("ROUTE_BREAK_DURATION", 30*time.Minute)
+	config.Routing.MaxDrivingPerShift = getDurationEnv("ROUTE_MAX_DRIVING_PER_SHIFT", 11*time.Hour)
+	config.Routing.OffDutyDuration = getDurationEnv("ROUTE_OFF_DUTY_DURATION", 10*time.Hour)
+	config.Routing.DeviationThreshold = getFloatEnv("ROUTE_DEVIATION_THRESHOLD", 0.25)
+
+	config.Geofencing.DefaultRadiusMiles = getFloatEnv("GEOFENCE_DEFAULT_RADIUS_MILES", 0.25)
+	config.Geofencing.BeginOnExit = getBoolEnv("GEOFENCE_BEGIN_ON_EXIT", true)
+	config.Geofencing.ArriveOnEntry = getBoolEnv("GEOFENCE_ARRIVE_ON_ENTRY", true)
+	config.Geofencing.CompleteOnArrival = getBoolEnv("GEOFENCE_COMPLETE_ON_ARRIVAL", false)
+	config.Geofencing.DepartureWindow = getDurationEnv("GEOFENCE_DEPARTURE_WINDOW", 12*time.Hour)
+
+	config.HOS.MaxDriving = getDurationEnv("HOS_MAX_DRIVING", 11*time.Hour)
+	config.HOS.DutyWindow = getDurationEnv("HOS_DUTY_WINDOW", 14*time.Hour)
+	config.HOS.ShiftReset = getDurationEnv("HOS_SHIFT_RESET", 10*time.Hour)
+	config.HOS.BreakAfter = getDurationEnv("HOS_BREAK_AFTER", 8*time.Hour)
+	config.HOS.BreakDuration = getDurationEnv("HOS_BREAK_DURATION", 30*time.Minute)
+	config.HOS.CycleLimit = getDurationEnv("HOS_CYCLE_LIMIT", 70*time.Hour)
+	config.HOS.CycleDays = getIntEnv("HOS_CYCLE_DAYS", 8)
+	config.HOS.CycleRestart = getDurationEnv("HOS_CYCLE_RESTART", 34*time.Hour)
+
+	config.Maintenance.BlockOverdueDispatch = getBoolEnv("MAINTENANCE_BLOCK_OVERDUE_DISPATCH", false)
 
 	return config
 }
@@ -105,6 +285,15 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value, exists := os.LookupEnv(key); exists {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -121,6 +310,63 @@ func getSliceEnv(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// getKeyFilesEnv parses a comma separated list of kid=path pairs, keeping their order
+func getKeyFilesEnv(key string) []KeyFile {
+	value, exists := os.LookupEnv(key)
+	if !exists || strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	files := make([]KeyFile, 0)
+	for _, entry := range strings.Split(value, ",") {
+		id, path, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		files = append(files, KeyFile{ID: strings.TrimSpace(id), Path: strings.TrimSpace(path)})
+	}
+
+	return files
+}
+
+// getRateLimitRulesEnv parses per-route overrides such as "POST /api/v1/trips=20/1m;GET /api/v1/trucks=600/1h".
+// routes are the method and the path template they were registered with. rules are separated with semicolons
+// since paths and durations can't contain them.
+func getRateLimitRulesEnv(key string) map[string]RateLimitRule {
+	rules := make(map[string]RateLimitRule)
+
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return rules
+	}
+
+	for _, entry := range strings.Split(value, ";") {
+		route, rule, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+
+		requests, period, found := strings.Cut(rule, "/")
+		if !found {
+			continue
+		}
+
+		requestCount, err := strconv.Atoi(strings.TrimSpace(requests))
+		if err != nil || requestCount <= 0 {
+			continue
+		}
+
+		duration, err := time.ParseDuration(strings.TrimSpace(period))
+		if err != nil || duration <= 0 {
+			continue
+		}
+
+		rules[strings.TrimSpace(route)] = RateLimitRule{Requests: requestCount, Period: duration}
+	}
+
+	return rules
+}
+
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf(
 		"mongodb+srv://%s:%s@%s/?retryWrites=true&w=majority&appName=Waybill",
@@ -133,3 +379,9 @@ func (c *Config) GetDSN() string {
 func (c *Config) IsProduction() bool {
 	return c.App.Environment == "production"
 }
+
+// the in-memory backend keeps everything in process and loses it on restart. it has no transactions either, so a
+// change that fails part way isn't rolled back - it's meant for local demos and tests, never production.
+func (c *Config) UsesInMemoryDatabase() bool {
+	return c.Database.Backend == DatabaseBackendMemory
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AuditActionCreate     AuditAction = "CREATE"
+	AuditActionUpdate     AuditAction = "UPDATE"
+	AuditActionDelete     AuditAction = "DELETE"
+	AuditActionTransition AuditAction = "TRANSITION"
+	AuditActionLockout    AuditAction = "LOCKOUT"
+	AuditActionUnlock     AuditAction = "UNLOCK"
+	AuditActionCorrection AuditAction = "CORRECTION"
+)
+
+type AuditEntityType string
+
+const (
+	AuditEntityDriver              AuditEntityType = "DRIVER"
+	AuditEntityFacility            AuditEntityType = "FACILITY"
+	AuditEntityFuelLog             AuditEntityType = "FUEL_LOG"
+	AuditEntityIncidentReport      AuditEntityType = "INCIDENT_REPORT"
+	AuditEntityMaintenanceLog      AuditEntityType = "MAINTENANCE_LOG"
+	AuditEntityMaintenanceSchedule AuditEntityType = "MAINTENANCE_SCHEDULE"
+	AuditEntityTrip                AuditEntityType = "TRIP"
+	AuditEntityTruck               AuditEntityType = "TRUCK"
+	AuditEntityUser                AuditEntityType = "USER"
+	AuditEntityWorkOrder           AuditEntityType = "WORK_ORDER"
+)
+
+// an AuditEvent is an append-only record of a single mutation. Before and After only hold the fields that
+// changed, keyed by their json names, so a create has no Before and a delete has no After.
+type AuditEvent struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	EntityType     AuditEntityType    `bson:"entity_type" json:"entity_type"`
+	EntityID       primitive.ObjectID `bson:"entity_id" json:"entity_id"`
+	Action         AuditAction        `bson:"action" json:"action"`
+	Before         map[string]any     `bson:"before,omitempty" json:"before,omitempty"`
+	After          map[string]any     `bson:"after,omitempty" json:"after,omitempty"`
+	Timestamp      primitive.DateTime `bson:"timestamp" json:"timestamp"`
+}
+
+type AuditEventFilter struct {
+	OrganizationID primitive.ObjectID
+	EntityType     AuditEntityType
+	EntityID       primitive.ObjectID
+	Limit          int64
+	Offset         int64
+}
+
+func NewAuditEventFilter() AuditEventFilter {
+	return AuditEventFilter{
+		Limit:  10,
+		Offset: 0,
+	}
+}
+
+// fields that change on every write and would only add noise to the diff
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+func NewAuditEvent(
+	organizationID,
+	userID primitive.ObjectID,
+	entityType AuditEntityType,
+	entityID primitive.ObjectID,
+	action AuditAction,
+	before, after any) (*AuditEvent, error) {
+	beforeSnapshot, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterSnapshot, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &AuditEvent{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		EntityType:     entityType,
+		EntityID:       entityID,
+		Action:         action,
+		Before:         make(map[string]any),
+		After:          make(map[string]any),
+		Timestamp:      primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	for field, value := range beforeSnapshot {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if afterValue, ok := afterSnapshot[field]; !ok || !reflect.DeepEqual(value, afterValue) {
+			event.Before[field] = value
+		}
+	}
+
+	for field, value := range afterSnapshot {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if beforeValue, ok := beforeSnapshot[field]; !ok || !reflect.DeepEqual(value, beforeValue) {
+			event.After[field] = value
+		}
+	}
+
+	return event, nil
+}
+
+// auditSnapshot flattens an entity into its json representation so the diff matches what clients see.
+// a nil entity (including a typed nil pointer) has an empty snapshot.
+func auditSnapshot(entity any) (map[string]any, error) {
+	if entity == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot entity for audit: %w", err)
+	}
+
+	var snapshot map[string]any
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot entity for audit: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+type actorContextKey struct{}
+
+// ContextWithActor records which user is making the request so that services can attribute the audit
+// events they write without every method having to take the caller's id
+func ContextWithActor(ctx context.Context, userID primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, userID)
+}
+
+func ActorFromContext(ctx context.Context) (primitive.ObjectID, bool) {
+	userID, ok := ctx.Value(actorContextKey{}).(primitive.ObjectID)
+	return userID, ok
+}
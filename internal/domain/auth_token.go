@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a RefreshToken is a long-lived, single-use credential that can be traded for a new access token. only a hash of
+// the token is stored. every token issued from the same login shares a FamilyID, so when a rotated token is
+// presented a second time (which means it was copied) the whole family can be revoked at once.
+type RefreshToken struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	FamilyID   primitive.ObjectID  `bson:"family_id" json:"family_id"`
+	TokenHash  string              `bson:"token_hash" json:"-"`
+	ExpiresAt  primitive.DateTime  `bson:"expires_at" json:"expires_at"`
+	RotatedAt  *primitive.DateTime `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+	RevokedAt  *primitive.DateTime `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  primitive.DateTime  `bson:"created_at" json:"created_at"`
+}
+
+// NewRefreshToken returns the stored token along with the plaintext value, which is only ever handed to the client
+func NewRefreshToken(userID, familyID primitive.ObjectID, ttl time.Duration) (*RefreshToken, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	plaintext := base64.RawURLEncoding.EncodeToString(buf)
+	now := time.Now()
+
+	return &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(plaintext),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	}, plaintext, nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt.Time())
+}
+
+// a token that has already been traded in, or whose family was revoked, can't be used again
+func (t *RefreshToken) IsSpent() bool {
+	return t.RotatedAt != nil || t.RevokedAt != nil
+}
+
+// a RevokedToken blocks an access token before it expires on its own. it only needs to be kept until then.
+type RevokedToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	JTI       string             `bson:"jti" json:"jti"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ExpiresAt primitive.DateTime `bson:"expires_at" json:"expires_at"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
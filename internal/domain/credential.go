@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DateLayout is how calendar dates, like birthdays and expiration dates, are written in requests and responses
+const DateLayout = "2006-01-02"
+
+// ParseDate reads a calendar date, stored as midnight UTC
+func ParseDate(field, value string) (primitive.DateTime, error) {
+	if value == "" {
+		return 0, fmt.Errorf("%s is required", field)
+	}
+
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a date formatted as YYYY-MM-DD", field)
+	}
+
+	return primitive.NewDateTimeFromTime(date), nil
+}
+
+func FormatDate(date primitive.DateTime) string {
+	if date == 0 {
+		return ""
+	}
+
+	return date.Time().UTC().Format(DateLayout)
+}
+
+type CredentialType string
+
+const (
+	// the license lives on the driver itself; it only shows up as a credential in expiration reports
+	CredentialTypeLicense           CredentialType = "LICENSE"
+	CredentialTypeMedicalCard       CredentialType = "MEDICAL_CARD"
+	CredentialTypeHazmatEndorsement CredentialType = "HAZMAT_ENDORSEMENT"
+	CredentialTypeTWIC              CredentialType = "TWIC"
+)
+
+func (t CredentialType) IsValid() bool {
+	switch t {
+	case CredentialTypeMedicalCard, CredentialTypeHazmatEndorsement, CredentialTypeTWIC:
+		return true
+	}
+	return false
+}
+
+// a Credential is a certification a driver holds on top of their license
+type Credential struct {
+	Type      CredentialType     `bson:"type" json:"type"`
+	Number    string             `bson:"number,omitempty" json:"number,omitempty"`
+	ExpiresOn primitive.DateTime `bson:"expires_on" json:"expires_on"`
+}
+
+func NewCredential(credentialType CredentialType, number, expiresOn string) (*Credential, error) {
+	if !credentialType.IsValid() {
+		return nil, fmt.Errorf("invalid credential type: %s", credentialType)
+	}
+
+	expiry, err := ParseDate("expires_on", expiresOn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		Type:      credentialType,
+		Number:    number,
+		ExpiresOn: expiry,
+	}, nil
+}
+
+// a credential is good through the whole of the day it expires on
+func expiredBy(expiresOn primitive.DateTime, at time.Time) bool {
+	return !at.Before(expiresOn.Time().AddDate(0, 0, 1))
+}
+
+// a CredentialExpiration is one line of the expiring credentials report
+type CredentialExpiration struct {
+	DriverID      primitive.ObjectID `json:"driver_id"`
+	DriverName    string             `json:"driver_name"`
+	Credential    CredentialType     `json:"credential"`
+	Number        string             `json:"number,omitempty"`
+	ExpiresOn     primitive.DateTime `json:"expires_on"`
+	DaysRemaining int                `json:"days_remaining"`
+	Expired       bool               `json:"expired"`
+}
+
+// Credential looks up one of the driver's credentials, if they hold it
+func (d *Driver) Credential(credentialType CredentialType) *Credential {
+	for i := range d.Credentials {
+		if d.Credentials[i].Type == credentialType {
+			return &d.Credentials[i]
+		}
+	}
+
+	return nil
+}
+
+// Expirations lists the driver's license and credentials that expire before the cutoff, which includes any that
+// already have
+func (d *Driver) Expirations(cutoff, now time.Time) []CredentialExpiration {
+	today := now.UTC().Truncate(24 * time.Hour)
+	expirations := make([]CredentialExpiration, 0)
+
+	add := func(credentialType CredentialType, number string, expiresOn primitive.DateTime) {
+		if expiresOn == 0 || !expiresOn.Time().Before(cutoff) {
+			return
+		}
+
+		expirations = append(expirations, CredentialExpiration{
+			DriverID:      d.ID,
+			DriverName:    d.FirstName + " " + d.LastName,
+			Credential:    credentialType,
+			Number:        number,
+			ExpiresOn:     expiresOn,
+			DaysRemaining: int(expiresOn.Time().Sub(today).Hours() / 24),
+			Expired:       expiredBy(expiresOn, now),
+		})
+	}
+
+	add(CredentialTypeLicense, d.LicenseNumber, d.LicenseExpiration)
+	for _, credential := range d.Credentials {
+		add(credential.Type, credential.Number, credential.ExpiresOn)
+	}
+
+	return expirations
+}
+
+// SortCredentialExpirations puts the soonest expirations first
+func SortCredentialExpirations(expirations []CredentialExpiration) {
+	sort.SliceStable(expirations, func(i, j int) bool {
+		return expirations[i].ExpiresOn < expirations[j].ExpiresOn
+	})
+}
+
+// EnsureLicensed refuses a driver whose license will have expired by the time given, so they can't be put on a
+// trip or truck they aren't allowed to drive. a license with no expiration on record is refused too, since nothing
+// shows it's still good.
+func (d *Driver) EnsureLicensed(at time.Time) error {
+	if d.LicenseExpiration == 0 || expiredBy(d.LicenseExpiration, at) {
+		return &CredentialExpiredError{DriverID: d.ID, Credential: CredentialTypeLicense, ExpiresOn: d.LicenseExpiration}
+	}
+
+	return nil
+}
@@ -40,13 +40,15 @@ const (
 
 type Driver struct {
 	ID                primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID    primitive.ObjectID         `bson:"organization_id" json:"organization_id"`
 	UserID            primitive.ObjectID         `bson:"user_id" json:"user_id"`
 	FirstName         string                     `bson:"first_name" json:"first_name"`
 	LastName          string                     `bson:"last_name" json:"last_name"`
-	DOB               string                     `bson:"dob" json:"dob"`
+	DOB               primitive.DateTime         `bson:"dob" json:"dob"`
 	LicenseNumber     string                     `bson:"license_number" json:"license_number"`
 	LicenseState      string                     `bson:"license_state" json:"license_state"`
-	LicenseExpiration string                     `bson:"license_expiration" json:"license_expiration"`
+	LicenseExpiration primitive.DateTime         `bson:"license_expiration" json:"license_expiration"`
+	Credentials       []Credential               `bson:"credentials,omitempty" json:"credentials,omitempty"`
 	Phone             PhoneNumber                `bson:"phone" json:"phone"`
 	Email             Email                      `bson:"email" json:"email"`
 	Address           Address                    `bson:"address" json:"address"`
@@ -64,6 +66,7 @@ type Address struct {
 }
 
 func NewDriver(
+	organizationID,
 	userID primitive.ObjectID,
 	firstName,
 	lastName,
@@ -73,7 +76,8 @@ func NewDriver(
 	licenseExpiration,
 	phoneNumber,
 	email string,
-	address Address) (*Driver, error) {
+	address Address,
+	credentials []Credential) (*Driver, error) {
 
 	validEmail, err := NewEmail(email)
 	if err != nil {
@@ -85,16 +89,23 @@ func NewDriver(
 		return nil, err
 	}
 
+	dob, licenseExpiry, err := validateDriverLicense(dateOfBirth, licenseNumber, licenseState, licenseExpiration, credentials)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 
 	driver := &Driver{
+		OrganizationID:    organizationID,
 		UserID:            userID,
 		FirstName:         firstName,
 		LastName:          lastName,
-		DOB:               dateOfBirth,
+		DOB:               dob,
 		LicenseNumber:     licenseNumber,
 		LicenseState:      licenseState,
-		LicenseExpiration: licenseExpiration,
+		LicenseExpiration: licenseExpiry,
+		Credentials:       credentials,
 		Phone:             validPhone,
 		Email:             validEmail,
 		Address:           address,
@@ -110,8 +121,90 @@ func NewDriver(
 	return driver, nil
 }
 
+// validateDriverLicense checks the details every driver needs, returning the parsed birth and license expiration
+// dates. an expired license is allowed, since drivers are often entered before they renew; it's assigning them
+// that's refused.
+func validateDriverLicense(dateOfBirth, licenseNumber, licenseState, licenseExpiration string, credentials []Credential) (primitive.DateTime, primitive.DateTime, error) {
+	dob, err := ParseDate("dob", dateOfBirth)
+	if err != nil {
+		return 0, 0, err
+	}
+	if dob.Time().After(time.Now()) {
+		return 0, 0, fmt.Errorf("dob can't be in the future")
+	}
+
+	if licenseNumber == "" {
+		return 0, 0, fmt.Errorf("license_number is required")
+	}
+	if licenseState == "" {
+		return 0, 0, fmt.Errorf("license_state is required")
+	}
+
+	licenseExpiry, err := ParseDate("license_expiration", licenseExpiration)
+	if err != nil {
+		return 0, 0, err
+	}
+	if licenseExpiry <= dob {
+		return 0, 0, fmt.Errorf("license_expiration must be after dob")
+	}
+
+	seen := make(map[CredentialType]bool, len(credentials))
+	for _, credential := range credentials {
+		if seen[credential.Type] {
+			return 0, 0, fmt.Errorf("a driver can only hold one %s credential", credential.Type)
+		}
+		seen[credential.Type] = true
+	}
+
+	return dob, licenseExpiry, nil
+}
+
+// NewDriverUpdate validates the fields a driver update replaces
+func NewDriverUpdate(
+	firstName,
+	lastName,
+	dateOfBirth,
+	licenseNumber,
+	licenseState,
+	licenseExpiration,
+	phoneNumber,
+	email string,
+	address Address,
+	employmentStatus EmploymentStatus,
+	credentials []Credential) (*Driver, error) {
+
+	validEmail, err := NewEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	validPhone, err := NewPhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	dob, licenseExpiry, err := validateDriverLicense(dateOfBirth, licenseNumber, licenseState, licenseExpiration, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Driver{
+		FirstName:         firstName,
+		LastName:          lastName,
+		DOB:               dob,
+		LicenseNumber:     licenseNumber,
+		LicenseState:      licenseState,
+		LicenseExpiration: licenseExpiry,
+		Credentials:       credentials,
+		Phone:             validPhone,
+		Email:             validEmail,
+		Address:           address,
+		EmploymentStatus:  employmentStatus,
+	}, nil
+}
+
 type DriverFilter struct {
-	UserID           primitive.ObjectID
+	OrganizationID   primitive.ObjectID
 	LicenseState     string
 	Phone            PhoneNumber
 	Email            Email
@@ -191,3 +284,12 @@ func (d *Driver) ActivateDriver() error {
 
 	return nil
 }
+
+// only active drivers can be sent out on a trip
+func (d *Driver) EnsureDispatchable() error {
+	if d.EmploymentStatus != EmploymentStatusActive {
+		return &DriverStateError{CurrentState: d.EmploymentStatus}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EmailTokenPurpose string
+
+const (
+	EmailTokenVerifyEmail   EmailTokenPurpose = "verify_email"
+	EmailTokenResetPassword EmailTokenPurpose = "reset_password"
+)
+
+// TokenUseAccess is the token_use claim of access tokens. email tokens are signed with the same keys, so the
+// claim is what stops a link from an email being presented as an access token.
+const TokenUseAccess = "access"
+
+// TokenIssuer is the iss claim of every token we sign
+const TokenIssuer = "waybill"
+
+// TokenAudience is the aud claim of a token with the given token_use. the keys are published for other services to
+// verify access tokens with, and one that checks the audience can't be handed an email link or a login challenge
+// in place of an access token, even if it knows nothing about token_use.
+func TokenAudience(tokenUse string) string {
+	return TokenIssuer + "/" + tokenUse
+}
+
+// an EmailToken records a signed token sent in an email. the token itself carries the record's id, and the record
+// is what makes it single use: it's spent when the token is redeemed, or when a newer token for the same purpose
+// replaces it.
+type EmailToken struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Purpose   EmailTokenPurpose   `bson:"purpose" json:"purpose"`
+	ExpiresAt primitive.DateTime  `bson:"expires_at" json:"expires_at"`
+	UsedAt    *primitive.DateTime `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	CreatedAt primitive.DateTime  `bson:"created_at" json:"created_at"`
+}
+
+// NewEmailToken picks the id up front, since it has to be in the signed token before the record is stored
+func NewEmailToken(userID primitive.ObjectID, purpose EmailTokenPurpose, ttl time.Duration) *EmailToken {
+	now := time.Now()
+
+	return &EmailToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	}
+}
+
+func (t *EmailToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt.Time())
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
@@ -3,15 +3,38 @@ package domain
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var ErrAPIKeyNotFound = errors.New("api key not found")
+var ErrEmailTokenNotFound = errors.New("email token not found")
 var ErrDriverNotFound = errors.New("driver not found")
 var ErrFacilityNotFound = errors.New("facility not found")
 var ErrFuelLogNotFound = errors.New("fuel log not found")
 var ErrIncidentReportNotFound = errors.New("incident report not found")
 var ErrMaintenanceLogNotFound = errors.New("maintenance log not found")
+var ErrMaintenanceScheduleNotFound = errors.New("maintenance schedule not found")
+var ErrMembershipNotFound = errors.New("membership not found")
+var ErrOrganizationNotFound = errors.New("organization not found")
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
 var ErrTripNotFound = errors.New("trip not found")
+var ErrTripStopNotFound = errors.New("trip stop not found")
 var ErrTruckNotFound = errors.New("truck not found")
+var ErrUserNotFound = errors.New("user not found")
+var ErrWorkOrderNotFound = errors.New("work order not found")
+
+var ErrUserExists = errors.New("user already exists")
+var ErrMembershipExists = errors.New("user is already a member of this organization")
+var ErrLastOwner = errors.New("an organization must keep at least one owner")
+var ErrDutyStatusOutOfOrder = errors.New("a duty status change can't start before the driver's latest one")
+var ErrWorkOrderClosed = errors.New("a completed or canceled work order can't be changed")
+var ErrWorkOrderActive = errors.New("a work order that has been started has to be completed or canceled before it can be deleted")
+var ErrWorkOrderCompletedEarly = errors.New("a work order can't be completed before it was started")
+var ErrTripInTransit = errors.New("a trip that is under way has to be completed before it can be deleted")
+var ErrFuelLogTripMismatch = errors.New("a fuel log's truck and driver have to match the trip it was bought on")
 
 type TripStateError struct {
 	CurrentState TripStatus
@@ -30,3 +53,145 @@ type TruckStateError struct {
 func (e *TruckStateError) Error() string {
 	return fmt.Sprintf("invalid state transition from %s to %s", e.CurrentState, e.DesiredState)
 }
+
+// TruckOnTripError is returned when a truck's status is changed by hand while a trip is relying on it
+type TruckOnTripError struct {
+	TruckID    primitive.ObjectID
+	TripID     primitive.ObjectID
+	TripStatus TripStatus
+}
+
+func (e *TruckOnTripError) Error() string {
+	return fmt.Sprintf("truck is held by %s trip %s, which moves it in and out of transit", strings.ToLower(strings.ReplaceAll(string(e.TripStatus), "_", " ")), e.TripID.Hex())
+}
+
+type WorkOrderStateError struct {
+	CurrentState WorkOrderStatus
+	DesiredState WorkOrderStatus
+}
+
+func (e *WorkOrderStateError) Error() string {
+	return fmt.Sprintf("invalid state transition from %s to %s", e.CurrentState, e.DesiredState)
+}
+
+type DriverStateError struct {
+	CurrentState EmploymentStatus
+}
+
+func (e *DriverStateError) Error() string {
+	return fmt.Sprintf("driver cannot be dispatched while %s", e.CurrentState)
+}
+
+// TripStopError is returned when a stop can't be worked yet, or when a trip can't be completed because one of its
+// stops hasn't been resolved
+type TripStopError struct {
+	StopID primitive.ObjectID
+	Reason string
+}
+
+func (e *TripStopError) Error() string {
+	return fmt.Sprintf("stop %s %s", e.StopID.Hex(), e.Reason)
+}
+
+// HOSLimitError is returned when a driver has used up one of their hours of service limits
+type HOSLimitError struct {
+	DriverID primitive.ObjectID
+	Limit    string
+}
+
+func (e *HOSLimitError) Error() string {
+	return fmt.Sprintf("driver has no driving time left under the hours of service %s limit", e.Limit)
+}
+
+// CredentialExpiredError is returned when a driver's license or other credential won't be valid when it's needed
+type CredentialExpiredError struct {
+	DriverID   primitive.ObjectID
+	Credential CredentialType
+	ExpiresOn  primitive.DateTime
+}
+
+// a zero ExpiresOn means there's no expiration on record, which can't be shown to be current
+func (e *CredentialExpiredError) Error() string {
+	credential := strings.ToLower(strings.ReplaceAll(string(e.Credential), "_", " "))
+	if e.ExpiresOn == 0 {
+		return fmt.Sprintf("driver's %s has no expiration date on record", credential)
+	}
+
+	return fmt.Sprintf("driver's %s expired on %s", credential, FormatDate(e.ExpiresOn))
+}
+
+// HazmatComplianceError lists everything keeping a trip from carrying its hazardous cargo
+type HazmatComplianceError struct {
+	Violations []HazmatViolation
+}
+
+func (e *HazmatComplianceError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+
+	return fmt.Sprintf("trip fails %d hazmat check(s): %s", len(e.Violations), strings.Join(messages, "; "))
+}
+
+// MaintenanceOverdueError is returned when a truck is past due on safety-critical maintenance
+type MaintenanceOverdueError struct {
+	TruckID   primitive.ObjectID
+	Schedules []string
+}
+
+func (e *MaintenanceOverdueError) Error() string {
+	return fmt.Sprintf("truck is overdue on safety-critical maintenance: %s", strings.Join(e.Schedules, ", "))
+}
+
+// OdometerRollbackError is returned when a reading would take a truck's odometer below one already recorded
+type OdometerRollbackError struct {
+	TruckID primitive.ObjectID
+	Reading int
+	Minimum int
+}
+
+func (e *OdometerRollbackError) Error() string {
+	return fmt.Sprintf("odometer reading %d for truck %s is below its last reading of %d", e.Reading, e.TruckID.Hex(), e.Minimum)
+}
+
+// OdometerAheadError is returned when a reading dated before another is further along than that later one
+type OdometerAheadError struct {
+	TruckID primitive.ObjectID
+	Reading int
+	Maximum int
+}
+
+func (e *OdometerAheadError) Error() string {
+	return fmt.Sprintf("odometer reading %d for truck %s is past the reading of %d taken after it", e.Reading, e.TruckID.Hex(), e.Maximum)
+}
+
+// OdometerImplausibleError is returned when a reading is further along than the truck could have driven since the
+// one before it
+type OdometerImplausibleError struct {
+	TruckID      primitive.ObjectID
+	Reading      int
+	Previous     int
+	AllowedMiles int
+}
+
+func (e *OdometerImplausibleError) Error() string {
+	return fmt.Sprintf("odometer reading %d for truck %s is %d miles past its last reading of %d, more than the %d it could have covered since", e.Reading, e.TruckID.Hex(), e.Reading-e.Previous, e.Previous, e.AllowedMiles)
+}
+
+type ScheduleConflictError struct {
+	Conflicts []TripConflict
+}
+
+func (e *ScheduleConflictError) Error() string {
+	return fmt.Sprintf("trip conflicts with %d existing driver or truck assignment(s)", len(e.Conflicts))
+}
+
+// LoginThrottledError is returned for every login attempt made before RetryAt, right password or not
+type LoginThrottledError struct {
+	RetryAt time.Time
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "too many failed login attempts, try again later"
+}
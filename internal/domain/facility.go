@@ -29,16 +29,24 @@ func (f FacilityService) IsValid() bool {
 
 type Facility struct {
 	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID    primitive.ObjectID `bson:"organization_id" json:"organization_id"`
 	UserID            primitive.ObjectID `bson:"user_id" json:"user_id"`
 	FacilityNumber    string             `bson:"facility_number" json:"facility_number"`
 	Name              string             `bson:"name" json:"name"`
 	Type              string             `bson:"type" json:"type"`
 	Address           Address            `bson:"address" json:"address"`
+	Location          *GeoPoint          `bson:"location,omitempty" json:"location,omitempty"`
+	Geofence          *Geofence          `bson:"geofence,omitempty" json:"geofence,omitempty"`
 	ContactInfo       ContactInfo        `bson:"contact_info" json:"contact_info"`
 	ParkingCapacity   int                `bson:"parking_capacity" json:"parking_capacity"`
 	ServicesAvailable []FacilityService  `bson:"services_available" json:"services_available"`
+	AcceptsHazmat     bool               `bson:"accepts_hazmat" json:"accepts_hazmat"`
+	HazardClasses     []HazardClass      `bson:"hazard_classes,omitempty" json:"hazard_classes,omitempty"`
 	CreatedAt         primitive.DateTime `bson:"created_at" json:"created_at"`
 	UpdatedAt         primitive.DateTime `bson:"updated_at" json:"updated_at"`
+
+	// only set on facilities found with a proximity search
+	DistanceMiles *float64 `bson:"distance_miles,omitempty" json:"distance_miles,omitempty"`
 }
 
 type ContactInfo struct {
@@ -47,6 +55,7 @@ type ContactInfo struct {
 }
 
 func NewFacility(
+	organizationID,
 	userID primitive.ObjectID,
 	facilityNumber string,
 	name string,
@@ -64,6 +73,7 @@ func NewFacility(
 	}
 
 	return &Facility{
+		OrganizationID:    organizationID,
 		UserID:            userID,
 		FacilityNumber:    facilityNumber,
 		Name:              name,
@@ -78,23 +88,29 @@ func NewFacility(
 }
 
 type FacilityFilter struct {
-	UserID          primitive.ObjectID
+	OrganizationID  primitive.ObjectID
 	StateCode       string
 	Type            string
 	ServicesInclude []FacilityService
 	MinCapacity     *int
 	MaxCapacity     *int
-	Limit           int64
-	Offset          int64
+
+	// Near limits the results to facilities within RadiusMiles of a point, closest first. a zero radius
+	// doesn't limit the distance.
+	Near        *GeoPoint
+	RadiusMiles float64
+
+	Limit  int64
+	Offset int64
 }
 
 // we're going to set a limit of 10 and an offset of 0 by default, but the actual values will be set by the query params
 // in the handler. If we want to add any other defaults, we can do so here. Stuff like only showing active facilities, etc.
 func NewFacilityFilter() FacilityFilter {
 	return FacilityFilter{
-		Limit:  10,
-		Offset: 0,
-		UserID: primitive.NilObjectID,
+		Limit:          10,
+		Offset:         0,
+		OrganizationID: primitive.NilObjectID,
 	}
 }
 
@@ -0,0 +1,319 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FuelAnalyticsDefaultDays is how far back fuel analytics look when no start date is given
+const FuelAnalyticsDefaultDays = 30
+
+type FuelGrouping string
+
+const (
+	FuelGroupingTruck       FuelGrouping = "TRUCK"
+	FuelGroupingDriver      FuelGrouping = "DRIVER"
+	FuelGroupingFuelType    FuelGrouping = "FUEL_TYPE"
+	FuelGroupingTrailerType FuelGrouping = "TRAILER_TYPE"
+	FuelGroupingLane        FuelGrouping = "LANE"
+)
+
+func (g FuelGrouping) IsValid() bool {
+	switch g {
+	case FuelGroupingTruck,
+		FuelGroupingDriver,
+		FuelGroupingFuelType,
+		FuelGroupingTrailerType,
+		FuelGroupingLane:
+		return true
+	}
+	return false
+}
+
+// a FuelAnalyticsFilter picks out the organization's records from From up to, but not including, To
+type FuelAnalyticsFilter struct {
+	OrganizationID primitive.ObjectID
+	GroupBy        FuelGrouping
+	From           primitive.DateTime
+	To             primitive.DateTime
+}
+
+// NewFuelAnalyticsFilter covers from through to, both days included. either end can be left off: to defaults to
+// today, and from to FuelAnalyticsDefaultDays before to.
+func NewFuelAnalyticsFilter(organizationID primitive.ObjectID, groupBy FuelGrouping, from, to string) (FuelAnalyticsFilter, error) {
+	if groupBy != "" && !groupBy.IsValid() {
+		return FuelAnalyticsFilter{}, fmt.Errorf("invalid grouping: %s", groupBy)
+	}
+
+	last := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		parsed, err := ParseDate("to", to)
+		if err != nil {
+			return FuelAnalyticsFilter{}, err
+		}
+		last = parsed.Time().UTC()
+	}
+
+	first := last.AddDate(0, 0, -FuelAnalyticsDefaultDays)
+	if from != "" {
+		parsed, err := ParseDate("from", from)
+		if err != nil {
+			return FuelAnalyticsFilter{}, err
+		}
+		first = parsed.Time().UTC()
+	}
+
+	if first.After(last) {
+		return FuelAnalyticsFilter{}, fmt.Errorf("from can't be after to")
+	}
+
+	return FuelAnalyticsFilter{
+		OrganizationID: organizationID,
+		GroupBy:        groupBy,
+		From:           primitive.NewDateTimeFromTime(first),
+		To:             primitive.NewDateTimeFromTime(last.AddDate(0, 0, 1)),
+	}, nil
+}
+
+// a FuelEfficiency sums up the completed trips in one group. only the fields identifying the group are set: the
+// truck for TRUCK, the driver for DRIVER, and so on, with both facilities for LANE.
+//
+// MPG only counts the miles of trips whose fuel is known, and CostPerMile only those whose fuel was paid for, so
+// trips missing either don't drag the figures down. each is nil when no trip in the group has what it needs.
+type FuelEfficiency struct {
+	TruckID           *primitive.ObjectID `bson:"truck_id,omitempty"`
+	TruckNumber       string              `bson:"truck_number,omitempty"`
+	DriverID          *primitive.ObjectID `bson:"driver_id,omitempty"`
+	DriverName        string              `bson:"driver_name,omitempty"`
+	FuelType          FuelType            `bson:"fuel_type,omitempty"`
+	TrailerType       TrailerType         `bson:"trailer_type,omitempty"`
+	StartFacilityID   *primitive.ObjectID `bson:"start_facility_id,omitempty"`
+	StartFacilityName string              `bson:"start_facility_name,omitempty"`
+	EndFacilityID     *primitive.ObjectID `bson:"end_facility_id,omitempty"`
+	EndFacilityName   string              `bson:"end_facility_name,omitempty"`
+	Trips             int                 `bson:"trips"`
+	Miles             int                 `bson:"miles"`
+	Gallons           float64             `bson:"gallons"`
+	FuelCost          float64             `bson:"fuel_cost"`
+	MPG               *float64            `bson:"mpg"`
+	CostPerMile       *float64            `bson:"cost_per_mile"`
+
+	fueledMiles int
+	costedMiles int
+}
+
+// TripFuel is the fuel a trip burned and what was paid for it. the trip's own fuel usage is used when it was
+// recorded, and otherwise what was bought on the trip.
+func TripFuel(trip *Trip, fuelLogs []*FuelLog) (gallons, cost float64) {
+	var purchased float64
+	for _, fuelLog := range fuelLogs {
+		purchased += fuelLog.GallonsPurchased
+		cost += fuelLog.TotalCost
+	}
+
+	if trip.FuelUsage > 0 {
+		return trip.FuelUsage, cost
+	}
+
+	return purchased, cost
+}
+
+// AddTrip counts one completed trip toward the group. ComputeRatios has to be called once every trip is in.
+func (e *FuelEfficiency) AddTrip(miles int, gallons, cost float64) {
+	e.Trips++
+	e.Miles += miles
+	e.Gallons += gallons
+	e.FuelCost += cost
+
+	if gallons > 0 {
+		e.fueledMiles += miles
+	}
+	if cost > 0 {
+		e.costedMiles += miles
+	}
+}
+
+func (e *FuelEfficiency) ComputeRatios() {
+	e.MPG = nil
+	e.CostPerMile = nil
+
+	if e.Gallons > 0 {
+		mpg := roundTo(float64(e.fueledMiles)/e.Gallons, 2)
+		e.MPG = &mpg
+	}
+	if e.costedMiles > 0 {
+		costPerMile := roundTo(e.FuelCost/float64(e.costedMiles), 2)
+		e.CostPerMile = &costPerMile
+	}
+}
+
+// SortFuelEfficiencies puts the groups that covered the most ground first
+func SortFuelEfficiencies(efficiencies []FuelEfficiency) {
+	sort.SliceStable(efficiencies, func(i, j int) bool {
+		if efficiencies[i].Miles != efficiencies[j].Miles {
+			return efficiencies[i].Miles > efficiencies[j].Miles
+		}
+		return efficiencies[i].Trips > efficiencies[j].Trips
+	})
+}
+
+const (
+	// FuelOutlierScore is how many robust deviations from the typical fill-up a fill-up can be before it's
+	// suspicious. 3.5 is the usual cutoff for a modified z-score.
+	FuelOutlierScore = 3.5
+	// FuelOutlierMinimumFillUps is how many fill-ups it takes to say what a typical one looks like
+	FuelOutlierMinimumFillUps = 5
+	// MinPlausibleMPG and MaxPlausibleMPG bound the mileage a truck can get between fill-ups. buying more fuel than
+	// the miles could have burned, or far less, means the gallons or the odometer are wrong.
+	MinPlausibleMPG = 2.0
+	MaxPlausibleMPG = 15.0
+)
+
+type FuelOutlierReason string
+
+const (
+	FuelOutlierCostMismatch   FuelOutlierReason = "COST_MISMATCH"
+	FuelOutlierUnusualVolume  FuelOutlierReason = "UNUSUAL_VOLUME"
+	FuelOutlierUnusualPrice   FuelOutlierReason = "UNUSUAL_PRICE"
+	FuelOutlierImplausibleMPG FuelOutlierReason = "IMPLAUSIBLE_MPG"
+)
+
+// a FuelFillUp is a fuel log along with the truck it went into and the odometer reading at the truck's fill-up
+// before it
+type FuelFillUp struct {
+	FuelLogID               primitive.ObjectID  `bson:"_id"`
+	TripID                  *primitive.ObjectID `bson:"trip_id,omitempty"`
+	TruckID                 primitive.ObjectID  `bson:"truck_id"`
+	TruckNumber             string              `bson:"truck_number"`
+	Date                    primitive.DateTime  `bson:"date"`
+	Location                string              `bson:"location"`
+	GallonsPurchased        float64             `bson:"gallons_purchased"`
+	PricePerGallon          float64             `bson:"price_per_gallon"`
+	TotalCost               float64             `bson:"total_cost"`
+	OdometerReading         int                 `bson:"odometer_reading"`
+	PreviousOdometerReading *int                `bson:"previous_odometer_reading,omitempty"`
+}
+
+// a FuelOutlier is a suspicious fill-up, with what made it suspicious and what a typical fill-up looks like for
+// comparison
+type FuelOutlier struct {
+	FillUp             *FuelFillUp
+	Reasons            []FuelOutlierReason
+	MilesSincePrevious *int
+	MPG                *float64
+	TypicalGallons     *float64
+	TypicalPrice       *float64
+}
+
+// FindFuelOutliers flags fill-ups whose cost doesn't add up, whose volume is far from the truck's typical fill-up,
+// whose price is far from what the organization typically pays, or whose gallons don't fit the miles driven since
+// the truck's previous fill-up
+func FindFuelOutliers(fillUps []*FuelFillUp) []FuelOutlier {
+	outliers := make([]FuelOutlier, 0)
+
+	prices := make([]float64, 0, len(fillUps))
+	gallonsByTruck := make(map[primitive.ObjectID][]float64)
+	for _, fillUp := range fillUps {
+		prices = append(prices, fillUp.PricePerGallon)
+		gallonsByTruck[fillUp.TruckID] = append(gallonsByTruck[fillUp.TruckID], fillUp.GallonsPurchased)
+	}
+	typicalPrice, priceScale := robustBaseline(prices)
+
+	for _, fillUp := range fillUps {
+		outlier := FuelOutlier{FillUp: fillUp}
+
+		expected := fillUp.GallonsPurchased * fillUp.PricePerGallon
+		if math.Abs(fillUp.TotalCost-expected) > math.Max(1, 0.02*expected) {
+			outlier.Reasons = append(outlier.Reasons, FuelOutlierCostMismatch)
+		}
+
+		if typicalGallons, scale := robustBaseline(gallonsByTruck[fillUp.TruckID]); typicalGallons != nil {
+			outlier.TypicalGallons = typicalGallons
+			if outlierScore(fillUp.GallonsPurchased, *typicalGallons, scale) > FuelOutlierScore {
+				outlier.Reasons = append(outlier.Reasons, FuelOutlierUnusualVolume)
+			}
+		}
+
+		if typicalPrice != nil {
+			outlier.TypicalPrice = typicalPrice
+			if outlierScore(fillUp.PricePerGallon, *typicalPrice, priceScale) > FuelOutlierScore {
+				outlier.Reasons = append(outlier.Reasons, FuelOutlierUnusualPrice)
+			}
+		}
+
+		// a reading that went backward is the odometer report's to flag, not this one's
+		if fillUp.PreviousOdometerReading != nil && fillUp.GallonsPurchased > 0 {
+			miles := fillUp.OdometerReading - *fillUp.PreviousOdometerReading
+			if miles > 0 {
+				mpg := roundTo(float64(miles)/fillUp.GallonsPurchased, 2)
+				outlier.MilesSincePrevious = &miles
+				outlier.MPG = &mpg
+				if mpg < MinPlausibleMPG || mpg > MaxPlausibleMPG {
+					outlier.Reasons = append(outlier.Reasons, FuelOutlierImplausibleMPG)
+				}
+			}
+		}
+
+		if len(outlier.Reasons) > 0 {
+			outliers = append(outliers, outlier)
+		}
+	}
+
+	sort.SliceStable(outliers, func(i, j int) bool {
+		if outliers[i].FillUp.TruckNumber != outliers[j].FillUp.TruckNumber {
+			return outliers[i].FillUp.TruckNumber < outliers[j].FillUp.TruckNumber
+		}
+		return outliers[i].FillUp.Date < outliers[j].FillUp.Date
+	})
+
+	return outliers
+}
+
+// robustBaseline returns the median of the values and their median absolute deviation, which unlike a mean and
+// standard deviation aren't pulled toward the outliers being looked for. the deviation is kept to at least 5% of
+// the median so that a run of identical fill-ups doesn't make every small difference look suspicious. there's no
+// baseline until there are FuelOutlierMinimumFillUps values.
+func robustBaseline(values []float64) (*float64, float64) {
+	if len(values) < FuelOutlierMinimumFillUps {
+		return nil, 0
+	}
+
+	median := medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, value := range values {
+		deviations[i] = math.Abs(value - median)
+	}
+
+	return &median, math.Max(medianOf(deviations), 0.05*median)
+}
+
+// outlierScore is the modified z-score of a value against a median and median absolute deviation
+func outlierScore(value, median, deviation float64) float64 {
+	if deviation == 0 {
+		return 0
+	}
+
+	return 0.6745 * math.Abs(value-median) / deviation
+}
+
+func medianOf(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
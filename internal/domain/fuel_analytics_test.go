@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFindFuelOutliers(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	truckA, truckB := primitive.NewObjectID(), primitive.NewObjectID()
+
+	// fill-ups are named by their location so the outliers can be told apart
+	fillUp := func(name string, truckID primitive.ObjectID, day int, gallons, price, total float64) *FuelFillUp {
+		number := "A"
+		if truckID == truckB {
+			number = "B"
+		}
+		return &FuelFillUp{
+			FuelLogID:        primitive.NewObjectID(),
+			TruckID:          truckID,
+			TruckNumber:      number,
+			Date:             primitive.NewDateTimeFromTime(start.AddDate(0, 0, day)),
+			Location:         name,
+			GallonsPurchased: gallons,
+			PricePerGallon:   price,
+			TotalCost:        total,
+		}
+	}
+	driven := func(f *FuelFillUp, previous, reading int) *FuelFillUp {
+		f.PreviousOdometerReading = &previous
+		f.OdometerReading = reading
+		return f
+	}
+
+	type flagged struct {
+		name    string
+		reasons []FuelOutlierReason
+	}
+
+	tests := []struct {
+		name    string
+		fillUps []*FuelFillUp
+		want    []flagged
+	}{
+		{
+			name:    "nothing to look at",
+			fillUps: nil,
+		},
+		{
+			name: "cost within rounding",
+			fillUps: []*FuelFillUp{
+				fillUp("a", truckA, 0, 100, 4, 400.9),
+				fillUp("b", truckA, 1, 10, 4, 40.9),
+			},
+		},
+		{
+			name: "cost that doesn't add up",
+			fillUps: []*FuelFillUp{
+				fillUp("a", truckA, 0, 100, 4, 390),
+				fillUp("b", truckA, 1, 10, 4, 38.5),
+			},
+			want: []flagged{
+				{"a", []FuelOutlierReason{FuelOutlierCostMismatch}},
+				{"b", []FuelOutlierReason{FuelOutlierCostMismatch}},
+			},
+		},
+		{
+			name: "too few fill-ups to say what's typical",
+			fillUps: []*FuelFillUp{
+				fillUp("a", truckA, 0, 100, 4, 400),
+				fillUp("b", truckA, 1, 100, 4, 400),
+				fillUp("c", truckA, 2, 100, 4, 400),
+				fillUp("d", truckA, 3, 400, 9, 3600),
+			},
+		},
+		{
+			name: "unusual volume for the truck",
+			fillUps: []*FuelFillUp{
+				fillUp("a", truckA, 0, 100, 4, 400),
+				fillUp("b", truckA, 1, 102, 4, 408),
+				fillUp("c", truckA, 2, 98, 4, 392),
+				fillUp("d", truckA, 3, 101, 4, 404),
+				fillUp("e", truckA, 4, 300, 4, 1200),
+			},
+			want: []flagged{
+				{"e", []FuelOutlierReason{FuelOutlierUnusualVolume}},
+			},
+		},
+		{
+			name: "unusual price across the organization",
+			fillUps: []*FuelFillUp{
+				fillUp("a", truckA, 0, 100, 4, 400),
+				fillUp("b", truckA, 1, 100, 4.1, 410),
+				fillUp("c", truckB, 2, 100, 3.9, 390),
+				fillUp("d", truckB, 3, 100, 4, 400),
+				fillUp("e", truckB, 4, 100, 8, 800),
+			},
+			want: []flagged{
+				{"e", []FuelOutlierReason{FuelOutlierUnusualPrice}},
+			},
+		},
+		{
+			name: "mileage since the last fill-up",
+			fillUps: []*FuelFillUp{
+				driven(fillUp("plausible", truckA, 0, 100, 4, 400), 1_000, 1_600),
+				driven(fillUp("too little", truckA, 1, 100, 4, 400), 1_600, 1_700),
+				driven(fillUp("too much", truckA, 2, 100, 4, 400), 1_700, 3_300),
+				driven(fillUp("went backward", truckA, 3, 100, 4, 400), 3_300, 3_200),
+			},
+			want: []flagged{
+				{"too little", []FuelOutlierReason{FuelOutlierImplausibleMPG}},
+				{"too much", []FuelOutlierReason{FuelOutlierImplausibleMPG}},
+			},
+		},
+		{
+			name: "every reason on one fill-up",
+			fillUps: []*FuelFillUp{
+				fillUp("a", truckA, 0, 100, 4, 400),
+				fillUp("b", truckA, 1, 100, 4, 400),
+				fillUp("c", truckA, 2, 100, 4, 400),
+				fillUp("d", truckA, 3, 100, 4, 400),
+				driven(fillUp("e", truckA, 4, 500, 9, 100), 1_000, 1_100),
+			},
+			want: []flagged{
+				{"e", []FuelOutlierReason{FuelOutlierCostMismatch, FuelOutlierUnusualVolume, FuelOutlierUnusualPrice, FuelOutlierImplausibleMPG}},
+			},
+		},
+		{
+			name: "ordered by truck then date",
+			fillUps: []*FuelFillUp{
+				fillUp("B later", truckB, 5, 10, 4, 1),
+				fillUp("A later", truckA, 4, 10, 4, 1),
+				fillUp("B earlier", truckB, 1, 10, 4, 1),
+				fillUp("A earlier", truckA, 2, 10, 4, 1),
+			},
+			want: []flagged{
+				{"A earlier", []FuelOutlierReason{FuelOutlierCostMismatch}},
+				{"A later", []FuelOutlierReason{FuelOutlierCostMismatch}},
+				{"B earlier", []FuelOutlierReason{FuelOutlierCostMismatch}},
+				{"B later", []FuelOutlierReason{FuelOutlierCostMismatch}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]flagged, 0)
+			for _, outlier := range FindFuelOutliers(tt.fillUps) {
+				got = append(got, flagged{outlier.FillUp.Location, outlier.Reasons})
+			}
+			if tt.want == nil {
+				tt.want = []flagged{}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindFuelOutliers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindFuelOutliersMileage(t *testing.T) {
+	previous := 1_000
+	fillUp := &FuelFillUp{
+		FuelLogID:               primitive.NewObjectID(),
+		TruckID:                 primitive.NewObjectID(),
+		GallonsPurchased:        60,
+		PricePerGallon:          4,
+		TotalCost:               240,
+		OdometerReading:         1_090,
+		PreviousOdometerReading: &previous,
+	}
+
+	outliers := FindFuelOutliers([]*FuelFillUp{fillUp})
+	if len(outliers) != 1 {
+		t.Fatalf("FindFuelOutliers() flagged %d fill-ups, want 1", len(outliers))
+	}
+
+	outlier := outliers[0]
+	if outlier.MilesSincePrevious == nil || *outlier.MilesSincePrevious != 90 {
+		t.Errorf("MilesSincePrevious = %v, want 90", outlier.MilesSincePrevious)
+	}
+	if outlier.MPG == nil || *outlier.MPG != 1.5 {
+		t.Errorf("MPG = %v, want 1.5", outlier.MPG)
+	}
+	if outlier.TypicalGallons != nil || outlier.TypicalPrice != nil {
+		t.Errorf("a single fill-up shouldn't have a typical volume or price")
+	}
+}
@@ -1,16 +1,27 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// a FuelLog is fuel bought for one of the organization's trucks, optionally by one of its drivers, at one of its
+// facilities, or on one of its trips
 type FuelLog struct {
 	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID   primitive.ObjectID  `bson:"organization_id" json:"organization_id"`
+	UserID           primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	TruckID          primitive.ObjectID  `bson:"truck_id" json:"truck_id"`
+	Truck            *Truck              `bson:"truck,omitempty" json:"truck,omitempty"`
+	DriverID         *primitive.ObjectID `bson:"driver_id,omitempty" json:"driver_id,omitempty"`
+	Driver           *Driver             `bson:"driver,omitempty" json:"driver,omitempty"`
+	FacilityID       *primitive.ObjectID `bson:"facility_id,omitempty" json:"facility_id,omitempty"`
+	Facility         *Facility           `bson:"facility,omitempty" json:"facility,omitempty"`
 	TripID           *primitive.ObjectID `bson:"trip_id,omitempty" json:"trip_id,omitempty"`
 	Trip             *Trip               `bson:"trip,omitempty" json:"trip,omitempty"`
-	Date             string              `bson:"date" json:"date"`
+	Date             primitive.DateTime  `bson:"date" json:"date"`
 	GallonsPurchased float64             `bson:"gallons_purchased" json:"gallons_purchased"`
 	PricePerGallon   float64             `bson:"price_per_gallon" json:"price_per_gallon"`
 	TotalCost        float64             `bson:"total_cost" json:"total_cost"`
@@ -21,6 +32,11 @@ type FuelLog struct {
 }
 
 func NewFuelLog(
+	organizationID,
+	userID,
+	truckID primitive.ObjectID,
+	driverID,
+	facilityID,
 	tripId *primitive.ObjectID,
 	date,
 	location string,
@@ -28,11 +44,32 @@ func NewFuelLog(
 	pricePerGallon,
 	totalCost float64,
 	odometerReading int) (*FuelLog, error) {
+	if truckID.IsZero() {
+		return nil, fmt.Errorf("truck_id is required")
+	}
+
+	purchased, err := ParseDate("date", date)
+	if err != nil {
+		return nil, err
+	}
+	if purchased.Time().After(time.Now()) {
+		return nil, fmt.Errorf("date can't be in the future")
+	}
+
+	if odometerReading < 0 {
+		return nil, fmt.Errorf("odometer_reading can't be negative")
+	}
+
 	now := time.Now()
 
 	return &FuelLog{
+		OrganizationID:   organizationID,
+		UserID:           userID,
+		TruckID:          truckID,
+		DriverID:         driverID,
+		FacilityID:       facilityID,
 		TripID:           tripId,
-		Date:             date,
+		Date:             purchased,
 		GallonsPurchased: gallonsPurchased,
 		PricePerGallon:   pricePerGallon,
 		TotalCost:        totalCost,
@@ -43,10 +80,38 @@ func NewFuelLog(
 	}, nil
 }
 
+// MatchTrip checks the fuel log against the trip it was bought on. the trip's truck and driver are the ones its fuel
+// was bought for and by, so a log naming others is refused, and one that leaves the driver off is given the trip's.
+func (f *FuelLog) MatchTrip(trip *Trip) error {
+	if trip.TruckID != nil && *trip.TruckID != f.TruckID {
+		return ErrFuelLogTripMismatch
+	}
+
+	if trip.DriverID != nil {
+		if f.DriverID == nil {
+			driverID := *trip.DriverID
+			f.DriverID = &driverID
+		} else if *f.DriverID != *trip.DriverID {
+			return ErrFuelLogTripMismatch
+		}
+	}
+
+	return nil
+}
+
+// a FuelLogFilter's date range runs from From up to, but not including, To. Location matches any part of the
+// location written on the log, ignoring case.
 type FuelLogFilter struct {
-	TripID *primitive.ObjectID
-	Limit  int64
-	Offset int64
+	OrganizationID primitive.ObjectID
+	TripID         *primitive.ObjectID
+	TruckID        *primitive.ObjectID
+	DriverID       *primitive.ObjectID
+	FacilityID     *primitive.ObjectID
+	From           *primitive.DateTime
+	To             *primitive.DateTime
+	Location       string
+	Limit          int64
+	Offset         int64
 }
 
 func NewFuelLogFilter() FuelLogFilter {
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	geoJSONPoint = "Point"
+
+	// mean radius of the earth, which is close enough for routing distances
+	EarthRadiusMiles = 3958.8
+	MetersPerMile    = 1609.344
+)
+
+// a GeoPoint is a GeoJSON point, which is what mongo's 2dsphere index expects. GeoJSON puts the longitude first.
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+func NewGeoPoint(lat, lng float64) (*GeoPoint, error) {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if math.IsNaN(lng) || lng < -180 || lng > 180 {
+		return nil, fmt.Errorf("longitude must be between -180 and 180")
+	}
+
+	return &GeoPoint{Type: geoJSONPoint, Coordinates: []float64{lng, lat}}, nil
+}
+
+func (p *GeoPoint) Lat() float64 {
+	return p.Coordinates[1]
+}
+
+func (p *GeoPoint) Lng() float64 {
+	return p.Coordinates[0]
+}
+
+// DistanceMiles is the great-circle distance between two points
+func (p *GeoPoint) DistanceMiles(other *GeoPoint) float64 {
+	lat1, lat2 := radians(p.Lat()), radians(other.Lat())
+	dLat := lat2 - lat1
+	dLng := radians(other.Lng() - p.Lng())
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * EarthRadiusMiles * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+const geoJSONPolygon = "Polygon"
+
+// a GeoPolygon is a GeoJSON polygon with a single ring. the ring is closed, so its last point repeats the first.
+type GeoPolygon struct {
+	Type        string        `bson:"type" json:"type"`
+	Coordinates [][][]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+func NewGeoPolygon(vertices []*GeoPoint) (*GeoPolygon, error) {
+	if len(vertices) < 3 {
+		return nil, fmt.Errorf("a polygon needs at least 3 points")
+	}
+
+	ring := make([][]float64, 0, len(vertices)+1)
+	for _, vertex := range vertices {
+		ring = append(ring, []float64{vertex.Lng(), vertex.Lat()})
+	}
+
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, []float64{first[0], first[1]})
+	}
+	if len(ring) < 4 {
+		return nil, fmt.Errorf("a polygon needs at least 3 distinct points")
+	}
+
+	return &GeoPolygon{Type: geoJSONPolygon, Coordinates: [][][]float64{ring}}, nil
+}
+
+// Contains casts a ray from the point and counts how many edges it crosses. edges are treated as straight lines
+// on the map, which is indistinguishable from the great-circle path at the size of a yard or a depot.
+func (p *GeoPolygon) Contains(point *GeoPoint) bool {
+	if len(p.Coordinates) == 0 {
+		return false
+	}
+
+	ring := p.Coordinates[0]
+	x, y := point.Lng(), point.Lat()
+	inside := false
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
@@ -0,0 +1,309 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GeofenceType string
+
+const (
+	GeofenceTypeRadius  GeofenceType = "RADIUS"
+	GeofenceTypePolygon GeofenceType = "POLYGON"
+)
+
+func (t GeofenceType) IsValid() bool {
+	switch t {
+	case GeofenceTypeRadius, GeofenceTypePolygon:
+		return true
+	}
+	return false
+}
+
+// a Geofence is the boundary of a facility's grounds. a radius fence is centered on the facility's location; a
+// polygon fence traces the lot itself, for facilities whose gate is nowhere near the middle of their address.
+type Geofence struct {
+	Type        GeofenceType `bson:"type" json:"type"`
+	RadiusMiles float64      `bson:"radius_miles,omitempty" json:"radius_miles,omitempty"`
+	Boundary    *GeoPolygon  `bson:"boundary,omitempty" json:"boundary,omitempty"`
+
+	// overrides the organization's default rules for trips starting or ending here
+	Rules *GeofenceRules `bson:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// GeofenceRules say which trip transitions a facility's geofence is allowed to make on its own
+type GeofenceRules struct {
+	BeginOnExit       bool `bson:"begin_on_exit" json:"begin_on_exit"`
+	ArriveOnEntry     bool `bson:"arrive_on_entry" json:"arrive_on_entry"`
+	CompleteOnArrival bool `bson:"complete_on_arrival" json:"complete_on_arrival"`
+}
+
+// GeofencePolicy is what applies to facilities that don't say otherwise. facilities without a geofence of their own
+// get a circle of DefaultRadiusMiles around their location.
+type GeofencePolicy struct {
+	DefaultRadiusMiles float64
+	Rules              GeofenceRules
+
+	// a truck leaving the start facility only begins a trip scheduled to depart within this long of the exit, so
+	// a trip next week isn't started by today's run to the fuel stop
+	DepartureWindow time.Duration
+}
+
+func NewGeofence(fenceType GeofenceType, radiusMiles float64, boundary *GeoPolygon, rules *GeofenceRules) (*Geofence, error) {
+	switch fenceType {
+	case GeofenceTypeRadius:
+		if radiusMiles <= 0 {
+			return nil, fmt.Errorf("a radius geofence needs a positive radius_miles")
+		}
+		boundary = nil
+	case GeofenceTypePolygon:
+		if boundary == nil {
+			return nil, fmt.Errorf("a polygon geofence needs a boundary")
+		}
+		radiusMiles = 0
+	default:
+		return nil, fmt.Errorf("invalid geofence type: %s", fenceType)
+	}
+
+	return &Geofence{
+		Type:        fenceType,
+		RadiusMiles: radiusMiles,
+		Boundary:    boundary,
+		Rules:       rules,
+	}, nil
+}
+
+// GeofenceContains reports whether the point is inside the facility's fence. a radius fence around a facility that
+// hasn't been placed contains nothing.
+func (f *Facility) GeofenceContains(policy GeofencePolicy, point *GeoPoint) bool {
+	fence := f.Geofence
+	if fence == nil {
+		if policy.DefaultRadiusMiles <= 0 {
+			return false
+		}
+		fence = &Geofence{Type: GeofenceTypeRadius, RadiusMiles: policy.DefaultRadiusMiles}
+	}
+
+	switch fence.Type {
+	case GeofenceTypeRadius:
+		return f.Location != nil && f.Location.DistanceMiles(point) <= fence.RadiusMiles
+	case GeofenceTypePolygon:
+		return fence.Boundary != nil && fence.Boundary.Contains(point)
+	}
+
+	return false
+}
+
+func (f *Facility) GeofenceRules(policy GeofencePolicy) GeofenceRules {
+	if f.Geofence != nil && f.Geofence.Rules != nil {
+		return *f.Geofence.Rules
+	}
+
+	return policy.Rules
+}
+
+type LocationEventType string
+
+const (
+	LocationEventEnter LocationEventType = "ENTER"
+	LocationEventExit  LocationEventType = "EXIT"
+)
+
+func (t LocationEventType) IsValid() bool {
+	switch t {
+	case LocationEventEnter, LocationEventExit:
+		return true
+	}
+	return false
+}
+
+// a LocationEvent is a truck crossing a facility's geofence. they're worked out from position pings, or reported
+// directly by telematics units that do their own geofencing.
+type LocationEvent struct {
+	FacilityID primitive.ObjectID `json:"facility_id"`
+	Type       LocationEventType  `json:"type"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+func NewLocationEvent(facilityID primitive.ObjectID, eventType LocationEventType, occurredAt time.Time) (*LocationEvent, error) {
+	if facilityID.IsZero() {
+		return nil, fmt.Errorf("facility_id is required")
+	}
+	if !eventType.IsValid() {
+		return nil, fmt.Errorf("invalid location event type: %s", eventType)
+	}
+	if occurredAt.IsZero() {
+		return nil, fmt.Errorf("occurred_at is required")
+	}
+	if occurredAt.After(time.Now().Add(maxPositionClockSkew)) {
+		return nil, fmt.Errorf("location event can't occur in the future")
+	}
+
+	return &LocationEvent{FacilityID: facilityID, Type: eventType, OccurredAt: occurredAt}, nil
+}
+
+// GeofenceCrossings finds each time the truck's path went into or out of the facility's geofence. previous is the
+// last position known before these, if there is one, and positions must be in the order they were recorded. the
+// crossing is placed at the first ping on the new side of the fence.
+func (f *Facility) GeofenceCrossings(policy GeofencePolicy, previous *Position, positions []Position) []LocationEvent {
+	events := make([]LocationEvent, 0)
+
+	var wasInside *bool
+	if previous != nil {
+		inside := f.GeofenceContains(policy, &previous.Location)
+		wasInside = &inside
+	}
+
+	for i := range positions {
+		inside := f.GeofenceContains(policy, &positions[i].Location)
+		if wasInside != nil && inside != *wasInside {
+			eventType := LocationEventExit
+			if inside {
+				eventType = LocationEventEnter
+			}
+
+			events = append(events, LocationEvent{
+				FacilityID: f.ID,
+				Type:       eventType,
+				OccurredAt: positions[i].RecordedAt.Time(),
+			})
+		}
+		wasInside = &inside
+	}
+
+	return events
+}
+
+func SortLocationEvents(events []LocationEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].OccurredAt.Before(events[j].OccurredAt)
+	})
+}
+
+type TripAutomationAction string
+
+const (
+	TripAutomationBegin    TripAutomationAction = "BEGIN"
+	TripAutomationArrive   TripAutomationAction = "ARRIVE"
+	TripAutomationComplete TripAutomationAction = "COMPLETE"
+)
+
+var tripAutomationOutcomes = map[TripAutomationAction][2]string{
+	TripAutomationBegin:    {"the trip was begun automatically", "the trip couldn't be begun automatically"},
+	TripAutomationArrive:   {"its arrival was recorded automatically", "its arrival couldn't be recorded automatically"},
+	TripAutomationComplete: {"the trip was completed automatically", "the trip couldn't be completed automatically"},
+}
+
+// a TripAutomation is a transition a geofence made on a trip's behalf. Note is what was added to the trip, which
+// says why when the transition couldn't be made.
+type TripAutomation struct {
+	TripID     primitive.ObjectID   `json:"trip_id"`
+	FacilityID primitive.ObjectID   `json:"facility_id"`
+	Action     TripAutomationAction `json:"action"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	Applied    bool                 `json:"applied"`
+	Note       string               `json:"note"`
+
+	// why the automation stopped short of what the rules allow, added to the note
+	caveat string
+}
+
+// AutomationFor decides what a truck crossing the facility's geofence means for one of its trips, if anything.
+// leaving the start facility begins a scheduled trip, and entering the end facility of a trip in transit records
+// its arrival, completing it too when the rules allow and no stops are left open.
+func (t *Trip) AutomationFor(policy GeofencePolicy, facility *Facility, event LocationEvent) (*TripAutomation, bool) {
+	rules := facility.GeofenceRules(policy)
+	at := event.OccurredAt.UTC().Format(time.RFC3339)
+
+	automation := &TripAutomation{
+		TripID:     t.ID,
+		FacilityID: facility.ID,
+		OccurredAt: event.OccurredAt,
+	}
+
+	switch {
+	case event.Type == LocationEventExit && t.Status == TripStatusScheduled && sameFacility(t.StartFacilityID, facility.ID):
+		if !rules.BeginOnExit {
+			return nil, false
+		}
+		if policy.DepartureWindow > 0 && absDuration(event.OccurredAt.Sub(t.DepartureTime.Scheduled.Time())) > policy.DepartureWindow {
+			return nil, false
+		}
+
+		automation.Action = TripAutomationBegin
+		automation.Note = fmt.Sprintf("The truck left %s at %s", facility.Name, at)
+
+	case event.Type == LocationEventEnter && t.Status == TripStatusInTransit && sameFacility(t.EndFacilityID, facility.ID):
+		if !rules.ArriveOnEntry || t.ArrivalTime.Actual != nil {
+			return nil, false
+		}
+		// a round trip starts inside the same fence it ends in, so the truck has to have left before it can arrive
+		if t.DepartureTime.Actual != nil && !event.OccurredAt.After(t.DepartureTime.Actual.Time()) {
+			return nil, false
+		}
+
+		automation.Action = TripAutomationArrive
+		automation.Note = fmt.Sprintf("The truck entered %s at %s", facility.Name, at)
+
+		if rules.CompleteOnArrival {
+			if stop := t.UnresolvedStop(); stop != nil {
+				automation.caveat = fmt.Sprintf("The trip was left open because stop %s hasn't been departed or skipped.", stop.ID.Hex())
+			} else {
+				automation.Action = TripAutomationComplete
+			}
+		}
+
+	default:
+		return nil, false
+	}
+
+	return automation, true
+}
+
+// Resolve finishes the automation's note once it's known whether the trip took the transition. refusal is the
+// reason it didn't.
+func (a *TripAutomation) Resolve(refusal error) {
+	outcomes := tripAutomationOutcomes[a.Action]
+	a.Applied = refusal == nil
+
+	if a.Applied {
+		a.Note = fmt.Sprintf("%s, so %s.", a.Note, outcomes[0])
+	} else {
+		a.Note = fmt.Sprintf("%s, but %s: %v.", a.Note, outcomes[1], refusal)
+	}
+
+	if a.caveat != "" {
+		a.Note += " " + a.caveat
+	}
+}
+
+// RecordArrival notes when the trip reached its destination without finishing it, which is left to the driver
+// once the load is off
+func (t *Trip) RecordArrival(arrivalTime time.Time) error {
+	if t.Status != TripStatusInTransit {
+		return &TripStateError{CurrentState: t.Status, DesiredState: TripStatusInTransit}
+	}
+
+	arrival := primitive.NewDateTimeFromTime(arrivalTime)
+	t.ArrivalTime = TimeWindow{
+		Scheduled: t.ArrivalTime.Scheduled,
+		Actual:    &arrival,
+	}
+	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+func sameFacility(id *primitive.ObjectID, facilityID primitive.ObjectID) bool {
+	return id != nil && *id == facilityID
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,278 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a HazardClass is a DOT hazard class ("3"), or a division of one ("2.1")
+type HazardClass string
+
+var hazardClassRegex = regexp.MustCompile(`^[1-9](\.[1-6])?$`)
+
+func (c HazardClass) IsValid() bool {
+	return hazardClassRegex.MatchString(string(c))
+}
+
+// Class is the hazard class a division belongs to
+func (c HazardClass) Class() HazardClass {
+	return HazardClass(strings.SplitN(string(c), ".", 2)[0])
+}
+
+var unNumberRegex = regexp.MustCompile(`^UN\d{4}$`)
+
+// the trailers each hazard class can be hauled in. livestock, auto carrier and logging trailers can't carry
+// hazardous materials at all.
+var hazmatTrailerTypes = map[HazardClass][]TrailerType{
+	"1": {TrailerTypeDryVan, TrailerTypeIntermodal},
+	"2": {TrailerTypeTanker, TrailerTypeDryVan, TrailerTypeFlatBed, TrailerTypeIntermodal},
+	"3": {TrailerTypeTanker, TrailerTypeDryVan, TrailerTypeIntermodal},
+	"4": {TrailerTypeDryVan, TrailerTypePneumaticTank, TrailerTypeIntermodal},
+	"5": {TrailerTypeDryVan, TrailerTypeTanker, TrailerTypePneumaticTank, TrailerTypeIntermodal},
+	"6": {TrailerTypeDryVan, TrailerTypeTanker, TrailerTypeIntermodal},
+	"7": {TrailerTypeDryVan, TrailerTypeFlatBed, TrailerTypeIntermodal},
+	"8": {TrailerTypeTanker, TrailerTypeDryVan, TrailerTypeIntermodal},
+	"9": {TrailerTypeDryVan, TrailerTypeRefrigerated, TrailerTypeFlatBed, TrailerTypeTanker, TrailerTypePneumaticTank, TrailerTypeIntermodal},
+}
+
+// PermitsHazardClass reports whether the trailer type is allowed to carry the hazard class
+func (t TrailerType) PermitsHazardClass(class HazardClass) bool {
+	for _, permitted := range hazmatTrailerTypes[class.Class()] {
+		if permitted == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that hazmat cargo declares what it is, and that other cargo doesn't
+func (c Cargo) Validate() error {
+	if !c.Hazmat {
+		if c.UNNumber != "" || c.HazardClass != "" {
+			return fmt.Errorf("un_number and hazard_class can only be set on hazmat cargo")
+		}
+		return nil
+	}
+
+	if !unNumberRegex.MatchString(c.UNNumber) {
+		return fmt.Errorf("hazmat cargo needs a un_number like UN1203")
+	}
+	if !c.HazardClass.IsValid() {
+		return fmt.Errorf("hazmat cargo needs a hazard_class between 1 and 9, like 3 or 2.1")
+	}
+
+	return nil
+}
+
+// SetHazmatAcceptance sets whether the facility takes hazmat loads. a facility that accepts hazmat without
+// listing classes takes all of them.
+func (f *Facility) SetHazmatAcceptance(accepts bool, classes []HazardClass) error {
+	if !accepts && len(classes) > 0 {
+		return fmt.Errorf("hazard_classes can only be listed for facilities that accept hazmat")
+	}
+	for _, class := range classes {
+		if !class.IsValid() {
+			return fmt.Errorf("invalid hazard class: %s", class)
+		}
+	}
+
+	f.AcceptsHazmat = accepts
+	f.HazardClasses = classes
+	return nil
+}
+
+// AcceptsHazardClass reports whether the facility will take a load of the class. listing a whole class accepts
+// each of its divisions.
+func (f *Facility) AcceptsHazardClass(class HazardClass) bool {
+	if !f.AcceptsHazmat {
+		return false
+	}
+	if len(f.HazardClasses) == 0 {
+		return true
+	}
+
+	for _, accepted := range f.HazardClasses {
+		if accepted == class || accepted == class.Class() {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	HazmatViolationDriverRequired      = "DRIVER_REQUIRED"
+	HazmatViolationTruckRequired       = "TRUCK_REQUIRED"
+	HazmatViolationEndorsementMissing  = "ENDORSEMENT_MISSING"
+	HazmatViolationEndorsementExpired  = "ENDORSEMENT_EXPIRED"
+	HazmatViolationTrailerNotPermitted = "TRAILER_NOT_PERMITTED"
+	HazmatViolationFacilityRefuses     = "FACILITY_REFUSES_HAZMAT"
+	HazmatViolationClassMissing        = "HAZARD_CLASS_MISSING"
+)
+
+// a HazmatViolation is one reason a trip's assignments can't carry its hazardous cargo
+type HazmatViolation struct {
+	Code        string              `json:"code"`
+	Message     string              `json:"message"`
+	HazardClass HazardClass         `json:"hazard_class,omitempty"`
+	DriverID    *primitive.ObjectID `json:"driver_id,omitempty"`
+	TruckID     *primitive.ObjectID `json:"truck_id,omitempty"`
+	FacilityID  *primitive.ObjectID `json:"facility_id,omitempty"`
+}
+
+// a HazmatCheck is what a trip's hazmat compliance is checked against. Facilities holds every facility on the
+// route, keyed by id.
+type HazmatCheck struct {
+	Driver     *Driver
+	Truck      *Truck
+	Facilities map[primitive.ObjectID]*Facility
+	At         time.Time
+
+	// a trip can be planned before it's staffed, but it can't leave without an endorsed driver and a truck
+	RequireAssignments bool
+}
+
+// HazardClasses lists each hazard class the trip carries, on its own cargo or picked up and dropped off at its stops
+func (t *Trip) HazardClasses() []HazardClass {
+	classes := make([]HazardClass, 0)
+	seen := make(map[HazardClass]bool)
+
+	add := func(cargo Cargo) {
+		if cargo.Hazmat && !seen[cargo.HazardClass] {
+			seen[cargo.HazardClass] = true
+			classes = append(classes, cargo.HazardClass)
+		}
+	}
+
+	add(t.Cargo)
+	for _, stop := range t.Stops {
+		add(stop.Cargo)
+	}
+
+	return classes
+}
+
+// HazmatViolations checks everything the trip's hazardous cargo needs: a driver with a hazmat endorsement that's
+// good on the day, a trailer that's allowed to carry each class, and facilities that will take it
+func (t *Trip) HazmatViolations(check HazmatCheck) []HazmatViolation {
+	violations := make([]HazmatViolation, 0)
+
+	classes := t.HazardClasses()
+	if len(classes) == 0 {
+		return violations
+	}
+
+	// cargo marked hazmat before classes were required has none, and there's nothing to hold the trailer or
+	// facilities to until it's given one
+	known := make([]HazardClass, 0, len(classes))
+	for _, class := range classes {
+		if class.IsValid() {
+			known = append(known, class)
+		}
+	}
+	if len(known) < len(classes) {
+		violations = append(violations, HazmatViolation{
+			Code:    HazmatViolationClassMissing,
+			Message: "hazmat cargo on the trip has no hazard class",
+		})
+	}
+	classes = known
+
+	switch {
+	case check.Driver != nil:
+		driverID := check.Driver.ID
+		endorsement := check.Driver.Credential(CredentialTypeHazmatEndorsement)
+		if endorsement == nil {
+			violations = append(violations, HazmatViolation{
+				Code:     HazmatViolationEndorsementMissing,
+				Message:  "the driver doesn't hold a hazmat endorsement",
+				DriverID: &driverID,
+			})
+		} else if expiredBy(endorsement.ExpiresOn, check.At) {
+			violations = append(violations, HazmatViolation{
+				Code:     HazmatViolationEndorsementExpired,
+				Message:  fmt.Sprintf("the driver's hazmat endorsement expired on %s", FormatDate(endorsement.ExpiresOn)),
+				DriverID: &driverID,
+			})
+		}
+	case check.RequireAssignments:
+		violations = append(violations, HazmatViolation{
+			Code:    HazmatViolationDriverRequired,
+			Message: "a hazmat trip needs a driver with a hazmat endorsement",
+		})
+	}
+
+	switch {
+	case check.Truck != nil:
+		truckID := check.Truck.ID
+		for _, class := range classes {
+			if !check.Truck.TrailerType.PermitsHazardClass(class) {
+				violations = append(violations, HazmatViolation{
+					Code:        HazmatViolationTrailerNotPermitted,
+					Message:     fmt.Sprintf("a %s trailer can't carry hazard class %s", check.Truck.TrailerType, class),
+					HazardClass: class,
+					TruckID:     &truckID,
+				})
+			}
+		}
+	case check.RequireAssignments:
+		violations = append(violations, HazmatViolation{
+			Code:    HazmatViolationTruckRequired,
+			Message: "a hazmat trip needs a truck",
+		})
+	}
+
+	for _, id := range t.routeFacilityIDs() {
+		facility := check.Facilities[id]
+		if facility == nil {
+			continue
+		}
+
+		facilityID := facility.ID
+		for _, class := range classes {
+			if !facility.AcceptsHazardClass(class) {
+				violations = append(violations, HazmatViolation{
+					Code:        HazmatViolationFacilityRefuses,
+					Message:     fmt.Sprintf("%s doesn't accept hazard class %s", facility.Name, class),
+					HazardClass: class,
+					FacilityID:  &facilityID,
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// routeFacilityIDs lists each facility the trip visits once, in the order it visits them
+func (t *Trip) routeFacilityIDs() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(t.Stops)+2)
+	seen := make(map[primitive.ObjectID]bool)
+
+	add := func(id *primitive.ObjectID) {
+		if id != nil && !seen[*id] {
+			seen[*id] = true
+			ids = append(ids, *id)
+		}
+	}
+
+	add(t.StartFacilityID)
+	for i := range t.Stops {
+		add(&t.Stops[i].FacilityID)
+	}
+	add(t.EndFacilityID)
+
+	return ids
+}
+
+// EnsureHazmatCompliant refuses a trip whose hazardous cargo breaks any of the checks
+func (t *Trip) EnsureHazmatCompliant(check HazmatCheck) error {
+	if violations := t.HazmatViolations(check); len(violations) > 0 {
+		return &HazmatComplianceError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTripHazmatViolations(t *testing.T) {
+	at := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	day := func(year int, month time.Month, d int) primitive.DateTime {
+		return primitive.NewDateTimeFromTime(time.Date(year, month, d, 0, 0, 0, 0, time.UTC))
+	}
+
+	endorsed := &Driver{
+		ID:          primitive.NewObjectID(),
+		Credentials: []Credential{{Type: CredentialTypeHazmatEndorsement, ExpiresOn: day(2025, 1, 1)}},
+	}
+	lapsed := &Driver{
+		ID:          primitive.NewObjectID(),
+		Credentials: []Credential{{Type: CredentialTypeHazmatEndorsement, ExpiresOn: day(2024, 5, 31)}},
+	}
+	expiresToday := &Driver{
+		ID:          primitive.NewObjectID(),
+		Credentials: []Credential{{Type: CredentialTypeHazmatEndorsement, ExpiresOn: day(2024, 6, 1)}},
+	}
+	unendorsed := &Driver{ID: primitive.NewObjectID()}
+
+	tanker := &Truck{ID: primitive.NewObjectID(), TrailerType: TrailerTypeTanker}
+	livestock := &Truck{ID: primitive.NewObjectID(), TrailerType: TrailerTypeLiveStock}
+
+	anyHazmat := &Facility{ID: primitive.NewObjectID(), Name: "Any", AcceptsHazmat: true}
+	gasesOnly := &Facility{ID: primitive.NewObjectID(), Name: "Gases", AcceptsHazmat: true, HazardClasses: []HazardClass{"2"}}
+	noHazmat := &Facility{ID: primitive.NewObjectID(), Name: "None"}
+	facilities := map[primitive.ObjectID]*Facility{
+		anyHazmat.ID: anyHazmat,
+		gasesOnly.ID: gasesOnly,
+		noHazmat.ID:  noHazmat,
+	}
+
+	hazmat := func(class HazardClass) Cargo {
+		return Cargo{Description: "load", Hazmat: true, UNNumber: "UN1203", HazardClass: class}
+	}
+	trip := func(cargo Cargo, start, end *Facility, stops ...TripStop) *Trip {
+		trip := &Trip{Cargo: cargo, Stops: stops}
+		if start != nil {
+			trip.StartFacilityID = &start.ID
+		}
+		if end != nil {
+			trip.EndFacilityID = &end.ID
+		}
+		return trip
+	}
+	stop := func(facility *Facility, cargo Cargo) TripStop {
+		return TripStop{ID: primitive.NewObjectID(), FacilityID: facility.ID, Cargo: cargo}
+	}
+
+	tests := []struct {
+		name  string
+		trip  *Trip
+		check HazmatCheck
+		want  []string
+	}{
+		{
+			name:  "cargo that isn't hazmat",
+			trip:  trip(Cargo{Description: "load"}, noHazmat, noHazmat),
+			check: HazmatCheck{Driver: unendorsed, Truck: livestock, Facilities: facilities, At: at, RequireAssignments: true},
+		},
+		{
+			name:  "everything in order",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Driver: endorsed, Truck: tanker, Facilities: facilities, At: at, RequireAssignments: true},
+		},
+		{
+			name:  "planned without assignments",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Facilities: facilities, At: at},
+		},
+		{
+			name:  "leaving without assignments",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Facilities: facilities, At: at, RequireAssignments: true},
+			want:  []string{HazmatViolationDriverRequired, HazmatViolationTruckRequired},
+		},
+		{
+			name:  "driver without an endorsement",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Driver: unendorsed, Truck: tanker, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationEndorsementMissing},
+		},
+		{
+			name:  "endorsement expired the day before",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Driver: lapsed, Truck: tanker, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationEndorsementExpired},
+		},
+		{
+			name:  "endorsement good through the day it expires",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Driver: expiresToday, Truck: tanker, Facilities: facilities, At: at},
+		},
+		{
+			name:  "trailer can't carry hazmat",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat),
+			check: HazmatCheck{Driver: endorsed, Truck: livestock, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationTrailerNotPermitted},
+		},
+		{
+			name:  "trailer is checked against each class picked up along the way",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat, stop(anyHazmat, hazmat("8"))),
+			check: HazmatCheck{Driver: endorsed, Truck: livestock, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationTrailerNotPermitted, HazmatViolationTrailerNotPermitted},
+		},
+		{
+			name:  "facility that takes no hazmat",
+			trip:  trip(hazmat("3"), noHazmat, anyHazmat),
+			check: HazmatCheck{Driver: endorsed, Truck: tanker, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationFacilityRefuses},
+		},
+		{
+			name:  "facility listing a class takes its divisions",
+			trip:  trip(hazmat("2.1"), gasesOnly, gasesOnly),
+			check: HazmatCheck{Driver: endorsed, Truck: tanker, Facilities: facilities, At: at},
+		},
+		{
+			name:  "facility that doesn't list the class",
+			trip:  trip(hazmat("3"), anyHazmat, anyHazmat, stop(gasesOnly, Cargo{Description: "load"})),
+			check: HazmatCheck{Driver: endorsed, Truck: tanker, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationFacilityRefuses},
+		},
+		{
+			name:  "facility visited twice is reported once",
+			trip:  trip(hazmat("3"), noHazmat, noHazmat),
+			check: HazmatCheck{Driver: endorsed, Truck: tanker, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationFacilityRefuses},
+		},
+		{
+			name:  "cargo without a class",
+			trip:  trip(hazmat(""), anyHazmat, anyHazmat, stop(anyHazmat, hazmat(""))),
+			check: HazmatCheck{Driver: endorsed, Truck: livestock, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationClassMissing},
+		},
+		{
+			name:  "the classes that are known are still checked",
+			trip:  trip(hazmat(""), anyHazmat, anyHazmat, stop(anyHazmat, hazmat("3"))),
+			check: HazmatCheck{Driver: endorsed, Truck: livestock, Facilities: facilities, At: at},
+			want:  []string{HazmatViolationClassMissing, HazmatViolationTrailerNotPermitted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, violation := range tt.trip.HazmatViolations(tt.check) {
+				got = append(got, violation.Code)
+			}
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HazmatViolations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
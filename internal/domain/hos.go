@@ -0,0 +1,268 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DutyStatus string
+
+const (
+	DutyStatusOffDuty          DutyStatus = "OFF_DUTY"
+	DutyStatusSleeper          DutyStatus = "SLEEPER"
+	DutyStatusDriving          DutyStatus = "DRIVING"
+	DutyStatusOnDutyNotDriving DutyStatus = "ON_DUTY_NOT_DRIVING"
+)
+
+func (s DutyStatus) IsValid() bool {
+	switch s {
+	case DutyStatusOffDuty, DutyStatusSleeper, DutyStatusDriving, DutyStatusOnDutyNotDriving:
+		return true
+	}
+	return false
+}
+
+// time in the sleeper berth counts as rest, the same as time off duty
+func (s DutyStatus) IsOnDuty() bool {
+	return s == DutyStatusDriving || s == DutyStatusOnDutyNotDriving
+}
+
+// a DutyStatusEvent is one change in a driver's duty status. the status lasts until the driver's next event, so
+// the log is only ever appended to.
+type DutyStatusEvent struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	DriverID       primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Status         DutyStatus         `bson:"status" json:"status"`
+	StartedAt      primitive.DateTime `bson:"started_at" json:"started_at"`
+	Location       string             `bson:"location,omitempty" json:"location,omitempty"`
+	Note           string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt      primitive.DateTime `bson:"created_at" json:"created_at"`
+}
+
+type DutyStatusFilter struct {
+	OrganizationID primitive.ObjectID
+	DriverID       primitive.ObjectID
+	From           *primitive.DateTime
+	To             *primitive.DateTime
+	Limit          int64
+	Offset         int64
+}
+
+func NewDutyStatusFilter() DutyStatusFilter {
+	return DutyStatusFilter{
+		Limit:  10,
+		Offset: 0,
+	}
+}
+
+func NewDutyStatusEvent(
+	organizationID,
+	driverID,
+	userID primitive.ObjectID,
+	status DutyStatus,
+	startedAt time.Time,
+	location,
+	note string) (*DutyStatusEvent, error) {
+	if !status.IsValid() {
+		return nil, fmt.Errorf("invalid duty status: %s", status)
+	}
+	if startedAt.IsZero() {
+		return nil, fmt.Errorf("started_at is required")
+	}
+	if startedAt.After(time.Now().Add(maxPositionClockSkew)) {
+		return nil, fmt.Errorf("a duty status can't start in the future")
+	}
+	if len(note) > MaxNoteLength {
+		return nil, fmt.Errorf("note exceeds maximum length of %d characters", MaxNoteLength)
+	}
+
+	return &DutyStatusEvent{
+		OrganizationID: organizationID,
+		DriverID:       driverID,
+		UserID:         userID,
+		Status:         status,
+		StartedAt:      primitive.NewDateTimeFromTime(startedAt),
+		Location:       location,
+		Note:           note,
+	}, nil
+}
+
+// HOSRules are the hours of service limits. the defaults are the federal property-carrying rules: 11 hours of
+// driving within a 14 hour window after 10 hours off, a 30 minute break after 8 hours of driving, and 70 hours on
+// duty in any 8 days, which a 34 hour restart resets.
+type HOSRules struct {
+	MaxDriving    time.Duration
+	DutyWindow    time.Duration
+	ShiftReset    time.Duration
+	BreakAfter    time.Duration
+	BreakDuration time.Duration
+	CycleLimit    time.Duration
+	CycleDays     int
+	CycleRestart  time.Duration
+}
+
+const (
+	HOSLimitDriving = "driving"
+	HOSLimitWindow  = "window"
+	HOSLimitBreak   = "break"
+	HOSLimitCycle   = "cycle"
+)
+
+// an HOSSummary is where a driver stands against the hours of service limits at a moment
+type HOSSummary struct {
+	DriverID                primitive.ObjectID `json:"driver_id"`
+	AsOf                    time.Time          `json:"as_of"`
+	CurrentStatus           DutyStatus         `json:"current_status"`
+	StatusSince             *time.Time         `json:"status_since,omitempty"`
+	ShiftStartedAt          *time.Time         `json:"shift_started_at,omitempty"`
+	DrivingRemainingMinutes int                `json:"driving_remaining_minutes"`
+	WindowRemainingMinutes  int                `json:"window_remaining_minutes"`
+	BreakRemainingMinutes   int                `json:"break_remaining_minutes"`
+	CycleRemainingMinutes   int                `json:"cycle_remaining_minutes"`
+	AvailableMinutes        int                `json:"available_minutes"`
+
+	// the limit that runs out first, which is the one holding the driver back when nothing is available
+	LimitedBy string `json:"limited_by"`
+}
+
+// Lookback is how far back the log has to be read to summarize it. a restart that ends inside the cycle may have
+// begun before it.
+func (r HOSRules) Lookback() time.Duration {
+	return time.Duration(r.CycleDays)*24*time.Hour + r.CycleRestart
+}
+
+type dutyInterval struct {
+	status     DutyStatus
+	start, end time.Time
+}
+
+// Summarize works out the driver's remaining time from their log. events has to include the event in effect at
+// the start of the lookback; anything before that doesn't matter. a driver with no log is treated as fully rested.
+func (r HOSRules) Summarize(driverID primitive.ObjectID, events []*DutyStatusEvent, asOf time.Time) HOSSummary {
+	sorted := make([]*DutyStatusEvent, 0, len(events))
+	for _, event := range events {
+		if !event.StartedAt.Time().After(asOf) {
+			sorted = append(sorted, event)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartedAt < sorted[j].StartedAt
+	})
+
+	intervals := make([]dutyInterval, 0, len(sorted))
+	for i, event := range sorted {
+		end := asOf
+		if i+1 < len(sorted) {
+			end = sorted[i+1].StartedAt.Time()
+		}
+		intervals = append(intervals, dutyInterval{status: event.Status, start: event.StartedAt.Time(), end: end})
+	}
+
+	summary := HOSSummary{
+		DriverID:      driverID,
+		AsOf:          asOf,
+		CurrentStatus: DutyStatusOffDuty,
+	}
+	if len(intervals) > 0 {
+		current := intervals[len(intervals)-1]
+		summary.CurrentStatus = current.status
+		summary.StatusSince = &current.start
+	}
+
+	var shiftStart *time.Time
+	var drivingInShift, drivingSinceBreak, restRun, breakRun time.Duration
+	cycleStart := asOf.Add(-time.Duration(r.CycleDays) * 24 * time.Hour)
+
+	for _, interval := range intervals {
+		length := interval.end.Sub(interval.start)
+
+		if interval.status.IsOnDuty() {
+			restRun = 0
+			if shiftStart == nil {
+				start := interval.start
+				shiftStart = &start
+			}
+		} else {
+			restRun += length
+			if restRun >= r.ShiftReset {
+				shiftStart = nil
+				drivingInShift = 0
+				drivingSinceBreak = 0
+			}
+			if restRun >= r.CycleRestart && interval.end.After(cycleStart) {
+				cycleStart = interval.end
+			}
+		}
+
+		if interval.status == DutyStatusDriving {
+			breakRun = 0
+			drivingInShift += length
+			drivingSinceBreak += length
+		} else {
+			breakRun += length
+			if breakRun >= r.BreakDuration {
+				drivingSinceBreak = 0
+			}
+		}
+	}
+
+	var cycleUsed time.Duration
+	for _, interval := range intervals {
+		if !interval.status.IsOnDuty() || !interval.end.After(cycleStart) {
+			continue
+		}
+		start := interval.start
+		if start.Before(cycleStart) {
+			start = cycleStart
+		}
+		cycleUsed += interval.end.Sub(start)
+	}
+
+	window := r.DutyWindow
+	if shiftStart != nil {
+		window -= asOf.Sub(*shiftStart)
+		summary.ShiftStartedAt = shiftStart
+	}
+
+	remaining := []struct {
+		limit string
+		left  time.Duration
+		field *int
+	}{
+		{HOSLimitDriving, r.MaxDriving - drivingInShift, &summary.DrivingRemainingMinutes},
+		{HOSLimitWindow, window, &summary.WindowRemainingMinutes},
+		{HOSLimitBreak, r.BreakAfter - drivingSinceBreak, &summary.BreakRemainingMinutes},
+		{HOSLimitCycle, r.CycleLimit - cycleUsed, &summary.CycleRemainingMinutes},
+	}
+
+	available := time.Duration(-1)
+	for _, limit := range remaining {
+		left := limit.left
+		if left < 0 {
+			left = 0
+		}
+		*limit.field = int(left / time.Minute)
+
+		if available < 0 || left < available {
+			available = left
+			summary.LimitedBy = limit.limit
+		}
+	}
+	summary.AvailableMinutes = int(available / time.Minute)
+
+	return summary
+}
+
+// EnsureAvailable refuses to put a driver behind the wheel once any of their limits has run out
+func (s *HOSSummary) EnsureAvailable() error {
+	if s.AvailableMinutes > 0 {
+		return nil
+	}
+
+	return &HOSLimitError{DriverID: s.DriverID, Limit: s.LimitedBy}
+}
@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var federalHOSRules = HOSRules{
+	MaxDriving:    11 * time.Hour,
+	DutyWindow:    14 * time.Hour,
+	ShiftReset:    10 * time.Hour,
+	BreakAfter:    8 * time.Hour,
+	BreakDuration: 30 * time.Minute,
+	CycleLimit:    70 * time.Hour,
+	CycleDays:     8,
+	CycleRestart:  34 * time.Hour,
+}
+
+func dutyEvent(status DutyStatus, at time.Time) *DutyStatusEvent {
+	return &DutyStatusEvent{Status: status, StartedAt: primitive.NewDateTimeFromTime(at)}
+}
+
+func TestHOSRulesSummarize(t *testing.T) {
+	asOf := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return asOf.Add(-d) }
+
+	// nine hours on duty at the start of each of the last eight days, off the rest of the day
+	longWeek := make([]*DutyStatusEvent, 0, 16)
+	for day := 8; day >= 1; day-- {
+		start := ago(time.Duration(day) * 24 * time.Hour)
+		longWeek = append(longWeek,
+			dutyEvent(DutyStatusOnDutyNotDriving, start),
+			dutyEvent(DutyStatusOffDuty, start.Add(9*time.Hour)))
+	}
+
+	tests := []struct {
+		name          string
+		events        []*DutyStatusEvent
+		wantStatus    DutyStatus
+		wantDriving   int
+		wantWindow    int
+		wantBreak     int
+		wantCycle     int
+		wantAvailable int
+		wantLimitedBy string
+	}{
+		{
+			name:          "no log is fully rested",
+			wantStatus:    DutyStatusOffDuty,
+			wantDriving:   660,
+			wantWindow:    840,
+			wantBreak:     480,
+			wantCycle:     4200,
+			wantAvailable: 480,
+			wantLimitedBy: HOSLimitBreak,
+		},
+		{
+			name:          "driving part of a shift",
+			events:        []*DutyStatusEvent{dutyEvent(DutyStatusDriving, ago(5*time.Hour))},
+			wantStatus:    DutyStatusDriving,
+			wantDriving:   360,
+			wantWindow:    540,
+			wantBreak:     180,
+			wantCycle:     3900,
+			wantAvailable: 180,
+			wantLimitedBy: HOSLimitBreak,
+		},
+		{
+			name: "a thirty minute break resets the break clock but not the shift",
+			events: []*DutyStatusEvent{
+				dutyEvent(DutyStatusDriving, ago(8*time.Hour+30*time.Minute)),
+				dutyEvent(DutyStatusOffDuty, ago(30*time.Minute)),
+			},
+			wantStatus:    DutyStatusOffDuty,
+			wantDriving:   180,
+			wantWindow:    330,
+			wantBreak:     480,
+			wantCycle:     3720,
+			wantAvailable: 180,
+			wantLimitedBy: HOSLimitDriving,
+		},
+		{
+			name: "a break that's too short doesn't count",
+			events: []*DutyStatusEvent{
+				dutyEvent(DutyStatusDriving, ago(8*time.Hour+15*time.Minute)),
+				dutyEvent(DutyStatusOffDuty, ago(15*time.Minute)),
+			},
+			wantStatus:    DutyStatusOffDuty,
+			wantDriving:   180,
+			wantWindow:    345,
+			wantBreak:     0,
+			wantCycle:     3720,
+			wantAvailable: 0,
+			wantLimitedBy: HOSLimitBreak,
+		},
+		{
+			name: "ten hours off starts a new shift",
+			events: []*DutyStatusEvent{
+				dutyEvent(DutyStatusDriving, ago(22*time.Hour)),
+				dutyEvent(DutyStatusSleeper, ago(12*time.Hour)),
+			},
+			wantStatus:    DutyStatusSleeper,
+			wantDriving:   660,
+			wantWindow:    840,
+			wantBreak:     480,
+			wantCycle:     3600,
+			wantAvailable: 480,
+			wantLimitedBy: HOSLimitBreak,
+		},
+		{
+			name:          "on duty without driving still uses the window",
+			events:        []*DutyStatusEvent{dutyEvent(DutyStatusOnDutyNotDriving, ago(13*time.Hour))},
+			wantStatus:    DutyStatusOnDutyNotDriving,
+			wantDriving:   660,
+			wantWindow:    60,
+			wantBreak:     480,
+			wantCycle:     3420,
+			wantAvailable: 60,
+			wantLimitedBy: HOSLimitWindow,
+		},
+		{
+			name:          "the cycle runs out",
+			events:        longWeek,
+			wantStatus:    DutyStatusOffDuty,
+			wantDriving:   660,
+			wantWindow:    840,
+			wantBreak:     480,
+			wantCycle:     0,
+			wantAvailable: 0,
+			wantLimitedBy: HOSLimitCycle,
+		},
+		{
+			name: "a 34 hour restart resets the cycle",
+			events: []*DutyStatusEvent{
+				dutyEvent(DutyStatusOnDutyNotDriving, ago(7*24*time.Hour)),
+				dutyEvent(DutyStatusOffDuty, ago(7*24*time.Hour-60*time.Hour)),
+				dutyEvent(DutyStatusOnDutyNotDriving, ago(2*time.Hour)),
+			},
+			wantStatus:    DutyStatusOnDutyNotDriving,
+			wantDriving:   660,
+			wantWindow:    720,
+			wantBreak:     480,
+			wantCycle:     4080,
+			wantAvailable: 480,
+			wantLimitedBy: HOSLimitBreak,
+		},
+		{
+			name: "events are read in the order they happened",
+			events: []*DutyStatusEvent{
+				dutyEvent(DutyStatusOffDuty, ago(30*time.Minute)),
+				dutyEvent(DutyStatusDriving, ago(8*time.Hour+30*time.Minute)),
+			},
+			wantStatus:    DutyStatusOffDuty,
+			wantDriving:   180,
+			wantWindow:    330,
+			wantBreak:     480,
+			wantCycle:     3720,
+			wantAvailable: 180,
+			wantLimitedBy: HOSLimitDriving,
+		},
+		{
+			name:          "events after the moment are ignored",
+			events:        []*DutyStatusEvent{dutyEvent(DutyStatusDriving, asOf.Add(time.Hour))},
+			wantStatus:    DutyStatusOffDuty,
+			wantDriving:   660,
+			wantWindow:    840,
+			wantBreak:     480,
+			wantCycle:     4200,
+			wantAvailable: 480,
+			wantLimitedBy: HOSLimitBreak,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := federalHOSRules.Summarize(primitive.NewObjectID(), tt.events, asOf)
+
+			if got.CurrentStatus != tt.wantStatus {
+				t.Errorf("CurrentStatus = %s, want %s", got.CurrentStatus, tt.wantStatus)
+			}
+			if got.DrivingRemainingMinutes != tt.wantDriving {
+				t.Errorf("DrivingRemainingMinutes = %d, want %d", got.DrivingRemainingMinutes, tt.wantDriving)
+			}
+			if got.WindowRemainingMinutes != tt.wantWindow {
+				t.Errorf("WindowRemainingMinutes = %d, want %d", got.WindowRemainingMinutes, tt.wantWindow)
+			}
+			if got.BreakRemainingMinutes != tt.wantBreak {
+				t.Errorf("BreakRemainingMinutes = %d, want %d", got.BreakRemainingMinutes, tt.wantBreak)
+			}
+			if got.CycleRemainingMinutes != tt.wantCycle {
+				t.Errorf("CycleRemainingMinutes = %d, want %d", got.CycleRemainingMinutes, tt.wantCycle)
+			}
+			if got.AvailableMinutes != tt.wantAvailable {
+				t.Errorf("AvailableMinutes = %d, want %d", got.AvailableMinutes, tt.wantAvailable)
+			}
+			if got.LimitedBy != tt.wantLimitedBy {
+				t.Errorf("LimitedBy = %s, want %s", got.LimitedBy, tt.wantLimitedBy)
+			}
+		})
+	}
+}
@@ -37,6 +37,7 @@ func (i IncidentType) IsValid() bool {
 
 type IncidentReport struct {
 	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"_id,omitempty"`
+	OrganizationID primitive.ObjectID  `bson:"organization_id" json:"organization_id"`
 	UserID         primitive.ObjectID  `bson:"user_id" json:"user_id"`
 	TripID         *primitive.ObjectID `bson:"trip_id,omitempty" json:"trip_id,omitempty"`
 	Trip           *Trip               `bson:"trip,omitempty" json:"trip,omitempty"`
@@ -54,6 +55,7 @@ type IncidentReport struct {
 }
 
 func NewIncidentReport(
+	organizationID,
 	userID primitive.ObjectID,
 	tripId,
 	truckId,
@@ -71,6 +73,7 @@ func NewIncidentReport(
 	now := time.Now()
 
 	return &IncidentReport{
+		OrganizationID: organizationID,
 		UserID:         userID,
 		TripID:         tripId,
 		TruckID:        truckId,
@@ -86,19 +89,19 @@ func NewIncidentReport(
 }
 
 type IncidentReportFilter struct {
-	UserID   primitive.ObjectID
-	TripID   *primitive.ObjectID
-	TruckID  *primitive.ObjectID
-	DriverID *primitive.ObjectID
-	Type     IncidentType
-	Limit    int64
-	Offset   int64
+	OrganizationID primitive.ObjectID
+	TripID         *primitive.ObjectID
+	TruckID        *primitive.ObjectID
+	DriverID       *primitive.ObjectID
+	Type           IncidentType
+	Limit          int64
+	Offset         int64
 }
 
 func NewIncidentReportFilter() IncidentReportFilter {
 	return IncidentReportFilter{
-		Limit:  10,
-		Offset: 0,
-		UserID: primitive.NilObjectID,
+		Limit:          10,
+		Offset:         0,
+		OrganizationID: primitive.NilObjectID,
 	}
 }
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a LoginAttempt counts the recent failed logins against either an account or a client address, and records when
+// the next attempt is allowed. it's deleted when the account logs in successfully or is unlocked.
+type LoginAttempt struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Key           string              `bson:"key" json:"-"`
+	Failures      int                 `bson:"failures" json:"failures"`
+	LastFailureAt primitive.DateTime  `bson:"last_failure_at" json:"last_failure_at"`
+	RetryAt       *primitive.DateTime `bson:"retry_at,omitempty" json:"retry_at,omitempty"`
+	LockedUntil   *primitive.DateTime `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+
+	// once its failures have aged out and any wait it imposes is over the attempt is dropped by a ttl index
+	ExpiresAt primitive.DateTime `bson:"expires_at" json:"-"`
+}
+
+// emails are matched without regard to case so that a guesser can't get a fresh counter by changing one. this key
+// counts failures against the account from every address.
+func AccountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// AccountAddressAttemptKey counts failures against the account from one address, so that whoever is guessing from
+// there only shuts themselves out of it
+func AccountAddressAttemptKey(email, ip string) string {
+	return AccountAttemptKey(email) + "|" + ip
+}
+
+func IPAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// BlockedUntil reports whether a login has to wait, and until when
+func (a *LoginAttempt) BlockedUntil(now time.Time) (time.Time, bool) {
+	var until time.Time
+	if a.RetryAt != nil {
+		until = a.RetryAt.Time()
+	}
+	if a.LockedUntil != nil && a.LockedUntil.Time().After(until) {
+		until = a.LockedUntil.Time()
+	}
+
+	return until, now.Before(until)
+}
+
+// Extend pushes the expiry out to the given time, if it's later than the current one
+func (a *LoginAttempt) Extend(until time.Time) {
+	if until.After(a.ExpiresAt.Time()) {
+		a.ExpiresAt = primitive.NewDateTimeFromTime(until)
+	}
+}
+
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(a.LockedUntil.Time())
+}
+
+// a LockoutPolicy decides how long a login has to wait after a run of failures. MaxAccountFailures locks an
+// account from one address only, since locking it everywhere would let anyone who knows the email keep its owner
+// out. the account is only locked for everyone at MaxAccountWideFailures, which should take more addresses than a
+// single guesser is likely to have, at the cost of letting that many guesses through in a window.
+type LockoutPolicy struct {
+	Enabled                bool
+	MaxAccountFailures     int
+	MaxAccountWideFailures int
+	MaxIPFailures          int
+	Duration               time.Duration
+	FailureWindow          time.Duration
+	BaseDelay              time.Duration
+}
+
+// Delay doubles with every failure in a row, up to the lockout duration
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < failures && delay < p.Duration; i++ {
+		delay *= 2
+	}
+
+	if p.Duration > 0 && delay > p.Duration {
+		return p.Duration
+	}
+
+	return delay
+}
@@ -24,24 +24,30 @@ func (m MaintenanceServiceType) IsValid() bool {
 }
 
 type MaintenanceLog struct {
-	ID          primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID      primitive.ObjectID     `bson:"user_id" json:"user_id"`
-	TruckID     *primitive.ObjectID    `bson:"truck_id,omitempty" json:"truck_id,omitempty"`
-	Truck       *Truck                 `bson:"truck,omitempty" json:"truck,omitempty"`
-	Date        string                 `bson:"date" json:"date"`
-	ServiceType MaintenanceServiceType `bson:"service_type" json:"service_type"`
-	Cost        float64                `bson:"cost" json:"cost"`
-	Notes       string                 `bson:"notes" json:"notes"`
-	Mechanic    string                 `bson:"mechanic" json:"mechanic"`
-	Location    string                 `bson:"location" json:"location"`
-	CreatedAt   primitive.DateTime     `bson:"created_at" json:"created_at"`
-	UpdatedAt   primitive.DateTime     `bson:"updated_at" json:"updated_at"`
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID primitive.ObjectID     `bson:"organization_id" json:"organization_id"`
+	UserID         primitive.ObjectID     `bson:"user_id" json:"user_id"`
+	TruckID        *primitive.ObjectID    `bson:"truck_id,omitempty" json:"truck_id,omitempty"`
+	Truck          *Truck                 `bson:"truck,omitempty" json:"truck,omitempty"`
+	ScheduleID     *primitive.ObjectID    `bson:"schedule_id,omitempty" json:"schedule_id,omitempty"`
+	Date           primitive.DateTime     `bson:"date" json:"date"`
+	Mileage        int                    `bson:"mileage,omitempty" json:"mileage,omitempty"`
+	ServiceType    MaintenanceServiceType `bson:"service_type" json:"service_type"`
+	Cost           float64                `bson:"cost" json:"cost"`
+	Notes          string                 `bson:"notes" json:"notes"`
+	Mechanic       string                 `bson:"mechanic" json:"mechanic"`
+	Location       string                 `bson:"location" json:"location"`
+	CreatedAt      primitive.DateTime     `bson:"created_at" json:"created_at"`
+	UpdatedAt      primitive.DateTime     `bson:"updated_at" json:"updated_at"`
 }
 
 func NewMaintenanceLog(
-	truckId *primitive.ObjectID,
+	truckId,
+	scheduleID *primitive.ObjectID,
+	organizationID,
 	userID primitive.ObjectID,
 	date string,
+	mileage int,
 	serviceType MaintenanceServiceType,
 	notes,
 	mechanic,
@@ -52,28 +58,49 @@ func NewMaintenanceLog(
 		return nil, fmt.Errorf("invalid service type provided: %s", serviceType)
 	}
 
+	serviced, err := ParseDate("date", date)
+	if err != nil {
+		return nil, err
+	}
+	if serviced.Time().After(time.Now()) {
+		return nil, fmt.Errorf("date can't be in the future")
+	}
+
+	if mileage < 0 {
+		return nil, fmt.Errorf("mileage can't be negative")
+	}
+
+	// a scheduled service is only done once it's been done on a particular truck
+	if scheduleID != nil && truckId == nil {
+		return nil, fmt.Errorf("truck_id is required when logging a scheduled service")
+	}
+
 	now := time.Now()
 
 	return &MaintenanceLog{
-		TruckID:     truckId,
-		UserID:      userID,
-		Date:        date,
-		ServiceType: serviceType,
-		Cost:        cost,
-		Notes:       notes,
-		Mechanic:    mechanic,
-		Location:    location,
-		CreatedAt:   primitive.NewDateTimeFromTime(now),
-		UpdatedAt:   primitive.NewDateTimeFromTime(now),
+		TruckID:        truckId,
+		ScheduleID:     scheduleID,
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Date:           serviced,
+		Mileage:        mileage,
+		ServiceType:    serviceType,
+		Cost:           cost,
+		Notes:          notes,
+		Mechanic:       mechanic,
+		Location:       location,
+		CreatedAt:      primitive.NewDateTimeFromTime(now),
+		UpdatedAt:      primitive.NewDateTimeFromTime(now),
 	}, nil
 }
 
 type MaintenanceLogFilter struct {
-	UserID      primitive.ObjectID
-	TruckID     *primitive.ObjectID
-	ServiceType MaintenanceServiceType
-	Limit       int64
-	Offset      int64
+	OrganizationID primitive.ObjectID
+	TruckID        *primitive.ObjectID
+	ScheduleID     *primitive.ObjectID
+	ServiceType    MaintenanceServiceType
+	Limit          int64
+	Offset         int64
 }
 
 func NewMaintenanceLogFilter() MaintenanceLogFilter {
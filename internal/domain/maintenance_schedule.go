@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a MaintenanceSchedule is a service that comes due every IntervalMiles or IntervalDays after it was last done,
+// whichever comes first. it covers a single truck, every truck of a make (narrowed to a model when one is given),
+// or the whole fleet when neither is set.
+type MaintenanceSchedule struct {
+	ID             primitive.ObjectID    `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID primitive.ObjectID    `bson:"organization_id" json:"organization_id"`
+	UserID         primitive.ObjectID    `bson:"user_id" json:"user_id"`
+	Name           string                `bson:"name" json:"name"`
+	Description    string                `bson:"description,omitempty" json:"description,omitempty"`
+	IntervalMiles  int                   `bson:"interval_miles,omitempty" json:"interval_miles,omitempty"`
+	IntervalDays   int                   `bson:"interval_days,omitempty" json:"interval_days,omitempty"`
+	SafetyCritical bool                  `bson:"safety_critical" json:"safety_critical"`
+	TruckID        *primitive.ObjectID   `bson:"truck_id,omitempty" json:"truck_id,omitempty"`
+	Make           string                `bson:"make,omitempty" json:"make,omitempty"`
+	Model          string                `bson:"model,omitempty" json:"model,omitempty"`
+	Baselines      []MaintenanceBaseline `bson:"baselines,omitempty" json:"baselines,omitempty"`
+	CreatedAt      primitive.DateTime    `bson:"created_at" json:"created_at"`
+	UpdatedAt      primitive.DateTime    `bson:"updated_at" json:"updated_at"`
+}
+
+// a MaintenanceBaseline is where a truck stood when a schedule started applying to it, which is what the first
+// service is counted from
+type MaintenanceBaseline struct {
+	TruckID primitive.ObjectID `bson:"truck_id" json:"truck_id"`
+	Mileage int                `bson:"mileage" json:"mileage"`
+	Date    primitive.DateTime `bson:"date" json:"date"`
+}
+
+type MaintenanceScheduleFilter struct {
+	OrganizationID primitive.ObjectID
+	TruckID        *primitive.ObjectID
+	Make           string
+	Limit          int64
+	Offset         int64
+}
+
+func NewMaintenanceScheduleFilter() MaintenanceScheduleFilter {
+	return MaintenanceScheduleFilter{
+		Limit:  10,
+		Offset: 0,
+	}
+}
+
+func NewMaintenanceSchedule(
+	organizationID,
+	userID primitive.ObjectID,
+	name,
+	description string,
+	intervalMiles,
+	intervalDays int,
+	safetyCritical bool,
+	truckID *primitive.ObjectID,
+	vehicleMake,
+	model string) (*MaintenanceSchedule, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if intervalMiles < 0 || intervalDays < 0 {
+		return nil, fmt.Errorf("intervals can't be negative")
+	}
+	if intervalMiles == 0 && intervalDays == 0 {
+		return nil, fmt.Errorf("a schedule needs an interval_miles, an interval_days, or both")
+	}
+	if truckID != nil && vehicleMake != "" {
+		return nil, fmt.Errorf("a schedule applies to a truck or to a make and model, not both")
+	}
+	if model != "" && vehicleMake == "" {
+		return nil, fmt.Errorf("make is required when a model is given")
+	}
+
+	now := time.Now()
+
+	return &MaintenanceSchedule{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Name:           name,
+		Description:    description,
+		IntervalMiles:  intervalMiles,
+		IntervalDays:   intervalDays,
+		SafetyCritical: safetyCritical,
+		TruckID:        truckID,
+		Make:           vehicleMake,
+		Model:          model,
+		CreatedAt:      primitive.NewDateTimeFromTime(now),
+		UpdatedAt:      primitive.NewDateTimeFromTime(now),
+	}, nil
+}
+
+// AppliesTo reports whether the truck is on this schedule. makes and models are matched ignoring case, since
+// they're typed in by hand on both sides.
+func (s *MaintenanceSchedule) AppliesTo(truck *Truck) bool {
+	switch {
+	case s.TruckID != nil:
+		return *s.TruckID == truck.ID
+	case s.Make != "":
+		return strings.EqualFold(s.Make, truck.Make) && (s.Model == "" || strings.EqualFold(s.Model, truck.Model))
+	}
+
+	return true
+}
+
+// Baseline records where each truck the schedule applies to stands now, for any that don't have a baseline yet
+func (s *MaintenanceSchedule) Baseline(trucks []*Truck, now time.Time) {
+	for _, truck := range trucks {
+		if !s.AppliesTo(truck) || s.baselineFor(truck) != nil {
+			continue
+		}
+		s.Baselines = append(s.Baselines, MaintenanceBaseline{
+			TruckID: truck.ID,
+			Mileage: truck.Mileage,
+			Date:    primitive.NewDateTimeFromTime(now),
+		})
+	}
+}
+
+func (s *MaintenanceSchedule) baselineFor(truck *Truck) *MaintenanceBaseline {
+	for i := range s.Baselines {
+		if s.Baselines[i].TruckID == truck.ID {
+			return &s.Baselines[i]
+		}
+	}
+
+	return nil
+}
+
+// start is where the truck stood when the schedule started applying to it. a truck added after the schedule
+// was set up starts on it from the mileage it was put in service with.
+func (s *MaintenanceSchedule) start(truck *Truck) (int, time.Time) {
+	if baseline := s.baselineFor(truck); baseline != nil {
+		return baseline.Mileage, baseline.Date.Time()
+	}
+
+	date := truck.CreatedAt.Time()
+	if created := s.CreatedAt.Time(); created.After(date) {
+		date = created
+	}
+
+	return truck.InServiceMileage, date
+}
+
+// a MaintenanceDue is where one truck stands on one of its schedules. the mileage fields are only set for
+// schedules with a mileage interval, and NextDueOn and DaysRemaining only for ones with a time interval.
+type MaintenanceDue struct {
+	ScheduleID           primitive.ObjectID `json:"schedule_id"`
+	ScheduleName         string             `json:"schedule_name"`
+	SafetyCritical       bool               `json:"safety_critical"`
+	TruckID              primitive.ObjectID `json:"truck_id"`
+	TruckNumber          string             `json:"truck_number"`
+	LastPerformedOn      primitive.DateTime `json:"last_performed_on"`
+	LastPerformedMileage *int               `json:"last_performed_mileage,omitempty"`
+	CurrentMileage       int                `json:"current_mileage"`
+	NextDueMileage       *int               `json:"next_due_mileage,omitempty"`
+	MilesRemaining       *int               `json:"miles_remaining,omitempty"`
+	NextDueOn            primitive.DateTime `json:"next_due_on"`
+	DaysRemaining        *int               `json:"days_remaining,omitempty"`
+	Overdue              bool               `json:"overdue"`
+}
+
+// Due works out when the truck next needs this service, counting from the last time it was logged against the
+// schedule. a truck that has never had it is counted from where it stood when the schedule started applying.
+func (s *MaintenanceSchedule) Due(truck *Truck, last *MaintenanceLog, now time.Time) MaintenanceDue {
+	due := MaintenanceDue{
+		ScheduleID:     s.ID,
+		ScheduleName:   s.Name,
+		SafetyCritical: s.SafetyCritical,
+		TruckID:        truck.ID,
+		TruckNumber:    truck.TruckNumber,
+		CurrentMileage: truck.Mileage,
+	}
+
+	baseMileage, baseDate := s.start(truck)
+	if last != nil {
+		baseMileage = last.Mileage
+		baseDate = last.Date.Time()
+		due.LastPerformedOn = last.Date
+		due.LastPerformedMileage = &last.Mileage
+	}
+
+	if s.IntervalMiles > 0 {
+		next := baseMileage + s.IntervalMiles
+		remaining := next - truck.Mileage
+		due.NextDueMileage = &next
+		due.MilesRemaining = &remaining
+		due.Overdue = remaining < 0
+	}
+
+	if s.IntervalDays > 0 {
+		today := now.UTC().Truncate(24 * time.Hour)
+		next := baseDate.UTC().Truncate(24*time.Hour).AddDate(0, 0, s.IntervalDays)
+		remaining := int(next.Sub(today).Hours() / 24)
+		due.NextDueOn = primitive.NewDateTimeFromTime(next)
+		due.DaysRemaining = &remaining
+		due.Overdue = due.Overdue || remaining < 0
+	}
+
+	return due
+}
+
+// DueWithin reports whether the service is overdue or will come due within the given miles or days
+func (d MaintenanceDue) DueWithin(miles, days int) bool {
+	return d.Overdue ||
+		(d.MilesRemaining != nil && *d.MilesRemaining <= miles) ||
+		(d.DaysRemaining != nil && *d.DaysRemaining <= days)
+}
+
+// SortMaintenanceDue puts overdue services first, then groups the rest by truck
+func SortMaintenanceDue(due []MaintenanceDue) {
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].Overdue != due[j].Overdue {
+			return due[i].Overdue
+		}
+		if due[i].TruckNumber != due[j].TruckNumber {
+			return due[i].TruckNumber < due[j].TruckNumber
+		}
+		return due[i].ScheduleName < due[j].ScheduleName
+	})
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMaintenanceScheduleDue(t *testing.T) {
+	now := time.Date(2024, 6, 15, 14, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) primitive.DateTime {
+		return primitive.NewDateTimeFromTime(now.AddDate(0, 0, -days))
+	}
+
+	// an old truck that was already on the road when the schedule was set up, and one bought since
+	veteran := &Truck{ID: primitive.NewObjectID(), TruckNumber: "T1", Mileage: 104_000, InServiceMileage: 2_000, CreatedAt: daysAgo(400)}
+	newcomer := &Truck{ID: primitive.NewObjectID(), TruckNumber: "T2", Mileage: 8_000, InServiceMileage: 2_000, CreatedAt: daysAgo(20)}
+
+	schedule := func(miles, days int, baselines ...MaintenanceBaseline) *MaintenanceSchedule {
+		return &MaintenanceSchedule{
+			ID:            primitive.NewObjectID(),
+			Name:          "oil change",
+			IntervalMiles: miles,
+			IntervalDays:  days,
+			Baselines:     baselines,
+			CreatedAt:     daysAgo(60),
+		}
+	}
+	veteranBaseline := MaintenanceBaseline{TruckID: veteran.ID, Mileage: 100_000, Date: daysAgo(60)}
+	logged := &MaintenanceLog{Mileage: 103_500, Date: daysAgo(10)}
+
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name              string
+		schedule          *MaintenanceSchedule
+		truck             *Truck
+		last              *MaintenanceLog
+		wantNextMileage   *int
+		wantMilesLeft     *int
+		wantNextDueOn     time.Time
+		wantDaysRemaining *int
+		wantOverdue       bool
+	}{
+		{
+			name:            "counted from the truck's mileage when the schedule started",
+			schedule:        schedule(5_000, 0, veteranBaseline),
+			truck:           veteran,
+			wantNextMileage: intPtr(105_000),
+			wantMilesLeft:   intPtr(1_000),
+		},
+		{
+			name:            "a truck added later is counted from the mileage it was put in service with",
+			schedule:        schedule(5_000, 0, veteranBaseline),
+			truck:           newcomer,
+			wantNextMileage: intPtr(7_000),
+			wantMilesLeft:   intPtr(-1_000),
+			wantOverdue:     true,
+		},
+		{
+			name:            "counted from the last service once there is one",
+			schedule:        schedule(5_000, 0, veteranBaseline),
+			truck:           veteran,
+			last:            logged,
+			wantNextMileage: intPtr(108_500),
+			wantMilesLeft:   intPtr(4_500),
+		},
+		{
+			name:              "days counted from when the schedule started",
+			schedule:          schedule(0, 90, veteranBaseline),
+			truck:             veteran,
+			wantNextDueOn:     now.AddDate(0, 0, 30).Truncate(24 * time.Hour),
+			wantDaysRemaining: intPtr(30),
+		},
+		{
+			name:              "days for a truck added later counted from the day it was added",
+			schedule:          schedule(0, 14),
+			truck:             newcomer,
+			wantNextDueOn:     now.AddDate(0, 0, -6).Truncate(24 * time.Hour),
+			wantDaysRemaining: intPtr(-6),
+			wantOverdue:       true,
+		},
+		{
+			name:              "days for a truck without a baseline that predates the schedule",
+			schedule:          schedule(0, 90),
+			truck:             veteran,
+			wantNextDueOn:     now.AddDate(0, 0, 30).Truncate(24 * time.Hour),
+			wantDaysRemaining: intPtr(30),
+		},
+		{
+			name:              "overdue on time even with miles to spare",
+			schedule:          schedule(5_000, 7, veteranBaseline),
+			truck:             veteran,
+			last:              logged,
+			wantNextMileage:   intPtr(108_500),
+			wantMilesLeft:     intPtr(4_500),
+			wantNextDueOn:     now.AddDate(0, 0, -3).Truncate(24 * time.Hour),
+			wantDaysRemaining: intPtr(-3),
+			wantOverdue:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.Due(tt.truck, tt.last, now)
+
+			if !equalIntPtr(got.NextDueMileage, tt.wantNextMileage) {
+				t.Errorf("NextDueMileage = %v, want %v", derefInt(got.NextDueMileage), derefInt(tt.wantNextMileage))
+			}
+			if !equalIntPtr(got.MilesRemaining, tt.wantMilesLeft) {
+				t.Errorf("MilesRemaining = %v, want %v", derefInt(got.MilesRemaining), derefInt(tt.wantMilesLeft))
+			}
+			if !equalIntPtr(got.DaysRemaining, tt.wantDaysRemaining) {
+				t.Errorf("DaysRemaining = %v, want %v", derefInt(got.DaysRemaining), derefInt(tt.wantDaysRemaining))
+			}
+			if !tt.wantNextDueOn.IsZero() && !got.NextDueOn.Time().Equal(tt.wantNextDueOn) {
+				t.Errorf("NextDueOn = %v, want %v", got.NextDueOn.Time().UTC(), tt.wantNextDueOn)
+			}
+			if got.Overdue != tt.wantOverdue {
+				t.Errorf("Overdue = %v, want %v", got.Overdue, tt.wantOverdue)
+			}
+			if got.CurrentMileage != tt.truck.Mileage {
+				t.Errorf("CurrentMileage = %d, want %d", got.CurrentMileage, tt.truck.Mileage)
+			}
+		})
+	}
+}
+
+func TestMaintenanceScheduleBaseline(t *testing.T) {
+	now := time.Date(2024, 6, 15, 14, 0, 0, 0, time.UTC)
+
+	volvo := &Truck{ID: primitive.NewObjectID(), Make: "Volvo", Mileage: 50_000}
+	kenworth := &Truck{ID: primitive.NewObjectID(), Make: "Kenworth", Mileage: 70_000}
+	earlier := MaintenanceBaseline{TruckID: volvo.ID, Mileage: 40_000, Date: primitive.NewDateTimeFromTime(now.AddDate(0, -1, 0))}
+
+	schedule := &MaintenanceSchedule{Make: "volvo", Baselines: []MaintenanceBaseline{earlier}}
+	another := &Truck{ID: primitive.NewObjectID(), Make: "VOLVO", Mileage: 12_000}
+	schedule.Baseline([]*Truck{volvo, kenworth, another}, now)
+
+	want := []MaintenanceBaseline{
+		earlier,
+		{TruckID: another.ID, Mileage: 12_000, Date: primitive.NewDateTimeFromTime(now)},
+	}
+	if len(schedule.Baselines) != len(want) {
+		t.Fatalf("Baselines = %+v, want %+v", schedule.Baselines, want)
+	}
+	for i := range want {
+		if schedule.Baselines[i] != want[i] {
+			t.Errorf("Baselines[%d] = %+v, want %+v", i, schedule.Baselines[i], want[i])
+		}
+	}
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func derefInt(v *int) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,254 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxPlausibleSpeedMPH is the fastest a truck is taken to average between two odometer readings. covering more
+// ground than that means one of the readings is wrong.
+const MaxPlausibleSpeedMPH = 80
+
+type OdometerDiscrepancyReason string
+
+const (
+	OdometerWentBackward       OdometerDiscrepancyReason = "WENT_BACKWARD"
+	OdometerImpossibleDistance OdometerDiscrepancyReason = "IMPOSSIBLE_DISTANCE"
+	OdometerOutsideTrip        OdometerDiscrepancyReason = "OUTSIDE_TRIP"
+)
+
+type OdometerSource string
+
+const (
+	OdometerSourceTrip    OdometerSource = "TRIP"
+	OdometerSourceFuelLog OdometerSource = "FUEL_LOG"
+)
+
+// an OdometerDiscrepancy is a trip or fuel log whose reading doesn't square with the one before it, or with the
+// trip it was taken on
+type OdometerDiscrepancy struct {
+	TruckID         primitive.ObjectID
+	TruckNumber     string
+	Source          OdometerSource
+	SourceID        primitive.ObjectID
+	Reason          OdometerDiscrepancyReason
+	RecordedAt      primitive.DateTime
+	Reading         int
+	PreviousReading *int
+	AllowedMiles    *int
+}
+
+// RecordOdometer moves the truck's mileage up to a reading taken at the given time. odometers only count up, so a
+// reading below the current one is refused, and so is one further along than the truck could have driven since its
+// last reading.
+func (t *Truck) RecordOdometer(reading int, at time.Time) error {
+	if reading < t.Mileage {
+		return &OdometerRollbackError{TruckID: t.ID, Reading: reading, Minimum: t.Mileage}
+	}
+
+	if reading > t.Mileage && t.MileageRecordedAt != 0 {
+		hours := at.Sub(t.MileageRecordedAt.Time()).Hours()
+		if allowed := max(plausibleMiles(hours), 0); reading-t.Mileage > allowed {
+			return &OdometerImplausibleError{TruckID: t.ID, Reading: reading, Previous: t.Mileage, AllowedMiles: allowed}
+		}
+	}
+
+	t.Mileage = reading
+	if recordedAt := primitive.NewDateTimeFromTime(at); recordedAt > t.MileageRecordedAt {
+		t.MileageRecordedAt = recordedAt
+	}
+	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+// CorrectOdometer sets the truck's mileage to a reading without holding it to the ones before, for putting right an
+// odometer that a bad reading pushed too far or that was replaced
+func (t *Truck) CorrectOdometer(reading int, at time.Time) error {
+	if reading < 0 {
+		return fmt.Errorf("mileage can't be negative")
+	}
+
+	t.Mileage = reading
+	t.MileageRecordedAt = primitive.NewDateTimeFromTime(at)
+	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+// MinimumOdometer is the lowest reading the truck could show on the trip. until the trip is over the truck's latest
+// reading is the floor; afterwards anything since the truck left will do.
+func (t *Trip) MinimumOdometer(truck *Truck) int {
+	switch t.Status {
+	case TripStatusCompleted, TripStatusFailedDelivery, TripStatusCanceled:
+		if t.StartOdometer != nil {
+			return *t.StartOdometer
+		}
+		return 0
+	}
+
+	return truck.Mileage
+}
+
+// CheckOdometer holds a fuel log's reading up against the truck's other readings. one bought on a trip is held to
+// the trip's floor, which lets a fill-up be logged once the trip is over. any other has to fall between the readings
+// taken on the days before and after its date, so a receipt that turns up late is checked against where the truck
+// was then rather than where it is now.
+func (f *FuelLog) CheckOdometer(truck *Truck, trip *Trip, fuelLogs []*FuelLog) error {
+	if trip != nil {
+		if minimum := trip.MinimumOdometer(truck); f.OdometerReading < minimum {
+			return &OdometerRollbackError{TruckID: truck.ID, Reading: f.OdometerReading, Minimum: minimum}
+		}
+		return nil
+	}
+
+	day := f.Date.Time().UTC().Truncate(24 * time.Hour)
+	var floor, ceiling *int
+	around := func(reading int, at time.Time) {
+		switch taken := at.UTC().Truncate(24 * time.Hour); {
+		case taken.Before(day) && (floor == nil || reading > *floor):
+			floor = &reading
+		case taken.After(day) && (ceiling == nil || reading < *ceiling):
+			ceiling = &reading
+		}
+	}
+
+	if truck.MileageRecordedAt != 0 {
+		around(truck.Mileage, truck.MileageRecordedAt.Time())
+	}
+	for _, other := range fuelLogs {
+		if other.ID != f.ID && other.TruckID == f.TruckID {
+			around(other.OdometerReading, other.Date.Time())
+		}
+	}
+
+	if floor != nil && f.OdometerReading < *floor {
+		return &OdometerRollbackError{TruckID: truck.ID, Reading: f.OdometerReading, Minimum: *floor}
+	}
+	if ceiling != nil && f.OdometerReading > *ceiling {
+		return &OdometerAheadError{TruckID: truck.ID, Reading: f.OdometerReading, Maximum: *ceiling}
+	}
+
+	return nil
+}
+
+// ReadingTakenBy is the latest the fuel log's odometer reading could have been taken. logs are only dated to the
+// day, so that's the end of the day, or now for one logged today.
+func (f *FuelLog) ReadingTakenBy(now time.Time) time.Time {
+	endOfDay := f.Date.Time().AddDate(0, 0, 1)
+	if endOfDay.After(now) {
+		return now
+	}
+
+	return endOfDay
+}
+
+// FindOdometerDiscrepancies checks a truck's trips and fuel logs, each in the order they happened, for readings that
+// go backward or cover more miles than the time between them allows. fuel logs are also checked against the trip
+// they were bought on.
+func FindOdometerDiscrepancies(truck *Truck, trips []*Trip, fuelLogs []*FuelLog) []OdometerDiscrepancy {
+	discrepancies := make([]OdometerDiscrepancy, 0)
+
+	flag := func(source OdometerSource, id primitive.ObjectID, reason OdometerDiscrepancyReason, at primitive.DateTime, reading int, previous, allowed *int) {
+		discrepancies = append(discrepancies, OdometerDiscrepancy{
+			TruckID:         truck.ID,
+			TruckNumber:     truck.TruckNumber,
+			Source:          source,
+			SourceID:        id,
+			Reason:          reason,
+			RecordedAt:      at,
+			Reading:         reading,
+			PreviousReading: previous,
+			AllowedMiles:    allowed,
+		})
+	}
+
+	departed := make([]*Trip, 0, len(trips))
+	tripsByID := make(map[primitive.ObjectID]*Trip, len(trips))
+	for _, trip := range trips {
+		tripsByID[trip.ID] = trip
+		if trip.StartOdometer != nil && trip.DepartureTime.Actual != nil {
+			departed = append(departed, trip)
+		}
+	}
+	sort.SliceStable(departed, func(i, j int) bool {
+		return *departed[i].DepartureTime.Actual < *departed[j].DepartureTime.Actual
+	})
+
+	var previousEnd *int
+	for _, trip := range departed {
+		start := *trip.StartOdometer
+		if previousEnd != nil && start < *previousEnd {
+			flag(OdometerSourceTrip, trip.ID, OdometerWentBackward, *trip.DepartureTime.Actual, start, previousEnd, nil)
+		}
+
+		if trip.EndOdometer == nil || trip.ArrivalTime.Actual == nil {
+			continue
+		}
+
+		end := *trip.EndOdometer
+		hours := trip.ArrivalTime.Actual.Time().Sub(trip.DepartureTime.Actual.Time()).Hours()
+		if allowed := plausibleMiles(hours); end-start > allowed {
+			flag(OdometerSourceTrip, trip.ID, OdometerImpossibleDistance, *trip.ArrivalTime.Actual, end, &start, &allowed)
+		}
+		previousEnd = &end
+	}
+
+	sorted := make([]*FuelLog, len(fuelLogs))
+	copy(sorted, fuelLogs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Date != sorted[j].Date {
+			return sorted[i].Date < sorted[j].Date
+		}
+		return sorted[i].CreatedAt < sorted[j].CreatedAt
+	})
+
+	var previous *FuelLog
+	for _, fuelLog := range sorted {
+		reading := fuelLog.OdometerReading
+
+		if previous != nil {
+			last := previous.OdometerReading
+			if reading < last {
+				flag(OdometerSourceFuelLog, fuelLog.ID, OdometerWentBackward, fuelLog.Date, reading, &last, nil)
+			}
+
+			// fuel logs are only dated to the day, so the two could be as much as a day further apart than their
+			// dates say
+			days := fuelLog.Date.Time().Sub(previous.Date.Time()).Hours()/24 + 1
+			if allowed := plausibleMiles(days * 24); reading-last > allowed {
+				flag(OdometerSourceFuelLog, fuelLog.ID, OdometerImpossibleDistance, fuelLog.Date, reading, &last, &allowed)
+			}
+		}
+		previous = fuelLog
+
+		if fuelLog.TripID == nil {
+			continue
+		}
+		if trip, ok := tripsByID[*fuelLog.TripID]; ok {
+			if (trip.StartOdometer != nil && reading < *trip.StartOdometer) || (trip.EndOdometer != nil && reading > *trip.EndOdometer) {
+				flag(OdometerSourceFuelLog, fuelLog.ID, OdometerOutsideTrip, fuelLog.Date, reading, nil, nil)
+			}
+		}
+	}
+
+	return discrepancies
+}
+
+func plausibleMiles(hours float64) int {
+	return int(math.Ceil(hours * MaxPlausibleSpeedMPH))
+}
+
+// SortOdometerDiscrepancies orders discrepancies by truck, then by when the reading was taken
+func SortOdometerDiscrepancies(discrepancies []OdometerDiscrepancy) {
+	sort.SliceStable(discrepancies, func(i, j int) bool {
+		if discrepancies[i].TruckNumber != discrepancies[j].TruckNumber {
+			return discrepancies[i].TruckNumber < discrepancies[j].TruckNumber
+		}
+		return discrepancies[i].RecordedAt < discrepancies[j].RecordedAt
+	})
+}
@@ -0,0 +1,309 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var odometerStart = time.Date(2024, 4, 1, 6, 0, 0, 0, time.UTC)
+
+func odometerTime(hours float64) *primitive.DateTime {
+	at := primitive.NewDateTimeFromTime(odometerStart.Add(time.Duration(hours * float64(time.Hour))))
+	return &at
+}
+
+// odometerTrip is a trip that left at the given hour with the given reading and, when arrived is positive, arrived
+// that many hours later with the end reading
+func odometerTrip(departed float64, start int, arrived float64, end int) *Trip {
+	trip := &Trip{ID: primitive.NewObjectID(), StartOdometer: &start}
+	trip.DepartureTime.Actual = odometerTime(departed)
+	if arrived > 0 {
+		trip.EndOdometer = &end
+		trip.ArrivalTime.Actual = odometerTime(departed + arrived)
+	}
+	return trip
+}
+
+func odometerFuelLog(day int, reading int, trip *Trip) *FuelLog {
+	fuelLog := &FuelLog{
+		ID:              primitive.NewObjectID(),
+		Date:            primitive.NewDateTimeFromTime(odometerStart.Truncate(24*time.Hour).AddDate(0, 0, day)),
+		OdometerReading: reading,
+	}
+	if trip != nil {
+		fuelLog.TripID = &trip.ID
+	}
+	return fuelLog
+}
+
+func TestFindOdometerDiscrepancies(t *testing.T) {
+	type flagged struct {
+		source   OdometerSource
+		reason   OdometerDiscrepancyReason
+		reading  int
+		previous any
+		allowed  any
+	}
+
+	first := odometerTrip(0, 1_000, 10, 1_500)
+	onFirst := odometerFuelLog(0, 1_200, first)
+
+	tests := []struct {
+		name     string
+		trips    []*Trip
+		fuelLogs []*FuelLog
+		want     []flagged
+	}{
+		{
+			name: "readings that add up",
+			trips: []*Trip{
+				first,
+				odometerTrip(24, 1_500, 2, 1_600),
+			},
+			fuelLogs: []*FuelLog{
+				onFirst,
+				odometerFuelLog(1, 1_550, nil),
+			},
+		},
+		{
+			name: "trip that starts behind where the last one ended",
+			trips: []*Trip{
+				first,
+				odometerTrip(24, 1_400, 2, 1_500),
+			},
+			want: []flagged{
+				{OdometerSourceTrip, OdometerWentBackward, 1_400, 1_500, nil},
+			},
+		},
+		{
+			name: "trips are read in the order they left",
+			trips: []*Trip{
+				odometerTrip(24, 1_500, 2, 1_600),
+				first,
+			},
+		},
+		{
+			name: "trip that covered more than the time allows",
+			trips: []*Trip{
+				odometerTrip(0, 1_000, 10, 1_900),
+			},
+			want: []flagged{
+				{OdometerSourceTrip, OdometerImpossibleDistance, 1_900, 1_000, 800},
+			},
+		},
+		{
+			name: "trips that haven't left or finished",
+			trips: []*Trip{
+				{ID: primitive.NewObjectID()},
+				odometerTrip(0, 1_000, 0, 0),
+				odometerTrip(24, 900, 2, 1_000),
+			},
+		},
+		{
+			name: "fuel log that goes backward",
+			fuelLogs: []*FuelLog{
+				odometerFuelLog(1, 1_900, nil),
+				odometerFuelLog(0, 2_000, nil),
+			},
+			want: []flagged{
+				{OdometerSourceFuelLog, OdometerWentBackward, 1_900, 2_000, nil},
+			},
+		},
+		{
+			name: "fuel logs a day apart can be up to two days' driving apart",
+			fuelLogs: []*FuelLog{
+				odometerFuelLog(0, 1_000, nil),
+				odometerFuelLog(1, 4_840, nil),
+				odometerFuelLog(2, 8_841, nil),
+			},
+			want: []flagged{
+				{OdometerSourceFuelLog, OdometerImpossibleDistance, 8_841, 4_840, 3_840},
+			},
+		},
+		{
+			name:  "fuel log outside the trip it was bought on",
+			trips: []*Trip{first},
+			fuelLogs: []*FuelLog{
+				odometerFuelLog(0, 900, first),
+				odometerFuelLog(0, 1_600, first),
+			},
+			want: []flagged{
+				{OdometerSourceFuelLog, OdometerOutsideTrip, 900, nil, nil},
+				{OdometerSourceFuelLog, OdometerOutsideTrip, 1_600, nil, nil},
+			},
+		},
+	}
+
+	deref := func(v *int) any {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truck := &Truck{ID: primitive.NewObjectID(), TruckNumber: "T1"}
+
+			got := make([]flagged, 0)
+			for _, discrepancy := range FindOdometerDiscrepancies(truck, tt.trips, tt.fuelLogs) {
+				if discrepancy.TruckID != truck.ID {
+					t.Errorf("discrepancy is for truck %s, want %s", discrepancy.TruckID.Hex(), truck.ID.Hex())
+				}
+				got = append(got, flagged{
+					source:   discrepancy.Source,
+					reason:   discrepancy.Reason,
+					reading:  discrepancy.Reading,
+					previous: deref(discrepancy.PreviousReading),
+					allowed:  deref(discrepancy.AllowedMiles),
+				})
+			}
+			if tt.want == nil {
+				tt.want = []flagged{}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindOdometerDiscrepancies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruckRecordOdometer(t *testing.T) {
+	recorded := primitive.NewDateTimeFromTime(odometerStart)
+
+	tests := []struct {
+		name        string
+		recordedAt  primitive.DateTime
+		reading     int
+		at          time.Time
+		wantMileage int
+		wantErr     any
+	}{
+		{name: "same reading", recordedAt: recorded, reading: 10_000, at: odometerStart, wantMileage: 10_000},
+		{name: "within reach", recordedAt: recorded, reading: 10_800, at: odometerStart.Add(10 * time.Hour), wantMileage: 10_800},
+		{name: "below the last reading", recordedAt: recorded, reading: 9_999, at: odometerStart.Add(time.Hour), wantMileage: 10_000, wantErr: &OdometerRollbackError{}},
+		{name: "further than the truck could drive", recordedAt: recorded, reading: 10_801, at: odometerStart.Add(10 * time.Hour), wantMileage: 10_000, wantErr: &OdometerImplausibleError{}},
+		{name: "higher reading taken before the last one", recordedAt: recorded, reading: 10_001, at: odometerStart.Add(-time.Hour), wantMileage: 10_000, wantErr: &OdometerImplausibleError{}},
+		{name: "no time on record for the last reading", reading: 500_000, at: odometerStart, wantMileage: 500_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truck := &Truck{ID: primitive.NewObjectID(), Mileage: 10_000, MileageRecordedAt: tt.recordedAt}
+
+			err := truck.RecordOdometer(tt.reading, tt.at)
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("RecordOdometer() error = %v", err)
+				}
+				if want := max(tt.recordedAt, primitive.NewDateTimeFromTime(tt.at)); truck.MileageRecordedAt != want {
+					t.Errorf("MileageRecordedAt = %v, want %v", truck.MileageRecordedAt.Time(), want.Time())
+				}
+			case *OdometerRollbackError:
+				if !errors.As(err, &want) {
+					t.Errorf("RecordOdometer() error = %v, want an OdometerRollbackError", err)
+				}
+			case *OdometerImplausibleError:
+				if !errors.As(err, &want) {
+					t.Errorf("RecordOdometer() error = %v, want an OdometerImplausibleError", err)
+				}
+			}
+
+			if truck.Mileage != tt.wantMileage {
+				t.Errorf("Mileage = %d, want %d", truck.Mileage, tt.wantMileage)
+			}
+		})
+	}
+}
+
+func TestFuelLogCheckOdometer(t *testing.T) {
+	truck := &Truck{
+		ID:                primitive.NewObjectID(),
+		Mileage:           5_000,
+		MileageRecordedAt: *odometerTime(10*24 + 4),
+	}
+	unrecorded := &Truck{ID: truck.ID, Mileage: 5_000}
+
+	onTruck := func(fuelLog *FuelLog) *FuelLog {
+		fuelLog.TruckID = truck.ID
+		return fuelLog
+	}
+	others := []*FuelLog{
+		onTruck(odometerFuelLog(1, 800, nil)),
+		onTruck(odometerFuelLog(3, 1_000, nil)),
+		odometerFuelLog(2, 3_000, nil),
+	}
+
+	underway := odometerTrip(10*24, 5_000, 0, 0)
+	underway.Status = TripStatusInTransit
+	finished := odometerTrip(9*24, 4_000, 10, 4_600)
+	finished.Status = TripStatusCompleted
+
+	tests := []struct {
+		name     string
+		truck    *Truck
+		fuelLog  *FuelLog
+		trip     *Trip
+		fuelLogs []*FuelLog
+		wantErr  error
+	}{
+		{name: "between the days around it", truck: truck, fuelLog: odometerFuelLog(2, 900, nil), fuelLogs: others},
+		{name: "a late receipt behind the truck's latest reading", truck: truck, fuelLog: odometerFuelLog(5, 2_000, nil), fuelLogs: others},
+		{
+			name:     "below a reading from an earlier day",
+			truck:    truck,
+			fuelLog:  odometerFuelLog(2, 799, nil),
+			fuelLogs: others,
+			wantErr:  &OdometerRollbackError{TruckID: truck.ID, Reading: 799, Minimum: 800},
+		},
+		{
+			name:     "past a reading from a later day",
+			truck:    truck,
+			fuelLog:  odometerFuelLog(2, 1_001, nil),
+			fuelLogs: others,
+			wantErr:  &OdometerAheadError{TruckID: truck.ID, Reading: 1_001, Maximum: 1_000},
+		},
+		{
+			name:     "past the truck's reading from a later day",
+			truck:    truck,
+			fuelLog:  odometerFuelLog(5, 5_001, nil),
+			fuelLogs: others,
+			wantErr:  &OdometerAheadError{TruckID: truck.ID, Reading: 5_001, Maximum: 5_000},
+		},
+		{
+			name:     "below the truck's reading from an earlier day",
+			truck:    truck,
+			fuelLog:  odometerFuelLog(11, 4_999, nil),
+			fuelLogs: others,
+			wantErr:  &OdometerRollbackError{TruckID: truck.ID, Reading: 4_999, Minimum: 5_000},
+		},
+		{name: "readings from the same day aren't ordered", truck: truck, fuelLog: odometerFuelLog(1, 700, nil), fuelLogs: others},
+		{name: "the log isn't held to itself", truck: truck, fuelLog: others[0], fuelLogs: others},
+		{name: "a truck without a time on its reading", truck: unrecorded, fuelLog: odometerFuelLog(5, 6_000, nil), fuelLogs: others},
+		{
+			name:    "bought on a trip that's underway",
+			truck:   truck,
+			fuelLog: odometerFuelLog(10, 4_999, underway),
+			trip:    underway,
+			wantErr: &OdometerRollbackError{TruckID: truck.ID, Reading: 4_999, Minimum: 5_000},
+		},
+		{name: "bought on a trip that's over", truck: truck, fuelLog: odometerFuelLog(9, 4_100, finished), trip: finished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fuelLog.TruckID = truck.ID
+
+			err := tt.fuelLog.CheckOdometer(tt.truck, tt.trip, tt.fuelLogs)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("CheckOdometer() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// an Organization owns the fleet: every driver, truck, facility, trip and log belongs to one, and users see
+// whatever their organizations own according to the role their membership grants them
+type Organization struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+func NewOrganization(name string) (*Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("organization name is required")
+	}
+
+	now := time.Now()
+
+	return &Organization{
+		Name:      name,
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+		UpdatedAt: primitive.NewDateTimeFromTime(now),
+	}, nil
+}
+
+type Role string
+
+const (
+	RoleOwner      Role = "OWNER"
+	RoleDispatcher Role = "DISPATCHER"
+	RoleMechanic   Role = "MECHANIC"
+	RoleDriver     Role = "DRIVER"
+	RoleReadOnly   Role = "READ_ONLY"
+)
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) HasPermission(permission Permission) bool {
+	return rolePermissions[r][permission]
+}
+
+type Permission string
+
+const (
+	PermissionDriversRead          Permission = "drivers:read"
+	PermissionDriversWrite         Permission = "drivers:write"
+	PermissionFacilitiesRead       Permission = "facilities:read"
+	PermissionFacilitiesWrite      Permission = "facilities:write"
+	PermissionFuelLogsRead         Permission = "fuel_logs:read"
+	PermissionFuelLogsWrite        Permission = "fuel_logs:write"
+	PermissionIncidentReportsRead  Permission = "incident_reports:read"
+	PermissionIncidentReportsWrite Permission = "incident_reports:write"
+	PermissionMaintenanceLogsRead  Permission = "maintenance_logs:read"
+	PermissionMaintenanceLogsWrite Permission = "maintenance_logs:write"
+	PermissionTripsRead            Permission = "trips:read"
+	PermissionTripsWrite           Permission = "trips:write"
+	PermissionTripsDispatch        Permission = "trips:dispatch"
+	PermissionTrucksRead           Permission = "trucks:read"
+	PermissionTrucksWrite          Permission = "trucks:write"
+	PermissionOdometerCorrect      Permission = "trucks:correct_odometer"
+	PermissionPositionsWrite       Permission = "positions:write"
+	PermissionHOSWrite             Permission = "hos:write"
+	PermissionHistoryRead          Permission = "history:read"
+	PermissionMembersRead          Permission = "members:read"
+	PermissionMembersManage        Permission = "members:manage"
+)
+
+var readPermissions = []Permission{
+	PermissionDriversRead,
+	PermissionFacilitiesRead,
+	PermissionFuelLogsRead,
+	PermissionIncidentReportsRead,
+	PermissionMaintenanceLogsRead,
+	PermissionTripsRead,
+	PermissionTrucksRead,
+	PermissionHistoryRead,
+	PermissionMembersRead,
+}
+
+// every role can read the whole fleet; roles differ only in what they can change
+var rolePermissions = map[Role]map[Permission]bool{
+	RoleOwner: permissionSet(
+		PermissionDriversWrite,
+		PermissionFacilitiesWrite,
+		PermissionFuelLogsWrite,
+		PermissionIncidentReportsWrite,
+		PermissionMaintenanceLogsWrite,
+		PermissionTripsWrite,
+		PermissionTripsDispatch,
+		PermissionTrucksWrite,
+		PermissionOdometerCorrect,
+		PermissionPositionsWrite,
+		PermissionHOSWrite,
+		PermissionMembersManage,
+	),
+	RoleDispatcher: permissionSet(
+		PermissionDriversWrite,
+		PermissionFacilitiesWrite,
+		PermissionFuelLogsWrite,
+		PermissionIncidentReportsWrite,
+		PermissionTripsWrite,
+		PermissionTripsDispatch,
+		PermissionTrucksWrite,
+		PermissionPositionsWrite,
+		PermissionHOSWrite,
+	),
+	RoleMechanic: permissionSet(
+		PermissionMaintenanceLogsWrite,
+		PermissionTrucksWrite,
+	),
+	RoleDriver: permissionSet(
+		PermissionFuelLogsWrite,
+		PermissionIncidentReportsWrite,
+		PermissionTripsDispatch,
+		PermissionPositionsWrite,
+		PermissionHOSWrite,
+	),
+	RoleReadOnly: permissionSet(),
+}
+
+func permissionSet(permissions ...Permission) map[Permission]bool {
+	set := make(map[Permission]bool, len(readPermissions)+len(permissions))
+	for _, permission := range readPermissions {
+		set[permission] = true
+	}
+	for _, permission := range permissions {
+		set[permission] = true
+	}
+
+	return set
+}
+
+// a Membership ties a user to an organization with a single role
+type Membership struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	Organization   *Organization      `bson:"organization,omitempty" json:"organization,omitempty"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	User           *User              `bson:"user,omitempty" json:"user,omitempty"`
+	Role           Role               `bson:"role" json:"role"`
+	CreatedAt      primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt      primitive.DateTime `bson:"updated_at" json:"updated_at"`
+
+	// a request made with an api key is limited to the key's scopes on top of the role
+	Scopes []Permission `bson:"-" json:"-"`
+}
+
+func NewMembership(organizationID, userID primitive.ObjectID, role Role) (*Membership, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("invalid role provided: %s", role)
+	}
+
+	now := time.Now()
+
+	return &Membership{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+		CreatedAt:      primitive.NewDateTimeFromTime(now),
+		UpdatedAt:      primitive.NewDateTimeFromTime(now),
+	}, nil
+}
+
+func (m *Membership) HasPermission(permission Permission) bool {
+	if !m.Role.HasPermission(permission) {
+		return false
+	}
+
+	if m.Scopes == nil {
+		return true
+	}
+
+	for _, scope := range m.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+type membershipContextKey struct{}
+
+// ContextWithMembership records the membership a request is acting under: which organization's data it can
+// reach and what its role lets it do there
+func ContextWithMembership(ctx context.Context, membership *Membership) context.Context {
+	return context.WithValue(ctx, membershipContextKey{}, membership)
+}
+
+func MembershipFromContext(ctx context.Context) (*Membership, bool) {
+	membership, ok := ctx.Value(membershipContextKey{}).(*Membership)
+	return membership, ok && membership != nil
+}
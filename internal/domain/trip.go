@@ -22,6 +22,7 @@ const (
 
 type Trip struct {
 	ID              primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID  primitive.ObjectID         `bson:"organization_id" json:"organization_id"`
 	UserID          primitive.ObjectID         `bson:"user_id" json:"user_id"`
 	TripNumber      string                     `bson:"trip_number" json:"trip_number"`
 	DriverID        *primitive.ObjectID        `bson:"driver_id,omitempty" json:"driver_id,omitempty"`
@@ -32,12 +33,16 @@ type Trip struct {
 	StartFacility   *Facility                  `bson:"start_facility,omitempty" json:"start_facility,omitempty"`
 	EndFacilityID   *primitive.ObjectID        `bson:"end_facility_id,omitempty" json:"end_facility_id,omitempty"`
 	EndFacility     *Facility                  `bson:"end_facility,omitempty" json:"end_facility,omitempty"`
+	Stops           []TripStop                 `bson:"stops" json:"stops"`
 	DepartureTime   TimeWindow                 `bson:"departure_time" json:"departure_time"`
 	ArrivalTime     TimeWindow                 `bson:"arrival_time" json:"arrival_time"`
 	Status          TripStatus                 `bson:"status" json:"status"`
 	Cargo           Cargo                      `bson:"cargo" json:"cargo"`
 	FuelUsage       float64                    `bson:"fuel_usage_gallons" json:"fuel_usage_gallons"`
 	DistanceMiles   int                        `bson:"distance_miles" json:"distance_miles"`
+	StartOdometer   *int                       `bson:"start_odometer,omitempty" json:"start_odometer,omitempty"`
+	EndOdometer     *int                       `bson:"end_odometer,omitempty" json:"end_odometer,omitempty"`
+	Estimate        *TripEstimate              `bson:"estimate,omitempty" json:"estimate,omitempty"`
 	Notes           []TripNote                 `bson:"notes" json:"notes"`
 	CreatedAt       primitive.DateTime         `bson:"created_at" json:"created_at"`
 	UpdatedAt       primitive.DateTime         `bson:"updated_at" json:"updated_at"`
@@ -58,9 +63,14 @@ type Cargo struct {
 	Description string  `bson:"description" json:"description"`
 	Weight      float64 `bson:"weight" json:"weight"`
 	Hazmat      bool    `bson:"hazmat" json:"hazmat"`
+
+	// declared for hazmat cargo only
+	UNNumber    string      `bson:"un_number,omitempty" json:"un_number,omitempty"`
+	HazardClass HazardClass `bson:"hazard_class,omitempty" json:"hazard_class,omitempty"`
 }
 
 func NewTrip(
+	organizationID,
 	userID primitive.ObjectID,
 	tripNumber string,
 	driverId,
@@ -69,19 +79,30 @@ func NewTrip(
 	endFacilityID *primitive.ObjectID,
 	departureTime,
 	arrivalTime TimeWindow,
+	stops []TripStop,
 	cargo Cargo,
 	fuelUsage float64,
 	distanceMiles int) (*Trip, error) {
 
+	if stops == nil {
+		stops = make([]TripStop, 0)
+	}
+
+	if err := cargo.Validate(); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 
 	trip := &Trip{
+		OrganizationID:  organizationID,
 		UserID:          userID,
 		TripNumber:      tripNumber,
 		DriverID:        driverId,
 		TruckID:         truckId,
 		StartFacilityID: startFacilityID,
 		EndFacilityID:   endFacilityID,
+		Stops:           stops,
 		DepartureTime:   departureTime,
 		ArrivalTime:     arrivalTime,
 		Status:          TripStatusScheduled,
@@ -101,11 +122,12 @@ func NewTrip(
 }
 
 type TripFilter struct {
-	UserID          primitive.ObjectID
+	OrganizationID  primitive.ObjectID
 	DriverID        *primitive.ObjectID
 	TruckID         *primitive.ObjectID
 	StartFacilityID *primitive.ObjectID
 	EndFacilityID   *primitive.ObjectID
+	Deviating       bool
 	Limit           int64
 	Offset          int64
 }
@@ -156,7 +178,7 @@ func (t *Trip) BeginTrip(departureTime time.Time) error {
 	oldTrip := *t
 
 	if err := t.StateMachine.Transition(TripStatusInTransit); err != nil {
-		return fmt.Errorf("failed to start trip from status %s: %w", t.Status, err)
+		return &TripStateError{CurrentState: t.Status, DesiredState: TripStatusInTransit}
 	}
 
 	departure := primitive.NewDateTimeFromTime(departureTime)
@@ -175,16 +197,24 @@ func (t *Trip) BeginTrip(departureTime time.Time) error {
 
 func (t *Trip) CancelTrip() error {
 	if err := t.StateMachine.Transition(TripStatusCanceled); err != nil {
-		return fmt.Errorf("failed to cancel trip from status %s: %w", t.Status, err)
+		return &TripStateError{CurrentState: t.Status, DesiredState: TripStatusCanceled}
 	}
 
 	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
 	return nil
 }
 
+// a trip is only complete once every stop has been made or skipped. a trip that ends early goes through
+// CompleteTripUnsuccessfully instead.
 func (t *Trip) CompleteTripSuccessfully(arrivalTime time.Time) error {
+	if t.Status == TripStatusInTransit {
+		if stop := t.UnresolvedStop(); stop != nil {
+			return &TripStopError{StopID: stop.ID, Reason: "has to be departed or skipped before the trip can be completed"}
+		}
+	}
+
 	if err := t.StateMachine.Transition(TripStatusCompleted); err != nil {
-		return fmt.Errorf("failed to complete trip from status %s: %w", t.Status, err)
+		return &TripStateError{CurrentState: t.Status, DesiredState: TripStatusCompleted}
 	}
 
 	now := time.Now()
@@ -200,7 +230,7 @@ func (t *Trip) CompleteTripSuccessfully(arrivalTime time.Time) error {
 
 func (t *Trip) CompleteTripUnsuccessfully(arrivalTime time.Time) error {
 	if err := t.StateMachine.Transition(TripStatusFailedDelivery); err != nil {
-		return fmt.Errorf("failed to mark trip as failed delivery from status %s: %w", t.Status, err)
+		return &TripStateError{CurrentState: t.Status, DesiredState: TripStatusFailedDelivery}
 	}
 
 	now := time.Now()
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ConflictResource string
+
+const (
+	ConflictResourceDriver ConflictResource = "DRIVER"
+	ConflictResourceTruck  ConflictResource = "TRUCK"
+)
+
+// a TripConflict describes one driver or truck that is booked on two trips whose scheduled windows overlap
+type TripConflict struct {
+	Resource              ConflictResource   `json:"resource"`
+	ResourceID            primitive.ObjectID `json:"resource_id"`
+	TripID                primitive.ObjectID `json:"trip_id"`
+	TripNumber            string             `json:"trip_number"`
+	ConflictingTripID     primitive.ObjectID `json:"conflicting_trip_id"`
+	ConflictingTripNumber string             `json:"conflicting_trip_number"`
+	OverlapStart          primitive.DateTime `json:"overlap_start"`
+	OverlapEnd            primitive.DateTime `json:"overlap_end"`
+}
+
+// TripOverlapQuery finds the active trips that share a driver or truck with a trip during its scheduled window
+type TripOverlapQuery struct {
+	OrganizationID primitive.ObjectID
+	DriverID       *primitive.ObjectID
+	TruckID        *primitive.ObjectID
+	Start          primitive.DateTime
+	End            primitive.DateTime
+	ExcludeID      *primitive.ObjectID
+}
+
+// only trips that haven't finished yet can hold a driver or truck
+func ActiveTripStatuses() []TripStatus {
+	return []TripStatus{TripStatusScheduled, TripStatusInTransit}
+}
+
+func (t *Trip) IsActive() bool {
+	return t.Status == TripStatusScheduled || t.Status == TripStatusInTransit
+}
+
+// scheduled windows are half-open, so a trip that departs the moment another arrives does not overlap it
+func (t *Trip) OverlapsWith(other *Trip) bool {
+	return t.DepartureTime.Scheduled < other.ArrivalTime.Scheduled &&
+		other.DepartureTime.Scheduled < t.ArrivalTime.Scheduled
+}
+
+func (t *Trip) OverlapQuery() TripOverlapQuery {
+	query := TripOverlapQuery{
+		OrganizationID: t.OrganizationID,
+		DriverID:       t.DriverID,
+		TruckID:        t.TruckID,
+		Start:          t.DepartureTime.Scheduled,
+		End:            t.ArrivalTime.Scheduled,
+	}
+
+	if !t.ID.IsZero() {
+		id := t.ID
+		query.ExcludeID = &id
+	}
+
+	return query
+}
+
+// ConflictsWith lists every resource the two trips are both holding at the same time
+func (t *Trip) ConflictsWith(other *Trip) []TripConflict {
+	if t.ID == other.ID || !t.OverlapsWith(other) {
+		return nil
+	}
+
+	overlapStart := t.DepartureTime.Scheduled
+	if other.DepartureTime.Scheduled > overlapStart {
+		overlapStart = other.DepartureTime.Scheduled
+	}
+
+	overlapEnd := t.ArrivalTime.Scheduled
+	if other.ArrivalTime.Scheduled < overlapEnd {
+		overlapEnd = other.ArrivalTime.Scheduled
+	}
+
+	conflict := TripConflict{
+		TripID:                t.ID,
+		TripNumber:            t.TripNumber,
+		ConflictingTripID:     other.ID,
+		ConflictingTripNumber: other.TripNumber,
+		OverlapStart:          overlapStart,
+		OverlapEnd:            overlapEnd,
+	}
+
+	conflicts := make([]TripConflict, 0, 2)
+
+	if t.DriverID != nil && other.DriverID != nil && *t.DriverID == *other.DriverID {
+		driverConflict := conflict
+		driverConflict.Resource = ConflictResourceDriver
+		driverConflict.ResourceID = *t.DriverID
+		conflicts = append(conflicts, driverConflict)
+	}
+
+	if t.TruckID != nil && other.TruckID != nil && *t.TruckID == *other.TruckID {
+		truckConflict := conflict
+		truckConflict.Resource = ConflictResourceTruck
+		truckConflict.ResourceID = *t.TruckID
+		conflicts = append(conflicts, truckConflict)
+	}
+
+	return conflicts
+}
@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DeviationFieldDistance   = "distance_miles"
+	DeviationFieldTravelTime = "travel_hours"
+)
+
+// RoutePolicy holds the assumptions trip estimates are built on. the rest rules default to the federal hours of
+// service limits: a 30 minute break after 8 hours of driving, and 10 hours off after 11 hours behind the wheel.
+type RoutePolicy struct {
+	// straight-line distance is multiplied by this to approximate the miles actually driven
+	RoadFactor         float64
+	AverageSpeedMPH    float64
+	BreakAfter         time.Duration
+	BreakDuration      time.Duration
+	MaxDrivingPerShift time.Duration
+	OffDutyDuration    time.Duration
+
+	// entered values further than this fraction from the estimate are flagged
+	DeviationThreshold float64
+}
+
+// a TripEstimate is what the route says a trip should take. it's recalculated whenever the trip is saved.
+type TripEstimate struct {
+	DistanceMiles    int                 `bson:"distance_miles" json:"distance_miles"`
+	TravelMinutes    int                 `bson:"travel_minutes" json:"travel_minutes"`
+	SuggestedArrival *primitive.DateTime `bson:"suggested_arrival,omitempty" json:"suggested_arrival,omitempty"`
+	Deviations       []TripDeviation     `bson:"deviations" json:"deviations"`
+}
+
+// a TripDeviation is an entered value that is materially off from the estimate, which usually means a typo or a
+// route that isn't what it looks like
+type TripDeviation struct {
+	Field            string  `bson:"field" json:"field"`
+	Entered          float64 `bson:"entered" json:"entered"`
+	Estimated        float64 `bson:"estimated" json:"estimated"`
+	DeviationPercent float64 `bson:"deviation_percent" json:"deviation_percent"`
+}
+
+// RoadMiles is the driving distance along the route, visiting the points in order
+func (p RoutePolicy) RoadMiles(route []*GeoPoint) float64 {
+	miles := 0.0
+	for i := 1; i < len(route); i++ {
+		miles += route[i-1].DistanceMiles(route[i])
+	}
+
+	return miles * p.RoadFactor
+}
+
+// TravelTime is how long driving the distance takes once the required breaks and off-duty periods are added
+func (p RoutePolicy) TravelTime(miles float64) time.Duration {
+	if p.AverageSpeedMPH <= 0 || miles <= 0 {
+		return 0
+	}
+
+	driving := time.Duration(miles / p.AverageSpeedMPH * float64(time.Hour))
+	total := driving
+
+	shift := p.MaxDrivingPerShift
+	if shift <= 0 {
+		shift = driving
+	}
+
+	for remaining := driving; remaining > 0; remaining -= shift {
+		driven := remaining
+		if driven > shift {
+			driven = shift
+		}
+
+		if p.BreakAfter > 0 && driven > p.BreakAfter {
+			total += time.Duration((driven-1)/p.BreakAfter) * p.BreakDuration
+		}
+
+		if remaining > shift {
+			total += p.OffDutyDuration
+		}
+	}
+
+	return total
+}
+
+// ApplyEstimate works out the trip's distance and travel time over the route, including the time planned at each
+// stop, and fills in the distance and scheduled arrival if they were left off. whatever was entered is compared
+// against the estimate and flagged if it's too far off.
+func (t *Trip) ApplyEstimate(policy RoutePolicy, route []*GeoPoint) {
+	miles := policy.RoadMiles(route)
+	travel := policy.TravelTime(miles)
+	for _, stop := range t.Stops {
+		if stop.DepartureTime.Scheduled > stop.ArrivalTime.Scheduled {
+			travel += stop.DepartureTime.Scheduled.Time().Sub(stop.ArrivalTime.Scheduled.Time())
+		}
+	}
+	travel = travel.Round(time.Minute)
+
+	estimate := &TripEstimate{
+		DistanceMiles: int(math.Round(miles)),
+		TravelMinutes: int(travel.Minutes()),
+		Deviations:    make([]TripDeviation, 0),
+	}
+
+	if t.DistanceMiles == 0 {
+		t.DistanceMiles = estimate.DistanceMiles
+	} else if deviation, ok := deviates(DeviationFieldDistance, float64(t.DistanceMiles), miles, policy.DeviationThreshold); ok {
+		estimate.Deviations = append(estimate.Deviations, deviation)
+	}
+
+	if t.DepartureTime.Scheduled != 0 {
+		arrival := primitive.NewDateTimeFromTime(t.DepartureTime.Scheduled.Time().Add(travel))
+		estimate.SuggestedArrival = &arrival
+
+		if t.ArrivalTime.Scheduled == 0 {
+			t.ArrivalTime.Scheduled = arrival
+		} else {
+			entered := t.ArrivalTime.Scheduled.Time().Sub(t.DepartureTime.Scheduled.Time())
+			if deviation, ok := deviates(DeviationFieldTravelTime, entered.Hours(), travel.Hours(), policy.DeviationThreshold); ok {
+				estimate.Deviations = append(estimate.Deviations, deviation)
+			}
+		}
+	}
+
+	t.Estimate = estimate
+}
+
+func (t *Trip) HasDeviations() bool {
+	return t.Estimate != nil && len(t.Estimate.Deviations) > 0
+}
+
+func deviates(field string, entered, estimated, threshold float64) (TripDeviation, bool) {
+	if estimated <= 0 || threshold <= 0 {
+		return TripDeviation{}, false
+	}
+
+	fraction := (entered - estimated) / estimated
+	if math.Abs(fraction) <= threshold {
+		return TripDeviation{}, false
+	}
+
+	return TripDeviation{
+		Field:            field,
+		Entered:          math.Round(entered*10) / 10,
+		Estimated:        math.Round(estimated*10) / 10,
+		DeviationPercent: math.Round(fraction * 100),
+	}, true
+}
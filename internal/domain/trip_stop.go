@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TripStopType string
+
+const (
+	TripStopTypePickup   TripStopType = "PICKUP"
+	TripStopTypeDelivery TripStopType = "DELIVERY"
+)
+
+func (t TripStopType) IsValid() bool {
+	return t == TripStopTypePickup || t == TripStopTypeDelivery
+}
+
+type TripStopStatus string
+
+const (
+	TripStopStatusPending  TripStopStatus = "PENDING"
+	TripStopStatusArrived  TripStopStatus = "ARRIVED"
+	TripStopStatusDeparted TripStopStatus = "DEPARTED"
+	TripStopStatusSkipped  TripStopStatus = "SKIPPED"
+)
+
+// a TripStop is one pickup or drop on a multi-stop trip. stops are worked in the order they're listed, between
+// the trip's start and end facilities.
+type TripStop struct {
+	ID            primitive.ObjectID `bson:"_id" json:"id"`
+	FacilityID    primitive.ObjectID `bson:"facility_id" json:"facility_id"`
+	Facility      *Facility          `bson:"facility,omitempty" json:"facility,omitempty"`
+	Type          TripStopType       `bson:"type" json:"type"`
+	ArrivalTime   TimeWindow         `bson:"arrival_time" json:"arrival_time"`
+	DepartureTime TimeWindow         `bson:"departure_time" json:"departure_time"`
+	Cargo         Cargo              `bson:"cargo" json:"cargo"`
+	Status        TripStopStatus     `bson:"status" json:"status"`
+}
+
+func NewTripStop(
+	facilityID primitive.ObjectID,
+	stopType TripStopType,
+	arrivalTime,
+	departureTime primitive.DateTime,
+	cargo Cargo) (TripStop, error) {
+	if facilityID.IsZero() {
+		return TripStop{}, fmt.Errorf("stop facility is required")
+	}
+
+	if !stopType.IsValid() {
+		return TripStop{}, fmt.Errorf("invalid stop type provided: %s", stopType)
+	}
+
+	if departureTime < arrivalTime {
+		return TripStop{}, fmt.Errorf("stop departure can't be scheduled before its arrival")
+	}
+
+	if err := cargo.Validate(); err != nil {
+		return TripStop{}, fmt.Errorf("invalid stop cargo: %w", err)
+	}
+
+	return TripStop{
+		ID:            primitive.NewObjectID(),
+		FacilityID:    facilityID,
+		Type:          stopType,
+		ArrivalTime:   TimeWindow{Scheduled: arrivalTime},
+		DepartureTime: TimeWindow{Scheduled: departureTime},
+		Cargo:         cargo,
+		Status:        TripStopStatusPending,
+	}, nil
+}
+
+// a stop is resolved once the truck has left it or it has been dropped from the route
+func (s TripStop) IsResolved() bool {
+	return s.Status == TripStopStatusDeparted || s.Status == TripStopStatusSkipped
+}
+
+// ArriveAtStop records the truck reaching a stop. stops have to be reached in order, so every stop before it
+// has to be resolved first.
+func (t *Trip) ArriveAtStop(stopID primitive.ObjectID, arrivalTime time.Time) error {
+	if t.Status != TripStatusInTransit {
+		return &TripStopError{StopID: stopID, Reason: fmt.Sprintf("can't be reached while the trip is %s", t.Status)}
+	}
+
+	stops, i, err := t.editStop(stopID)
+	if err != nil {
+		return err
+	}
+
+	if stops[i].Status != TripStopStatusPending {
+		return &TripStopError{StopID: stopID, Reason: fmt.Sprintf("has already been %s", stops[i].Status)}
+	}
+
+	for _, previous := range stops[:i] {
+		if !previous.IsResolved() {
+			return &TripStopError{StopID: stopID, Reason: fmt.Sprintf("can't be reached before stop %s", previous.ID.Hex())}
+		}
+	}
+
+	arrival := primitive.NewDateTimeFromTime(arrivalTime)
+	stops[i].ArrivalTime.Actual = &arrival
+	stops[i].Status = TripStopStatusArrived
+
+	t.Stops = stops
+	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	return nil
+}
+
+func (t *Trip) DepartStop(stopID primitive.ObjectID, departureTime time.Time) error {
+	stops, i, err := t.editStop(stopID)
+	if err != nil {
+		return err
+	}
+
+	if stops[i].Status != TripStopStatusArrived {
+		return &TripStopError{StopID: stopID, Reason: fmt.Sprintf("can't be departed while %s", stops[i].Status)}
+	}
+
+	if departureTime.Before(stops[i].ArrivalTime.Actual.Time()) {
+		return &TripStopError{StopID: stopID, Reason: "can't be departed before it was reached"}
+	}
+
+	departure := primitive.NewDateTimeFromTime(departureTime)
+	stops[i].DepartureTime.Actual = &departure
+	stops[i].Status = TripStopStatusDeparted
+
+	t.Stops = stops
+	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	return nil
+}
+
+// SkipStop drops a stop the truck won't be making, such as a delivery the receiver has canceled. it can be done
+// ahead of time, so it isn't held to the order the stops are reached in.
+func (t *Trip) SkipStop(stopID primitive.ObjectID) error {
+	if t.Status != TripStatusScheduled && t.Status != TripStatusInTransit {
+		return &TripStopError{StopID: stopID, Reason: fmt.Sprintf("can't be skipped while the trip is %s", t.Status)}
+	}
+
+	stops, i, err := t.editStop(stopID)
+	if err != nil {
+		return err
+	}
+
+	if stops[i].Status != TripStopStatusPending {
+		return &TripStopError{StopID: stopID, Reason: fmt.Sprintf("can't be skipped once it has been %s", stops[i].Status)}
+	}
+
+	stops[i].Status = TripStopStatusSkipped
+
+	t.Stops = stops
+	t.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	return nil
+}
+
+// UnresolvedStop returns the first stop that still has to be made, if there is one
+func (t *Trip) UnresolvedStop() *TripStop {
+	for i := range t.Stops {
+		if !t.Stops[i].IsResolved() {
+			return &t.Stops[i]
+		}
+	}
+
+	return nil
+}
+
+// editStop finds a stop in a copy of the trip's stops, so that a snapshot of the trip taken before the change
+// still holds the stops as they were
+func (t *Trip) editStop(stopID primitive.ObjectID) ([]TripStop, int, error) {
+	for i, stop := range t.Stops {
+		if stop.ID == stopID {
+			stops := make([]TripStop, len(t.Stops))
+			copy(stops, t.Stops)
+			return stops, i, nil
+		}
+	}
+
+	return nil, 0, ErrTripStopNotFound
+}
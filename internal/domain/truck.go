@@ -81,25 +81,29 @@ const (
 )
 
 type Truck struct {
-	ID               primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID           primitive.ObjectID         `bson:"user_id" json:"user_id"`
-	TruckNumber      string                     `bson:"truck_number" json:"truck_number"`
-	VIN              string                     `bson:"vin" json:"vin"`
-	Make             string                     `bson:"make" json:"make"`
-	Model            string                     `bson:"model" json:"model"`
-	Year             int                        `bson:"year" json:"year"`
-	LicensePlate     LicensePlate               `bson:"license_plate" json:"license_plate"`
-	Mileage          int                        `bson:"mileage" json:"mileage"`
-	Status           TruckStatus                `bson:"status" json:"status"`
-	AssignedDriverID *primitive.ObjectID        `bson:"assigned_driver_id,omitempty" json:"assigned_driver_id,omitempty"`
-	AssignedDriver   *Driver                    `bson:"assigned_driver,omitempty" json:"assigned_driver,omitempty"`
-	TrailerType      TrailerType                `bson:"trailer_type" json:"trailer_type"`
-	CapacityTons     float64                    `bson:"capacity_tons" json:"capacity_tons"`
-	FuelType         FuelType                   `bson:"fuel_type" json:"fuel_type"`
-	LastMaintenance  string                     `bson:"last_maintenance" json:"last_maintenance"`
-	CreatedAt        primitive.DateTime         `bson:"created_at" json:"created_at"`
-	UpdatedAt        primitive.DateTime         `bson:"updated_at" json:"updated_at"`
-	StateMachine     *statemachine.StateMachine `bson:"-" json:"-"`
+	ID                primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID    primitive.ObjectID         `bson:"organization_id" json:"organization_id"`
+	UserID            primitive.ObjectID         `bson:"user_id" json:"user_id"`
+	TruckNumber       string                     `bson:"truck_number" json:"truck_number"`
+	VIN               string                     `bson:"vin" json:"vin"`
+	Make              string                     `bson:"make" json:"make"`
+	Model             string                     `bson:"model" json:"model"`
+	Year              int                        `bson:"year" json:"year"`
+	LicensePlate      LicensePlate               `bson:"license_plate" json:"license_plate"`
+	Mileage           int                        `bson:"mileage" json:"mileage"`
+	InServiceMileage  int                        `bson:"in_service_mileage" json:"in_service_mileage"`
+	MileageRecordedAt primitive.DateTime         `bson:"mileage_recorded_at,omitempty" json:"mileage_recorded_at,omitempty"`
+	Status            TruckStatus                `bson:"status" json:"status"`
+	AssignedDriverID  *primitive.ObjectID        `bson:"assigned_driver_id,omitempty" json:"assigned_driver_id,omitempty"`
+	AssignedDriver    *Driver                    `bson:"assigned_driver,omitempty" json:"assigned_driver,omitempty"`
+	TrailerType       TrailerType                `bson:"trailer_type" json:"trailer_type"`
+	CapacityTons      float64                    `bson:"capacity_tons" json:"capacity_tons"`
+	FuelType          FuelType                   `bson:"fuel_type" json:"fuel_type"`
+	LastMaintenance   string                     `bson:"last_maintenance" json:"last_maintenance"`
+	LastPosition      *Position                  `bson:"last_position,omitempty" json:"last_position,omitempty"`
+	CreatedAt         primitive.DateTime         `bson:"created_at" json:"created_at"`
+	UpdatedAt         primitive.DateTime         `bson:"updated_at" json:"updated_at"`
+	StateMachine      *statemachine.StateMachine `bson:"-" json:"-"`
 }
 
 type LicensePlate struct {
@@ -108,6 +112,7 @@ type LicensePlate struct {
 }
 
 func NewTruck(
+	organizationID,
 	userID primitive.ObjectID,
 	truckNumber,
 	vin,
@@ -132,22 +137,25 @@ func NewTruck(
 	now := time.Now()
 
 	truck := &Truck{
-		UserID:           userID,
-		TruckNumber:      truckNumber,
-		VIN:              vin,
-		Make:             vehicleMake,
-		Model:            model,
-		Year:             year,
-		LicensePlate:     licensePlate,
-		Mileage:          mileage,
-		Status:           TruckStatusAvailable,
-		AssignedDriverID: nil,
-		TrailerType:      trailerType,
-		CapacityTons:     capacityTons,
-		FuelType:         fuelType,
-		LastMaintenance:  LastMaintenance,
-		CreatedAt:        primitive.NewDateTimeFromTime(now),
-		UpdatedAt:        primitive.NewDateTimeFromTime(now),
+		OrganizationID:    organizationID,
+		UserID:            userID,
+		TruckNumber:       truckNumber,
+		VIN:               vin,
+		Make:              vehicleMake,
+		Model:             model,
+		Year:              year,
+		LicensePlate:      licensePlate,
+		Mileage:           mileage,
+		InServiceMileage:  mileage,
+		MileageRecordedAt: primitive.NewDateTimeFromTime(now),
+		Status:            TruckStatusAvailable,
+		AssignedDriverID:  nil,
+		TrailerType:       trailerType,
+		CapacityTons:      capacityTons,
+		FuelType:          fuelType,
+		LastMaintenance:   LastMaintenance,
+		CreatedAt:         primitive.NewDateTimeFromTime(now),
+		UpdatedAt:         primitive.NewDateTimeFromTime(now),
 	}
 
 	if err := truck.InitializeStateMachine(); err != nil {
@@ -158,7 +166,7 @@ func NewTruck(
 }
 
 type TruckFilter struct {
-	UserID           primitive.ObjectID
+	OrganizationID   primitive.ObjectID
 	VIN              string
 	Status           TruckStatus
 	AssignedDriverID *primitive.ObjectID
@@ -170,9 +178,9 @@ type TruckFilter struct {
 
 func NewTruckFilter() TruckFilter {
 	return TruckFilter{
-		Limit:  10,
-		Offset: 0,
-		UserID: primitive.NilObjectID,
+		Limit:          10,
+		Offset:         0,
+		OrganizationID: primitive.NilObjectID,
 	}
 }
 
@@ -216,28 +224,28 @@ func (t *Truck) InitializeStateMachine() error {
 
 func (t *Truck) MakeTruckAvailable() error {
 	if err := t.StateMachine.Transition(TruckStatusAvailable); err != nil {
-		return fmt.Errorf("failed to transition truck to available: %w", err)
+		return &TruckStateError{CurrentState: t.Status, DesiredState: TruckStatusAvailable}
 	}
 	return nil
 }
 
 func (t *Truck) SetTruckInTransit() error {
 	if err := t.StateMachine.Transition(TruckStatusInTransit); err != nil {
-		return fmt.Errorf("failed to transition truck to in-transit: %w", err)
+		return &TruckStateError{CurrentState: t.Status, DesiredState: TruckStatusInTransit}
 	}
 	return nil
 }
 
 func (t *Truck) SetTruckInMaintenance() error {
 	if err := t.StateMachine.Transition(TruckStatusUnderMaintenance); err != nil {
-		return fmt.Errorf("failed to transition truck to maintenance: %w", err)
+		return &TruckStateError{CurrentState: t.Status, DesiredState: TruckStatusUnderMaintenance}
 	}
 	return nil
 }
 
 func (t *Truck) RetireTruck() error {
 	if err := t.StateMachine.Transition(TruckStatusRetired); err != nil {
-		return fmt.Errorf("failed to transition truck to retired: %w", err)
+		return &TruckStateError{CurrentState: t.Status, DesiredState: TruckStatusRetired}
 	}
 	return nil
 }
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// device clocks drift, so pings are allowed to be a little ahead of ours before they're refused
+const maxPositionClockSkew = 5 * time.Minute
+
+// a Position is where a truck was at a moment, as reported by its GPS unit
+type Position struct {
+	Location   GeoPoint           `bson:"location" json:"location"`
+	SpeedMPH   *float64           `bson:"speed_mph,omitempty" json:"speed_mph,omitempty"`
+	Heading    *float64           `bson:"heading,omitempty" json:"heading,omitempty"`
+	RecordedAt primitive.DateTime `bson:"recorded_at" json:"recorded_at"`
+}
+
+// a TruckPosition is one ping in a truck's position history
+type TruckPosition struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	TruckID        primitive.ObjectID `bson:"truck_id" json:"truck_id"`
+	Position       `bson:",inline"`
+	ReceivedAt     primitive.DateTime `bson:"received_at" json:"received_at"`
+}
+
+func NewPosition(lat, lng float64, speedMPH, heading *float64, recordedAt time.Time) (*Position, error) {
+	location, err := NewGeoPoint(lat, lng)
+	if err != nil {
+		return nil, err
+	}
+
+	if recordedAt.IsZero() {
+		return nil, fmt.Errorf("position recorded_at is required")
+	}
+	if recordedAt.After(time.Now().Add(maxPositionClockSkew)) {
+		return nil, fmt.Errorf("position can't be recorded in the future")
+	}
+
+	if speedMPH != nil && (math.IsNaN(*speedMPH) || *speedMPH < 0) {
+		return nil, fmt.Errorf("speed can't be negative")
+	}
+	if heading != nil && (math.IsNaN(*heading) || *heading < 0 || *heading >= 360) {
+		return nil, fmt.Errorf("heading must be between 0 and 360 degrees")
+	}
+
+	return &Position{
+		Location:   *location,
+		SpeedMPH:   speedMPH,
+		Heading:    heading,
+		RecordedAt: primitive.NewDateTimeFromTime(recordedAt),
+	}, nil
+}
+
+func NewTruckPosition(organizationID, truckID primitive.ObjectID, position Position) *TruckPosition {
+	return &TruckPosition{
+		OrganizationID: organizationID,
+		TruckID:        truckID,
+		Position:       position,
+		ReceivedAt:     primitive.NewDateTimeFromTime(time.Now()),
+	}
+}
+
+// a TripTrack is the breadcrumb trail a trip's truck left between the trip's actual departure and arrival
+type TripTrack struct {
+	TripID    primitive.ObjectID  `json:"trip_id"`
+	TruckID   *primitive.ObjectID `json:"truck_id,omitempty"`
+	From      *primitive.DateTime `json:"from,omitempty"`
+	To        *primitive.DateTime `json:"to,omitempty"`
+	Positions []Position          `json:"positions"`
+}
+
+// TrackWindow is the span a trip's track covers. a trip still on the road is tracked up to now, and a trip that
+// hasn't left has no track.
+func (t *Trip) TrackWindow(now time.Time) (primitive.DateTime, primitive.DateTime, bool) {
+	if t.DepartureTime.Actual == nil {
+		return 0, 0, false
+	}
+
+	to := primitive.NewDateTimeFromTime(now)
+	if t.ArrivalTime.Actual != nil {
+		to = *t.ArrivalTime.Actual
+	}
+
+	return *t.DepartureTime.Actual, to, true
+}
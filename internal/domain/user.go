@@ -7,11 +7,18 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email     string             `bson:"email" json:"email"`
-	Password  string             `bson:"password" json:"-"` // "-" prevents password from being included in JSON responses
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email             string             `bson:"email" json:"email"`
+	Password          string             `bson:"password" json:"-"`                      // "-" prevents password from being included in JSON responses
+	PasswordChangedAt *time.Time         `bson:"password_changed_at,omitempty" json:"-"` // access tokens issued before this are no longer accepted
+	EmailVerifiedAt   *time.Time         `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	TwoFactor         *TwoFactor         `bson:"two_factor,omitempty" json:"-"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 type RegisterRequest struct {
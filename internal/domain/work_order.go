@@ -0,0 +1,331 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	statemachine "github.com/jwald3/lollipop"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkOrderStatus string
+
+const (
+	WorkOrderStatusOpen          WorkOrderStatus = "OPEN"
+	WorkOrderStatusInProgress    WorkOrderStatus = "IN_PROGRESS"
+	WorkOrderStatusAwaitingParts WorkOrderStatus = "AWAITING_PARTS"
+	WorkOrderStatusCompleted     WorkOrderStatus = "COMPLETED"
+	WorkOrderStatusCanceled      WorkOrderStatus = "CANCELED"
+)
+
+func (s WorkOrderStatus) IsValid() bool {
+	switch s {
+	case WorkOrderStatusOpen,
+		WorkOrderStatusInProgress,
+		WorkOrderStatusAwaitingParts,
+		WorkOrderStatusCompleted,
+		WorkOrderStatusCanceled:
+		return true
+	}
+	return false
+}
+
+// IsClosed reports whether the work order is finished with, one way or the other
+func (s WorkOrderStatus) IsClosed() bool {
+	return s == WorkOrderStatusCompleted || s == WorkOrderStatusCanceled
+}
+
+// HoldsTruck reports whether the truck is in the shop for this work order
+func (s WorkOrderStatus) HoldsTruck() bool {
+	return s == WorkOrderStatusInProgress || s == WorkOrderStatusAwaitingParts
+}
+
+type WorkOrderLineType string
+
+const (
+	WorkOrderLineLabor WorkOrderLineType = "LABOR"
+	WorkOrderLinePart  WorkOrderLineType = "PART"
+)
+
+func (t WorkOrderLineType) IsValid() bool {
+	switch t {
+	case WorkOrderLineLabor, WorkOrderLinePart:
+		return true
+	}
+	return false
+}
+
+// a WorkOrderLine is one charge on a work order. labor is quantified in hours at an hourly rate, and parts by the
+// unit.
+type WorkOrderLine struct {
+	Type        WorkOrderLineType `bson:"type" json:"type"`
+	Description string            `bson:"description" json:"description"`
+	PartNumber  string            `bson:"part_number,omitempty" json:"part_number,omitempty"`
+	Quantity    float64           `bson:"quantity" json:"quantity"`
+	UnitCost    float64           `bson:"unit_cost" json:"unit_cost"`
+}
+
+func NewWorkOrderLine(lineType WorkOrderLineType, description, partNumber string, quantity, unitCost float64) (*WorkOrderLine, error) {
+	if !lineType.IsValid() {
+		return nil, fmt.Errorf("invalid line type: %s", lineType)
+	}
+	if description == "" {
+		return nil, fmt.Errorf("line description is required")
+	}
+	if quantity <= 0 {
+		return nil, fmt.Errorf("line quantity must be positive")
+	}
+	if unitCost < 0 {
+		return nil, fmt.Errorf("line unit_cost can't be negative")
+	}
+	if partNumber != "" && lineType != WorkOrderLinePart {
+		return nil, fmt.Errorf("only part lines can have a part_number")
+	}
+
+	return &WorkOrderLine{
+		Type:        lineType,
+		Description: description,
+		PartNumber:  partNumber,
+		Quantity:    quantity,
+		UnitCost:    unitCost,
+	}, nil
+}
+
+func (l WorkOrderLine) Total() float64 {
+	return l.Quantity * l.UnitCost
+}
+
+// a WorkOrder tracks a repair or service from the time it's opened until the truck is back on the road. starting
+// one takes its truck out of service, and completing it writes the MaintenanceLog the work is remembered by.
+type WorkOrder struct {
+	ID               primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID   primitive.ObjectID         `bson:"organization_id" json:"organization_id"`
+	UserID           primitive.ObjectID         `bson:"user_id" json:"user_id"`
+	TruckID          primitive.ObjectID         `bson:"truck_id" json:"truck_id"`
+	ScheduleID       *primitive.ObjectID        `bson:"schedule_id,omitempty" json:"schedule_id,omitempty"`
+	Title            string                     `bson:"title" json:"title"`
+	Description      string                     `bson:"description,omitempty" json:"description,omitempty"`
+	ServiceType      MaintenanceServiceType     `bson:"service_type" json:"service_type"`
+	Mechanic         string                     `bson:"mechanic,omitempty" json:"mechanic,omitempty"`
+	Location         string                     `bson:"location,omitempty" json:"location,omitempty"`
+	Status           WorkOrderStatus            `bson:"status" json:"status"`
+	Lines            []WorkOrderLine            `bson:"lines" json:"lines"`
+	Mileage          int                        `bson:"mileage,omitempty" json:"mileage,omitempty"`
+	StartedAt        *primitive.DateTime        `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	ClosedAt         *primitive.DateTime        `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	MaintenanceLogID *primitive.ObjectID        `bson:"maintenance_log_id,omitempty" json:"maintenance_log_id,omitempty"`
+	CreatedAt        primitive.DateTime         `bson:"created_at" json:"created_at"`
+	UpdatedAt        primitive.DateTime         `bson:"updated_at" json:"updated_at"`
+	StateMachine     *statemachine.StateMachine `bson:"-" json:"-"`
+}
+
+type WorkOrderFilter struct {
+	OrganizationID primitive.ObjectID
+	TruckID        *primitive.ObjectID
+	Status         WorkOrderStatus
+	Limit          int64
+	Offset         int64
+}
+
+func NewWorkOrderFilter() WorkOrderFilter {
+	return WorkOrderFilter{
+		Limit:  10,
+		Offset: 0,
+	}
+}
+
+func NewWorkOrder(
+	organizationID,
+	userID,
+	truckID primitive.ObjectID,
+	scheduleID *primitive.ObjectID,
+	title,
+	description string,
+	serviceType MaintenanceServiceType,
+	mechanic,
+	location string,
+	lines []WorkOrderLine) (*WorkOrder, error) {
+	if truckID.IsZero() {
+		return nil, fmt.Errorf("truck_id is required")
+	}
+	if title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if !serviceType.IsValid() {
+		return nil, fmt.Errorf("invalid service type provided: %s", serviceType)
+	}
+	if lines == nil {
+		lines = make([]WorkOrderLine, 0)
+	}
+
+	now := time.Now()
+
+	workOrder := &WorkOrder{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		TruckID:        truckID,
+		ScheduleID:     scheduleID,
+		Title:          title,
+		Description:    description,
+		ServiceType:    serviceType,
+		Mechanic:       mechanic,
+		Location:       location,
+		Status:         WorkOrderStatusOpen,
+		Lines:          lines,
+		CreatedAt:      primitive.NewDateTimeFromTime(now),
+		UpdatedAt:      primitive.NewDateTimeFromTime(now),
+	}
+
+	if err := workOrder.InitializeStateMachine(); err != nil {
+		return nil, fmt.Errorf("failed to initialize state machine: %w", err)
+	}
+
+	return workOrder, nil
+}
+
+func (w *WorkOrder) InitializeStateMachine() error {
+	sm := statemachine.NewStateMachine(w.Status)
+
+	sm.AddSimpleTransition(WorkOrderStatusOpen, WorkOrderStatusInProgress)
+	sm.AddSimpleTransition(WorkOrderStatusOpen, WorkOrderStatusCanceled)
+
+	sm.AddSimpleTransition(WorkOrderStatusInProgress, WorkOrderStatusAwaitingParts)
+	sm.AddSimpleTransition(WorkOrderStatusInProgress, WorkOrderStatusCompleted)
+	sm.AddSimpleTransition(WorkOrderStatusInProgress, WorkOrderStatusCanceled)
+
+	sm.AddSimpleTransition(WorkOrderStatusAwaitingParts, WorkOrderStatusInProgress)
+	sm.AddSimpleTransition(WorkOrderStatusAwaitingParts, WorkOrderStatusCanceled)
+
+	sm.SetEntryAction(WorkOrderStatusInProgress, func() error {
+		w.Status = WorkOrderStatusInProgress
+		return nil
+	})
+
+	sm.SetEntryAction(WorkOrderStatusAwaitingParts, func() error {
+		w.Status = WorkOrderStatusAwaitingParts
+		return nil
+	})
+
+	sm.SetEntryAction(WorkOrderStatusCompleted, func() error {
+		w.Status = WorkOrderStatusCompleted
+		return nil
+	})
+
+	sm.SetEntryAction(WorkOrderStatusCanceled, func() error {
+		w.Status = WorkOrderStatusCanceled
+		return nil
+	})
+
+	w.StateMachine = sm
+
+	return nil
+}
+
+// Start puts a mechanic on the work order. coming back from waiting on parts goes through here too, and keeps the
+// time work first started.
+func (w *WorkOrder) Start(at time.Time) error {
+	if err := w.StateMachine.Transition(WorkOrderStatusInProgress); err != nil {
+		return &WorkOrderStateError{CurrentState: w.Status, DesiredState: WorkOrderStatusInProgress}
+	}
+
+	if w.StartedAt == nil {
+		started := primitive.NewDateTimeFromTime(at)
+		w.StartedAt = &started
+	}
+	w.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+func (w *WorkOrder) AwaitParts() error {
+	if err := w.StateMachine.Transition(WorkOrderStatusAwaitingParts); err != nil {
+		return &WorkOrderStateError{CurrentState: w.Status, DesiredState: WorkOrderStatusAwaitingParts}
+	}
+
+	w.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+// Complete closes out the work order with the truck's odometer reading at the time
+func (w *WorkOrder) Complete(at time.Time, mileage int) error {
+	if mileage < 0 {
+		return fmt.Errorf("mileage can't be negative")
+	}
+	if w.StartedAt != nil && at.Before(w.StartedAt.Time()) {
+		return ErrWorkOrderCompletedEarly
+	}
+
+	if err := w.StateMachine.Transition(WorkOrderStatusCompleted); err != nil {
+		return &WorkOrderStateError{CurrentState: w.Status, DesiredState: WorkOrderStatusCompleted}
+	}
+
+	closed := primitive.NewDateTimeFromTime(at)
+	w.ClosedAt = &closed
+	w.Mileage = mileage
+	w.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+func (w *WorkOrder) Cancel(at time.Time) error {
+	if err := w.StateMachine.Transition(WorkOrderStatusCanceled); err != nil {
+		return &WorkOrderStateError{CurrentState: w.Status, DesiredState: WorkOrderStatusCanceled}
+	}
+
+	closed := primitive.NewDateTimeFromTime(at)
+	w.ClosedAt = &closed
+	w.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+func (w *WorkOrder) LaborCost() float64 {
+	return w.linesCost(WorkOrderLineLabor)
+}
+
+func (w *WorkOrder) PartsCost() float64 {
+	return w.linesCost(WorkOrderLinePart)
+}
+
+func (w *WorkOrder) TotalCost() float64 {
+	return w.LaborCost() + w.PartsCost()
+}
+
+func (w *WorkOrder) linesCost(lineType WorkOrderLineType) float64 {
+	var total float64
+	for _, line := range w.Lines {
+		if line.Type == lineType {
+			total += line.Total()
+		}
+	}
+
+	return total
+}
+
+// MaintenanceLog is the record a completed work order leaves behind, costed at the total of its lines
+func (w *WorkOrder) MaintenanceLog() *MaintenanceLog {
+	truckID := w.TruckID
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	notes := w.Title
+	if w.Description != "" {
+		notes += ": " + w.Description
+	}
+
+	return &MaintenanceLog{
+		OrganizationID: w.OrganizationID,
+		UserID:         w.UserID,
+		TruckID:        &truckID,
+		ScheduleID:     w.ScheduleID,
+		Date:           primitive.NewDateTimeFromTime(w.ClosedAt.Time().UTC().Truncate(24 * time.Hour)),
+		Mileage:        w.Mileage,
+		ServiceType:    w.ServiceType,
+		Cost:           w.TotalCost(),
+		Notes:          notes,
+		Mechanic:       w.Mechanic,
+		Location:       w.Location,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
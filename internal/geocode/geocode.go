@@ -0,0 +1,34 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jwald3/waybill/internal/config"
+	"github.com/jwald3/waybill/internal/domain"
+)
+
+var ErrNotFound = errors.New("address could not be geocoded")
+
+// a Geocoder turns a postal address into coordinates. addresses it can't place are reported with ErrNotFound,
+// which callers treat as "no coordinates" rather than a failure.
+type Geocoder interface {
+	Geocode(ctx context.Context, address domain.Address) (*domain.GeoPoint, error)
+}
+
+// New returns the ZIP centroid geocoder when GEOCODER_ZIP_CENTROIDS points at a data file, and a geocoder that
+// never finds anything otherwise
+func New(cfg *config.Config) (Geocoder, error) {
+	if cfg.Geocoding.ZipCentroidsFile == "" {
+		return Disabled{}, nil
+	}
+
+	return LoadZipCentroids(cfg.Geocoding.ZipCentroidsFile)
+}
+
+// Disabled is used when no geocoding data is configured; facilities only get coordinates when they're given
+type Disabled struct{}
+
+func (Disabled) Geocode(ctx context.Context, address domain.Address) (*domain.GeoPoint, error) {
+	return nil, ErrNotFound
+}
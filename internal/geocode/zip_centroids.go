@@ -0,0 +1,107 @@
+package geocode
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jwald3/waybill/internal/domain"
+)
+
+// ZipCentroids places an address at the center of its ZIP code. that's only accurate to a few miles, which is
+// plenty for finding nearby facilities and estimating trip distances, and it works without calling out to anyone.
+type ZipCentroids struct {
+	centroids map[string]domain.GeoPoint
+}
+
+// LoadZipCentroids reads a CSV with a header row naming zip, lat and lng columns (latitude and longitude, or lon,
+// are accepted too). any other columns are ignored, so most published ZIP code datasets can be used as they are.
+func LoadZipCentroids(path string) (*ZipCentroids, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip centroids: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip centroids header: %w", err)
+	}
+
+	zipCol, latCol, lngCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "zip", "zipcode", "zip_code", "postal_code":
+			zipCol = i
+		case "lat", "latitude":
+			latCol = i
+		case "lng", "lon", "long", "longitude":
+			lngCol = i
+		}
+	}
+	if zipCol < 0 || latCol < 0 || lngCol < 0 {
+		return nil, fmt.Errorf("zip centroids need zip, lat and lng columns")
+	}
+
+	centroids := make(map[string]domain.GeoPoint)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zip centroids: %w", err)
+		}
+		if len(record) <= zipCol || len(record) <= latCol || len(record) <= lngCol {
+			return nil, fmt.Errorf("zip centroids line %d is missing columns", line)
+		}
+
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(record[latCol]), 64)
+		lng, lngErr := strconv.ParseFloat(strings.TrimSpace(record[lngCol]), 64)
+		if latErr != nil || lngErr != nil {
+			return nil, fmt.Errorf("zip centroids line %d has invalid coordinates", line)
+		}
+
+		point, err := domain.NewGeoPoint(lat, lng)
+		if err != nil {
+			return nil, fmt.Errorf("zip centroids line %d: %w", line, err)
+		}
+
+		centroids[normalizeZip(record[zipCol])] = *point
+	}
+
+	return &ZipCentroids{centroids: centroids}, nil
+}
+
+func (g *ZipCentroids) Geocode(ctx context.Context, address domain.Address) (*domain.GeoPoint, error) {
+	centroid, ok := g.centroids[normalizeZip(address.Zip)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// hand back a copy so the caller can't move the shared centroid
+	return domain.NewGeoPoint(centroid.Lat(), centroid.Lng())
+}
+
+// ZIP+4 codes are cut down to the five digit code, and codes that lost their leading zeros to a spreadsheet are
+// padded back out
+func normalizeZip(zip string) string {
+	zip = strings.TrimSpace(zip)
+	if i := strings.IndexByte(zip, '-'); i >= 0 {
+		zip = zip[:i]
+	}
+	if len(zip) > 0 && len(zip) < 5 {
+		zip = strings.Repeat("0", 5-len(zip)) + zip
+	}
+
+	return zip
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+var (
+	invalidAPIKeyId = "invalid api key id"
+)
+
+// DTOS =======================================================
+
+type APIKeyCreateRequest struct {
+	Name      string              `json:"name"`
+	Scopes    []domain.Permission `json:"scopes"`
+	ReadOnly  bool                `json:"read_only"`
+	ExpiresAt *time.Time          `json:"expires_at,omitempty"`
+}
+
+type APIKeyResponse struct {
+	ID             primitive.ObjectID  `json:"id"`
+	OrganizationID primitive.ObjectID  `json:"organization_id"`
+	UserID         primitive.ObjectID  `json:"user_id"`
+	Name           string              `json:"name"`
+	Prefix         string              `json:"prefix"`
+	Scopes         []domain.Permission `json:"scopes"`
+	ExpiresAt      *primitive.DateTime `json:"expires_at,omitempty"`
+	LastUsedAt     *primitive.DateTime `json:"last_used_at,omitempty"`
+	RevokedAt      *primitive.DateTime `json:"revoked_at,omitempty"`
+	CreatedAt      primitive.DateTime  `json:"created_at"`
+	UpdatedAt      primitive.DateTime  `json:"updated_at"`
+}
+
+// the plaintext key is only ever returned here, when it's created
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func apiKeyDomainToResponse(k *domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:             k.ID,
+		OrganizationID: k.OrganizationID,
+		UserID:         k.UserID,
+		Name:           k.Name,
+		Prefix:         k.Prefix,
+		Scopes:         k.Scopes,
+		ExpiresAt:      k.ExpiresAt,
+		LastUsedAt:     k.LastUsedAt,
+		RevokedAt:      k.RevokedAt,
+		CreatedAt:      k.CreatedAt,
+		UpdatedAt:      k.UpdatedAt,
+	}
+}
+
+// =================================================================
+
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	var req APIKeyCreateRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	scopes := req.Scopes
+	if req.ReadOnly {
+		if len(scopes) > 0 {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "scopes and read_only can't be combined"})
+			return
+		}
+		scopes = domain.ReadOnlyScopes()
+	}
+
+	key, plaintext, err := h.apiKeyService.Create(r.Context(), membership, req.Name, scopes, req.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, service.ErrScopeExceedsRole) {
+			WriteJSON(w, http.StatusForbidden, Response{Error: err.Error()})
+			return
+		}
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, APIKeyCreatedResponse{
+		APIKeyResponse: apiKeyDomainToResponse(key),
+		Key:            plaintext,
+	})
+}
+
+// List returns the caller's own keys, or every key in the organization for those who manage its members
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	filter := domain.NewAPIKeyFilter()
+	filter.OrganizationID = membership.OrganizationID
+	if !membership.HasPermission(domain.PermissionMembersManage) {
+		filter.UserID = &membership.UserID
+	}
+	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
+	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
+
+	result, err := h.apiKeyService.List(r.Context(), filter)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch api keys"})
+		return
+	}
+
+	keyResponses := make([]APIKeyResponse, len(result.APIKeys))
+	for i, k := range result.APIKeys {
+		keyResponses[i] = apiKeyDomainToResponse(k)
+	}
+
+	var nextOffset *int64
+	if filter.Offset+filter.Limit < result.Total {
+		next := filter.Offset + filter.Limit
+		nextOffset = &next
+	}
+
+	response := PaginatedResponse{
+		Items:      keyResponses,
+		Total:      result.Total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+		NextOffset: nextOffset,
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidAPIKeyId})
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(r.Context(), objectID, membership); err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "api key not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to revoke api key"})
+		return
+	}
+
+	WriteJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// History serves the audit trail for a single entity. every resource shares the same audit collection, so the
+// handler is built per entity type and mounted under each resource's /{id}/history route.
+func (h *AuditHandler) History(entityType domain.AuditEntityType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		membership, ok := domain.MembershipFromContext(r.Context())
+		if !ok {
+			WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+			return
+		}
+
+		idStr := mux.Vars(r)["id"]
+		objectID, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+
+		filter := domain.NewAuditEventFilter()
+		filter.OrganizationID = membership.OrganizationID
+		filter.EntityType = entityType
+		filter.EntityID = objectID
+		filter.Limit = int64(getQueryIntParam(r, "limit", 10))
+		filter.Offset = int64(getQueryIntParam(r, "offset", 0))
+
+		result, err := h.auditService.History(r.Context(), filter)
+		if err != nil {
+			WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch history"})
+			return
+		}
+
+		var nextOffset *int64
+		if filter.Offset+filter.Limit < result.Total {
+			next := filter.Offset + filter.Limit
+			nextOffset = &next
+		}
+
+		response := PaginatedResponse{
+			Items:      result.AuditEvents,
+			Total:      result.Total,
+			Limit:      filter.Limit,
+			Offset:     filter.Offset,
+			NextOffset: nextOffset,
+		}
+
+		WriteJSON(w, http.StatusOK, response)
+	}
+}
@@ -2,10 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AuthHandler struct {
@@ -44,15 +51,273 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.authService.Login(r.Context(), &req)
+	token, err := h.authService.Login(r.Context(), &req, middleware.ClientIPFromRequest(r))
 	if err != nil {
+		var throttled *domain.LoginThrottledError
+		if errors.As(err, &throttled) {
+			writeThrottled(w, throttled)
+			return
+		}
 		if err == service.ErrInvalidCredentials {
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
+		if err == service.ErrEmailNotVerified {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req domain.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(token)
+}
+
+// Logout revokes the caller's access token. sending the refresh token as well signs the session out everywhere
+// it was refreshed to; without it the refresh token stays usable until it expires.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req domain.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.authService.Logout(r.Context(), claims, req.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req domain.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			http.Error(w, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification always answers 202 so that it can't be used to check whether an address has an account
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req domain.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.authService.ResendVerification(r.Context(), req.Email)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPassword always answers 202, for the same reason as ResendVerification
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req domain.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.authService.ForgotPassword(r.Context(), req.Email)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req domain.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			http.Error(w, "invalid or expired token", http.StatusBadRequest)
+		case errors.Is(err, service.ErrPasswordTooShort):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyTwoFactor trades the challenge from a login and a code for the session's tokens
+func (h *AuthHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req domain.TwoFactorVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "challenge token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.authService.VerifyTwoFactor(r.Context(), req.ChallengeToken, req.Code, middleware.ClientIPFromRequest(r))
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(token)
+}
+
+// EnrollTwoFactor returns a new secret and its otpauth:// URI for the caller's authenticator app
+func (h *AuthHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := domain.ActorFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.authService.EnrollTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	h.withTwoFactorCode(w, r, func(userID primitive.ObjectID, code string) {
+		codes, err := h.authService.ConfirmTwoFactor(r.Context(), userID, code)
+		if err != nil {
+			writeTwoFactorError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(domain.RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	h.withTwoFactorCode(w, r, func(userID primitive.ObjectID, code string) {
+		if err := h.authService.DisableTwoFactor(r.Context(), userID, code); err != nil {
+			writeTwoFactorError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.withTwoFactorCode(w, r, func(userID primitive.ObjectID, code string) {
+		codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), userID, code)
+		if err != nil {
+			writeTwoFactorError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(domain.RecoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+// withTwoFactorCode reads the caller and the code every two-factor change has to be confirmed with
+func (h *AuthHandler) withTwoFactorCode(w http.ResponseWriter, r *http.Request, next func(userID primitive.ObjectID, code string)) {
+	userID, ok := domain.ActorFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req domain.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	next(userID, req.Code)
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	var throttled *domain.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		writeThrottled(w, throttled)
+	case errors.Is(err, service.ErrInvalidToken), errors.Is(err, service.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled), errors.Is(err, service.ErrTwoFactorNotEnrolled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeThrottled(w http.ResponseWriter, throttled *domain.LoginThrottledError) {
+	retryAfter := math.Ceil(time.Until(throttled.RetryAt).Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, retryAfter))))
+	http.Error(w, throttled.Error(), http.StatusTooManyRequests)
 }
@@ -3,10 +3,8 @@ package handler
 import (
 	"net/http"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/domain"
-	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -26,15 +24,16 @@ var (
 // DTOS =======================================================
 
 type DriverCreateRequest struct {
-	FirstName         string         `json:"first_name"`
-	LastName          string         `json:"last_name"`
-	DOB               string         `json:"dob"`
-	LicenseNumber     string         `json:"license_number"`
-	LicenseState      string         `json:"license_state"`
-	LicenseExpiration string         `json:"license_expiration"`
-	Phone             string         `json:"phone"`
-	Email             string         `json:"email"`
-	Address           domain.Address `json:"address"`
+	FirstName         string          `json:"first_name"`
+	LastName          string          `json:"last_name"`
+	DOB               string          `json:"dob"`
+	LicenseNumber     string          `json:"license_number"`
+	LicenseState      string          `json:"license_state"`
+	LicenseExpiration string          `json:"license_expiration"`
+	Phone             string          `json:"phone"`
+	Email             string          `json:"email"`
+	Address           domain.Address  `json:"address"`
+	Credentials       []CredentialDTO `json:"credentials,omitempty"`
 }
 
 type DriverUpdateRequest struct {
@@ -48,6 +47,7 @@ type DriverUpdateRequest struct {
 	Email             string                  `json:"email"`
 	Address           domain.Address          `json:"address"`
 	EmploymentStatus  domain.EmploymentStatus `json:"employment_status"`
+	Credentials       []CredentialDTO         `json:"credentials,omitempty"`
 }
 
 type DriverResponse struct {
@@ -63,16 +63,66 @@ type DriverResponse struct {
 	Email             domain.Email            `json:"email"`
 	Address           domain.Address          `json:"address"`
 	EmploymentStatus  domain.EmploymentStatus `json:"employment_status"`
+	Credentials       []CredentialDTO         `json:"credentials"`
 	CreatedAt         primitive.DateTime      `json:"created_at"`
 	UpdatedAt         primitive.DateTime      `json:"updated_at"`
 }
 
+// dates are written as YYYY-MM-DD both ways
+type CredentialDTO struct {
+	Type      domain.CredentialType `json:"type"`
+	Number    string                `json:"number,omitempty"`
+	ExpiresOn string                `json:"expires_on"`
+}
+
+type CredentialExpirationResponse struct {
+	DriverID      primitive.ObjectID    `json:"driver_id"`
+	DriverName    string                `json:"driver_name"`
+	Credential    domain.CredentialType `json:"credential"`
+	Number        string                `json:"number,omitempty"`
+	ExpiresOn     string                `json:"expires_on"`
+	DaysRemaining int                   `json:"days_remaining"`
+	Expired       bool                  `json:"expired"`
+}
+
 type ListDriversResponse struct {
 	Drivers []DriverResponse `json:"drivers"`
 }
 
-func driverRequestToDomainCreate(userID primitive.ObjectID, req DriverCreateRequest) (*domain.Driver, error) {
+func credentialsToDomain(reqs []CredentialDTO) ([]domain.Credential, error) {
+	credentials := make([]domain.Credential, 0, len(reqs))
+	for _, req := range reqs {
+		credential, err := domain.NewCredential(req.Type, req.Number, req.ExpiresOn)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, *credential)
+	}
+
+	return credentials, nil
+}
+
+func credentialsToResponse(credentials []domain.Credential) []CredentialDTO {
+	responses := make([]CredentialDTO, len(credentials))
+	for i, c := range credentials {
+		responses[i] = CredentialDTO{
+			Type:      c.Type,
+			Number:    c.Number,
+			ExpiresOn: domain.FormatDate(c.ExpiresOn),
+		}
+	}
+
+	return responses
+}
+
+func driverRequestToDomainCreate(orgID, userID primitive.ObjectID, req DriverCreateRequest) (*domain.Driver, error) {
+	credentials, err := credentialsToDomain(req.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
 	return domain.NewDriver(
+		orgID,
 		userID,
 		req.FirstName,
 		req.LastName,
@@ -83,32 +133,29 @@ func driverRequestToDomainCreate(userID primitive.ObjectID, req DriverCreateRequ
 		req.Phone,
 		req.Email,
 		req.Address,
+		credentials,
 	)
 }
 
 func driverRequestToDomainUpdate(req DriverUpdateRequest) (*domain.Driver, error) {
-	validEmail, err := domain.NewEmail(req.Email)
+	credentials, err := credentialsToDomain(req.Credentials)
 	if err != nil {
 		return nil, err
 	}
 
-	validPhone, err := domain.NewPhoneNumber(req.Phone)
-	if err != nil {
-		return nil, err
-	}
-
-	return &domain.Driver{
-		FirstName:         req.FirstName,
-		LastName:          req.LastName,
-		DOB:               req.DOB,
-		LicenseNumber:     req.LicenseNumber,
-		LicenseState:      req.LicenseState,
-		LicenseExpiration: req.LicenseExpiration,
-		Phone:             validPhone,
-		Email:             validEmail,
-		Address:           req.Address,
-		EmploymentStatus:  req.EmploymentStatus,
-	}, nil
+	return domain.NewDriverUpdate(
+		req.FirstName,
+		req.LastName,
+		req.DOB,
+		req.LicenseNumber,
+		req.LicenseState,
+		req.LicenseExpiration,
+		req.Phone,
+		req.Email,
+		req.Address,
+		req.EmploymentStatus,
+		credentials,
+	)
 }
 
 func driverDomainToResponse(d *domain.Driver) DriverResponse {
@@ -117,14 +164,15 @@ func driverDomainToResponse(d *domain.Driver) DriverResponse {
 		UserID:            d.UserID,
 		FirstName:         d.FirstName,
 		LastName:          d.LastName,
-		DOB:               d.DOB,
+		DOB:               domain.FormatDate(d.DOB),
 		LicenseNumber:     d.LicenseNumber,
 		LicenseState:      d.LicenseState,
-		LicenseExpiration: d.LicenseExpiration,
+		LicenseExpiration: domain.FormatDate(d.LicenseExpiration),
 		Phone:             d.Phone,
 		Email:             d.Email,
 		Address:           d.Address,
 		EmploymentStatus:  d.EmploymentStatus,
+		Credentials:       credentialsToResponse(d.Credentials),
 		CreatedAt:         d.CreatedAt,
 		UpdatedAt:         d.UpdatedAt,
 	}
@@ -133,21 +181,9 @@ func driverDomainToResponse(d *domain.Driver) DriverResponse {
 // =================================================================
 
 func (h *DriverHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -157,7 +193,7 @@ func (h *DriverHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	driver, err := driverRequestToDomainCreate(userID, req)
+	driver, err := driverRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
@@ -172,21 +208,9 @@ func (h *DriverHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DriverHandler) GetById(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -197,7 +221,7 @@ func (h *DriverHandler) GetById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	driver, err := h.driverService.GetById(r.Context(), objectID, userID)
+	driver, err := h.driverService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "driver not found"})
 		return
@@ -207,6 +231,12 @@ func (h *DriverHandler) GetById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DriverHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -227,6 +257,7 @@ func (h *DriverHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	driver.ID = objectID
+	driver.OrganizationID = membership.OrganizationID
 
 	if err := h.driverService.Update(r.Context(), driver); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update driver"})
@@ -237,21 +268,9 @@ func (h *DriverHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DriverHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -262,7 +281,7 @@ func (h *DriverHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.driverService.Delete(r.Context(), objectID, userID)
+	err = h.driverService.Delete(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		if err == domain.ErrDriverNotFound {
 			WriteJSON(w, http.StatusNotFound, Response{Error: "driver not found"})
@@ -276,26 +295,14 @@ func (h *DriverHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DriverHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
 	filter := domain.NewDriverFilter()
-	filter.UserID = userID
+	filter.OrganizationID = membership.OrganizationID
 
 	if licenseState := r.URL.Query().Get("licenseState"); licenseState != "" {
 		filter.LicenseState = licenseState
@@ -342,21 +349,9 @@ func (h *DriverHandler) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DriverHandler) SuspendDriver(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -367,12 +362,12 @@ func (h *DriverHandler) SuspendDriver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.driverService.SuspendDriver(r.Context(), objectID, userID); err != nil {
+	if err := h.driverService.SuspendDriver(r.Context(), objectID, membership.OrganizationID); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
-	updatedDriver, err := h.driverService.GetById(r.Context(), objectID, userID)
+	updatedDriver, err := h.driverService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated driver"})
 		return
@@ -382,21 +377,9 @@ func (h *DriverHandler) SuspendDriver(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DriverHandler) TerminateDriver(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -407,12 +390,12 @@ func (h *DriverHandler) TerminateDriver(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.driverService.TerminateDriver(r.Context(), objectID, userID); err != nil {
+	if err := h.driverService.TerminateDriver(r.Context(), objectID, membership.OrganizationID); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
-	updatedDriver, err := h.driverService.GetById(r.Context(), objectID, userID)
+	updatedDriver, err := h.driverService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated driver"})
 		return
@@ -422,21 +405,9 @@ func (h *DriverHandler) TerminateDriver(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *DriverHandler) ActivateDriver(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -447,12 +418,12 @@ func (h *DriverHandler) ActivateDriver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.driverService.ActivateDriver(r.Context(), objectID, userID); err != nil {
+	if err := h.driverService.ActivateDriver(r.Context(), objectID, membership.OrganizationID); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
-	updatedDriver, err := h.driverService.GetById(r.Context(), objectID, userID)
+	updatedDriver, err := h.driverService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated driver"})
 		return
@@ -460,3 +431,40 @@ func (h *DriverHandler) ActivateDriver(w http.ResponseWriter, r *http.Request) {
 
 	WriteJSON(w, http.StatusOK, Response{Data: driverDomainToResponse(updatedDriver)})
 }
+
+// ListExpiring reports the licenses and credentials that have expired or will within withinDays days (30 by
+// default), so they can be renewed before the driver is grounded
+func (h *DriverHandler) ListExpiring(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	withinDays := getQueryIntParam(r, "withinDays", 30)
+	if withinDays < 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "withinDays can't be negative"})
+		return
+	}
+
+	expirations, err := h.driverService.ListExpiring(r.Context(), membership.OrganizationID, withinDays)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch expiring credentials"})
+		return
+	}
+
+	responses := make([]CredentialExpirationResponse, len(expirations))
+	for i, e := range expirations {
+		responses[i] = CredentialExpirationResponse{
+			DriverID:      e.DriverID,
+			DriverName:    e.DriverName,
+			Credential:    e.Credential,
+			Number:        e.Number,
+			ExpiresOn:     domain.FormatDate(e.ExpiresOn),
+			DaysRemaining: e.DaysRemaining,
+			Expired:       e.Expired,
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: responses})
+}
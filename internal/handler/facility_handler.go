@@ -6,10 +6,8 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/domain"
-	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -33,9 +31,13 @@ type FacilityCreateRequest struct {
 	Name              string                   `json:"name"`
 	Type              string                   `json:"type"`
 	Address           domain.Address           `json:"address"`
+	Coordinates       *CoordinatesRequest      `json:"coordinates,omitempty"`
+	Geofence          *GeofenceRequest         `json:"geofence,omitempty"`
 	ContactInfo       domain.ContactInfo       `json:"contact_info"`
 	ParkingCapacity   int                      `json:"parking_capacity"`
 	ServicesAvailable []domain.FacilityService `json:"services_available"`
+	AcceptsHazmat     bool                     `json:"accepts_hazmat"`
+	HazardClasses     []domain.HazardClass     `json:"hazard_classes,omitempty"`
 }
 
 type FacilityUpdateRequest struct {
@@ -43,9 +45,27 @@ type FacilityUpdateRequest struct {
 	Name              string                   `json:"name"`
 	Type              string                   `json:"type"`
 	Address           domain.Address           `json:"address"`
+	Coordinates       *CoordinatesRequest      `json:"coordinates,omitempty"`
+	Geofence          *GeofenceRequest         `json:"geofence,omitempty"`
 	ContactInfo       domain.ContactInfo       `json:"contact_info"`
 	ParkingCapacity   int                      `json:"parking_capacity"`
 	ServicesAvailable []domain.FacilityService `json:"services_available"`
+	AcceptsHazmat     bool                     `json:"accepts_hazmat"`
+	HazardClasses     []domain.HazardClass     `json:"hazard_classes,omitempty"`
+}
+
+// coordinates are optional; without them the facility is placed by its address
+type CoordinatesRequest struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// a radius geofence is centered on the facility's location; a polygon geofence lists its corners in order
+type GeofenceRequest struct {
+	Type        domain.GeofenceType   `json:"type"`
+	RadiusMiles float64               `json:"radius_miles,omitempty"`
+	Boundary    []CoordinatesRequest  `json:"boundary,omitempty"`
+	Rules       *domain.GeofenceRules `json:"rules,omitempty"`
 }
 
 type FacilityUpdateAvailableServicesRequest struct {
@@ -58,9 +78,14 @@ type FacilityResponse struct {
 	Name              string                   `json:"name"`
 	Type              string                   `json:"type"`
 	Address           domain.Address           `json:"address"`
+	Location          *domain.GeoPoint         `json:"location,omitempty"`
+	Geofence          *domain.Geofence         `json:"geofence,omitempty"`
+	DistanceMiles     *float64                 `json:"distance_miles,omitempty"`
 	ContactInfo       domain.ContactInfo       `json:"contact_info"`
 	ParkingCapacity   int                      `json:"parking_capacity"`
 	ServicesAvailable []domain.FacilityService `json:"services_available"`
+	AcceptsHazmat     bool                     `json:"accepts_hazmat"`
+	HazardClasses     []domain.HazardClass     `json:"hazard_classes,omitempty"`
 	CreatedAt         primitive.DateTime       `json:"created_at"`
 	UpdatedAt         primitive.DateTime       `json:"updated_at"`
 }
@@ -69,9 +94,10 @@ type ListFacilitiesResponse struct {
 	Facilities []FacilityResponse `json:"facilities"`
 }
 
-func facilityRequestToDomainCreate(userId primitive.ObjectID, req FacilityCreateRequest) (*domain.Facility, error) {
-	return domain.NewFacility(
-		userId,
+func facilityRequestToDomainCreate(orgID, userID primitive.ObjectID, req FacilityCreateRequest) (*domain.Facility, error) {
+	facility, err := domain.NewFacility(
+		orgID,
+		userID,
 		req.FacilityNumber,
 		req.Name,
 		req.Type,
@@ -80,6 +106,57 @@ func facilityRequestToDomainCreate(userId primitive.ObjectID, req FacilityCreate
 		req.ParkingCapacity,
 		req.ServicesAvailable,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if facility.Location, err = coordinatesToDomain(req.Coordinates); err != nil {
+		return nil, err
+	}
+
+	if facility.Geofence, err = geofenceToDomain(req.Geofence); err != nil {
+		return nil, err
+	}
+
+	if err := facility.SetHazmatAcceptance(req.AcceptsHazmat, req.HazardClasses); err != nil {
+		return nil, err
+	}
+
+	return facility, nil
+}
+
+func coordinatesToDomain(req *CoordinatesRequest) (*domain.GeoPoint, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	return domain.NewGeoPoint(req.Lat, req.Lng)
+}
+
+func geofenceToDomain(req *GeofenceRequest) (*domain.Geofence, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	var boundary *domain.GeoPolygon
+	if req.Type == domain.GeofenceTypePolygon {
+		vertices := make([]*domain.GeoPoint, 0, len(req.Boundary))
+		for _, coordinates := range req.Boundary {
+			vertex, err := coordinatesToDomain(&coordinates)
+			if err != nil {
+				return nil, fmt.Errorf("invalid geofence boundary: %w", err)
+			}
+			vertices = append(vertices, vertex)
+		}
+
+		polygon, err := domain.NewGeoPolygon(vertices)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geofence boundary: %w", err)
+		}
+		boundary = polygon
+	}
+
+	return domain.NewGeofence(req.Type, req.RadiusMiles, boundary, req.Rules)
 }
 
 func facilityRequestToDomainUpdate(req FacilityUpdateRequest) (*domain.Facility, error) {
@@ -89,15 +166,33 @@ func facilityRequestToDomainUpdate(req FacilityUpdateRequest) (*domain.Facility,
 		}
 	}
 
-	return &domain.Facility{
+	location, err := coordinatesToDomain(req.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	geofence, err := geofenceToDomain(req.Geofence)
+	if err != nil {
+		return nil, err
+	}
+
+	facility := &domain.Facility{
 		FacilityNumber:    req.FacilityNumber,
 		Name:              req.Name,
 		Type:              req.Type,
 		Address:           req.Address,
+		Location:          location,
+		Geofence:          geofence,
 		ContactInfo:       req.ContactInfo,
 		ParkingCapacity:   req.ParkingCapacity,
 		ServicesAvailable: req.ServicesAvailable,
-	}, nil
+	}
+
+	if err := facility.SetHazmatAcceptance(req.AcceptsHazmat, req.HazardClasses); err != nil {
+		return nil, err
+	}
+
+	return facility, nil
 }
 
 func facilityDomainToResponse(f *domain.Facility) FacilityResponse {
@@ -107,9 +202,14 @@ func facilityDomainToResponse(f *domain.Facility) FacilityResponse {
 		Name:              f.Name,
 		Type:              f.Type,
 		Address:           f.Address,
+		Location:          f.Location,
+		Geofence:          f.Geofence,
+		DistanceMiles:     f.DistanceMiles,
 		ContactInfo:       f.ContactInfo,
 		ParkingCapacity:   f.ParkingCapacity,
 		ServicesAvailable: f.ServicesAvailable,
+		AcceptsHazmat:     f.AcceptsHazmat,
+		HazardClasses:     f.HazardClasses,
 		CreatedAt:         f.CreatedAt,
 		UpdatedAt:         f.UpdatedAt,
 	}
@@ -118,21 +218,9 @@ func facilityDomainToResponse(f *domain.Facility) FacilityResponse {
 // =================================================================
 
 func (h *FacilityHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -143,7 +231,7 @@ func (h *FacilityHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	facility, err := facilityRequestToDomainCreate(userID, req)
+	facility, err := facilityRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
@@ -158,21 +246,9 @@ func (h *FacilityHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FacilityHandler) GetById(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -183,7 +259,7 @@ func (h *FacilityHandler) GetById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	facility, err := h.facilityService.GetById(r.Context(), objectID, userID)
+	facility, err := h.facilityService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "facility not found"})
 		return
@@ -193,6 +269,12 @@ func (h *FacilityHandler) GetById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FacilityHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -213,6 +295,7 @@ func (h *FacilityHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	facility.ID = objectID
+	facility.OrganizationID = membership.OrganizationID
 
 	if err := h.facilityService.Update(r.Context(), facility); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update user"})
@@ -223,21 +306,9 @@ func (h *FacilityHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FacilityHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -248,7 +319,7 @@ func (h *FacilityHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.facilityService.Delete(r.Context(), objectID, userID)
+	err = h.facilityService.Delete(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		if err == domain.ErrFacilityNotFound {
 			WriteJSON(w, http.StatusNotFound, Response{Error: "facility not found"})
@@ -263,27 +334,15 @@ func (h *FacilityHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FacilityHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
 	filter := domain.NewFacilityFilter()
 
-	filter.UserID = userID
+	filter.OrganizationID = membership.OrganizationID
 
 	// Parse query parameters, adding them to the filter if they're present
 	// any unrecognized query params will be ignored and not added to the filter
@@ -320,6 +379,25 @@ func (h *FacilityHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// a proximity search is given as ?near=40.75,-73.99&radiusMiles=50 and sorts the results by distance
+	if near := r.URL.Query().Get("near"); near != "" {
+		point, err := parseNear(near)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+		filter.Near = point
+
+		if radiusStr := r.URL.Query().Get("radiusMiles"); radiusStr != "" {
+			radius, err := strconv.ParseFloat(radiusStr, 64)
+			if err != nil || radius <= 0 {
+				WriteJSON(w, http.StatusBadRequest, Response{Error: "radiusMiles must be a positive number"})
+				return
+			}
+			filter.RadiusMiles = radius
+		}
+	}
+
 	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
 	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
 
@@ -352,21 +430,9 @@ func (h *FacilityHandler) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FacilityHandler) UpdateAvailableFacilityServices(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -394,7 +460,7 @@ func (h *FacilityHandler) UpdateAvailableFacilityServices(w http.ResponseWriter,
 	}
 
 	// Update services
-	err = h.facilityService.UpdateAvailableFacilityServices(r.Context(), objectID, userID, req.AvailableServices)
+	err = h.facilityService.UpdateAvailableFacilityServices(r.Context(), objectID, membership.OrganizationID, req.AvailableServices)
 	if err != nil {
 		switch {
 		case err == domain.ErrFacilityNotFound:
@@ -406,7 +472,7 @@ func (h *FacilityHandler) UpdateAvailableFacilityServices(w http.ResponseWriter,
 	}
 
 	// Fetch updated facility to return in response
-	updatedFacility, err := h.facilityService.GetById(r.Context(), objectID, userID)
+	updatedFacility, err := h.facilityService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "services updated but failed to fetch updated facility"})
 		return
@@ -414,3 +480,18 @@ func (h *FacilityHandler) UpdateAvailableFacilityServices(w http.ResponseWriter,
 
 	WriteJSON(w, http.StatusOK, Response{Data: facilityDomainToResponse(updatedFacility)})
 }
+
+func parseNear(value string) (*domain.GeoPoint, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("near must be given as lat,lng")
+	}
+
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lng, lngErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if latErr != nil || lngErr != nil {
+		return nil, fmt.Errorf("near must be given as lat,lng")
+	}
+
+	return domain.NewGeoPoint(lat, lng)
+}
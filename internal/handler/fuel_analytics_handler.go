@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FuelAnalyticsHandler struct {
+	analyticsService service.FuelAnalyticsService
+}
+
+func NewFuelAnalyticsHandler(analyticsService service.FuelAnalyticsService) *FuelAnalyticsHandler {
+	return &FuelAnalyticsHandler{analyticsService: analyticsService}
+}
+
+// DTOS =======================================================
+
+type FuelEfficiencyResponse struct {
+	TruckID           *primitive.ObjectID `json:"truck_id,omitempty"`
+	TruckNumber       string              `json:"truck_number,omitempty"`
+	DriverID          *primitive.ObjectID `json:"driver_id,omitempty"`
+	DriverName        string              `json:"driver_name,omitempty"`
+	FuelType          domain.FuelType     `json:"fuel_type,omitempty"`
+	TrailerType       domain.TrailerType  `json:"trailer_type,omitempty"`
+	StartFacilityID   *primitive.ObjectID `json:"start_facility_id,omitempty"`
+	StartFacilityName string              `json:"start_facility_name,omitempty"`
+	EndFacilityID     *primitive.ObjectID `json:"end_facility_id,omitempty"`
+	EndFacilityName   string              `json:"end_facility_name,omitempty"`
+	Trips             int                 `json:"trips"`
+	Miles             int                 `json:"miles"`
+	Gallons           float64             `json:"gallons"`
+	FuelCost          float64             `json:"fuel_cost"`
+	MPG               *float64            `json:"mpg"`
+	CostPerMile       *float64            `json:"cost_per_mile"`
+}
+
+type FuelOutlierResponse struct {
+	FuelLogID               primitive.ObjectID         `json:"fuel_log_id"`
+	TripID                  *primitive.ObjectID        `json:"trip_id,omitempty"`
+	TruckID                 primitive.ObjectID         `json:"truck_id"`
+	TruckNumber             string                     `json:"truck_number"`
+	Date                    string                     `json:"date"`
+	Location                string                     `json:"location"`
+	GallonsPurchased        float64                    `json:"gallons_purchased"`
+	PricePerGallon          float64                    `json:"price_per_gallon"`
+	TotalCost               float64                    `json:"total_cost"`
+	OdometerReading         int                        `json:"odometer_reading"`
+	PreviousOdometerReading *int                       `json:"previous_odometer_reading,omitempty"`
+	MilesSincePrevious      *int                       `json:"miles_since_previous,omitempty"`
+	MPG                     *float64                   `json:"mpg,omitempty"`
+	TypicalGallons          *float64                   `json:"typical_gallons,omitempty"`
+	TypicalPrice            *float64                   `json:"typical_price_per_gallon,omitempty"`
+	Reasons                 []domain.FuelOutlierReason `json:"reasons"`
+}
+
+func fuelEfficiencyDomainToResponse(e domain.FuelEfficiency) FuelEfficiencyResponse {
+	return FuelEfficiencyResponse{
+		TruckID:           e.TruckID,
+		TruckNumber:       e.TruckNumber,
+		DriverID:          e.DriverID,
+		DriverName:        e.DriverName,
+		FuelType:          e.FuelType,
+		TrailerType:       e.TrailerType,
+		StartFacilityID:   e.StartFacilityID,
+		StartFacilityName: e.StartFacilityName,
+		EndFacilityID:     e.EndFacilityID,
+		EndFacilityName:   e.EndFacilityName,
+		Trips:             e.Trips,
+		Miles:             e.Miles,
+		Gallons:           e.Gallons,
+		FuelCost:          e.FuelCost,
+		MPG:               e.MPG,
+		CostPerMile:       e.CostPerMile,
+	}
+}
+
+func fuelOutlierDomainToResponse(o domain.FuelOutlier) FuelOutlierResponse {
+	return FuelOutlierResponse{
+		FuelLogID:               o.FillUp.FuelLogID,
+		TripID:                  o.FillUp.TripID,
+		TruckID:                 o.FillUp.TruckID,
+		TruckNumber:             o.FillUp.TruckNumber,
+		Date:                    domain.FormatDate(o.FillUp.Date),
+		Location:                o.FillUp.Location,
+		GallonsPurchased:        o.FillUp.GallonsPurchased,
+		PricePerGallon:          o.FillUp.PricePerGallon,
+		TotalCost:               o.FillUp.TotalCost,
+		OdometerReading:         o.FillUp.OdometerReading,
+		PreviousOdometerReading: o.FillUp.PreviousOdometerReading,
+		MilesSincePrevious:      o.MilesSincePrevious,
+		MPG:                     o.MPG,
+		TypicalGallons:          o.TypicalGallons,
+		TypicalPrice:            o.TypicalPrice,
+		Reasons:                 o.Reasons,
+	}
+}
+
+// =================================================================
+
+// Efficiency returns a handler reporting MPG and fuel cost per mile for the trips completed between the from and to
+// dates, grouped as given
+func (h *FuelAnalyticsHandler) Efficiency(groupBy domain.FuelGrouping) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		membership, ok := domain.MembershipFromContext(r.Context())
+		if !ok {
+			WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+			return
+		}
+
+		filter, err := domain.NewFuelAnalyticsFilter(membership.OrganizationID, groupBy, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+
+		efficiencies, err := h.analyticsService.Efficiency(r.Context(), filter)
+		if err != nil {
+			WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch fuel efficiency"})
+			return
+		}
+
+		responses := make([]FuelEfficiencyResponse, len(efficiencies))
+		for i, e := range efficiencies {
+			responses[i] = fuelEfficiencyDomainToResponse(e)
+		}
+
+		WriteJSON(w, http.StatusOK, Response{Data: responses})
+	}
+}
+
+// Outliers reports the suspicious fill-ups bought between the from and to dates
+func (h *FuelAnalyticsHandler) Outliers(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	filter, err := domain.NewFuelAnalyticsFilter(membership.OrganizationID, "", r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	outliers, err := h.analyticsService.Outliers(r.Context(), filter)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch fuel outliers"})
+		return
+	}
+
+	responses := make([]FuelOutlierResponse, len(outliers))
+	for i, o := range outliers {
+		responses[i] = fuelOutlierDomainToResponse(o)
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: responses})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -24,6 +25,9 @@ var (
 // DTOS =======================================================
 
 type FuelLogCreateRequest struct {
+	TruckID          primitive.ObjectID  `json:"truck_id"`
+	DriverID         *primitive.ObjectID `json:"driver_id"`
+	FacilityID       *primitive.ObjectID `json:"facility_id"`
 	TripID           *primitive.ObjectID `json:"trip_id"`
 	Date             string              `json:"date"`
 	GallonsPurchased float64             `json:"gallons_purchased"`
@@ -34,6 +38,9 @@ type FuelLogCreateRequest struct {
 }
 
 type FuelLogUpdateRequest struct {
+	TruckID          primitive.ObjectID  `json:"truck_id"`
+	DriverID         *primitive.ObjectID `json:"driver_id"`
+	FacilityID       *primitive.ObjectID `json:"facility_id"`
 	TripID           *primitive.ObjectID `json:"trip_id"`
 	Date             string              `json:"date"`
 	GallonsPurchased float64             `json:"gallons_purchased"`
@@ -45,6 +52,12 @@ type FuelLogUpdateRequest struct {
 
 type FuelLogResponse struct {
 	ID               primitive.ObjectID  `json:"id,omitempty"`
+	TruckID          primitive.ObjectID  `json:"truck_id"`
+	Truck            *domain.Truck       `json:"truck,omitempty"`
+	DriverID         *primitive.ObjectID `json:"driver_id,omitempty"`
+	Driver           *domain.Driver      `json:"driver,omitempty"`
+	FacilityID       *primitive.ObjectID `json:"facility_id,omitempty"`
+	Facility         *domain.Facility    `json:"facility,omitempty"`
 	TripID           *primitive.ObjectID `json:"trip_id,omitempty"`
 	Trip             *domain.Trip        `json:"trip,omitempty"`
 	Date             string              `json:"date"`
@@ -61,8 +74,13 @@ type ListFuelLogsResponse struct {
 	FuelLogs []FuelLogResponse `json:"fuel_logs"`
 }
 
-func fuelLogRequestToDomainCreate(req FuelLogCreateRequest) (*domain.FuelLog, error) {
+func fuelLogRequestToDomainCreate(orgID, userID primitive.ObjectID, req FuelLogCreateRequest) (*domain.FuelLog, error) {
 	return domain.NewFuelLog(
+		orgID,
+		userID,
+		req.TruckID,
+		req.DriverID,
+		req.FacilityID,
 		req.TripID,
 		req.Date,
 		req.Location,
@@ -73,25 +91,35 @@ func fuelLogRequestToDomainCreate(req FuelLogCreateRequest) (*domain.FuelLog, er
 	)
 }
 
-func fuelLogRequestToDomainUpdate(req FuelLogUpdateRequest) (*domain.FuelLog, error) {
-
-	return &domain.FuelLog{
-		TripID:           req.TripID,
-		Date:             req.Date,
-		GallonsPurchased: req.GallonsPurchased,
-		PricePerGallon:   req.PricePerGallon,
-		TotalCost:        req.TotalCost,
-		Location:         req.Location,
-		OdometerReading:  req.OdometerReading,
-	}, nil
+func fuelLogRequestToDomainUpdate(orgID, userID primitive.ObjectID, req FuelLogUpdateRequest) (*domain.FuelLog, error) {
+	return domain.NewFuelLog(
+		orgID,
+		userID,
+		req.TruckID,
+		req.DriverID,
+		req.FacilityID,
+		req.TripID,
+		req.Date,
+		req.Location,
+		req.GallonsPurchased,
+		req.PricePerGallon,
+		req.TotalCost,
+		req.OdometerReading,
+	)
 }
 
 func fuelLogDomainToResponse(f *domain.FuelLog) FuelLogResponse {
 	return FuelLogResponse{
 		ID:               f.ID,
+		TruckID:          f.TruckID,
+		Truck:            f.Truck,
+		DriverID:         f.DriverID,
+		Driver:           f.Driver,
+		FacilityID:       f.FacilityID,
+		Facility:         f.Facility,
 		TripID:           f.TripID,
 		Trip:             f.Trip,
-		Date:             f.Date,
+		Date:             domain.FormatDate(f.Date),
 		GallonsPurchased: f.GallonsPurchased,
 		PricePerGallon:   f.PricePerGallon,
 		TotalCost:        f.TotalCost,
@@ -102,9 +130,54 @@ func fuelLogDomainToResponse(f *domain.FuelLog) FuelLogResponse {
 	}
 }
 
+// a fuel log's truck, driver, facility or trip that can't be found is a 404, a log that doesn't match its trip is a
+// bad request, and a reading that would wind its truck's odometer back is a conflict with the readings already recorded
+func writeFuelLogError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrFuelLogNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "fuel log not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrTripNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "trip not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrTruckNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrDriverNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "driver not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrFacilityNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "facility not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrFuelLogTripMismatch) {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var rollbackErr *domain.OdometerRollbackError
+	var aheadErr *domain.OdometerAheadError
+	var implausibleErr *domain.OdometerImplausibleError
+	if errors.As(err, &rollbackErr) || errors.As(err, &aheadErr) || errors.As(err, &implausibleErr) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
+
 // =================================================================
 
 func (h *FuelLogHandler) Create(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	var req FuelLogCreateRequest
 
 	if err := ReadJSON(r, &req); err != nil {
@@ -112,14 +185,14 @@ func (h *FuelLogHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fuelLog, err := fuelLogRequestToDomainCreate(req)
+	fuelLog, err := fuelLogRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
 
 	if err := h.fuelLogService.Create(r.Context(), fuelLog); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+		writeFuelLogError(w, err)
 		return
 	}
 
@@ -127,6 +200,12 @@ func (h *FuelLogHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FuelLogHandler) GetById(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -134,7 +213,7 @@ func (h *FuelLogHandler) GetById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fuelLog, err := h.fuelLogService.GetById(r.Context(), objectID)
+	fuelLog, err := h.fuelLogService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "fuel log not found"})
 		return
@@ -144,6 +223,12 @@ func (h *FuelLogHandler) GetById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FuelLogHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -157,7 +242,7 @@ func (h *FuelLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fuelLog, err := fuelLogRequestToDomainUpdate(req)
+	fuelLog, err := fuelLogRequestToDomainUpdate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
@@ -166,7 +251,7 @@ func (h *FuelLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 	fuelLog.ID = objectID
 
 	if err := h.fuelLogService.Update(r.Context(), fuelLog); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update fuel log"})
+		writeFuelLogError(w, err)
 		return
 	}
 
@@ -174,6 +259,12 @@ func (h *FuelLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FuelLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -181,7 +272,7 @@ func (h *FuelLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.fuelLogService.Delete(r.Context(), objectID)
+	err = h.fuelLogService.Delete(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		if err == domain.ErrFuelLogNotFound {
 			WriteJSON(w, http.StatusNotFound, Response{Error: "fuel log not found"})
@@ -196,14 +287,73 @@ func (h *FuelLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FuelLogHandler) List(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	filter := domain.NewFuelLogFilter()
+	filter.OrganizationID = membership.OrganizationID
 
 	if tripId := r.URL.Query().Get("tripID"); tripId != "" {
-		if id, err := primitive.ObjectIDFromHex(tripId); err != nil {
+		if id, err := primitive.ObjectIDFromHex(tripId); err == nil {
 			filter.TripID = &id
+		} else {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid trip ID format"})
+			return
+		}
+	}
+
+	if truckId := r.URL.Query().Get("truckID"); truckId != "" {
+		if id, err := primitive.ObjectIDFromHex(truckId); err == nil {
+			filter.TruckID = &id
+		} else {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid truck ID format"})
+			return
 		}
 	}
 
+	if driverId := r.URL.Query().Get("driverID"); driverId != "" {
+		if id, err := primitive.ObjectIDFromHex(driverId); err == nil {
+			filter.DriverID = &id
+		} else {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid driver ID format"})
+			return
+		}
+	}
+
+	if facilityId := r.URL.Query().Get("facilityID"); facilityId != "" {
+		if id, err := primitive.ObjectIDFromHex(facilityId); err == nil {
+			filter.FacilityID = &id
+		} else {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid facility ID format"})
+			return
+		}
+	}
+
+	if from := r.URL.Query().Get("from"); from != "" {
+		date, err := domain.ParseDate("from", from)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+		filter.From = &date
+	}
+
+	// to is inclusive, so the range runs up to the start of the following day
+	if to := r.URL.Query().Get("to"); to != "" {
+		date, err := domain.ParseDate("to", to)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+		end := primitive.NewDateTimeFromTime(date.Time().AddDate(0, 0, 1))
+		filter.To = &end
+	}
+
+	filter.Location = r.URL.Query().Get("location")
+
 	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
 	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
 
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type Response struct {
@@ -44,6 +46,20 @@ func getQueryIntParam(r *http.Request, key string, defaultValue int) int {
 	return val
 }
 
+func getQueryBoolParam(r *http.Request, key string, defaultValue bool) bool {
+	valStr := r.URL.Query().Get(key)
+	if valStr == "" {
+		return defaultValue
+	}
+
+	val, err := strconv.ParseBool(valStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return val
+}
+
 func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{
 		"status": "ok",
@@ -52,3 +68,19 @@ func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// getQueryTimeParam reads an RFC 3339 timestamp. unlike the other query helpers a malformed value is an error
+// rather than the default, since quietly ignoring a time bound would widen the results.
+func getQueryTimeParam(r *http.Request, key string) (*time.Time, error) {
+	valStr := r.URL.Query().Get(key)
+	if valStr == "" {
+		return nil, nil
+	}
+
+	val, err := time.Parse(time.RFC3339, valStr)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+	}
+
+	return &val, nil
+}
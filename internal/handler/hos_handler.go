@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HOSHandler struct {
+	hosService service.HOSService
+}
+
+func NewHOSHandler(hosService service.HOSService) *HOSHandler {
+	return &HOSHandler{hosService: hosService}
+}
+
+// DTOS =======================================================
+
+type DutyStatusRequest struct {
+	Status    domain.DutyStatus `json:"status"`
+	StartedAt time.Time         `json:"started_at"`
+	Location  string            `json:"location,omitempty"`
+	Note      string            `json:"note,omitempty"`
+}
+
+func writeHOSError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrDriverNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "driver not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrDutyStatusOutOfOrder) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
+
+// =================================================================
+func (h *HOSHandler) Record(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	driverID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidDriverId})
+		return
+	}
+
+	var req DutyStatusRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	event, err := domain.NewDutyStatusEvent(
+		membership.OrganizationID,
+		driverID,
+		membership.UserID,
+		req.Status,
+		req.StartedAt,
+		req.Location,
+		req.Note,
+	)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.hosService.RecordDutyStatus(r.Context(), event); err != nil {
+		writeHOSError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, Response{Data: event})
+}
+
+func (h *HOSHandler) List(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	driverID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidDriverId})
+		return
+	}
+
+	filter := domain.NewDutyStatusFilter()
+	filter.OrganizationID = membership.OrganizationID
+	filter.DriverID = driverID
+	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
+	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
+
+	from, err := getQueryTimeParam(r, "from")
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	if from != nil {
+		dt := primitive.NewDateTimeFromTime(*from)
+		filter.From = &dt
+	}
+
+	to, err := getQueryTimeParam(r, "to")
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	if to != nil {
+		dt := primitive.NewDateTimeFromTime(*to)
+		filter.To = &dt
+	}
+
+	result, err := h.hosService.ListDutyStatus(r.Context(), filter)
+	if err != nil {
+		writeHOSError(w, err)
+		return
+	}
+
+	var nextOffset *int64
+	if filter.Offset+filter.Limit < result.Total {
+		next := filter.Offset + filter.Limit
+		nextOffset = &next
+	}
+
+	response := PaginatedResponse{
+		Items:      result.Events,
+		Total:      result.Total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+		NextOffset: nextOffset,
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// Summary is where the driver stands now, or at the time given as at, which is handy for checking whether they'll
+// have hours for a trip later in the day
+func (h *HOSHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	driverID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidDriverId})
+		return
+	}
+
+	asOf := time.Now()
+	at, err := getQueryTimeParam(r, "at")
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	if at != nil {
+		asOf = *at
+	}
+
+	summary, err := h.hosService.Summary(r.Context(), driverID, membership.OrganizationID, asOf)
+	if err != nil {
+		writeHOSError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: summary})
+}
@@ -4,10 +4,8 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/domain"
-	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -69,8 +67,9 @@ type ListIncidentReportsResponse struct {
 	IncidentReports []IncidentReportResponse `json:"fuel_logs"`
 }
 
-func incidentReportRequestToDomainCreate(userID primitive.ObjectID, req IncidentReportCreateRequest) (*domain.IncidentReport, error) {
+func incidentReportRequestToDomainCreate(orgID, userID primitive.ObjectID, req IncidentReportCreateRequest) (*domain.IncidentReport, error) {
 	return domain.NewIncidentReport(
+		orgID,
 		userID,
 		req.TripID,
 		req.TruckID,
@@ -122,21 +121,9 @@ func incidentReportDomainToResponse(i *domain.IncidentReport) IncidentReportResp
 // =================================================================
 
 func (h *IncidentReportHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -147,7 +134,7 @@ func (h *IncidentReportHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	incidentReport, err := incidentReportRequestToDomainCreate(userID, req)
+	incidentReport, err := incidentReportRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
@@ -162,21 +149,9 @@ func (h *IncidentReportHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *IncidentReportHandler) GetById(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -187,7 +162,7 @@ func (h *IncidentReportHandler) GetById(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	incidentReport, err := h.incidentReportService.GetById(r.Context(), objectID, userID)
+	incidentReport, err := h.incidentReportService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "incident report not found"})
 		return
@@ -197,6 +172,12 @@ func (h *IncidentReportHandler) GetById(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *IncidentReportHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -217,6 +198,7 @@ func (h *IncidentReportHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	incidentReport.ID = objectID
+	incidentReport.OrganizationID = membership.OrganizationID
 
 	if err := h.incidentReportService.Update(r.Context(), incidentReport); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update user"})
@@ -227,21 +209,9 @@ func (h *IncidentReportHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *IncidentReportHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -252,7 +222,7 @@ func (h *IncidentReportHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.incidentReportService.Delete(r.Context(), objectID, userID)
+	err = h.incidentReportService.Delete(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		if err == domain.ErrIncidentReportNotFound {
 			WriteJSON(w, http.StatusNotFound, Response{Error: "incident report not found"})
@@ -267,27 +237,15 @@ func (h *IncidentReportHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *IncidentReportHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
 	filter := domain.NewIncidentReportFilter()
 
-	filter.UserID = userID
+	filter.OrganizationID = membership.OrganizationID
 
 	if tripId := r.URL.Query().Get("tripID"); tripId != "" {
 		if id, err := primitive.ObjectIDFromHex(tripId); err != nil {
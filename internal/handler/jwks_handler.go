@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/jwald3/waybill/internal/signing"
+)
+
+type JWKSHandler struct {
+	keys *signing.KeySet
+}
+
+func NewJWKSHandler(keys *signing.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// Get publishes the public keys our tokens are signed with. the body is a bare JWK set rather than a Response
+// since that's the shape JWT libraries expect to fetch.
+func (h *JWKSHandler) Get(w http.ResponseWriter, r *http.Request) {
+	// verifiers cache the set, so keep this short enough that a newly added key is picked up before it signs
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	WriteJSON(w, http.StatusOK, h.keys.JWKS())
+}
@@ -1,13 +1,11 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/domain"
-	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -24,7 +22,9 @@ func NewMaintenanceLogHandler(maintenanceLogService service.MaintenanceLogServic
 
 type MaintenanceLogCreateRequest struct {
 	TruckID     *primitive.ObjectID           `json:"truck_id"`
+	ScheduleID  *primitive.ObjectID           `json:"schedule_id"`
 	Date        string                        `json:"date"`
+	Mileage     int                           `json:"mileage"`
 	ServiceType domain.MaintenanceServiceType `json:"service_type"`
 	Cost        float64                       `json:"cost"`
 	Notes       string                        `json:"notes"`
@@ -34,7 +34,9 @@ type MaintenanceLogCreateRequest struct {
 
 type MaintenanceLogUpdateRequest struct {
 	TruckID     *primitive.ObjectID           `json:"truck_id"`
+	ScheduleID  *primitive.ObjectID           `json:"schedule_id"`
 	Date        string                        `json:"date"`
+	Mileage     int                           `json:"mileage"`
 	ServiceType domain.MaintenanceServiceType `json:"service_type"`
 	Cost        float64                       `json:"cost"`
 	Notes       string                        `json:"notes"`
@@ -46,7 +48,9 @@ type MaintenanceLogResponse struct {
 	ID          primitive.ObjectID            `json:"id,omitempty"`
 	TruckID     *primitive.ObjectID           `json:"truck_id,omitempty"`
 	Truck       *domain.Truck                 `json:"truck,omitempty"`
+	ScheduleID  *primitive.ObjectID           `json:"schedule_id,omitempty"`
 	Date        string                        `json:"date"`
+	Mileage     int                           `json:"mileage,omitempty"`
 	ServiceType domain.MaintenanceServiceType `json:"service_type"`
 	Cost        float64                       `json:"cost"`
 	Notes       string                        `json:"notes"`
@@ -60,11 +64,14 @@ type ListMaintenanceLogsResponse struct {
 	MaintenanceLogs []MaintenanceLogResponse `json:"maintenance_logs"`
 }
 
-func maintenanceLogRequestToDomainCreate(userID primitive.ObjectID, req MaintenanceLogCreateRequest) (*domain.MaintenanceLog, error) {
+func maintenanceLogRequestToDomainCreate(orgID, userID primitive.ObjectID, req MaintenanceLogCreateRequest) (*domain.MaintenanceLog, error) {
 	return domain.NewMaintenanceLog(
 		req.TruckID,
+		req.ScheduleID,
+		orgID,
 		userID,
 		req.Date,
+		req.Mileage,
 		req.ServiceType,
 		req.Notes,
 		req.Mechanic,
@@ -73,20 +80,20 @@ func maintenanceLogRequestToDomainCreate(userID primitive.ObjectID, req Maintena
 	)
 }
 
-func maintenanceLogRequestToDomainUpdate(req MaintenanceLogUpdateRequest) (*domain.MaintenanceLog, error) {
-	if !req.ServiceType.IsValid() {
-		return nil, fmt.Errorf("invalid service type provided: %s", req.ServiceType)
-	}
-
-	return &domain.MaintenanceLog{
-		TruckID:     req.TruckID,
-		Date:        req.Date,
-		ServiceType: req.ServiceType,
-		Notes:       req.Notes,
-		Mechanic:    req.Mechanic,
-		Location:    req.Location,
-		Cost:        req.Cost,
-	}, nil
+func maintenanceLogRequestToDomainUpdate(orgID, userID primitive.ObjectID, req MaintenanceLogUpdateRequest) (*domain.MaintenanceLog, error) {
+	return domain.NewMaintenanceLog(
+		req.TruckID,
+		req.ScheduleID,
+		orgID,
+		userID,
+		req.Date,
+		req.Mileage,
+		req.ServiceType,
+		req.Notes,
+		req.Mechanic,
+		req.Location,
+		req.Cost,
+	)
 }
 
 func maintenanceLogDomainToResponse(m *domain.MaintenanceLog) MaintenanceLogResponse {
@@ -94,7 +101,9 @@ func maintenanceLogDomainToResponse(m *domain.MaintenanceLog) MaintenanceLogResp
 		ID:          m.ID,
 		TruckID:     m.TruckID,
 		Truck:       m.Truck,
-		Date:        m.Date,
+		ScheduleID:  m.ScheduleID,
+		Date:        domain.FormatDate(m.Date),
+		Mileage:     m.Mileage,
 		ServiceType: m.ServiceType,
 		Notes:       m.Notes,
 		Mechanic:    m.Mechanic,
@@ -108,21 +117,9 @@ func maintenanceLogDomainToResponse(m *domain.MaintenanceLog) MaintenanceLogResp
 // =================================================================
 
 func (h *MaintenanceLogHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -133,13 +130,17 @@ func (h *MaintenanceLogHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	maintenanceLog, err := maintenanceLogRequestToDomainCreate(userID, req)
+	maintenanceLog, err := maintenanceLogRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
 
 	if err := h.maintenanceLogService.Create(r.Context(), maintenanceLog); err != nil {
+		if errors.Is(err, domain.ErrMaintenanceScheduleNotFound) || errors.Is(err, domain.ErrTruckNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: err.Error()})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
@@ -148,21 +149,9 @@ func (h *MaintenanceLogHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *MaintenanceLogHandler) GetById(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -173,7 +162,7 @@ func (h *MaintenanceLogHandler) GetById(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	maintenanceLog, err := h.maintenanceLogService.GetById(r.Context(), objectID, userID)
+	maintenanceLog, err := h.maintenanceLogService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "maintenance log not found"})
 		return
@@ -183,6 +172,12 @@ func (h *MaintenanceLogHandler) GetById(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *MaintenanceLogHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -196,7 +191,7 @@ func (h *MaintenanceLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	maintenanceLog, err := maintenanceLogRequestToDomainUpdate(req)
+	maintenanceLog, err := maintenanceLogRequestToDomainUpdate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
@@ -205,6 +200,10 @@ func (h *MaintenanceLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 	maintenanceLog.ID = objectID
 
 	if err := h.maintenanceLogService.Update(r.Context(), maintenanceLog); err != nil {
+		if errors.Is(err, domain.ErrMaintenanceLogNotFound) || errors.Is(err, domain.ErrMaintenanceScheduleNotFound) || errors.Is(err, domain.ErrTruckNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: err.Error()})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update user"})
 		return
 	}
@@ -213,21 +212,9 @@ func (h *MaintenanceLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *MaintenanceLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -238,7 +225,7 @@ func (h *MaintenanceLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.maintenanceLogService.Delete(r.Context(), objectID, userID); err != nil {
+	if err := h.maintenanceLogService.Delete(r.Context(), objectID, membership.OrganizationID); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to delete maintenance log"})
 		return
 	}
@@ -247,26 +234,14 @@ func (h *MaintenanceLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *MaintenanceLogHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
 	filter := domain.NewMaintenanceLogFilter()
-	filter.UserID = userID
+	filter.OrganizationID = membership.OrganizationID
 
 	if truckId := r.URL.Query().Get("truckID"); truckId != "" {
 		if id, err := primitive.ObjectIDFromHex(truckId); err == nil {
@@ -277,6 +252,15 @@ func (h *MaintenanceLogHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if scheduleId := r.URL.Query().Get("scheduleID"); scheduleId != "" {
+		if id, err := primitive.ObjectIDFromHex(scheduleId); err == nil {
+			filter.ScheduleID = &id
+		} else {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid schedule ID format"})
+			return
+		}
+	}
+
 	if serviceType := r.URL.Query().Get("serviceType"); serviceType != "" {
 		filter.ServiceType = domain.MaintenanceServiceType(serviceType)
 	}
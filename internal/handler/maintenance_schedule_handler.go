@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MaintenanceScheduleHandler struct {
+	maintenanceService service.MaintenanceScheduleService
+}
+
+func NewMaintenanceScheduleHandler(maintenanceService service.MaintenanceScheduleService) *MaintenanceScheduleHandler {
+	return &MaintenanceScheduleHandler{maintenanceService: maintenanceService}
+}
+
+// DTOS =======================================================
+
+type MaintenanceScheduleRequest struct {
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	IntervalMiles  int                 `json:"interval_miles"`
+	IntervalDays   int                 `json:"interval_days"`
+	SafetyCritical bool                `json:"safety_critical"`
+	TruckID        *primitive.ObjectID `json:"truck_id"`
+	Make           string              `json:"make"`
+	Model          string              `json:"model"`
+}
+
+type MaintenanceScheduleResponse struct {
+	ID             primitive.ObjectID  `json:"id,omitempty"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description,omitempty"`
+	IntervalMiles  int                 `json:"interval_miles,omitempty"`
+	IntervalDays   int                 `json:"interval_days,omitempty"`
+	SafetyCritical bool                `json:"safety_critical"`
+	TruckID        *primitive.ObjectID `json:"truck_id,omitempty"`
+	Make           string              `json:"make,omitempty"`
+	Model          string              `json:"model,omitempty"`
+	CreatedAt      primitive.DateTime  `json:"created_at"`
+	UpdatedAt      primitive.DateTime  `json:"updated_at"`
+}
+
+type MaintenanceDueResponse struct {
+	ScheduleID           primitive.ObjectID `json:"schedule_id"`
+	ScheduleName         string             `json:"schedule_name"`
+	SafetyCritical       bool               `json:"safety_critical"`
+	TruckID              primitive.ObjectID `json:"truck_id"`
+	TruckNumber          string             `json:"truck_number"`
+	LastPerformedOn      string             `json:"last_performed_on,omitempty"`
+	LastPerformedMileage *int               `json:"last_performed_mileage,omitempty"`
+	CurrentMileage       int                `json:"current_mileage"`
+	NextDueMileage       *int               `json:"next_due_mileage,omitempty"`
+	MilesRemaining       *int               `json:"miles_remaining,omitempty"`
+	NextDueOn            string             `json:"next_due_on,omitempty"`
+	DaysRemaining        *int               `json:"days_remaining,omitempty"`
+	Overdue              bool               `json:"overdue"`
+}
+
+func maintenanceScheduleRequestToDomain(orgID, userID primitive.ObjectID, req MaintenanceScheduleRequest) (*domain.MaintenanceSchedule, error) {
+	return domain.NewMaintenanceSchedule(
+		orgID,
+		userID,
+		req.Name,
+		req.Description,
+		req.IntervalMiles,
+		req.IntervalDays,
+		req.SafetyCritical,
+		req.TruckID,
+		req.Make,
+		req.Model,
+	)
+}
+
+func maintenanceScheduleDomainToResponse(s *domain.MaintenanceSchedule) MaintenanceScheduleResponse {
+	return MaintenanceScheduleResponse{
+		ID:             s.ID,
+		Name:           s.Name,
+		Description:    s.Description,
+		IntervalMiles:  s.IntervalMiles,
+		IntervalDays:   s.IntervalDays,
+		SafetyCritical: s.SafetyCritical,
+		TruckID:        s.TruckID,
+		Make:           s.Make,
+		Model:          s.Model,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+	}
+}
+
+func maintenanceDueDomainToResponse(d domain.MaintenanceDue) MaintenanceDueResponse {
+	return MaintenanceDueResponse{
+		ScheduleID:           d.ScheduleID,
+		ScheduleName:         d.ScheduleName,
+		SafetyCritical:       d.SafetyCritical,
+		TruckID:              d.TruckID,
+		TruckNumber:          d.TruckNumber,
+		LastPerformedOn:      domain.FormatDate(d.LastPerformedOn),
+		LastPerformedMileage: d.LastPerformedMileage,
+		CurrentMileage:       d.CurrentMileage,
+		NextDueMileage:       d.NextDueMileage,
+		MilesRemaining:       d.MilesRemaining,
+		NextDueOn:            domain.FormatDate(d.NextDueOn),
+		DaysRemaining:        d.DaysRemaining,
+		Overdue:              d.Overdue,
+	}
+}
+
+// writeMaintenanceScheduleError reports a missing schedule or truck as a 404 rather than a server failure
+func writeMaintenanceScheduleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrMaintenanceScheduleNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "maintenance schedule not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrTruckNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
+
+// =================================================================
+
+func (h *MaintenanceScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	var req MaintenanceScheduleRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	schedule, err := maintenanceScheduleRequestToDomain(membership.OrganizationID, membership.UserID, req)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.maintenanceService.Create(r.Context(), schedule); err != nil {
+		writeMaintenanceScheduleError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, maintenanceScheduleDomainToResponse(schedule))
+}
+
+func (h *MaintenanceScheduleHandler) GetById(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	schedule, err := h.maintenanceService.GetById(r.Context(), objectID, membership.OrganizationID)
+	if err != nil {
+		writeMaintenanceScheduleError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: maintenanceScheduleDomainToResponse(schedule)})
+}
+
+func (h *MaintenanceScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var req MaintenanceScheduleRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	schedule, err := maintenanceScheduleRequestToDomain(membership.OrganizationID, membership.UserID, req)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	schedule.ID = objectID
+
+	if err := h.maintenanceService.Update(r.Context(), schedule); err != nil {
+		writeMaintenanceScheduleError(w, err)
+		return
+	}
+
+	updated, err := h.maintenanceService.GetById(r.Context(), objectID, membership.OrganizationID)
+	if err != nil {
+		writeMaintenanceScheduleError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: maintenanceScheduleDomainToResponse(updated)})
+}
+
+func (h *MaintenanceScheduleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.maintenanceService.Delete(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeMaintenanceScheduleError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *MaintenanceScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	filter := domain.NewMaintenanceScheduleFilter()
+	filter.OrganizationID = membership.OrganizationID
+
+	if truckId := r.URL.Query().Get("truckID"); truckId != "" {
+		id, err := primitive.ObjectIDFromHex(truckId)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid truck ID format"})
+			return
+		}
+		filter.TruckID = &id
+	}
+
+	filter.Make = r.URL.Query().Get("make")
+	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
+	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
+
+	result, err := h.maintenanceService.List(r.Context(), filter)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch maintenance schedules"})
+		return
+	}
+
+	responses := make([]MaintenanceScheduleResponse, len(result.Schedules))
+	for i, s := range result.Schedules {
+		responses[i] = maintenanceScheduleDomainToResponse(s)
+	}
+
+	var nextOffset *int64
+	if filter.Offset+filter.Limit < result.Total {
+		next := filter.Offset + filter.Limit
+		nextOffset = &next
+	}
+
+	WriteJSON(w, http.StatusOK, PaginatedResponse{
+		Items:      responses,
+		Total:      result.Total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+		NextOffset: nextOffset,
+	})
+}
+
+// Due reports the scheduled services that are overdue or will come due within withinMiles miles (1,000 by
+// default) or withinDays days (30 by default), overdue ones first
+func (h *MaintenanceScheduleHandler) Due(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	withinMiles := getQueryIntParam(r, "withinMiles", 1000)
+	withinDays := getQueryIntParam(r, "withinDays", 30)
+	if withinMiles < 0 || withinDays < 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "withinMiles and withinDays can't be negative"})
+		return
+	}
+
+	due, err := h.maintenanceService.Due(r.Context(), membership.OrganizationID, withinMiles, withinDays)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch maintenance due"})
+		return
+	}
+
+	responses := make([]MaintenanceDueResponse, len(due))
+	for i, d := range due {
+		responses[i] = maintenanceDueDomainToResponse(d)
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: responses})
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OdometerHandler struct {
+	odometerService service.OdometerService
+}
+
+func NewOdometerHandler(odometerService service.OdometerService) *OdometerHandler {
+	return &OdometerHandler{odometerService: odometerService}
+}
+
+// DTOS =======================================================
+
+type OdometerDiscrepancyResponse struct {
+	TruckID         primitive.ObjectID               `json:"truck_id"`
+	TruckNumber     string                           `json:"truck_number"`
+	Source          domain.OdometerSource            `json:"source"`
+	SourceID        primitive.ObjectID               `json:"source_id"`
+	Reason          domain.OdometerDiscrepancyReason `json:"reason"`
+	RecordedAt      primitive.DateTime               `json:"recorded_at"`
+	Reading         int                              `json:"reading"`
+	PreviousReading *int                             `json:"previous_reading,omitempty"`
+	AllowedMiles    *int                             `json:"allowed_miles,omitempty"`
+}
+
+func odometerDiscrepancyDomainToResponse(d domain.OdometerDiscrepancy) OdometerDiscrepancyResponse {
+	return OdometerDiscrepancyResponse{
+		TruckID:         d.TruckID,
+		TruckNumber:     d.TruckNumber,
+		Source:          d.Source,
+		SourceID:        d.SourceID,
+		Reason:          d.Reason,
+		RecordedAt:      d.RecordedAt,
+		Reading:         d.Reading,
+		PreviousReading: d.PreviousReading,
+		AllowedMiles:    d.AllowedMiles,
+	}
+}
+
+// =================================================================
+
+// Discrepancies reports the trips and fuel logs whose odometer readings go backward or imply more miles than could
+// have been driven, across the fleet or for one truck
+func (h *OdometerHandler) Discrepancies(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	var truckID *primitive.ObjectID
+	if truckId := r.URL.Query().Get("truckID"); truckId != "" {
+		id, err := primitive.ObjectIDFromHex(truckId)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid truck ID format"})
+			return
+		}
+		truckID = &id
+	}
+
+	discrepancies, err := h.odometerService.Discrepancies(r.Context(), membership.OrganizationID, truckID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTruckNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch odometer discrepancies"})
+		return
+	}
+
+	responses := make([]OdometerDiscrepancyResponse, len(discrepancies))
+	for i, d := range discrepancies {
+		responses[i] = odometerDiscrepancyDomainToResponse(d)
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: responses})
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrganizationHandler struct {
+	organizationService service.OrganizationService
+}
+
+func NewOrganizationHandler(organizationService service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+	}
+}
+
+var (
+	invalidMembershipId = "invalid membership id"
+)
+
+// DTOS =======================================================
+
+type OrganizationCreateRequest struct {
+	Name string `json:"name"`
+}
+
+type MemberCreateRequest struct {
+	Email string      `json:"email"`
+	Role  domain.Role `json:"role"`
+}
+
+type MemberUpdateRequest struct {
+	Role domain.Role `json:"role"`
+}
+
+type MembershipResponse struct {
+	ID             primitive.ObjectID   `json:"id"`
+	OrganizationID primitive.ObjectID   `json:"organization_id"`
+	Organization   *domain.Organization `json:"organization,omitempty"`
+	UserID         primitive.ObjectID   `json:"user_id"`
+	User           *domain.User         `json:"user,omitempty"`
+	Role           domain.Role          `json:"role"`
+	CreatedAt      primitive.DateTime   `json:"created_at"`
+	UpdatedAt      primitive.DateTime   `json:"updated_at"`
+}
+
+func membershipDomainToResponse(m *domain.Membership) MembershipResponse {
+	return MembershipResponse{
+		ID:             m.ID,
+		OrganizationID: m.OrganizationID,
+		Organization:   m.Organization,
+		UserID:         m.UserID,
+		User:           m.User,
+		Role:           m.Role,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
+
+func membershipsDomainToResponse(memberships []*domain.Membership) []MembershipResponse {
+	responses := make([]MembershipResponse, len(memberships))
+	for i, membership := range memberships {
+		responses[i] = membershipDomainToResponse(membership)
+	}
+
+	return responses
+}
+
+func writeMembershipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrMembershipNotFound), errors.Is(err, domain.ErrUserNotFound):
+		WriteJSON(w, http.StatusNotFound, Response{Error: err.Error()})
+	case errors.Is(err, domain.ErrMembershipExists), errors.Is(err, domain.ErrLastOwner):
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+	default:
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update organization members"})
+	}
+}
+
+// =================================================================
+
+// Create starts a new organization with the caller as its owner
+func (h *OrganizationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := domain.ActorFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		return
+	}
+
+	var req OrganizationCreateRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	membership, err := h.organizationService.CreateOrganization(r.Context(), req.Name, userID)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, membershipDomainToResponse(membership))
+}
+
+// List returns every organization the caller belongs to along with their role in each
+func (h *OrganizationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := domain.ActorFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		return
+	}
+
+	memberships, err := h.organizationService.ListMemberships(r.Context(), userID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch organizations"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: membershipsDomainToResponse(memberships)})
+}
+
+func (h *OrganizationHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	members, err := h.organizationService.ListMembers(r.Context(), membership.OrganizationID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch members"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: membershipsDomainToResponse(members)})
+}
+
+func (h *OrganizationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	var req MemberCreateRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	if !req.Role.IsValid() {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid role provided: " + string(req.Role)})
+		return
+	}
+
+	member, err := h.organizationService.AddMember(r.Context(), membership.OrganizationID, req.Email, req.Role)
+	if err != nil {
+		writeMembershipError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, membershipDomainToResponse(member))
+}
+
+func (h *OrganizationHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidMembershipId})
+		return
+	}
+
+	var req MemberUpdateRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	if !req.Role.IsValid() {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid role provided: " + string(req.Role)})
+		return
+	}
+
+	member, err := h.organizationService.UpdateMemberRole(r.Context(), objectID, membership.OrganizationID, req.Role)
+	if err != nil {
+		writeMembershipError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, membershipDomainToResponse(member))
+}
+
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidMembershipId})
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeMembershipError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusNoContent, nil)
+}
+
+// UnlockMember clears a member's failed logins, lifting a lockout early
+func (h *OrganizationHandler) UnlockMember(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidMembershipId})
+		return
+	}
+
+	if err := h.organizationService.UnlockMember(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeMembershipError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusNoContent, nil)
+}
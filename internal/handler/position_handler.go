@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// devices that lost signal send their backlog in one request; anything bigger than this should be split up
+const maxBulkPositions = 1000
+
+type PositionHandler struct {
+	positionService service.TruckPositionService
+	geofenceService service.GeofenceService
+}
+
+func NewPositionHandler(positionService service.TruckPositionService, geofenceService service.GeofenceService) *PositionHandler {
+	return &PositionHandler{
+		positionService: positionService,
+		geofenceService: geofenceService,
+	}
+}
+
+// DTOS =======================================================
+
+type PositionRequest struct {
+	Lat        *float64  `json:"lat"`
+	Lng        *float64  `json:"lng"`
+	SpeedMPH   *float64  `json:"speed_mph,omitempty"`
+	Heading    *float64  `json:"heading,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type BulkPositionRequest struct {
+	Positions []PositionRequest `json:"positions"`
+}
+
+// for telematics units that track geofences themselves and report when the truck crosses one
+type LocationEventRequest struct {
+	FacilityID primitive.ObjectID       `json:"facility_id"`
+	Type       domain.LocationEventType `json:"type"`
+	OccurredAt time.Time                `json:"occurred_at"`
+}
+
+type TruckPositionResponse struct {
+	ID         primitive.ObjectID `json:"id,omitempty"`
+	TruckID    primitive.ObjectID `json:"truck_id"`
+	Location   domain.GeoPoint    `json:"location"`
+	SpeedMPH   *float64           `json:"speed_mph,omitempty"`
+	Heading    *float64           `json:"heading,omitempty"`
+	RecordedAt primitive.DateTime `json:"recorded_at"`
+	ReceivedAt primitive.DateTime `json:"received_at"`
+}
+
+func positionRequestToDomain(req PositionRequest) (*domain.Position, error) {
+	if req.Lat == nil || req.Lng == nil {
+		return nil, fmt.Errorf("lat and lng are required")
+	}
+
+	return domain.NewPosition(*req.Lat, *req.Lng, req.SpeedMPH, req.Heading, req.RecordedAt)
+}
+
+func truckPositionDomainToResponse(p *domain.TruckPosition) TruckPositionResponse {
+	return TruckPositionResponse{
+		ID:         p.ID,
+		TruckID:    p.TruckID,
+		Location:   p.Location,
+		SpeedMPH:   p.SpeedMPH,
+		Heading:    p.Heading,
+		RecordedAt: p.RecordedAt,
+		ReceivedAt: p.ReceivedAt,
+	}
+}
+
+func writePositionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrTruckNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
+
+// =================================================================
+func (h *PositionHandler) Record(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	truckID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidTruckId})
+		return
+	}
+
+	var req PositionRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	position, err := positionRequestToDomain(req)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	recorded, err := h.positionService.Record(r.Context(), truckID, membership.OrganizationID, []domain.Position{*position})
+	if err != nil {
+		writePositionError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, Response{Data: truckPositionDomainToResponse(recorded[0])})
+}
+
+func (h *PositionHandler) RecordBulk(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	truckID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidTruckId})
+		return
+	}
+
+	var req BulkPositionRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	if len(req.Positions) == 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "at least one position is required"})
+		return
+	}
+	if len(req.Positions) > maxBulkPositions {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: fmt.Sprintf("at most %d positions can be sent at once", maxBulkPositions)})
+		return
+	}
+
+	// the batch is all or nothing, so a device can safely resend it after fixing a bad ping
+	positions := make([]domain.Position, 0, len(req.Positions))
+	for i, positionReq := range req.Positions {
+		position, err := positionRequestToDomain(positionReq)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: fmt.Sprintf("position %d: %v", i, err)})
+			return
+		}
+		positions = append(positions, *position)
+	}
+
+	recorded, err := h.positionService.Record(r.Context(), truckID, membership.OrganizationID, positions)
+	if err != nil {
+		writePositionError(w, err)
+		return
+	}
+
+	response := make([]TruckPositionResponse, len(recorded))
+	for i, position := range recorded {
+		response[i] = truckPositionDomainToResponse(position)
+	}
+
+	WriteJSON(w, http.StatusCreated, Response{Data: response})
+}
+
+func (h *PositionHandler) RecordLocationEvent(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	truckID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: invalidTruckId})
+		return
+	}
+
+	var req LocationEventRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	event, err := domain.NewLocationEvent(req.FacilityID, req.Type, req.OccurredAt)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	automations, err := h.geofenceService.ProcessEvent(r.Context(), truckID, membership.OrganizationID, *event)
+	if err != nil {
+		if errors.Is(err, domain.ErrFacilityNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "facility not found"})
+			return
+		}
+		writePositionError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: automations})
+}
+
+func (h *PositionHandler) TripTrack(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	tripID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	track, err := h.positionService.TripTrack(r.Context(), tripID, membership.OrganizationID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTripNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "trip not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: track})
+}
@@ -1,14 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/domain"
-	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -29,6 +28,7 @@ type TripCreateRequest struct {
 	TruckID         *primitive.ObjectID `json:"truck_id"`
 	StartFacilityID *primitive.ObjectID `json:"start_facility_id"`
 	EndFacilityID   *primitive.ObjectID `json:"end_facility_id"`
+	Stops           []TripStopRequest   `json:"stops"`
 	DepartureTime   domain.TimeWindow   `json:"departure_time"`
 	ArrivalTime     domain.TimeWindow   `json:"arrival_time"`
 	Cargo           domain.Cargo        `json:"cargo"`
@@ -42,6 +42,7 @@ type TripUpdateRequest struct {
 	TruckID         *primitive.ObjectID `json:"truck_id"`
 	StartFacilityID *primitive.ObjectID `json:"start_facility_id"`
 	EndFacilityID   *primitive.ObjectID `json:"end_facility_id"`
+	Stops           []TripStopRequest   `json:"stops"`
 	DepartureTime   domain.TimeWindow   `json:"departure_time"`
 	ArrivalTime     domain.TimeWindow   `json:"arrival_time"`
 	Cargo           domain.Cargo        `json:"cargo"`
@@ -49,6 +50,15 @@ type TripUpdateRequest struct {
 	DistanceMiles   int                 `json:"distance_miles"`
 }
 
+// only the scheduled times of a stop are taken from the request; the actual times are recorded as it's worked
+type TripStopRequest struct {
+	FacilityID    primitive.ObjectID  `json:"facility_id"`
+	Type          domain.TripStopType `json:"type"`
+	ArrivalTime   domain.TimeWindow   `json:"arrival_time"`
+	DepartureTime domain.TimeWindow   `json:"departure_time"`
+	Cargo         domain.Cargo        `json:"cargo"`
+}
+
 type AddNoteRequest struct {
 	Content string `json:"content"`
 }
@@ -59,40 +69,79 @@ type BeginTripRequest struct {
 
 type FinishTripSuccessfullyRequest struct {
 	ArrivalTime time.Time `json:"arrival_time"`
+	EndOdometer *int      `json:"end_odometer,omitempty"`
 }
 
 type FinishTripUnsuccessfullyRequest struct {
 	ArrivalTime time.Time `json:"arrival_time"`
+	EndOdometer *int      `json:"end_odometer,omitempty"`
+}
+
+type ArriveAtStopRequest struct {
+	ArrivalTime time.Time `json:"arrival_time"`
+}
+
+type DepartStopRequest struct {
+	DepartureTime time.Time `json:"departure_time"`
 }
 
 type TripResponse struct {
-	ID              primitive.ObjectID  `json:"id,omitempty"`
-	TripNumber      string              `json:"trip_number"`
-	DriverID        *primitive.ObjectID `json:"driver_id,omitempty"`
-	Driver          *domain.Driver      `json:"driver,omitempty"`
-	TruckID         *primitive.ObjectID `json:"truck_id,omitempty"`
-	Truck           *domain.Truck       `json:"truck,omitempty"`
-	StartFacilityID *primitive.ObjectID `json:"start_facility_id,omitempty"`
-	StartFacility   *domain.Facility    `json:"start_facility,omitempty"`
-	EndFacilityID   *primitive.ObjectID `json:"end_facility_id,omitempty"`
-	EndFacility     *domain.Facility    `json:"end_facility,omitempty"`
-	DepartureTime   domain.TimeWindow   `json:"departure_time"`
-	ArrivalTime     domain.TimeWindow   `json:"arrival_time"`
-	Status          domain.TripStatus   `json:"status"`
-	Cargo           domain.Cargo        `json:"cargo"`
-	FuelUsage       float64             `json:"fuel_usage_gallons"`
-	DistanceMiles   int                 `json:"distance_miles"`
-	Notes           []domain.TripNote   `json:"notes"`
-	CreatedAt       primitive.DateTime  `json:"created_at"`
-	UpdatedAt       primitive.DateTime  `json:"updated_at"`
+	ID                primitive.ObjectID    `json:"id,omitempty"`
+	TripNumber        string                `json:"trip_number"`
+	DriverID          *primitive.ObjectID   `json:"driver_id,omitempty"`
+	Driver            *domain.Driver        `json:"driver,omitempty"`
+	TruckID           *primitive.ObjectID   `json:"truck_id,omitempty"`
+	Truck             *domain.Truck         `json:"truck,omitempty"`
+	StartFacilityID   *primitive.ObjectID   `json:"start_facility_id,omitempty"`
+	StartFacility     *domain.Facility      `json:"start_facility,omitempty"`
+	EndFacilityID     *primitive.ObjectID   `json:"end_facility_id,omitempty"`
+	EndFacility       *domain.Facility      `json:"end_facility,omitempty"`
+	Stops             []domain.TripStop     `json:"stops"`
+	DepartureTime     domain.TimeWindow     `json:"departure_time"`
+	ArrivalTime       domain.TimeWindow     `json:"arrival_time"`
+	Status            domain.TripStatus     `json:"status"`
+	Cargo             domain.Cargo          `json:"cargo"`
+	FuelUsage         float64               `json:"fuel_usage_gallons"`
+	DistanceMiles     int                   `json:"distance_miles"`
+	StartOdometer     *int                  `json:"start_odometer,omitempty"`
+	EndOdometer       *int                  `json:"end_odometer,omitempty"`
+	Estimate          *domain.TripEstimate  `json:"estimate,omitempty"`
+	Notes             []domain.TripNote     `json:"notes"`
+	CreatedAt         primitive.DateTime    `json:"created_at"`
+	UpdatedAt         primitive.DateTime    `json:"updated_at"`
+	ScheduleConflicts []domain.TripConflict `json:"schedule_conflicts,omitempty"`
 }
 
 type ListTripsResponse struct {
 	Trips []TripResponse `json:"trips"`
 }
 
-func tripRequestToDomainCreate(userID primitive.ObjectID, req TripCreateRequest) (*domain.Trip, error) {
+// a nil list is kept as nil so that an update which leaves the stops off doesn't clear them
+func tripStopRequestsToDomain(reqs []TripStopRequest) ([]domain.TripStop, error) {
+	if reqs == nil {
+		return nil, nil
+	}
+
+	stops := make([]domain.TripStop, len(reqs))
+	for i, req := range reqs {
+		stop, err := domain.NewTripStop(req.FacilityID, req.Type, req.ArrivalTime.Scheduled, req.DepartureTime.Scheduled, req.Cargo)
+		if err != nil {
+			return nil, err
+		}
+		stops[i] = stop
+	}
+
+	return stops, nil
+}
+
+func tripRequestToDomainCreate(orgID, userID primitive.ObjectID, req TripCreateRequest) (*domain.Trip, error) {
+	stops, err := tripStopRequestsToDomain(req.Stops)
+	if err != nil {
+		return nil, err
+	}
+
 	return domain.NewTrip(
+		orgID,
 		userID,
 		req.TripNumber,
 		req.DriverID,
@@ -101,6 +150,7 @@ func tripRequestToDomainCreate(userID primitive.ObjectID, req TripCreateRequest)
 		req.EndFacilityID,
 		req.DepartureTime,
 		req.ArrivalTime,
+		stops,
 		req.Cargo,
 		req.FuelUsage,
 		req.DistanceMiles,
@@ -108,12 +158,22 @@ func tripRequestToDomainCreate(userID primitive.ObjectID, req TripCreateRequest)
 }
 
 func tripRequestToDomainUpdate(req TripUpdateRequest) (*domain.Trip, error) {
+	stops, err := tripStopRequestsToDomain(req.Stops)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Cargo.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &domain.Trip{
 		TripNumber:      req.TripNumber,
 		DriverID:        req.DriverID,
 		TruckID:         req.TruckID,
 		StartFacilityID: req.StartFacilityID,
 		EndFacilityID:   req.EndFacilityID,
+		Stops:           stops,
 		DepartureTime:   req.DepartureTime,
 		ArrivalTime:     req.ArrivalTime,
 		Cargo:           req.Cargo,
@@ -135,76 +195,133 @@ func tripDomainToResponse(t *domain.Trip) TripResponse {
 		StartFacility:   t.StartFacility,
 		EndFacilityID:   t.EndFacilityID,
 		EndFacility:     t.EndFacility,
+		Stops:           t.Stops,
 		DepartureTime:   t.DepartureTime,
 		ArrivalTime:     t.ArrivalTime,
 		Status:          t.Status,
 		Cargo:           t.Cargo,
 		FuelUsage:       t.FuelUsage,
 		DistanceMiles:   t.DistanceMiles,
+		StartOdometer:   t.StartOdometer,
+		EndOdometer:     t.EndOdometer,
+		Estimate:        t.Estimate,
 		Notes:           t.Notes,
 		CreatedAt:       t.CreatedAt,
 		UpdatedAt:       t.UpdatedAt,
 	}
 }
 
-// =================================================================
+// attempt to unwrap the error into one of the state error types. If one matches, the trip, one of its stops, its
+// truck or its driver isn't in a state that allows the transition, which is a conflict with the current state of
+// the resource rather than a server failure
+func writeTripTransitionError(w http.ResponseWriter, err error) {
+	var tripStateErr *domain.TripStateError
+	var tripStopErr *domain.TripStopError
+	var truckStateErr *domain.TruckStateError
+	var driverStateErr *domain.DriverStateError
+	var hosLimitErr *domain.HOSLimitError
+	var credentialErr *domain.CredentialExpiredError
+	var hazmatErr *domain.HazmatComplianceError
+	var maintenanceErr *domain.MaintenanceOverdueError
+	var rollbackErr *domain.OdometerRollbackError
+	var implausibleErr *domain.OdometerImplausibleError
 
-func (h *TripHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+	if errors.Is(err, domain.ErrTripStopNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "trip stop not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrFacilityNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "facility not found"})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
+	if errors.As(err, &hazmatErr) {
+		WriteJSON(w, http.StatusUnprocessableEntity, Response{Error: err.Error(), Data: hazmatErr.Violations})
 		return
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+	if errors.As(err, &tripStateErr) || errors.As(err, &tripStopErr) || errors.As(err, &truckStateErr) || errors.As(err, &driverStateErr) || errors.As(err, &hosLimitErr) || errors.As(err, &credentialErr) || errors.As(err, &maintenanceErr) || errors.As(err, &rollbackErr) || errors.As(err, &implausibleErr) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
 		return
 	}
 
-	var req TripCreateRequest
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
 
-	if err := ReadJSON(r, &req); err != nil {
-		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+// a schedule conflict is reported with the offending assignments so the client can decide whether to retry with force=true
+func writeTripScheduleError(w http.ResponseWriter, err error) {
+	var conflictErr *domain.ScheduleConflictError
+	if errors.As(err, &conflictErr) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error(), Data: conflictErr.Conflicts})
 		return
 	}
 
-	trip, err := tripRequestToDomainCreate(userID, req)
-	if err != nil {
-		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+	var credentialErr *domain.CredentialExpiredError
+	if errors.As(err, &credentialErr) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
 		return
 	}
 
-	if err := h.tripService.Create(r.Context(), trip); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	var hazmatErr *domain.HazmatComplianceError
+	if errors.As(err, &hazmatErr) {
+		WriteJSON(w, http.StatusUnprocessableEntity, Response{Error: err.Error(), Data: hazmatErr.Violations})
 		return
 	}
 
-	WriteJSON(w, http.StatusCreated, tripDomainToResponse(trip))
+	if errors.Is(err, domain.ErrDriverNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "driver not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrTruckNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrFacilityNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "facility not found"})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 }
 
-func (h *TripHandler) GetById(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+// =================================================================
+
+func (h *TripHandler) Create(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
+	var req TripCreateRequest
+
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
 		return
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	trip, err := tripRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	conflicts, err := h.tripService.Create(r.Context(), trip, getQueryBoolParam(r, "force", false))
+	if err != nil {
+		writeTripScheduleError(w, err)
+		return
+	}
+
+	response := tripDomainToResponse(trip)
+	response.ScheduleConflicts = conflicts
+
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *TripHandler) GetById(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -215,7 +332,7 @@ func (h *TripHandler) GetById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	trip, err := h.tripService.GetById(r.Context(), objectID, userID)
+	trip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "trip not found"})
 		return
@@ -225,6 +342,12 @@ func (h *TripHandler) GetById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TripHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -245,31 +368,36 @@ func (h *TripHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	trip.ID = objectID
+	trip.OrganizationID = membership.OrganizationID
 
-	if err := h.tripService.Update(r.Context(), trip); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update user"})
+	conflicts, err := h.tripService.Update(r.Context(), trip, getQueryBoolParam(r, "force", false))
+	if err != nil {
+		var conflictErr *domain.ScheduleConflictError
+		var credentialErr *domain.CredentialExpiredError
+		var hazmatErr *domain.HazmatComplianceError
+		if errors.As(err, &conflictErr) || errors.As(err, &credentialErr) || errors.As(err, &hazmatErr) || errors.Is(err, domain.ErrDriverNotFound) || errors.Is(err, domain.ErrTruckNotFound) || errors.Is(err, domain.ErrFacilityNotFound) {
+			writeTripScheduleError(w, err)
+			return
+		}
+		var stateErr *domain.TripStateError
+		if errors.As(err, &stateErr) {
+			WriteJSON(w, http.StatusConflict, Response{Error: "stops, driver and truck can only be changed before the trip begins"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update trip"})
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, Response{Data: tripDomainToResponse(trip)})
+	response := tripDomainToResponse(trip)
+	response.ScheduleConflicts = conflicts
+
+	WriteJSON(w, http.StatusOK, Response{Data: response})
 }
 
 func (h *TripHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -280,12 +408,16 @@ func (h *TripHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tripService.Delete(r.Context(), objectID, userID)
+	err = h.tripService.Delete(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		if err == domain.ErrTripNotFound {
 			WriteJSON(w, http.StatusNotFound, Response{Error: "trip not found"})
 			return
 		}
+		if err == domain.ErrTripInTransit {
+			WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to delete trip"})
 		return
 	}
@@ -294,26 +426,14 @@ func (h *TripHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TripHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
 	filter := domain.NewTripFilter()
-	filter.UserID = userID
+	filter.OrganizationID = membership.OrganizationID
 
 	if driverId := r.URL.Query().Get("driverID"); driverId != "" {
 		if id, err := primitive.ObjectIDFromHex(driverId); err == nil {
@@ -339,6 +459,9 @@ func (h *TripHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// trips whose entered distance or arrival is well off the estimate
+	filter.Deviating = getQueryBoolParam(r, "deviating", false)
+
 	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
 	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
 
@@ -370,22 +493,26 @@ func (h *TripHandler) List(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, response)
 }
 
-func (h *TripHandler) AddNote(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+func (h *TripHandler) ListConflicts(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
+	conflicts, err := h.tripService.ListConflicts(r.Context(), membership.OrganizationID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch trip conflicts"})
 		return
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+	WriteJSON(w, http.StatusOK, Response{Data: conflicts})
+}
+
+func (h *TripHandler) AddNote(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -407,13 +534,13 @@ func (h *TripHandler) AddNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.tripService.AddNote(r.Context(), objectID, userID, req.Content); err != nil {
+	if err := h.tripService.AddNote(r.Context(), objectID, membership.OrganizationID, req.Content); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
 	// Get the updated trip to return in the response
-	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, userID)
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "note added but failed to fetch updated trip"})
 		return
@@ -423,21 +550,9 @@ func (h *TripHandler) AddNote(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TripHandler) BeginTrip(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -459,18 +574,12 @@ func (h *TripHandler) BeginTrip(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.tripService.BeginTrip(r.Context(), objectID, userID, req.DepartureTime); err != nil {
-		// attempt to parse the error into the type "TripStateError". If it parses correctly,
-		// that means it is actually a trip state error and it needs to be handled as such
-		if _, ok := err.(*domain.TripStateError); ok {
-			WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
-			return
-		}
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.tripService.BeginTrip(r.Context(), objectID, membership.OrganizationID, req.DepartureTime); err != nil {
+		writeTripTransitionError(w, err)
 		return
 	}
 
-	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, userID)
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "departure time set but failed to fetch updated trip"})
 		return
@@ -480,21 +589,9 @@ func (h *TripHandler) BeginTrip(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TripHandler) CancelTrip(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -505,12 +602,12 @@ func (h *TripHandler) CancelTrip(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.tripService.CancelTrip(r.Context(), objectID, userID); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.tripService.CancelTrip(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeTripTransitionError(w, err)
 		return
 	}
 
-	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, userID)
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "trip cancelled but failed to fetch updated trip"})
 		return
@@ -520,21 +617,53 @@ func (h *TripHandler) CancelTrip(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TripHandler) FinishTripSuccessfully(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
+	idStr := mux.Vars(r)["id"]
+	objectID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var req FinishTripSuccessfullyRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	if req.ArrivalTime.IsZero() {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "arrival time is required"})
+		return
+	}
+
+	if req.EndOdometer != nil && *req.EndOdometer < 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "end odometer can't be negative"})
+		return
+	}
+
+	if err := h.tripService.FinishTripSuccessfully(r.Context(), objectID, membership.OrganizationID, req.ArrivalTime, req.EndOdometer); err != nil {
+		writeTripTransitionError(w, err)
 		return
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "arrival time set but failed to fetch updated trip"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: tripDomainToResponse(updatedTrip)})
+}
+
+func (h *TripHandler) FinishTripUnsuccessfully(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -545,7 +674,7 @@ func (h *TripHandler) FinishTripSuccessfully(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	var req FinishTripSuccessfullyRequest
+	var req FinishTripUnsuccessfullyRequest
 	if err := ReadJSON(r, &req); err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
 		return
@@ -556,12 +685,17 @@ func (h *TripHandler) FinishTripSuccessfully(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.tripService.FinishTripSuccessfully(r.Context(), objectID, userID, req.ArrivalTime); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if req.EndOdometer != nil && *req.EndOdometer < 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "end odometer can't be negative"})
+		return
+	}
+
+	if err := h.tripService.FinishTripUnsuccessfully(r.Context(), objectID, membership.OrganizationID, req.ArrivalTime, req.EndOdometer); err != nil {
+		writeTripTransitionError(w, err)
 		return
 	}
 
-	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, userID)
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "arrival time set but failed to fetch updated trip"})
 		return
@@ -570,51 +704,121 @@ func (h *TripHandler) FinishTripSuccessfully(w http.ResponseWriter, r *http.Requ
 	WriteJSON(w, http.StatusOK, Response{Data: tripDomainToResponse(updatedTrip)})
 }
 
-func (h *TripHandler) FinishTripUnsuccessfully(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+func (h *TripHandler) ArriveAtStop(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	stopID, err := primitive.ObjectIDFromHex(mux.Vars(r)["stopId"])
 	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid trip stop id"})
 		return
 	}
 
-	idStr := mux.Vars(r)["id"]
-	objectID, err := primitive.ObjectIDFromHex(idStr)
+	var req ArriveAtStopRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	if req.ArrivalTime.IsZero() {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "arrival time is required"})
+		return
+	}
+
+	if err := h.tripService.ArriveAtStop(r.Context(), objectID, membership.OrganizationID, stopID, req.ArrivalTime); err != nil {
+		writeTripTransitionError(w, err)
+		return
+	}
+
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "stop arrival recorded but failed to fetch updated trip"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: tripDomainToResponse(updatedTrip)})
+}
+
+func (h *TripHandler) DepartStop(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
 
-	var req FinishTripUnsuccessfullyRequest
+	stopID, err := primitive.ObjectIDFromHex(mux.Vars(r)["stopId"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid trip stop id"})
+		return
+	}
+
+	var req DepartStopRequest
 	if err := ReadJSON(r, &req); err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
 		return
 	}
 
-	if req.ArrivalTime.IsZero() {
-		WriteJSON(w, http.StatusBadRequest, Response{Error: "arrival time is required"})
+	if req.DepartureTime.IsZero() {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "departure time is required"})
 		return
 	}
 
-	if err := h.tripService.FinishTripUnsuccessfully(r.Context(), objectID, userID, req.ArrivalTime); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.tripService.DepartStop(r.Context(), objectID, membership.OrganizationID, stopID, req.DepartureTime); err != nil {
+		writeTripTransitionError(w, err)
 		return
 	}
 
-	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, userID)
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: "arrival time set but failed to fetch updated trip"})
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "stop departure recorded but failed to fetch updated trip"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: tripDomainToResponse(updatedTrip)})
+}
+
+func (h *TripHandler) SkipStop(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	stopID, err := primitive.ObjectIDFromHex(mux.Vars(r)["stopId"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid trip stop id"})
+		return
+	}
+
+	if err := h.tripService.SkipStop(r.Context(), objectID, membership.OrganizationID, stopID); err != nil {
+		writeTripTransitionError(w, err)
+		return
+	}
+
+	updatedTrip, err := h.tripService.GetById(r.Context(), objectID, membership.OrganizationID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "stop skipped but failed to fetch updated trip"})
 		return
 	}
 
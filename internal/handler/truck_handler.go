@@ -1,13 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/jwald3/waybill/internal/domain"
-	"github.com/jwald3/waybill/internal/middleware"
 	"github.com/jwald3/waybill/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -50,7 +49,6 @@ type TruckUpdateRequest struct {
 	Year             int                 `json:"year"`
 	LicensePlate     domain.LicensePlate `json:"license_plate"`
 	Mileage          int                 `json:"mileage"`
-	Status           domain.TruckStatus  `json:"status"`
 	AssignedDriverID *primitive.ObjectID `json:"assigned_driver_id,omitempty"`
 	TrailerType      domain.TrailerType  `json:"trailer_type"`
 	CapacityTons     float64             `json:"capacity_tons"`
@@ -71,31 +69,34 @@ type TruckUpdateLastMaintenanceRequest struct {
 }
 
 type TruckResponse struct {
-	ID               primitive.ObjectID  `json:"id,omitempty"`
-	TruckNumber      string              `json:"truck_number"`
-	VIN              string              `json:"vin"`
-	Make             string              `json:"make"`
-	Model            string              `json:"model"`
-	Year             int                 `json:"year"`
-	LicensePlate     domain.LicensePlate `json:"license_plate"`
-	Mileage          int                 `json:"mileage"`
-	Status           domain.TruckStatus  `json:"status"`
-	AssignedDriverID *primitive.ObjectID `json:"assigned_driver_id,omitempty"`
-	AssignedDriver   *domain.Driver      `json:"assigned_driver,omitempty"`
-	TrailerType      domain.TrailerType  `json:"trailer_type"`
-	CapacityTons     float64             `json:"capacity_tons"`
-	FuelType         domain.FuelType     `json:"fuel_type"`
-	LastMaintenance  string              `json:"last_maintenance"`
-	CreatedAt        primitive.DateTime  `json:"created_at"`
-	UpdatedAt        primitive.DateTime  `json:"updated_at"`
+	ID                primitive.ObjectID  `json:"id,omitempty"`
+	TruckNumber       string              `json:"truck_number"`
+	VIN               string              `json:"vin"`
+	Make              string              `json:"make"`
+	Model             string              `json:"model"`
+	Year              int                 `json:"year"`
+	LicensePlate      domain.LicensePlate `json:"license_plate"`
+	Mileage           int                 `json:"mileage"`
+	MileageRecordedAt primitive.DateTime  `json:"mileage_recorded_at,omitempty"`
+	Status            domain.TruckStatus  `json:"status"`
+	AssignedDriverID  *primitive.ObjectID `json:"assigned_driver_id,omitempty"`
+	AssignedDriver    *domain.Driver      `json:"assigned_driver,omitempty"`
+	TrailerType       domain.TrailerType  `json:"trailer_type"`
+	CapacityTons      float64             `json:"capacity_tons"`
+	FuelType          domain.FuelType     `json:"fuel_type"`
+	LastMaintenance   string              `json:"last_maintenance"`
+	LastPosition      *domain.Position    `json:"last_position,omitempty"`
+	CreatedAt         primitive.DateTime  `json:"created_at"`
+	UpdatedAt         primitive.DateTime  `json:"updated_at"`
 }
 
 type ListTrucksResponse struct {
 	Trucks []TruckResponse `json:"trucks"`
 }
 
-func truckRequestToDomainCreate(userID primitive.ObjectID, req TruckCreateRequest) (*domain.Truck, error) {
+func truckRequestToDomainCreate(orgID, userID primitive.ObjectID, req TruckCreateRequest) (*domain.Truck, error) {
 	return domain.NewTruck(
+		orgID,
 		userID,
 		req.TruckNumber,
 		req.VIN,
@@ -128,7 +129,6 @@ func truckRequestToDomainUpdate(req TruckUpdateRequest) (*domain.Truck, error) {
 		Year:             req.Year,
 		LicensePlate:     req.LicensePlate,
 		Mileage:          req.Mileage,
-		Status:           req.Status,
 		AssignedDriverID: req.AssignedDriverID,
 		TrailerType:      req.TrailerType,
 		CapacityTons:     req.CapacityTons,
@@ -139,43 +139,44 @@ func truckRequestToDomainUpdate(req TruckUpdateRequest) (*domain.Truck, error) {
 
 func truckDomainToResponse(t *domain.Truck) TruckResponse {
 	return TruckResponse{
-		ID:               t.ID,
-		TruckNumber:      t.TruckNumber,
-		VIN:              t.VIN,
-		Make:             t.Make,
-		Model:            t.Model,
-		Year:             t.Year,
-		LicensePlate:     t.LicensePlate,
-		Mileage:          t.Mileage,
-		Status:           t.Status,
-		AssignedDriverID: t.AssignedDriverID,
-		AssignedDriver:   t.AssignedDriver,
-		TrailerType:      t.TrailerType,
-		CapacityTons:     t.CapacityTons,
-		FuelType:         t.FuelType,
-		LastMaintenance:  t.LastMaintenance,
-		CreatedAt:        t.CreatedAt,
-		UpdatedAt:        t.UpdatedAt,
+		ID:                t.ID,
+		TruckNumber:       t.TruckNumber,
+		VIN:               t.VIN,
+		Make:              t.Make,
+		Model:             t.Model,
+		Year:              t.Year,
+		LicensePlate:      t.LicensePlate,
+		Mileage:           t.Mileage,
+		MileageRecordedAt: t.MileageRecordedAt,
+		Status:            t.Status,
+		AssignedDriverID:  t.AssignedDriverID,
+		AssignedDriver:    t.AssignedDriver,
+		TrailerType:       t.TrailerType,
+		CapacityTons:      t.CapacityTons,
+		FuelType:          t.FuelType,
+		LastMaintenance:   t.LastMaintenance,
+		LastPosition:      t.LastPosition,
+		CreatedAt:         t.CreatedAt,
+		UpdatedAt:         t.UpdatedAt,
 	}
 }
 
-// =================================================================
-func (h *TruckHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+func writeTruckTransitionError(w http.ResponseWriter, err error) {
+	var truckStateErr *domain.TruckStateError
+	var onTripErr *domain.TruckOnTripError
+	if errors.As(err, &truckStateErr) || errors.As(err, &onTripErr) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+// =================================================================
+func (h *TruckHandler) Create(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -186,7 +187,7 @@ func (h *TruckHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	truck, err := truckRequestToDomainCreate(userID, req)
+	truck, err := truckRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
@@ -201,21 +202,9 @@ func (h *TruckHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TruckHandler) GetById(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -226,7 +215,7 @@ func (h *TruckHandler) GetById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	truck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	truck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
 		return
@@ -236,6 +225,12 @@ func (h *TruckHandler) GetById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TruckHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -256,8 +251,20 @@ func (h *TruckHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	truck.ID = objectID
+	truck.OrganizationID = membership.OrganizationID
 
 	if err := h.truckService.Update(r.Context(), truck); err != nil {
+		var credentialErr *domain.CredentialExpiredError
+		var rollbackErr *domain.OdometerRollbackError
+		var implausibleErr *domain.OdometerImplausibleError
+		if errors.As(err, &credentialErr) || errors.As(err, &rollbackErr) || errors.As(err, &implausibleErr) {
+			WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrDriverNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "driver not found"})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to update truck"})
 		return
 	}
@@ -266,21 +273,9 @@ func (h *TruckHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TruckHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -291,7 +286,7 @@ func (h *TruckHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.truckService.Delete(r.Context(), objectID, userID)
+	err = h.truckService.Delete(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		if err == domain.ErrTruckNotFound {
 			WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
@@ -306,26 +301,14 @@ func (h *TruckHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TruckHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
 	filter := domain.NewTruckFilter()
-	filter.UserID = userID
+	filter.OrganizationID = membership.OrganizationID
 
 	if vin := r.URL.Query().Get("vin"); vin != "" {
 		filter.VIN = vin
@@ -382,21 +365,9 @@ func (h *TruckHandler) List(w http.ResponseWriter, r *http.Request) {
 
 // atomic methods
 func (h *TruckHandler) MakeTruckAvailable(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -407,12 +378,12 @@ func (h *TruckHandler) MakeTruckAvailable(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.truckService.MakeTruckAvailable(r.Context(), objectID, userID); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.truckService.MakeTruckAvailable(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeTruckTransitionError(w, err)
 		return
 	}
 
-	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated truck"})
 		return
@@ -422,21 +393,9 @@ func (h *TruckHandler) MakeTruckAvailable(w http.ResponseWriter, r *http.Request
 }
 
 func (h *TruckHandler) RetireTruck(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -447,12 +406,12 @@ func (h *TruckHandler) RetireTruck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.truckService.RetireTruck(r.Context(), objectID, userID); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.truckService.RetireTruck(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeTruckTransitionError(w, err)
 		return
 	}
 
-	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated truck"})
 		return
@@ -462,21 +421,9 @@ func (h *TruckHandler) RetireTruck(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TruckHandler) SetTruckInTransit(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -487,12 +434,12 @@ func (h *TruckHandler) SetTruckInTransit(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.truckService.SetTruckInTransit(r.Context(), objectID, userID); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.truckService.SetTruckInTransit(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeTruckTransitionError(w, err)
 		return
 	}
 
-	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated truck"})
 		return
@@ -502,21 +449,9 @@ func (h *TruckHandler) SetTruckInTransit(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *TruckHandler) SetTruckInMaintenance(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -527,12 +462,12 @@ func (h *TruckHandler) SetTruckInMaintenance(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.truckService.SetTruckInMaintenance(r.Context(), objectID, userID); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if err := h.truckService.SetTruckInMaintenance(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeTruckTransitionError(w, err)
 		return
 	}
 
-	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "status updated but failed to fetch updated truck"})
 		return
@@ -542,21 +477,9 @@ func (h *TruckHandler) SetTruckInMaintenance(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *TruckHandler) UpdateTruckMileage(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
-		return
-	}
-
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -573,12 +496,22 @@ func (h *TruckHandler) UpdateTruckMileage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.truckService.UpdateTruckMileage(r.Context(), objectID, userID, req.Mileage); err != nil {
+	if err := h.truckService.UpdateTruckMileage(r.Context(), objectID, membership.OrganizationID, req.Mileage); err != nil {
+		var rollbackErr *domain.OdometerRollbackError
+		var implausibleErr *domain.OdometerImplausibleError
+		if errors.As(err, &rollbackErr) || errors.As(err, &implausibleErr) {
+			WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrTruckNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
-	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "mileage updated but failed to fetch updated truck"})
 		return
@@ -587,22 +520,55 @@ func (h *TruckHandler) UpdateTruckMileage(w http.ResponseWriter, r *http.Request
 	WriteJSON(w, http.StatusOK, Response{Data: truckDomainToResponse(updatedTruck)})
 }
 
-func (h *TruckHandler) UpdateTruckLastMaintenance(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(jwt.MapClaims)
+// CorrectTruckMileage overrides the truck's odometer for when a bad reading has pushed it too far or the odometer was
+// replaced
+func (h *TruckHandler) CorrectTruckMileage(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
 	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "unauthorized"})
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
-	userIDStr, ok := claims["user_id"].(string)
-	if !ok {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id in token"})
+	idStr := mux.Vars(r)["id"]
+	objectID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var req TruckUpdateMileageRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	if req.Mileage < 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "mileage can't be negative"})
+		return
+	}
+
+	if err := h.truckService.CorrectTruckMileage(r.Context(), objectID, membership.OrganizationID, req.Mileage); err != nil {
+		if errors.Is(err, domain.ErrTruckNotFound) {
+			WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, Response{Error: "invalid user id format"})
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "mileage corrected but failed to fetch updated truck"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: truckDomainToResponse(updatedTruck)})
+}
+
+func (h *TruckHandler) UpdateTruckLastMaintenance(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
 		return
 	}
 
@@ -619,12 +585,12 @@ func (h *TruckHandler) UpdateTruckLastMaintenance(w http.ResponseWriter, r *http
 		return
 	}
 
-	if err := h.truckService.UpdateTruckMaintenance(r.Context(), objectID, userID, req.LastMaintenance); err != nil {
+	if err := h.truckService.UpdateTruckMaintenance(r.Context(), objectID, membership.OrganizationID, req.LastMaintenance); err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
 		return
 	}
 
-	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, userID)
+	updatedTruck, err := h.truckService.GetById(r.Context(), objectID, membership.OrganizationID)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, Response{Error: "maintenance updated but failed to fetch updated truck"})
 		return
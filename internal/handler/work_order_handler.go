@@ -0,0 +1,510 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkOrderHandler struct {
+	workOrderService service.WorkOrderService
+}
+
+func NewWorkOrderHandler(workOrderService service.WorkOrderService) *WorkOrderHandler {
+	return &WorkOrderHandler{workOrderService: workOrderService}
+}
+
+// DTOS =======================================================
+
+type WorkOrderLineRequest struct {
+	Type        domain.WorkOrderLineType `json:"type"`
+	Description string                   `json:"description"`
+	PartNumber  string                   `json:"part_number"`
+	Quantity    float64                  `json:"quantity"`
+	UnitCost    float64                  `json:"unit_cost"`
+}
+
+type WorkOrderCreateRequest struct {
+	TruckID     primitive.ObjectID            `json:"truck_id"`
+	ScheduleID  *primitive.ObjectID           `json:"schedule_id"`
+	Title       string                        `json:"title"`
+	Description string                        `json:"description"`
+	ServiceType domain.MaintenanceServiceType `json:"service_type"`
+	Mechanic    string                        `json:"mechanic"`
+	Location    string                        `json:"location"`
+	Lines       []WorkOrderLineRequest        `json:"lines"`
+}
+
+type WorkOrderUpdateRequest struct {
+	ScheduleID  *primitive.ObjectID           `json:"schedule_id"`
+	Title       string                        `json:"title"`
+	Description string                        `json:"description"`
+	ServiceType domain.MaintenanceServiceType `json:"service_type"`
+	Mechanic    string                        `json:"mechanic"`
+	Location    string                        `json:"location"`
+	Lines       []WorkOrderLineRequest        `json:"lines"`
+}
+
+type StartWorkOrderRequest struct {
+	StartedAt time.Time `json:"started_at"`
+}
+
+type CompleteWorkOrderRequest struct {
+	CompletedAt time.Time `json:"completed_at"`
+	Mileage     int       `json:"mileage"`
+}
+
+type WorkOrderLineResponse struct {
+	Type        domain.WorkOrderLineType `json:"type"`
+	Description string                   `json:"description"`
+	PartNumber  string                   `json:"part_number,omitempty"`
+	Quantity    float64                  `json:"quantity"`
+	UnitCost    float64                  `json:"unit_cost"`
+	Total       float64                  `json:"total"`
+}
+
+type WorkOrderResponse struct {
+	ID               primitive.ObjectID            `json:"id,omitempty"`
+	TruckID          primitive.ObjectID            `json:"truck_id"`
+	ScheduleID       *primitive.ObjectID           `json:"schedule_id,omitempty"`
+	Title            string                        `json:"title"`
+	Description      string                        `json:"description,omitempty"`
+	ServiceType      domain.MaintenanceServiceType `json:"service_type"`
+	Mechanic         string                        `json:"mechanic,omitempty"`
+	Location         string                        `json:"location,omitempty"`
+	Status           domain.WorkOrderStatus        `json:"status"`
+	Lines            []WorkOrderLineResponse       `json:"lines"`
+	LaborCost        float64                       `json:"labor_cost"`
+	PartsCost        float64                       `json:"parts_cost"`
+	TotalCost        float64                       `json:"total_cost"`
+	Mileage          int                           `json:"mileage,omitempty"`
+	StartedAt        *primitive.DateTime           `json:"started_at,omitempty"`
+	ClosedAt         *primitive.DateTime           `json:"closed_at,omitempty"`
+	MaintenanceLogID *primitive.ObjectID           `json:"maintenance_log_id,omitempty"`
+	CreatedAt        primitive.DateTime            `json:"created_at"`
+	UpdatedAt        primitive.DateTime            `json:"updated_at"`
+}
+
+func workOrderLinesRequestToDomain(req []WorkOrderLineRequest) ([]domain.WorkOrderLine, error) {
+	lines := make([]domain.WorkOrderLine, len(req))
+	for i, l := range req {
+		line, err := domain.NewWorkOrderLine(l.Type, l.Description, l.PartNumber, l.Quantity, l.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = *line
+	}
+
+	return lines, nil
+}
+
+func workOrderRequestToDomainCreate(orgID, userID primitive.ObjectID, req WorkOrderCreateRequest) (*domain.WorkOrder, error) {
+	lines, err := workOrderLinesRequestToDomain(req.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewWorkOrder(
+		orgID,
+		userID,
+		req.TruckID,
+		req.ScheduleID,
+		req.Title,
+		req.Description,
+		req.ServiceType,
+		req.Mechanic,
+		req.Location,
+		lines,
+	)
+}
+
+// the truck of an existing work order can't be changed, so the update is validated against the one it already has
+func workOrderRequestToDomainUpdate(orgID, userID, truckID primitive.ObjectID, req WorkOrderUpdateRequest) (*domain.WorkOrder, error) {
+	lines, err := workOrderLinesRequestToDomain(req.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewWorkOrder(
+		orgID,
+		userID,
+		truckID,
+		req.ScheduleID,
+		req.Title,
+		req.Description,
+		req.ServiceType,
+		req.Mechanic,
+		req.Location,
+		lines,
+	)
+}
+
+func workOrderDomainToResponse(wo *domain.WorkOrder) WorkOrderResponse {
+	lines := make([]WorkOrderLineResponse, len(wo.Lines))
+	for i, l := range wo.Lines {
+		lines[i] = WorkOrderLineResponse{
+			Type:        l.Type,
+			Description: l.Description,
+			PartNumber:  l.PartNumber,
+			Quantity:    l.Quantity,
+			UnitCost:    l.UnitCost,
+			Total:       l.Total(),
+		}
+	}
+
+	return WorkOrderResponse{
+		ID:               wo.ID,
+		TruckID:          wo.TruckID,
+		ScheduleID:       wo.ScheduleID,
+		Title:            wo.Title,
+		Description:      wo.Description,
+		ServiceType:      wo.ServiceType,
+		Mechanic:         wo.Mechanic,
+		Location:         wo.Location,
+		Status:           wo.Status,
+		Lines:            lines,
+		LaborCost:        wo.LaborCost(),
+		PartsCost:        wo.PartsCost(),
+		TotalCost:        wo.TotalCost(),
+		Mileage:          wo.Mileage,
+		StartedAt:        wo.StartedAt,
+		ClosedAt:         wo.ClosedAt,
+		MaintenanceLogID: wo.MaintenanceLogID,
+		CreatedAt:        wo.CreatedAt,
+		UpdatedAt:        wo.UpdatedAt,
+	}
+}
+
+// a work order or truck that isn't in a state that allows the change, or a mileage that doesn't square with the
+// truck's odometer, is a conflict rather than a server failure
+func writeWorkOrderError(w http.ResponseWriter, err error) {
+	var workOrderStateErr *domain.WorkOrderStateError
+	var truckStateErr *domain.TruckStateError
+	var rollbackErr *domain.OdometerRollbackError
+	var implausibleErr *domain.OdometerImplausibleError
+
+	if errors.Is(err, domain.ErrWorkOrderNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "work order not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrTruckNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "truck not found"})
+		return
+	}
+	if errors.Is(err, domain.ErrMaintenanceScheduleNotFound) {
+		WriteJSON(w, http.StatusNotFound, Response{Error: "maintenance schedule not found"})
+		return
+	}
+
+	if errors.Is(err, domain.ErrWorkOrderCompletedEarly) {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrWorkOrderClosed) || errors.Is(err, domain.ErrWorkOrderActive) || errors.As(err, &workOrderStateErr) || errors.As(err, &truckStateErr) || errors.As(err, &rollbackErr) || errors.As(err, &implausibleErr) {
+		WriteJSON(w, http.StatusConflict, Response{Error: err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, Response{Error: err.Error()})
+}
+
+// =================================================================
+
+func (h *WorkOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	var req WorkOrderCreateRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	workOrder, err := workOrderRequestToDomainCreate(membership.OrganizationID, membership.UserID, req)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.workOrderService.Create(r.Context(), workOrder); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, workOrderDomainToResponse(workOrder))
+}
+
+func (h *WorkOrderHandler) GetById(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	workOrder, err := h.workOrderService.GetById(r.Context(), objectID, membership.OrganizationID)
+	if err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: workOrderDomainToResponse(workOrder)})
+}
+
+func (h *WorkOrderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var req WorkOrderUpdateRequest
+	if err := ReadJSON(r, &req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+		return
+	}
+
+	existing, err := h.workOrderService.GetById(r.Context(), objectID, membership.OrganizationID)
+	if err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	workOrder, err := workOrderRequestToDomainUpdate(membership.OrganizationID, membership.UserID, existing.TruckID, req)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	workOrder.ID = objectID
+
+	if err := h.workOrderService.Update(r.Context(), workOrder); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	h.writeWorkOrder(w, r, objectID, membership.OrganizationID)
+}
+
+func (h *WorkOrderHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.workOrderService.Delete(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *WorkOrderHandler) List(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	filter := domain.NewWorkOrderFilter()
+	filter.OrganizationID = membership.OrganizationID
+
+	if truckId := r.URL.Query().Get("truckID"); truckId != "" {
+		id, err := primitive.ObjectIDFromHex(truckId)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid truck ID format"})
+			return
+		}
+		filter.TruckID = &id
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter.Status = domain.WorkOrderStatus(status)
+		if !filter.Status.IsValid() {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid work order status"})
+			return
+		}
+	}
+
+	filter.Limit = int64(getQueryIntParam(r, "limit", 10))
+	filter.Offset = int64(getQueryIntParam(r, "offset", 0))
+
+	result, err := h.workOrderService.List(r.Context(), filter)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, Response{Error: "failed to fetch work orders"})
+		return
+	}
+
+	responses := make([]WorkOrderResponse, len(result.WorkOrders))
+	for i, wo := range result.WorkOrders {
+		responses[i] = workOrderDomainToResponse(wo)
+	}
+
+	var nextOffset *int64
+	if filter.Offset+filter.Limit < result.Total {
+		next := filter.Offset + filter.Limit
+		nextOffset = &next
+	}
+
+	WriteJSON(w, http.StatusOK, PaginatedResponse{
+		Items:      responses,
+		Total:      result.Total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+		NextOffset: nextOffset,
+	})
+}
+
+// Start begins work on the work order, or resumes it once parts are in. started_at defaults to now.
+func (h *WorkOrderHandler) Start(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var req StartWorkOrderRequest
+	if r.ContentLength != 0 {
+		if err := ReadJSON(r, &req); err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+			return
+		}
+	}
+	if req.StartedAt.IsZero() {
+		req.StartedAt = time.Now()
+	}
+
+	if err := h.workOrderService.Start(r.Context(), objectID, membership.OrganizationID, req.StartedAt); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	h.writeWorkOrder(w, r, objectID, membership.OrganizationID)
+}
+
+func (h *WorkOrderHandler) AwaitParts(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.workOrderService.AwaitParts(r.Context(), objectID, membership.OrganizationID); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	h.writeWorkOrder(w, r, objectID, membership.OrganizationID)
+}
+
+// Complete closes out the work order. completed_at defaults to now and mileage to the truck's current odometer.
+func (h *WorkOrderHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	var req CompleteWorkOrderRequest
+	if r.ContentLength != 0 {
+		if err := ReadJSON(r, &req); err != nil {
+			WriteJSON(w, http.StatusBadRequest, Response{Error: "invalid request payload"})
+			return
+		}
+	}
+	if req.CompletedAt.IsZero() {
+		req.CompletedAt = time.Now()
+	}
+	if req.CompletedAt.After(time.Now()) {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "completed_at can't be in the future"})
+		return
+	}
+	if req.Mileage < 0 {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: "mileage can't be negative"})
+		return
+	}
+
+	if err := h.workOrderService.Complete(r.Context(), objectID, membership.OrganizationID, req.CompletedAt, req.Mileage); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	h.writeWorkOrder(w, r, objectID, membership.OrganizationID)
+}
+
+func (h *WorkOrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	membership, ok := domain.MembershipFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, Response{Error: "no organization membership"})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	if err := h.workOrderService.Cancel(r.Context(), objectID, membership.OrganizationID, time.Now()); err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	h.writeWorkOrder(w, r, objectID, membership.OrganizationID)
+}
+
+// writeWorkOrder responds with the work order as it stands after a change
+func (h *WorkOrderHandler) writeWorkOrder(w http.ResponseWriter, r *http.Request, id, orgID primitive.ObjectID) {
+	workOrder, err := h.workOrderService.GetById(r.Context(), id, orgID)
+	if err != nil {
+		writeWorkOrderError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, Response{Data: workOrderDomainToResponse(workOrder)})
+}
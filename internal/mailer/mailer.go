@@ -0,0 +1,129 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jwald3/waybill/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// a Mailer delivers transactional email such as verification and password reset links
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by MAIL_DRIVER
+func New(cfg *config.Config, log *zap.Logger) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.Mail.From, cfg.Mail.SMTP), nil
+	case DriverFile:
+		return NewFileMailer(cfg.Mail.From, cfg.Mail.Dir)
+	case DriverLog:
+		return NewLogMailer(cfg.Mail.From, log), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
+
+// the messages we send are plain text, so a minimal RFC 5322 rendering is all we need
+func render(from string, msg Message, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// header values come from user input (the recipient's address), so line breaks are refused rather than letting
+// them add headers of their own
+func validate(msg Message) error {
+	for _, value := range []string{msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("mail headers can't contain line breaks")
+		}
+	}
+
+	if msg.To == "" {
+		return fmt.Errorf("mail needs a recipient")
+	}
+
+	return nil
+}
+
+// LogMailer writes messages to the application log instead of sending them, for local development
+type LogMailer struct {
+	from string
+	log  *zap.Logger
+}
+
+func NewLogMailer(from string, log *zap.Logger) *LogMailer {
+	return &LogMailer{from: from, log: log}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	m.log.Info("email not sent, logged instead",
+		zap.String("from", m.from),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+
+	return nil
+}
+
+// FileMailer writes each message to its own .eml file in a directory, where it can be opened in a mail client
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	return &FileMailer{from: from, dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405"), now.UnixNano())
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), render(m.from, msg, now), 0o600); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+
+	return nil
+}
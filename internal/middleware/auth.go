@@ -2,19 +2,58 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/signing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type contextKey string
 
 const UserContextKey contextKey = "user"
 
-func Auth(jwtKey []byte) func(http.Handler) http.Handler {
+// APIKeyHeader carries an api key for integrations that can't go through the login flow
+const APIKeyHeader = "X-API-Key"
+
+// RevocationList reports whether an access token was revoked (e.g. by logging out, or by its user's password
+// changing after it was issued) before it expired
+type RevocationList interface {
+	IsRevoked(ctx context.Context, jti string, userID primitive.ObjectID, issuedAt time.Time) (bool, error)
+}
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plaintext string) (*domain.APIKey, error)
+}
+
+// Auth accepts either an api key or a bearer token. tokens are only accepted when signed by a key in the key
+// set, using the algorithm that key was issued for.
+func Auth(keys *signing.KeySet, revocations RevocationList, apiKeys APIKeyAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if plaintext := r.Header.Get(APIKeyHeader); plaintext != "" {
+				key, err := apiKeys.Authenticate(r.Context(), plaintext)
+				if err != nil {
+					if errors.Is(err, domain.ErrAPIKeyNotFound) {
+						http.Error(w, "invalid api key", http.StatusUnauthorized)
+						return
+					}
+					http.Error(w, "failed to verify api key", http.StatusInternalServerError)
+					return
+				}
+
+				// the key acts as the user who created it, so audit events are attributed to them
+				ctx := domain.ContextWithActor(r.Context(), key.UserID)
+				ctx = domain.ContextWithAPIKey(ctx, key)
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -27,23 +66,72 @@ func Auth(jwtKey []byte) func(http.Handler) http.Handler {
 				return
 			}
 
-			token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-				return jwtKey, nil
-			})
+			token, err := jwt.Parse(bearerToken[1], keys.Keyfunc,
+				jwt.WithValidMethods(keys.Algorithms()),
+				jwt.WithIssuer(domain.TokenIssuer),
+				jwt.WithAudience(domain.TokenAudience(domain.TokenUseAccess)))
 
 			if err != nil || !token.Valid {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
 
+			// tokens sent in emails are signed with the same keys, but aren't access tokens. their audience already
+			// differs, and token_use is checked as well
 			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
+			if !ok || claims["token_use"] != domain.TokenUseAccess {
+				http.Error(w, "invalid token claims", http.StatusUnauthorized)
+				return
+			}
+
+			jti, ok := claims["jti"].(string)
+			if !ok || jti == "" {
 				http.Error(w, "invalid token claims", http.StatusUnauthorized)
 				return
 			}
 
+			userIDStr, _ := claims["user_id"].(string)
+			userID, err := primitive.ObjectIDFromHex(userIDStr)
+			if err != nil {
+				http.Error(w, "invalid token claims", http.StatusUnauthorized)
+				return
+			}
+
+			issuedAt, err := claims.GetIssuedAt()
+			if err != nil || issuedAt == nil {
+				http.Error(w, "invalid token claims", http.StatusUnauthorized)
+				return
+			}
+
+			revoked, err := revocations.IsRevoked(r.Context(), jti, userID, issuedAt.Time)
+			if err != nil {
+				http.Error(w, "failed to verify token", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				http.Error(w, "token has been revoked", http.StatusUnauthorized)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+
+			// services attribute audit events to whoever is making the request
+			ctx = domain.ContextWithActor(ctx, userID)
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// RequireUserSession rejects requests made with an api key, for routes that manage credentials or memberships
+// and so should only ever be reached by a person
+func RequireUserSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := domain.APIKeyFromContext(r.Context()); ok {
+			http.Error(w, "not available to api keys", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
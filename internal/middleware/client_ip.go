@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const clientIPContextKey contextKey = "client_ip"
+
+// ClientIP records the address the request came from. behind trustedProxies proxies, each of which appends the
+// address it was reached from to X-Forwarded-For, the client is the entry the outermost proxy added, counting from
+// the right. entries further left were sent by the client itself, which could claim any address it liked.
+func ClientIP(trustedProxies int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+
+			if trustedProxies > 0 {
+				if forwarded := forwardedFor(r); len(forwarded) > 0 {
+					// fewer entries than proxies means the request didn't come through all of them, and the
+					// leftmost is then the furthest any proxy saw
+					ip = forwarded[max(len(forwarded)-trustedProxies, 0)]
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// forwardedFor lists every X-Forwarded-For entry in order, across however many headers were sent
+func forwardedFor(r *http.Request) []string {
+	var entries []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries
+}
+
+// ClientIPFromRequest returns the address recorded by ClientIP, falling back to the connection's address
+func ClientIPFromRequest(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok && ip != "" {
+		return ip
+	}
+
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
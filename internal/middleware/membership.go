@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizationHeader lets a user who belongs to several organizations pick which one a request acts on
+const OrganizationHeader = "X-Organization-ID"
+
+type MembershipResolver interface {
+	ResolveMembership(ctx context.Context, userID primitive.ObjectID, orgID *primitive.ObjectID) (*domain.Membership, error)
+}
+
+// Membership resolves the organization membership the authenticated user is acting under and stores it on the
+// request context. it has to run after Auth. an api key is tied to one organization and narrows the membership
+// to the key's scopes, so a key keeps working only for as long as its creator is still a member.
+func Membership(resolver MembershipResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := domain.ActorFromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			var orgID *primitive.ObjectID
+			if orgIDStr := r.Header.Get(OrganizationHeader); orgIDStr != "" {
+				id, err := primitive.ObjectIDFromHex(orgIDStr)
+				if err != nil {
+					http.Error(w, "invalid organization id format", http.StatusBadRequest)
+					return
+				}
+				orgID = &id
+			}
+
+			key, usingAPIKey := domain.APIKeyFromContext(r.Context())
+			if usingAPIKey {
+				if orgID != nil && *orgID != key.OrganizationID {
+					http.Error(w, "api key does not belong to this organization", http.StatusForbidden)
+					return
+				}
+				orgID = &key.OrganizationID
+			}
+
+			membership, err := resolver.ResolveMembership(r.Context(), userID, orgID)
+			if err != nil {
+				if errors.Is(err, domain.ErrMembershipNotFound) {
+					http.Error(w, "not a member of this organization", http.StatusForbidden)
+					return
+				}
+				http.Error(w, "failed to resolve organization membership", http.StatusInternalServerError)
+				return
+			}
+
+			if usingAPIKey {
+				membership.Scopes = append([]domain.Permission{}, key.Scopes...)
+			}
+
+			ctx := domain.ContextWithMembership(r.Context(), membership)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission rejects the request unless the caller's role grants the permission
+func RequirePermission(permission domain.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		membership, ok := domain.MembershipFromContext(r.Context())
+		if !ok || !membership.HasPermission(permission) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -1,23 +1,175 @@
 package middleware
 
 import (
+	"container/list"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/jwald3/waybill/internal/config"
+	"github.com/jwald3/waybill/internal/domain"
 	"golang.org/x/time/rate"
 )
 
-// here's some very simple rate limiting to illustrate how an API can easily include the ability to restrict excessive API calls.
-// this is just global limiting (all clients share the same pool of requests), but you'd realistically tie rate limits to individual consumers
-func RateLimitMiddleware(limiter *rate.Limiter) func(http.Handler) http.Handler {
+// RateLimiter keeps a token bucket per client (and per rule, so a route with its own limit doesn't eat into the
+// default budget). the number of buckets is capped: when it's full the least recently used bucket is dropped, and
+// buckets that have sat idle long enough to have refilled are dropped as requests come in.
+type RateLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*list.Element
+	recent      *list.List
+	maxClients  int
+	idleTimeout time.Duration
+	routes      map[string]config.RateLimitRule
+}
+
+type rateLimitBucket struct {
+	key      string
+	limiter  *rate.Limiter
+	idleTTL  time.Duration
+	lastSeen time.Time
+}
+
+func NewRateLimiter(cfg *config.Config) *RateLimiter {
+	return &RateLimiter{
+		buckets:     make(map[string]*list.Element),
+		recent:      list.New(),
+		maxClients:  cfg.RateLimit.MaxClients,
+		idleTimeout: cfg.RateLimit.IdleTimeout,
+		routes:      cfg.RateLimit.Routes,
+	}
+}
+
+// Limit applies the rule to every route it's used on, unless the route has an override in the config. it has
+// to run after Auth on authenticated routes so that requests are counted against the caller rather than their IP.
+func (l *RateLimiter) Limit(scope string, rule config.RateLimitRule) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// the limiter will determine if clients reached the max number of requests in the current window and either return an error or go to the next function/handler/middleware
-			if !limiter.Allow() {
+			ruleKey, activeRule := scope, rule
+			if route := routeKey(r); route != "" {
+				if override, ok := l.routes[route]; ok {
+					ruleKey, activeRule = route, override
+				}
+			}
+
+			if activeRule.Requests <= 0 || activeRule.Period <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+			limiter := l.bucket(ruleKey+"|"+l.clientKey(r), activeRule, now)
+
+			reservation := limiter.ReserveN(now, 1)
+			delay := reservation.DelayFrom(now)
+			if !reservation.OK() || delay > 0 {
+				reservation.CancelAt(now)
+				setRateLimitHeaders(w, limiter, activeRule, now)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
 				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 				return
 			}
 
+			setRateLimitHeaders(w, limiter, activeRule, now)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+func (l *RateLimiter) bucket(key string, rule config.RateLimitRule, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evictIdle(now)
+
+	if element, ok := l.buckets[key]; ok {
+		bucket := element.Value.(*rateLimitBucket)
+		bucket.lastSeen = now
+		l.recent.MoveToFront(element)
+		return bucket.limiter
+	}
+
+	for l.maxClients > 0 && l.recent.Len() >= l.maxClients {
+		l.removeElement(l.recent.Back())
+	}
+
+	// a bucket can only be forgotten once it would have refilled, or forgetting it would reset the client's limit
+	idleTTL := l.idleTimeout
+	if rule.Period > idleTTL {
+		idleTTL = rule.Period
+	}
+
+	bucket := &rateLimitBucket{
+		key:      key,
+		limiter:  rate.NewLimiter(rate.Every(rule.Period/time.Duration(rule.Requests)), rule.Requests),
+		idleTTL:  idleTTL,
+		lastSeen: now,
+	}
+	l.buckets[key] = l.recent.PushFront(bucket)
+
+	return bucket.limiter
+}
+
+// the list is ordered by last use, so idle buckets collect at the back
+func (l *RateLimiter) evictIdle(now time.Time) {
+	for element := l.recent.Back(); element != nil; element = l.recent.Back() {
+		bucket := element.Value.(*rateLimitBucket)
+		if now.Sub(bucket.lastSeen) < bucket.idleTTL {
+			return
+		}
+		l.removeElement(element)
+	}
+}
+
+func (l *RateLimiter) removeElement(element *list.Element) {
+	bucket := l.recent.Remove(element).(*rateLimitBucket)
+	delete(l.buckets, bucket.key)
+}
+
+// clientKey identifies who a request counts against: the api key or user it was authenticated as, falling back
+// to the client's IP address for anonymous requests
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if key, ok := domain.APIKeyFromContext(r.Context()); ok {
+		return "key:" + key.ID.Hex()
+	}
+
+	if claims, ok := r.Context().Value(UserContextKey).(jwt.MapClaims); ok {
+		if userID, ok := claims["user_id"].(string); ok && userID != "" {
+			return "user:" + userID
+		}
+	}
+
+	return "ip:" + ClientIPFromRequest(r)
+}
+
+func routeKey(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s %s", r.Method, template)
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, limiter *rate.Limiter, rule config.RateLimitRule, now time.Time) {
+	tokens := limiter.TokensAt(now)
+	remaining := int(math.Max(0, math.Floor(tokens)))
+
+	// seconds until the bucket is full again
+	missing := float64(rule.Requests) - tokens
+	reset := int(math.Ceil(missing * rule.Period.Seconds() / float64(rule.Requests)))
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Requests))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(reset))
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type apiKeyRepository struct {
+	apiKeys *mongo.Collection
+}
+
+type ListAPIKeysResult struct {
+	APIKeys []*domain.APIKey
+	Total   int64
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	List(ctx context.Context, filter domain.APIKeyFilter) (*ListAPIKeysResult, error)
+	Revoke(ctx context.Context, id, orgID primitive.ObjectID) error
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+}
+
+func NewAPIKeyRepository(db *database.MongoDB) APIKeyRepository {
+	return &apiKeyRepository{
+		apiKeys: db.Database.Collection("api_keys"),
+	}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	now := time.Now()
+	key.CreatedAt = primitive.NewDateTimeFromTime(now)
+	key.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	result, err := r.apiKeys.InsertOne(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	key.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *apiKeyRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.APIKey, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	return r.findOne(ctx, bson.M{"key_hash": keyHash})
+}
+
+func (r *apiKeyRepository) findOne(ctx context.Context, filter bson.M) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.apiKeys.FindOne(ctx, filter).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, filter domain.APIKeyFilter) (*ListAPIKeysResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
+	if filter.UserID != nil {
+		filterQuery["user_id"] = *filter.UserID
+	}
+
+	total, err := r.apiKeys.CountDocuments(ctx, filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.apiKeys.Find(ctx, filterQuery, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := make([]*domain.APIKey, 0, filter.Limit)
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %w", err)
+	}
+
+	return &ListAPIKeysResult{
+		APIKeys: keys,
+		Total:   total,
+	}, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id, orgID primitive.ObjectID) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	filter := bson.M{
+		"_id":             id,
+		"organization_id": orgID,
+		"revoked_at":      bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"revoked_at": now,
+			"updated_at": now,
+		},
+	}
+
+	result, err := r.apiKeys.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"last_used_at": primitive.NewDateTimeFromTime(usedAt),
+		},
+	}
+
+	if _, err := r.apiKeys.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditEventRepository struct {
+	auditEvents *mongo.Collection
+}
+
+// AuditEventRepository is append-only: events are never updated or deleted once written
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *domain.AuditEvent) error
+	List(ctx context.Context, filter domain.AuditEventFilter) (*ListAuditEventsResult, error)
+}
+
+type ListAuditEventsResult struct {
+	AuditEvents []*domain.AuditEvent
+	Total       int64
+}
+
+func NewAuditEventRepository(db *database.MongoDB) AuditEventRepository {
+	// the before/after snapshots are free-form documents, so decode nested values as maps rather than bson.D
+	// to keep them serializable as plain json objects
+	opts := options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
+
+	return &auditEventRepository{
+		auditEvents: db.Database.Collection("audit_events", opts),
+	}
+}
+
+func (r *auditEventRepository) Create(ctx context.Context, event *domain.AuditEvent) error {
+	result, err := r.auditEvents.InsertOne(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	event.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *auditEventRepository) List(ctx context.Context, filter domain.AuditEventFilter) (*ListAuditEventsResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	filterQuery := bson.M{
+		"organization_id": filter.OrganizationID,
+		"entity_type":     filter.EntityType,
+		"entity_id":       filter.EntityID,
+	}
+
+	total, err := r.auditEvents.CountDocuments(ctx, filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.auditEvents.Find(ctx, filterQuery, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	auditEvents := make([]*domain.AuditEvent, 0, filter.Limit)
+	if err := cursor.All(ctx, &auditEvents); err != nil {
+		return nil, fmt.Errorf("failed to decode audit events: %w", err)
+	}
+
+	return &ListAuditEventsResult{
+		AuditEvents: auditEvents,
+		Total:       total,
+	}, nil
+}
@@ -18,11 +18,12 @@ type driverRepository struct {
 
 type DriverRepository interface {
 	Create(ctx context.Context, driver *domain.Driver) error
-	GetById(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (*domain.Driver, error)
+	GetById(ctx context.Context, id primitive.ObjectID, orgID primitive.ObjectID) (*domain.Driver, error)
 	Update(ctx context.Context, driver *domain.Driver) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.DriverFilter) (*ListDriversResult, error)
-	UpdateEmploymentStatus(ctx context.Context, id primitive.ObjectID, status domain.EmploymentStatus) error
+	UpdateEmploymentStatus(ctx context.Context, id, orgID primitive.ObjectID, status domain.EmploymentStatus) error
+	ListExpiring(ctx context.Context, orgID primitive.ObjectID, before primitive.DateTime) ([]*domain.Driver, error)
 }
 
 type ListDriversResult struct {
@@ -41,20 +42,22 @@ func (r *driverRepository) Create(ctx context.Context, driver *domain.Driver) er
 	driver.CreatedAt = primitive.NewDateTimeFromTime(now)
 	driver.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.drivers.InsertOne(ctx, driver)
+	result, err := r.drivers.InsertOne(ctx, driver)
 	if err != nil {
 		return fmt.Errorf("failed to create driver: %w", err)
 	}
 
+	driver.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *driverRepository) GetById(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (*domain.Driver, error) {
+func (r *driverRepository) GetById(ctx context.Context, id primitive.ObjectID, orgID primitive.ObjectID) (*domain.Driver, error) {
 	pipeline := mongo.Pipeline{
 		{{Key: "$match",
 			Value: bson.M{
-				"_id":     id,
-				"user_id": userID,
+				"_id":             id,
+				"organization_id": orgID,
 			},
 		}},
 		{{
@@ -100,16 +103,21 @@ func (r *driverRepository) GetById(ctx context.Context, id primitive.ObjectID, u
 }
 
 func (r *driverRepository) Update(ctx context.Context, driver *domain.Driver) error {
-	filter := bson.M{"_id": driver.ID}
+	filter := bson.M{"_id": driver.ID, "organization_id": driver.OrganizationID}
 	update := bson.M{
 		"$set": bson.M{
-			"first_name":        driver.FirstName,
-			"last_name":         driver.LastName,
-			"phone":             driver.Phone,
-			"email":             driver.Email,
-			"address":           driver.Address,
-			"employment_status": driver.EmploymentStatus,
-			"updated_at":        primitive.NewDateTimeFromTime(time.Now()),
+			"first_name":         driver.FirstName,
+			"last_name":          driver.LastName,
+			"dob":                driver.DOB,
+			"license_number":     driver.LicenseNumber,
+			"license_state":      driver.LicenseState,
+			"license_expiration": driver.LicenseExpiration,
+			"credentials":        driver.Credentials,
+			"phone":              driver.Phone,
+			"email":              driver.Email,
+			"address":            driver.Address,
+			"employment_status":  driver.EmploymentStatus,
+			"updated_at":         primitive.NewDateTimeFromTime(time.Now()),
 		},
 	}
 
@@ -125,10 +133,10 @@ func (r *driverRepository) Update(ctx context.Context, driver *domain.Driver) er
 	return nil
 }
 
-func (r *driverRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+func (r *driverRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
 	result, err := r.drivers.DeleteOne(ctx, bson.M{
-		"_id":     id,
-		"user_id": userID,
+		"_id":             id,
+		"organization_id": orgID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete driver: %w", err)
@@ -152,7 +160,7 @@ func (r *driverRepository) List(ctx context.Context, filter domain.DriverFilter)
 		filter.Offset = 0
 	}
 
-	filterQuery := bson.M{"user_id": filter.UserID}
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
 
 	if filter.LicenseState != "" {
 		filterQuery["license_state"] = filter.LicenseState
@@ -211,8 +219,8 @@ func (r *driverRepository) List(ctx context.Context, filter domain.DriverFilter)
 	}, nil
 }
 
-func (r *driverRepository) UpdateEmploymentStatus(ctx context.Context, id primitive.ObjectID, status domain.EmploymentStatus) error {
-	filter := bson.M{"_id": id}
+func (r *driverRepository) UpdateEmploymentStatus(ctx context.Context, id, orgID primitive.ObjectID, status domain.EmploymentStatus) error {
+	filter := bson.M{"_id": id, "organization_id": orgID}
 	update := bson.M{
 		"$set": bson.M{
 			"employment_status": status,
@@ -231,3 +239,28 @@ func (r *driverRepository) UpdateEmploymentStatus(ctx context.Context, id primit
 
 	return nil
 }
+
+// ListExpiring finds the drivers still on staff whose license or some other credential expires before the cutoff
+func (r *driverRepository) ListExpiring(ctx context.Context, orgID primitive.ObjectID, before primitive.DateTime) ([]*domain.Driver, error) {
+	filter := bson.M{
+		"organization_id":   orgID,
+		"employment_status": bson.M{"$ne": domain.EmploymentStatusTerminated},
+		"$or": bson.A{
+			bson.M{"license_expiration": bson.M{"$lt": before}},
+			bson.M{"credentials.expires_on": bson.M{"$lt": before}},
+		},
+	}
+
+	cursor, err := r.drivers.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expiring drivers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	drivers := make([]*domain.Driver, 0)
+	if err := cursor.All(ctx, &drivers); err != nil {
+		return nil, fmt.Errorf("failed to decode drivers: %w", err)
+	}
+
+	return drivers, nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type dutyStatusRepository struct {
+	events *mongo.Collection
+}
+
+// DutyStatusRepository is append-only: a driver's log is corrected by recording a new status, never by editing
+// an old one
+type DutyStatusRepository interface {
+	Create(ctx context.Context, event *domain.DutyStatusEvent) error
+	Latest(ctx context.Context, orgID, driverID primitive.ObjectID) (*domain.DutyStatusEvent, error)
+	List(ctx context.Context, filter domain.DutyStatusFilter) (*ListDutyStatusEventsResult, error)
+	ListSince(ctx context.Context, orgID, driverID primitive.ObjectID, since primitive.DateTime) ([]*domain.DutyStatusEvent, error)
+}
+
+type ListDutyStatusEventsResult struct {
+	Events []*domain.DutyStatusEvent
+	Total  int64
+}
+
+func NewDutyStatusRepository(db *database.MongoDB) DutyStatusRepository {
+	return &dutyStatusRepository{
+		events: db.Database.Collection("duty_status_events"),
+	}
+}
+
+func (r *dutyStatusRepository) Create(ctx context.Context, event *domain.DutyStatusEvent) error {
+	event.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.events.InsertOne(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to create duty status event: %w", err)
+	}
+
+	event.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *dutyStatusRepository) Latest(ctx context.Context, orgID, driverID primitive.ObjectID) (*domain.DutyStatusEvent, error) {
+	filter := bson.M{
+		"organization_id": orgID,
+		"driver_id":       driverID,
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	var event domain.DutyStatusEvent
+	err := r.events.FindOne(ctx, filter, opts).Decode(&event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest duty status: %w", err)
+	}
+
+	return &event, nil
+}
+
+func (r *dutyStatusRepository) List(ctx context.Context, filter domain.DutyStatusFilter) (*ListDutyStatusEventsResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	filterQuery := bson.M{
+		"organization_id": filter.OrganizationID,
+		"driver_id":       filter.DriverID,
+	}
+
+	startedAt := bson.M{}
+	if filter.From != nil {
+		startedAt["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		startedAt["$lte"] = *filter.To
+	}
+	if len(startedAt) > 0 {
+		filterQuery["started_at"] = startedAt
+	}
+
+	total, err := r.events.CountDocuments(ctx, filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.events.Find(ctx, filterQuery, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duty status events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := make([]*domain.DutyStatusEvent, 0, filter.Limit)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode duty status events: %w", err)
+	}
+
+	return &ListDutyStatusEventsResult{
+		Events: events,
+		Total:  total,
+	}, nil
+}
+
+// ListSince returns the driver's log from since onwards, oldest first, led by the status the driver was already in
+// at since
+func (r *dutyStatusRepository) ListSince(ctx context.Context, orgID, driverID primitive.ObjectID, since primitive.DateTime) ([]*domain.DutyStatusEvent, error) {
+	base := bson.M{
+		"organization_id": orgID,
+		"driver_id":       driverID,
+	}
+
+	events := make([]*domain.DutyStatusEvent, 0)
+
+	var prior domain.DutyStatusEvent
+	priorQuery := bson.M{"started_at": bson.M{"$lt": since}}
+	for k, v := range base {
+		priorQuery[k] = v
+	}
+	err := r.events.FindOne(ctx, priorQuery, options.FindOne().SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}})).Decode(&prior)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to get prior duty status: %w", err)
+	}
+	if err == nil {
+		events = append(events, &prior)
+	}
+
+	base["started_at"] = bson.M{"$gte": since}
+	cursor, err := r.events.Find(ctx, base, options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duty status events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	recent := make([]*domain.DutyStatusEvent, 0)
+	if err := cursor.All(ctx, &recent); err != nil {
+		return nil, fmt.Errorf("failed to decode duty status events: %w", err)
+	}
+
+	return append(events, recent...), nil
+}
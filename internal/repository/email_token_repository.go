@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type emailTokenRepository struct {
+	emailTokens *mongo.Collection
+}
+
+type EmailTokenRepository interface {
+	Create(ctx context.Context, token *domain.EmailToken) error
+	Consume(ctx context.Context, id primitive.ObjectID, purpose domain.EmailTokenPurpose) (*domain.EmailToken, error)
+	InvalidateForUser(ctx context.Context, userID primitive.ObjectID, purpose domain.EmailTokenPurpose) error
+}
+
+func NewEmailTokenRepository(db *database.MongoDB) EmailTokenRepository {
+	return &emailTokenRepository{
+		emailTokens: db.Database.Collection("email_tokens"),
+	}
+}
+
+func (r *emailTokenRepository) Create(ctx context.Context, token *domain.EmailToken) error {
+	result, err := r.emailTokens.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create email token: %w", err)
+	}
+
+	token.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+// Consume spends the token and returns it. it only matches a token that is unspent and unexpired, so a token
+// redeemed twice at the same moment only works once.
+func (r *emailTokenRepository) Consume(ctx context.Context, id primitive.ObjectID, purpose domain.EmailTokenPurpose) (*domain.EmailToken, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	filter := bson.M{
+		"_id":        id,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+
+	var token domain.EmailToken
+	err := r.emailTokens.FindOneAndUpdate(ctx, filter, update).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, domain.ErrEmailTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume email token: %w", err)
+	}
+
+	token.UsedAt = &now
+
+	return &token, nil
+}
+
+func (r *emailTokenRepository) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, purpose domain.EmailTokenPurpose) error {
+	filter := bson.M{
+		"user_id": userID,
+		"purpose": purpose,
+		"used_at": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"used_at": primitive.NewDateTimeFromTime(time.Now())}}
+
+	if _, err := r.emailTokens.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to invalidate email tokens: %w", err)
+	}
+
+	return nil
+}
@@ -18,11 +18,11 @@ type facilityRepository struct {
 
 type FacilityRepository interface {
 	Create(ctx context.Context, facility *domain.Facility) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Facility, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Facility, error)
 	Update(ctx context.Context, facility *domain.Facility) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	ListWithFilter(ctx context.Context, filter domain.FacilityFilter) (*ListFacilitiesResult, error)
-	UpdateAvailableFacilityServices(ctx context.Context, id, userID primitive.ObjectID, servicesAvailable []domain.FacilityService) error
+	UpdateAvailableFacilityServices(ctx context.Context, id, orgID primitive.ObjectID, servicesAvailable []domain.FacilityService) error
 }
 
 type ListFacilitiesResult struct {
@@ -41,16 +41,18 @@ func (r *facilityRepository) Create(ctx context.Context, facility *domain.Facili
 	facility.CreatedAt = primitive.NewDateTimeFromTime(now)
 	facility.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.facilities.InsertOne(ctx, facility)
+	result, err := r.facilities.InsertOne(ctx, facility)
 	if err != nil {
 		return fmt.Errorf("failed to create facility: %w", err)
 	}
 
+	facility.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *facilityRepository) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Facility, error) {
-	filter := bson.M{"_id": id, "user_id": userID}
+func (r *facilityRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Facility, error) {
+	filter := bson.M{"_id": id, "organization_id": orgID}
 
 	var facility domain.Facility
 	err := r.facilities.FindOne(ctx, filter).Decode(&facility)
@@ -64,18 +66,39 @@ func (r *facilityRepository) GetById(ctx context.Context, id, userID primitive.O
 }
 
 func (r *facilityRepository) Update(ctx context.Context, facility *domain.Facility) error {
-	filter := bson.M{"_id": facility.ID}
-	update := bson.M{
-		"$set": bson.M{
-			"facility_number":    facility.FacilityNumber,
-			"name":               facility.Name,
-			"type":               facility.Type,
-			"address":            facility.Address,
-			"contact_info":       facility.ContactInfo,
-			"parking_capacity":   facility.ParkingCapacity,
-			"services_available": facility.ServicesAvailable,
-			"updated_at":         primitive.NewDateTimeFromTime(time.Now()),
-		},
+	filter := bson.M{"_id": facility.ID, "organization_id": facility.OrganizationID}
+	set := bson.M{
+		"facility_number":    facility.FacilityNumber,
+		"name":               facility.Name,
+		"type":               facility.Type,
+		"address":            facility.Address,
+		"contact_info":       facility.ContactInfo,
+		"parking_capacity":   facility.ParkingCapacity,
+		"services_available": facility.ServicesAvailable,
+		"accepts_hazmat":     facility.AcceptsHazmat,
+		"updated_at":         primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	unset := bson.M{}
+	if facility.Location != nil {
+		set["location"] = facility.Location
+	} else {
+		unset["location"] = ""
+	}
+	if facility.Geofence != nil {
+		set["geofence"] = facility.Geofence
+	} else {
+		unset["geofence"] = ""
+	}
+	if len(facility.HazardClasses) > 0 {
+		set["hazard_classes"] = facility.HazardClasses
+	} else {
+		unset["hazard_classes"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
 
 	result, err := r.facilities.UpdateOne(ctx, filter, update)
@@ -90,8 +113,8 @@ func (r *facilityRepository) Update(ctx context.Context, facility *domain.Facili
 	return nil
 }
 
-func (r *facilityRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	result, err := r.facilities.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+func (r *facilityRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.facilities.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
 	if err != nil {
 		return fmt.Errorf("failed to delete facility: %w", err)
 	}
@@ -118,8 +141,8 @@ func (r *facilityRepository) ListWithFilter(ctx context.Context, filter domain.F
 
 	filterQuery := bson.M{}
 
-	if filter.UserID != primitive.NilObjectID {
-		filterQuery["user_id"] = filter.UserID
+	if filter.OrganizationID != primitive.NilObjectID {
+		filterQuery["organization_id"] = filter.OrganizationID
 	}
 
 	// go through each of the filter options and add them to the filter query if they're not empty
@@ -153,9 +176,15 @@ func (r *facilityRepository) ListWithFilter(ctx context.Context, filter domain.F
 		}
 	}
 
+	// $geoNear can't be used to count, so the count looks for the same facilities with $geoWithin instead
+	countQuery := filterQuery
+	if filter.Near != nil {
+		countQuery = bson.M{"$and": bson.A{filterQuery, bson.M{"location": nearbyQuery(filter)}}}
+	}
+
 	// find the total number of facilities that match the filter (this is prior to pagination but after the filter is applied,
 	// so we're counting only the facilities that match the filter)
-	total, err := r.facilities.CountDocuments(ctx, filterQuery)
+	total, err := r.facilities.CountDocuments(ctx, countQuery)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get total count: %w", err)
 	}
@@ -167,6 +196,27 @@ func (r *facilityRepository) ListWithFilter(ctx context.Context, filter domain.F
 		{{Key: "$limit", Value: filter.Limit}},
 	}
 
+	// a proximity search sorts by distance instead, and $geoNear has to be the first stage
+	if filter.Near != nil {
+		geoNear := bson.M{
+			"near":               filter.Near,
+			"key":                "location",
+			"spherical":          true,
+			"query":              filterQuery,
+			"distanceField":      "distance_miles",
+			"distanceMultiplier": 1 / domain.MetersPerMile,
+		}
+		if filter.RadiusMiles > 0 {
+			geoNear["maxDistance"] = filter.RadiusMiles * domain.MetersPerMile
+		}
+
+		pipeline = mongo.Pipeline{
+			{{Key: "$geoNear", Value: geoNear}},
+			{{Key: "$skip", Value: filter.Offset}},
+			{{Key: "$limit", Value: filter.Limit}},
+		}
+	}
+
 	// find the facilities that match the filter and return paginated results
 	cursor, err := r.facilities.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -185,8 +235,8 @@ func (r *facilityRepository) ListWithFilter(ctx context.Context, filter domain.F
 	}, nil
 }
 
-func (r *facilityRepository) UpdateAvailableFacilityServices(ctx context.Context, id, userID primitive.ObjectID, servicesAvailable []domain.FacilityService) error {
-	filter := bson.M{"_id": id, "user_id": userID}
+func (r *facilityRepository) UpdateAvailableFacilityServices(ctx context.Context, id, orgID primitive.ObjectID, servicesAvailable []domain.FacilityService) error {
+	filter := bson.M{"_id": id, "organization_id": orgID}
 	update := bson.M{
 		"$set": bson.M{
 			"services_available": servicesAvailable,
@@ -205,3 +255,15 @@ func (r *facilityRepository) UpdateAvailableFacilityServices(ctx context.Context
 
 	return nil
 }
+
+// nearbyQuery matches the facilities a proximity search can return: every facility with coordinates, or only
+// those inside the radius when there is one
+func nearbyQuery(filter domain.FacilityFilter) bson.M {
+	if filter.RadiusMiles <= 0 {
+		return bson.M{"$exists": true}
+	}
+
+	return bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{filter.Near.Coordinates, filter.RadiusMiles / domain.EarthRadiusMiles},
+	}}
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fuelAnalyticsRepository struct {
+	trips    *mongo.Collection
+	fuelLogs *mongo.Collection
+}
+
+// FuelAnalyticsRepository derives fuel figures from trips and fuel logs. it has no collection of its own.
+type FuelAnalyticsRepository interface {
+	Efficiency(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelEfficiency, error)
+	ListFillUps(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]*domain.FuelFillUp, error)
+}
+
+func NewFuelAnalyticsRepository(db *database.MongoDB) FuelAnalyticsRepository {
+	return &fuelAnalyticsRepository{
+		trips:    db.Database.Collection("trips"),
+		fuelLogs: db.Database.Collection("fuel_logs"),
+	}
+}
+
+// Efficiency groups the trips completed in the filter's range. a trip's fuel is its own fuel usage when that was
+// recorded, and otherwise the gallons bought on it; its cost is always what was paid for the fuel bought on it.
+// trips missing what they'd be grouped by are left out.
+func (r *fuelAnalyticsRepository) Efficiency(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelEfficiency, error) {
+	match := bson.M{
+		"organization_id":     filter.OrganizationID,
+		"status":              domain.TripStatusCompleted,
+		"arrival_time.actual": bson.M{"$gte": filter.From, "$lt": filter.To},
+		"distance_miles":      bson.M{"$gt": 0},
+	}
+
+	var lookups mongo.Pipeline
+	var key any
+	group := bson.M{}
+	var keyFields bson.M
+
+	switch filter.GroupBy {
+	case domain.FuelGroupingTruck, domain.FuelGroupingFuelType, domain.FuelGroupingTrailerType:
+		match["truck_id"] = bson.M{"$ne": nil}
+		lookups = mongo.Pipeline{
+			{{Key: "$lookup", Value: bson.M{
+				"from":         "trucks",
+				"localField":   "truck_id",
+				"foreignField": "_id",
+				"as":           "truck",
+			}}},
+			{{Key: "$unwind", Value: "$truck"}},
+		}
+
+		switch filter.GroupBy {
+		case domain.FuelGroupingTruck:
+			key = "$truck_id"
+			group["truck_number"] = bson.M{"$first": "$truck.truck_number"}
+			keyFields = bson.M{"truck_id": "$_id"}
+		case domain.FuelGroupingFuelType:
+			key = "$truck.fuel_type"
+			keyFields = bson.M{"fuel_type": "$_id"}
+		default:
+			key = "$truck.trailer_type"
+			keyFields = bson.M{"trailer_type": "$_id"}
+		}
+	case domain.FuelGroupingDriver:
+		match["driver_id"] = bson.M{"$ne": nil}
+		lookups = mongo.Pipeline{
+			{{Key: "$lookup", Value: bson.M{
+				"from":         "drivers",
+				"localField":   "driver_id",
+				"foreignField": "_id",
+				"as":           "driver",
+			}}},
+			{{Key: "$unwind", Value: bson.M{
+				"path":                       "$driver",
+				"preserveNullAndEmptyArrays": true,
+			}}},
+		}
+		key = "$driver_id"
+		group["driver_name"] = bson.M{"$first": bson.M{"$concat": bson.A{"$driver.first_name", " ", "$driver.last_name"}}}
+		keyFields = bson.M{"driver_id": "$_id"}
+	case domain.FuelGroupingLane:
+		match["start_facility_id"] = bson.M{"$ne": nil}
+		match["end_facility_id"] = bson.M{"$ne": nil}
+		lookups = mongo.Pipeline{
+			{{Key: "$lookup", Value: bson.M{
+				"from":         "facilities",
+				"localField":   "start_facility_id",
+				"foreignField": "_id",
+				"as":           "start_facility",
+			}}},
+			{{Key: "$unwind", Value: bson.M{
+				"path":                       "$start_facility",
+				"preserveNullAndEmptyArrays": true,
+			}}},
+			{{Key: "$lookup", Value: bson.M{
+				"from":         "facilities",
+				"localField":   "end_facility_id",
+				"foreignField": "_id",
+				"as":           "end_facility",
+			}}},
+			{{Key: "$unwind", Value: bson.M{
+				"path":                       "$end_facility",
+				"preserveNullAndEmptyArrays": true,
+			}}},
+		}
+		key = bson.M{"start": "$start_facility_id", "end": "$end_facility_id"}
+		group["start_facility_name"] = bson.M{"$first": "$start_facility.name"}
+		group["end_facility_name"] = bson.M{"$first": "$end_facility.name"}
+		keyFields = bson.M{"start_facility_id": "$_id.start", "end_facility_id": "$_id.end"}
+	default:
+		return nil, fmt.Errorf("invalid grouping: %s", filter.GroupBy)
+	}
+
+	group["_id"] = key
+	group["trips"] = bson.M{"$sum": 1}
+	group["miles"] = bson.M{"$sum": "$distance_miles"}
+	group["gallons"] = bson.M{"$sum": "$gallons"}
+	group["fuel_cost"] = bson.M{"$sum": "$fuel_cost"}
+	group["fueled_miles"] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$gallons", 0}}, "$distance_miles", 0}}}
+	group["costed_miles"] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$fuel_cost", 0}}, "$distance_miles", 0}}}
+
+	keyFields["mpg"] = bson.M{"$cond": bson.A{
+		bson.M{"$gt": bson.A{"$gallons", 0}},
+		bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$fueled_miles", "$gallons"}}, 2}},
+		nil,
+	}}
+	keyFields["cost_per_mile"] = bson.M{"$cond": bson.A{
+		bson.M{"$gt": bson.A{"$costed_miles", 0}},
+		bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$fuel_cost", "$costed_miles"}}, 2}},
+		nil,
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "fuel_logs",
+			"localField":   "_id",
+			"foreignField": "trip_id",
+			"as":           "fuel_logs",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"fuel_cost": bson.M{"$sum": "$fuel_logs.total_cost"},
+			"gallons": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$fuel_usage_gallons", 0}},
+				"$fuel_usage_gallons",
+				bson.M{"$sum": "$fuel_logs.gallons_purchased"},
+			}},
+		}}},
+	}
+	pipeline = append(pipeline, lookups...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: group}},
+		bson.D{{Key: "$addFields", Value: keyFields}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "miles", Value: -1}, {Key: "trips", Value: -1}}}},
+	)
+
+	cursor, err := r.trips.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	efficiencies := make([]domain.FuelEfficiency, 0)
+	if err := cursor.All(ctx, &efficiencies); err != nil {
+		return nil, fmt.Errorf("failed to decode fuel efficiency: %w", err)
+	}
+
+	return efficiencies, nil
+}
+
+// ListFillUps lists the fuel bought in the filter's range for the organization's trucks, oldest first. each fill-up
+// carries the odometer reading from the truck's fill-up before it, even when that one falls before the range.
+func (r *fuelAnalyticsRepository) ListFillUps(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]*domain.FuelFillUp, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"organization_id": filter.OrganizationID,
+			"date":            bson.M{"$lt": filter.To},
+		}}},
+		{{Key: "$setWindowFields", Value: bson.M{
+			"partitionBy": "$truck_id",
+			"sortBy":      bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}},
+			"output": bson.M{
+				"previous_odometer_reading": bson.M{"$shift": bson.M{"output": "$odometer_reading", "by": -1}},
+			},
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"date": bson.M{"$gte": filter.From},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "trucks",
+			"localField":   "truck_id",
+			"foreignField": "_id",
+			"as":           "truck",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$truck",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"trip_id":                   1,
+			"truck_id":                  1,
+			"truck_number":              "$truck.truck_number",
+			"date":                      1,
+			"location":                  1,
+			"gallons_purchased":         1,
+			"price_per_gallon":          1,
+			"total_cost":                1,
+			"odometer_reading":          1,
+			"previous_odometer_reading": 1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.fuelLogs.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	fillUps := make([]*domain.FuelFillUp, 0)
+	if err := cursor.All(ctx, &fillUps); err != nil {
+		return nil, fmt.Errorf("failed to decode fill-ups: %w", err)
+	}
+
+	return fillUps, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/jwald3/waybill/internal/database"
@@ -18,10 +19,11 @@ type fuelLogRepository struct {
 
 type FuelLogRepository interface {
 	Create(ctx context.Context, fuelLog *domain.FuelLog) error
-	GetById(ctx context.Context, id primitive.ObjectID) (*domain.FuelLog, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.FuelLog, error)
 	Update(ctx context.Context, fuelLog *domain.FuelLog) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.FuelLogFilter) (*ListFuelLogsResult, error)
+	ListByTrucks(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.FuelLog, error)
 }
 
 type ListFuelLogsResult struct {
@@ -40,35 +42,24 @@ func (r *fuelLogRepository) Create(ctx context.Context, fuelLog *domain.FuelLog)
 	fuelLog.CreatedAt = primitive.NewDateTimeFromTime(now)
 	fuelLog.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.fuelLogs.InsertOne(ctx, fuelLog)
+	result, err := r.fuelLogs.InsertOne(ctx, fuelLog)
 	if err != nil {
 		return fmt.Errorf("failed to create fuelLog: %w", err)
 	}
 
+	fuelLog.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *fuelLogRepository) GetById(ctx context.Context, id primitive.ObjectID) (*domain.FuelLog, error) {
+func (r *fuelLogRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.FuelLog, error) {
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.M{
-			"_id": id,
-		}}},
-		{{Key: "$lookup", Value: bson.M{
-			"from":         "trips",
-			"localField":   "trip_id",
-			"foreignField": "_id",
-			"as":           "trip",
-		}}},
-		{{Key: "$unwind", Value: bson.M{
-			"path":                       "$trip",
-			"preserveNullAndEmptyArrays": true,
+			"_id":             id,
+			"organization_id": orgID,
 		}}},
-		{{
-			Key: "$project", Value: bson.M{
-				"truck_id": 0,
-			},
-		}},
 	}
+	pipeline = append(pipeline, fuelLogLookups()...)
 
 	var result domain.FuelLog
 	cursor, err := r.fuelLogs.Aggregate(ctx, pipeline)
@@ -92,9 +83,15 @@ func (r *fuelLogRepository) GetById(ctx context.Context, id primitive.ObjectID)
 }
 
 func (r *fuelLogRepository) Update(ctx context.Context, fuelLog *domain.FuelLog) error {
-	filter := bson.M{"_id": fuelLog.ID}
+	filter := bson.M{
+		"_id":             fuelLog.ID,
+		"organization_id": fuelLog.OrganizationID,
+	}
 	update := bson.M{
 		"$set": bson.M{
+			"truck_id":          fuelLog.TruckID,
+			"driver_id":         fuelLog.DriverID,
+			"facility_id":       fuelLog.FacilityID,
 			"trip_id":           fuelLog.TripID,
 			"date":              fuelLog.Date,
 			"gallons_purchased": fuelLog.GallonsPurchased,
@@ -112,14 +109,17 @@ func (r *fuelLogRepository) Update(ctx context.Context, fuelLog *domain.FuelLog)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("fuel log entry not found")
+		return domain.ErrFuelLogNotFound
 	}
 
 	return nil
 }
 
-func (r *fuelLogRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	result, err := r.fuelLogs.DeleteOne(ctx, bson.M{"_id": id})
+func (r *fuelLogRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.fuelLogs.DeleteOne(ctx, bson.M{
+		"_id":             id,
+		"organization_id": orgID,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete fuel log: %w", err)
 	}
@@ -142,37 +142,51 @@ func (r *fuelLogRepository) List(ctx context.Context, filter domain.FuelLogFilte
 		filter.Offset = 0
 	}
 
-	filterQuery := bson.M{}
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
 
-	if filter.TripID != &primitive.NilObjectID {
+	if filter.TripID != nil {
 		filterQuery["trip_id"] = filter.TripID
 	}
 
+	if filter.TruckID != nil {
+		filterQuery["truck_id"] = filter.TruckID
+	}
+
+	if filter.DriverID != nil {
+		filterQuery["driver_id"] = filter.DriverID
+	}
+
+	if filter.FacilityID != nil {
+		filterQuery["facility_id"] = filter.FacilityID
+	}
+
+	if filter.From != nil || filter.To != nil {
+		date := bson.M{}
+		if filter.From != nil {
+			date["$gte"] = filter.From
+		}
+		if filter.To != nil {
+			date["$lt"] = filter.To
+		}
+		filterQuery["date"] = date
+	}
+
+	if filter.Location != "" {
+		filterQuery["location"] = bson.M{"$regex": regexp.QuoteMeta(filter.Location), "$options": "i"}
+	}
+
 	total, err := r.fuelLogs.CountDocuments(ctx, filterQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total count: %w", err)
 	}
 
 	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filterQuery}},
 		{{Key: "$sort", Value: bson.M{"_id": -1}}},
 		{{Key: "$skip", Value: filter.Offset}},
 		{{Key: "$limit", Value: filter.Limit}},
-		{{Key: "$lookup", Value: bson.M{
-			"from":         "trips",
-			"localField":   "trip_id",
-			"foreignField": "_id",
-			"as":           "trip",
-		}}},
-		{{Key: "$unwind", Value: bson.M{
-			"path":                       "$trip",
-			"preserveNullAndEmptyArrays": true,
-		}}},
-		{{
-			Key: "$project", Value: bson.M{
-				"truck_id": 0,
-			},
-		}},
 	}
+	pipeline = append(pipeline, fuelLogLookups()...)
 
 	cursor, err := r.fuelLogs.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -190,3 +204,71 @@ func (r *fuelLogRepository) List(ctx context.Context, filter domain.FuelLogFilte
 		Total:    total,
 	}, nil
 }
+
+// ListByTrucks lists every fuel log bought for the organization's trucks, or for just the one truck, without
+// looking up what they refer to
+func (r *fuelLogRepository) ListByTrucks(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.FuelLog, error) {
+	filter := bson.M{"organization_id": orgID}
+	if truckID != nil {
+		filter["truck_id"] = truckID
+	}
+
+	cursor, err := r.fuelLogs.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find fuel logs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	fuelLogs := make([]*domain.FuelLog, 0)
+	if err := cursor.All(ctx, &fuelLogs); err != nil {
+		return nil, fmt.Errorf("failed to decode fuel logs: %w", err)
+	}
+
+	return fuelLogs, nil
+}
+
+// fuelLogLookups expands the truck, driver, facility and trip a fuel log refers to. the ids stay alongside them.
+func fuelLogLookups() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "trucks",
+			"localField":   "truck_id",
+			"foreignField": "_id",
+			"as":           "truck",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$truck",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "drivers",
+			"localField":   "driver_id",
+			"foreignField": "_id",
+			"as":           "driver",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$driver",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "facilities",
+			"localField":   "facility_id",
+			"foreignField": "_id",
+			"as":           "facility",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$facility",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "trips",
+			"localField":   "trip_id",
+			"foreignField": "_id",
+			"as":           "trip",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$trip",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+	}
+}
@@ -18,9 +18,9 @@ type incidentReportRepository struct {
 
 type IncidentReportRepository interface {
 	Create(ctx context.Context, incidentReport *domain.IncidentReport) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.IncidentReport, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.IncidentReport, error)
 	Update(ctx context.Context, incidentReport *domain.IncidentReport) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.IncidentReportFilter) (*ListIncidentReportsResult, error)
 }
 
@@ -40,17 +40,19 @@ func (r *incidentReportRepository) Create(ctx context.Context, incidentReport *d
 	incidentReport.CreatedAt = primitive.NewDateTimeFromTime(now)
 	incidentReport.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.incidentReports.InsertOne(ctx, incidentReport)
+	result, err := r.incidentReports.InsertOne(ctx, incidentReport)
 	if err != nil {
 		return fmt.Errorf("failed to create incidentReport: %w", err)
 	}
 
+	incidentReport.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *incidentReportRepository) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.IncidentReport, error) {
+func (r *incidentReportRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.IncidentReport, error) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"_id": id, "user_id": userID}}},
+		{{Key: "$match", Value: bson.M{"_id": id, "organization_id": orgID}}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "trips",
 			"localField":   "trip_id",
@@ -110,7 +112,7 @@ func (r *incidentReportRepository) GetById(ctx context.Context, id, userID primi
 }
 
 func (r *incidentReportRepository) Update(ctx context.Context, incidentReport *domain.IncidentReport) error {
-	filter := bson.M{"_id": incidentReport.ID}
+	filter := bson.M{"_id": incidentReport.ID, "organization_id": incidentReport.OrganizationID}
 	update := bson.M{
 		"$set": bson.M{
 			"truck_id":        incidentReport.TruckID,
@@ -136,8 +138,8 @@ func (r *incidentReportRepository) Update(ctx context.Context, incidentReport *d
 	return nil
 }
 
-func (r *incidentReportRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	result, err := r.incidentReports.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+func (r *incidentReportRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.incidentReports.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
 	if err != nil {
 		return fmt.Errorf("failed to delete incidentReport: %w", err)
 	}
@@ -160,7 +162,7 @@ func (r *incidentReportRepository) List(ctx context.Context, filter domain.Incid
 		filter.Offset = 0
 	}
 
-	filterQuery := bson.M{"user_id": filter.UserID}
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
 
 	if filter.TripID != nil {
 		filterQuery["trip_id"] = filter.TripID
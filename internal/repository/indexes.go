@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongo's NamespaceExists error, returned when creating a collection that is already there
+const namespaceExistsCode = 48
+
+// collections that have to be created up front because of how they're stored. truck positions are a time series,
+// which mongo buckets by truck and compresses far better than one document per ping.
+var timeSeriesCollections = map[string]*options.TimeSeriesOptions{
+	"truck_positions": options.TimeSeries().
+		SetTimeField("recorded_at").
+		SetMetaField("truck_id").
+		SetGranularity("seconds"),
+}
+
+type collectionIndexes struct {
+	collection string
+	models     []mongo.IndexModel
+}
+
+// the indexes queries can't run without. geo queries fail outright when their 2dsphere index is missing, the
+// lookups made on every request would scan their whole collection, and the unique ones are what keep two racing
+// writes from both going in. ttl indexes drop tokens and login attempts once they've run out.
+var requiredIndexes = []collectionIndexes{
+	{
+		collection: "users",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+	},
+	{
+		collection: "revoked_tokens",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "jti", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+	{
+		collection: "refresh_tokens",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "family_id", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+	{
+		collection: "api_keys",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+	},
+	{
+		collection: "login_attempts",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+	{
+		collection: "facilities",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "location", Value: "2dsphere"}}},
+		},
+	},
+	{
+		collection: "duty_status_events",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "driver_id", Value: 1}, {Key: "started_at", Value: -1}}},
+		},
+	},
+	{
+		collection: "truck_positions",
+		models: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "truck_id", Value: 1}, {Key: "recorded_at", Value: 1}}},
+		},
+	},
+}
+
+// EnsureIndexes creates any required collection or index that doesn't exist yet. creating an index that is already
+// there is a no-op, so this runs on every start.
+func EnsureIndexes(ctx context.Context, db *database.MongoDB) error {
+	for name, timeSeries := range timeSeriesCollections {
+		err := db.Database.CreateCollection(ctx, name, options.CreateCollection().SetTimeSeriesOptions(timeSeries))
+		var commandErr mongo.CommandError
+		if err != nil && !(errors.As(err, &commandErr) && commandErr.HasErrorCode(namespaceExistsCode)) {
+			return fmt.Errorf("failed to create %s collection: %w", name, err)
+		}
+	}
+
+	for _, indexes := range requiredIndexes {
+		if _, err := db.Database.Collection(indexes.collection).Indexes().CreateMany(ctx, indexes.models); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", indexes.collection, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginAttemptRepository struct {
+	loginAttempts *mongo.Collection
+}
+
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, key string) (*domain.LoginAttempt, error)
+	ListByKeyPrefix(ctx context.Context, prefix string) ([]*domain.LoginAttempt, error)
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*domain.LoginAttempt, error)
+	Block(ctx context.Context, key string, retryAt time.Time, lockedUntil *time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+func NewLoginAttemptRepository(db *database.MongoDB) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		loginAttempts: db.Database.Collection("login_attempts"),
+	}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	err := r.loginAttempts.FindOne(ctx, bson.M{"key": key}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) ListByKeyPrefix(ctx context.Context, prefix string) ([]*domain.LoginAttempt, error) {
+	// anchored, so the unique index on key can serve it
+	filter := bson.M{"key": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+
+	cursor, err := r.loginAttempts.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*domain.LoginAttempt
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, fmt.Errorf("failed to decode login attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RecordFailure counts a failure and returns the updated count. the count starts over when the last failure is
+// older than the window or a lockout has run out. it's a single update, and the key is unique, so that concurrent
+// failures can't be lost.
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*domain.LoginAttempt, error) {
+	startOver := bson.M{"$or": bson.A{
+		bson.M{"$lt": bson.A{"$last_failure_at", now.Add(-window)}},
+		bson.M{"$and": bson.A{
+			bson.M{"$gt": bson.A{"$locked_until", nil}},
+			bson.M{"$lte": bson.A{"$locked_until", now}},
+		}},
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"key":             key,
+			"failures":        bson.M{"$cond": bson.A{startOver, 1, bson.M{"$add": bson.A{"$failures", 1}}}},
+			"locked_until":    bson.M{"$cond": bson.A{startOver, "$$REMOVE", "$locked_until"}},
+			"last_failure_at": now,
+			"expires_at":      bson.M{"$max": bson.A{now.Add(window), "$expires_at"}},
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempt domain.LoginAttempt
+	if err := r.loginAttempts.FindOneAndUpdate(ctx, bson.M{"key": key}, pipeline, opts).Decode(&attempt); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) Block(ctx context.Context, key string, retryAt time.Time, lockedUntil *time.Time) error {
+	set := bson.M{"retry_at": retryAt}
+	expiresAt := bson.A{"$expires_at", retryAt}
+	if lockedUntil != nil {
+		set["locked_until"] = *lockedUntil
+		expiresAt = append(expiresAt, *lockedUntil)
+	}
+	set["expires_at"] = bson.M{"$max": expiresAt}
+
+	update := mongo.Pipeline{{{Key: "$set", Value: set}}}
+	if _, err := r.loginAttempts.UpdateOne(ctx, bson.M{"key": key}, update); err != nil {
+		return fmt.Errorf("failed to block login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) Delete(ctx context.Context, key string) error {
+	if _, err := r.loginAttempts.DeleteOne(ctx, bson.M{"key": key}); err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+
+	return nil
+}
@@ -18,10 +18,11 @@ type maintenanceLogRepository struct {
 
 type MaintenanceLogRepository interface {
 	Create(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.MaintenanceLog, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceLog, error)
 	Update(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.MaintenanceLogFilter) (*ListMaintenanceLogsResult, error)
+	ListLatestBySchedule(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.MaintenanceLog, error)
 }
 
 type ListMaintenanceLogsResult struct {
@@ -40,17 +41,19 @@ func (r *maintenanceLogRepository) Create(ctx context.Context, maintenanceLog *d
 	maintenanceLog.CreatedAt = primitive.NewDateTimeFromTime(now)
 	maintenanceLog.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.maintenanceLogs.InsertOne(ctx, maintenanceLog)
+	result, err := r.maintenanceLogs.InsertOne(ctx, maintenanceLog)
 	if err != nil {
 		return fmt.Errorf("failed to create maintenance log %w", err)
 	}
 
+	maintenanceLog.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *maintenanceLogRepository) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.MaintenanceLog, error) {
+func (r *maintenanceLogRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceLog, error) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"_id": id, "user_id": userID}}},
+		{{Key: "$match", Value: bson.M{"_id": id, "organization_id": orgID}}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "trucks",
 			"localField":   "truck_id",
@@ -87,11 +90,13 @@ func (r *maintenanceLogRepository) GetById(ctx context.Context, id, userID primi
 }
 
 func (r *maintenanceLogRepository) Update(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error {
-	filter := bson.M{"_id": maintenanceLog.ID}
+	filter := bson.M{"_id": maintenanceLog.ID, "organization_id": maintenanceLog.OrganizationID}
 	update := bson.M{
 		"$set": bson.M{
 			"truck_id":     maintenanceLog.TruckID,
+			"schedule_id":  maintenanceLog.ScheduleID,
 			"date":         maintenanceLog.Date,
+			"mileage":      maintenanceLog.Mileage,
 			"service_type": maintenanceLog.ServiceType,
 			"cost":         maintenanceLog.Cost,
 			"notes":        maintenanceLog.Notes,
@@ -113,8 +118,8 @@ func (r *maintenanceLogRepository) Update(ctx context.Context, maintenanceLog *d
 	return nil
 }
 
-func (r *maintenanceLogRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	result, err := r.maintenanceLogs.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+func (r *maintenanceLogRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.maintenanceLogs.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
 	if err != nil {
 		return fmt.Errorf("failed to delete maintenance log %w", err)
 	}
@@ -137,12 +142,16 @@ func (r *maintenanceLogRepository) List(ctx context.Context, filter domain.Maint
 		filter.Offset = 0
 	}
 
-	filterQuery := bson.M{"user_id": filter.UserID}
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
 
 	if filter.TruckID != nil {
 		filterQuery["truck_id"] = filter.TruckID
 	}
 
+	if filter.ScheduleID != nil {
+		filterQuery["schedule_id"] = filter.ScheduleID
+	}
+
 	if filter.ServiceType != "" {
 		filterQuery["service_type"] = filter.ServiceType
 	}
@@ -188,3 +197,38 @@ func (r *maintenanceLogRepository) List(ctx context.Context, filter domain.Maint
 		Total:           total,
 	}, nil
 }
+
+// ListLatestBySchedule returns the most recent log of each scheduled service on each truck, optionally for just one
+// truck. the truck id is left in place so the logs can be matched back to their trucks.
+func (r *maintenanceLogRepository) ListLatestBySchedule(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.MaintenanceLog, error) {
+	match := bson.M{
+		"organization_id": orgID,
+		"schedule_id":     bson.M{"$ne": nil},
+	}
+	if truckID != nil {
+		match["truck_id"] = truckID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"schedule_id": "$schedule_id", "truck_id": "$truck_id"},
+			"log": bson.M{"$first": "$$ROOT"},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$log"}}},
+	}
+
+	cursor, err := r.maintenanceLogs.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	maintenanceLogs := make([]*domain.MaintenanceLog, 0)
+	if err := cursor.All(ctx, &maintenanceLogs); err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance logs: %w", err)
+	}
+
+	return maintenanceLogs, nil
+}
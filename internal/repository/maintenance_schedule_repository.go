@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type maintenanceScheduleRepository struct {
+	schedules *mongo.Collection
+}
+
+type MaintenanceScheduleRepository interface {
+	Create(ctx context.Context, schedule *domain.MaintenanceSchedule) error
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceSchedule, error)
+	Update(ctx context.Context, schedule *domain.MaintenanceSchedule) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
+	List(ctx context.Context, filter domain.MaintenanceScheduleFilter) (*ListMaintenanceSchedulesResult, error)
+	ListAll(ctx context.Context, orgID primitive.ObjectID) ([]*domain.MaintenanceSchedule, error)
+}
+
+type ListMaintenanceSchedulesResult struct {
+	Schedules []*domain.MaintenanceSchedule
+	Total     int64
+}
+
+func NewMaintenanceScheduleRepository(db *database.MongoDB) MaintenanceScheduleRepository {
+	return &maintenanceScheduleRepository{
+		schedules: db.Database.Collection("maintenance_schedules"),
+	}
+}
+
+func (r *maintenanceScheduleRepository) Create(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	now := time.Now()
+	schedule.CreatedAt = primitive.NewDateTimeFromTime(now)
+	schedule.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	result, err := r.schedules.InsertOne(ctx, schedule)
+	if err != nil {
+		return fmt.Errorf("failed to create maintenance schedule: %w", err)
+	}
+
+	schedule.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *maintenanceScheduleRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceSchedule, error) {
+	var schedule domain.MaintenanceSchedule
+	err := r.schedules.FindOne(ctx, bson.M{"_id": id, "organization_id": orgID}).Decode(&schedule)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance schedule: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+func (r *maintenanceScheduleRepository) Update(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	filter := bson.M{"_id": schedule.ID, "organization_id": schedule.OrganizationID}
+	set := bson.M{
+		"name":            schedule.Name,
+		"safety_critical": schedule.SafetyCritical,
+		"baselines":       schedule.Baselines,
+		"updated_at":      primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	// the optional fields are removed when they're cleared, so narrowing a schedule from a make to one truck
+	// doesn't leave the make behind
+	unset := bson.M{}
+	if schedule.Description != "" {
+		set["description"] = schedule.Description
+	} else {
+		unset["description"] = ""
+	}
+	if schedule.IntervalMiles > 0 {
+		set["interval_miles"] = schedule.IntervalMiles
+	} else {
+		unset["interval_miles"] = ""
+	}
+	if schedule.IntervalDays > 0 {
+		set["interval_days"] = schedule.IntervalDays
+	} else {
+		unset["interval_days"] = ""
+	}
+	if schedule.TruckID != nil {
+		set["truck_id"] = schedule.TruckID
+	} else {
+		unset["truck_id"] = ""
+	}
+	if schedule.Make != "" {
+		set["make"] = schedule.Make
+	} else {
+		unset["make"] = ""
+	}
+	if schedule.Model != "" {
+		set["model"] = schedule.Model
+	} else {
+		unset["model"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := r.schedules.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance schedule: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrMaintenanceScheduleNotFound
+	}
+
+	return nil
+}
+
+func (r *maintenanceScheduleRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.schedules.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance schedule: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrMaintenanceScheduleNotFound
+	}
+
+	return nil
+}
+
+func (r *maintenanceScheduleRepository) List(ctx context.Context, filter domain.MaintenanceScheduleFilter) (*ListMaintenanceSchedulesResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
+
+	if filter.TruckID != nil {
+		filterQuery["truck_id"] = filter.TruckID
+	}
+	if filter.Make != "" {
+		filterQuery["make"] = filter.Make
+	}
+
+	total, err := r.schedules.CountDocuments(ctx, filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.schedules.Find(ctx, filterQuery, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find maintenance schedules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	schedules := make([]*domain.MaintenanceSchedule, 0, filter.Limit)
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance schedules: %w", err)
+	}
+
+	return &ListMaintenanceSchedulesResult{
+		Schedules: schedules,
+		Total:     total,
+	}, nil
+}
+
+// ListAll returns every schedule in the organization, for working out what is due across the fleet
+func (r *maintenanceScheduleRepository) ListAll(ctx context.Context, orgID primitive.ObjectID) ([]*domain.MaintenanceSchedule, error) {
+	cursor, err := r.schedules.Find(ctx, bson.M{"organization_id": orgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find maintenance schedules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	schedules := make([]*domain.MaintenanceSchedule, 0)
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance schedules: %w", err)
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type membershipRepository struct {
+	memberships *mongo.Collection
+}
+
+type MembershipRepository interface {
+	Create(ctx context.Context, membership *domain.Membership) error
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Membership, error)
+	GetByUserAndOrganization(ctx context.Context, userID, orgID primitive.ObjectID) (*domain.Membership, error)
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]*domain.Membership, error)
+	ListByOrganization(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Membership, error)
+	CountByRole(ctx context.Context, orgID primitive.ObjectID, role domain.Role) (int64, error)
+	UpdateRole(ctx context.Context, id, orgID primitive.ObjectID, role domain.Role) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
+}
+
+func NewMembershipRepository(db *database.MongoDB) MembershipRepository {
+	return &membershipRepository{
+		memberships: db.Database.Collection("memberships"),
+	}
+}
+
+func (r *membershipRepository) Create(ctx context.Context, membership *domain.Membership) error {
+	now := time.Now()
+	membership.CreatedAt = primitive.NewDateTimeFromTime(now)
+	membership.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	result, err := r.memberships.InsertOne(ctx, membership)
+	if err != nil {
+		return fmt.Errorf("failed to create membership: %w", err)
+	}
+
+	membership.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *membershipRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Membership, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+}
+
+func (r *membershipRepository) GetByUserAndOrganization(ctx context.Context, userID, orgID primitive.ObjectID) (*domain.Membership, error) {
+	return r.findOne(ctx, bson.M{"user_id": userID, "organization_id": orgID})
+}
+
+func (r *membershipRepository) findOne(ctx context.Context, filter bson.M) (*domain.Membership, error) {
+	var membership domain.Membership
+	err := r.memberships.FindOne(ctx, filter).Decode(&membership)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find membership: %w", err)
+	}
+
+	return &membership, nil
+}
+
+// ListByUser returns the user's memberships oldest first, each with its organization expanded
+func (r *membershipRepository) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]*domain.Membership, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "organizations",
+			"localField":   "organization_id",
+			"foreignField": "_id",
+			"as":           "organization",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$organization",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+	}
+
+	return r.aggregate(ctx, pipeline)
+}
+
+// ListByOrganization returns the organization's members oldest first, each with its user expanded
+func (r *membershipRepository) ListByOrganization(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Membership, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"organization_id": orgID}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$user",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"user.password": 0,
+		}}},
+	}
+
+	return r.aggregate(ctx, pipeline)
+}
+
+func (r *membershipRepository) aggregate(ctx context.Context, pipeline mongo.Pipeline) ([]*domain.Membership, error) {
+	cursor, err := r.memberships.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	memberships := make([]*domain.Membership, 0)
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, fmt.Errorf("failed to decode memberships: %w", err)
+	}
+
+	return memberships, nil
+}
+
+func (r *membershipRepository) CountByRole(ctx context.Context, orgID primitive.ObjectID, role domain.Role) (int64, error) {
+	count, err := r.memberships.CountDocuments(ctx, bson.M{"organization_id": orgID, "role": role})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count memberships: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *membershipRepository) UpdateRole(ctx context.Context, id, orgID primitive.ObjectID, role domain.Role) error {
+	filter := bson.M{"_id": id, "organization_id": orgID}
+	update := bson.M{
+		"$set": bson.M{
+			"role":       role,
+			"updated_at": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+
+	result, err := r.memberships.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update membership: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrMembershipNotFound
+	}
+
+	return nil
+}
+
+func (r *membershipRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.memberships.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+	if err != nil {
+		return fmt.Errorf("failed to delete membership: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrMembershipNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAPIKeyRepository(store *MemoryStore) APIKeyRepository {
+	return &memoryAPIKeyRepository{
+		store: store,
+	}
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	key.CreatedAt = primitive.NewDateTimeFromTime(now)
+	key.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&key.ID)
+
+	if err := r.store.put("api_keys", key.ID, key); err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	key, err := memoryGet[domain.APIKey](r.store, "api_keys", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	if key == nil || key.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	return key, nil
+}
+
+func (r *memoryAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	keys, err := memoryAll[domain.APIKey](r.store, "api_keys")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+
+	for _, key := range keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *memoryAPIKeyRepository) List(ctx context.Context, filter domain.APIKeyFilter) (*ListAPIKeysResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.APIKey](r.store, "api_keys")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %w", err)
+	}
+
+	matched := make([]*domain.APIKey, 0, len(all))
+	for _, key := range all {
+		if key.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.UserID != nil && key.UserID != *filter.UserID {
+			continue
+		}
+		matched = append(matched, key)
+	}
+
+	return &ListAPIKeysResult{
+		APIKeys: paginate(matched, filter.Limit, filter.Offset),
+		Total:   int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, err := memoryGet[domain.APIKey](r.store, "api_keys", id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if key == nil || key.OrganizationID != orgID || key.RevokedAt != nil {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	key.RevokedAt = &now
+	key.UpdatedAt = now
+
+	if err := r.store.put("api_keys", key.ID, key); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, err := memoryGet[domain.APIKey](r.store, "api_keys", id)
+	if err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+	if key == nil {
+		return nil
+	}
+
+	lastUsed := primitive.NewDateTimeFromTime(usedAt)
+	key.LastUsedAt = &lastUsed
+
+	if err := r.store.put("api_keys", key.ID, key); err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+
+	return nil
+}
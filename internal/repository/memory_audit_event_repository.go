@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/domain"
+)
+
+type memoryAuditEventRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAuditEventRepository(store *MemoryStore) AuditEventRepository {
+	return &memoryAuditEventRepository{
+		store: store,
+	}
+}
+
+func (r *memoryAuditEventRepository) Create(ctx context.Context, event *domain.AuditEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	newObjectIDIfMissing(&event.ID)
+
+	if err := r.store.put("audit_events", event.ID, event); err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryAuditEventRepository) List(ctx context.Context, filter domain.AuditEventFilter) (*ListAuditEventsResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.AuditEvent](r.store, "audit_events")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audit events: %w", err)
+	}
+
+	matched := make([]*domain.AuditEvent, 0, len(all))
+	for _, event := range all {
+		if event.OrganizationID != filter.OrganizationID || event.EntityType != filter.EntityType || event.EntityID != filter.EntityID {
+			continue
+		}
+		matched = append(matched, event)
+	}
+
+	return &ListAuditEventsResult{
+		AuditEvents: paginate(matched, filter.Limit, filter.Offset),
+		Total:       int64(len(matched)),
+	}, nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryDriverRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDriverRepository(store *MemoryStore) DriverRepository {
+	return &memoryDriverRepository{
+		store: store,
+	}
+}
+
+func (r *memoryDriverRepository) Create(ctx context.Context, driver *domain.Driver) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	driver.CreatedAt = primitive.NewDateTimeFromTime(now)
+	driver.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&driver.ID)
+
+	if err := r.store.put("drivers", driver.ID, driver); err != nil {
+		return fmt.Errorf("failed to create driver: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryDriverRepository) GetById(ctx context.Context, id primitive.ObjectID, orgID primitive.ObjectID) (*domain.Driver, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	driver, err := memoryGet[domain.Driver](r.store, "drivers", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode driver: %w", err)
+	}
+	if driver == nil || driver.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	return driver, nil
+}
+
+func (r *memoryDriverRepository) Update(ctx context.Context, driver *domain.Driver) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.Driver](r.store, "drivers", driver.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update driver: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != driver.OrganizationID {
+		return fmt.Errorf("driver not found")
+	}
+
+	existing.FirstName = driver.FirstName
+	existing.LastName = driver.LastName
+	existing.DOB = driver.DOB
+	existing.LicenseNumber = driver.LicenseNumber
+	existing.LicenseState = driver.LicenseState
+	existing.LicenseExpiration = driver.LicenseExpiration
+	existing.Credentials = driver.Credentials
+	existing.Phone = driver.Phone
+	existing.Email = driver.Email
+	existing.Address = driver.Address
+	existing.EmploymentStatus = driver.EmploymentStatus
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("drivers", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update driver: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryDriverRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	driver, err := memoryGet[domain.Driver](r.store, "drivers", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete driver: %w", err)
+	}
+	if driver == nil || driver.OrganizationID != orgID {
+		return domain.ErrDriverNotFound
+	}
+
+	r.store.remove("drivers", id)
+
+	return nil
+}
+
+func (r *memoryDriverRepository) List(ctx context.Context, filter domain.DriverFilter) (*ListDriversResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Driver](r.store, "drivers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode drivers: %w", err)
+	}
+
+	matched := make([]*domain.Driver, 0, len(all))
+	for _, driver := range all {
+		if driver.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.LicenseState != "" && driver.LicenseState != filter.LicenseState {
+			continue
+		}
+		if filter.Phone != "" && driver.Phone != filter.Phone {
+			continue
+		}
+		if filter.Email != "" && driver.Email != filter.Email {
+			continue
+		}
+		if filter.EmploymentStatus != "" && driver.EmploymentStatus != filter.EmploymentStatus {
+			continue
+		}
+		matched = append(matched, driver)
+	}
+
+	return &ListDriversResult{
+		Drivers: paginate(matched, filter.Limit, filter.Offset),
+		Total:   int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryDriverRepository) UpdateEmploymentStatus(ctx context.Context, id, orgID primitive.ObjectID, status domain.EmploymentStatus) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	driver, err := memoryGet[domain.Driver](r.store, "drivers", id)
+	if err != nil {
+		return fmt.Errorf("failed to update driver employment status: %w", err)
+	}
+	if driver == nil || driver.OrganizationID != orgID {
+		return domain.ErrDriverNotFound
+	}
+
+	driver.EmploymentStatus = status
+	driver.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("drivers", driver.ID, driver); err != nil {
+		return fmt.Errorf("failed to update driver employment status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryDriverRepository) ListExpiring(ctx context.Context, orgID primitive.ObjectID, before primitive.DateTime) ([]*domain.Driver, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Driver](r.store, "drivers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode drivers: %w", err)
+	}
+
+	drivers := make([]*domain.Driver, 0)
+	for _, driver := range all {
+		if driver.OrganizationID != orgID || driver.EmploymentStatus == domain.EmploymentStatusTerminated {
+			continue
+		}
+
+		expiring := driver.LicenseExpiration != 0 && driver.LicenseExpiration < before
+		for _, credential := range driver.Credentials {
+			expiring = expiring || credential.ExpiresOn < before
+		}
+		if expiring {
+			drivers = append(drivers, driver)
+		}
+	}
+
+	return drivers, nil
+}
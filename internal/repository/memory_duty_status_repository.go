@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryDutyStatusRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDutyStatusRepository(store *MemoryStore) DutyStatusRepository {
+	return &memoryDutyStatusRepository{
+		store: store,
+	}
+}
+
+func (r *memoryDutyStatusRepository) Create(ctx context.Context, event *domain.DutyStatusEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	newObjectIDIfMissing(&event.ID)
+
+	if err := r.store.put("duty_status_events", event.ID, event); err != nil {
+		return fmt.Errorf("failed to create duty status event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryDutyStatusRepository) Latest(ctx context.Context, orgID, driverID primitive.ObjectID) (*domain.DutyStatusEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	events, err := r.driverLog(orgID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	return events[len(events)-1], nil
+}
+
+func (r *memoryDutyStatusRepository) List(ctx context.Context, filter domain.DutyStatusFilter) (*ListDutyStatusEventsResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	events, err := r.driverLog(filter.OrganizationID, filter.DriverID)
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first, the same as the mongo query
+	matched := make([]*domain.DutyStatusEvent, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if filter.From != nil && event.StartedAt < *filter.From {
+			continue
+		}
+		if filter.To != nil && event.StartedAt > *filter.To {
+			continue
+		}
+		matched = append(matched, event)
+	}
+
+	return &ListDutyStatusEventsResult{
+		Events: paginate(matched, filter.Limit, filter.Offset),
+		Total:  int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryDutyStatusRepository) ListSince(ctx context.Context, orgID, driverID primitive.ObjectID, since primitive.DateTime) ([]*domain.DutyStatusEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	events, err := r.driverLog(orgID, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	first := sort.Search(len(events), func(i int) bool {
+		return events[i].StartedAt >= since
+	})
+	if first > 0 {
+		first--
+	}
+
+	return events[first:], nil
+}
+
+// driverLog is the driver's whole log, oldest first
+func (r *memoryDutyStatusRepository) driverLog(orgID, driverID primitive.ObjectID) ([]*domain.DutyStatusEvent, error) {
+	all, err := memoryAll[domain.DutyStatusEvent](r.store, "duty_status_events")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode duty status events: %w", err)
+	}
+
+	events := make([]*domain.DutyStatusEvent, 0)
+	for _, event := range all {
+		if event.OrganizationID == orgID && event.DriverID == driverID {
+			events = append(events, event)
+		}
+	}
+
+	// events that start together stay in the order they were recorded, as they do sorting on started_at then _id
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].StartedAt != events[j].StartedAt {
+			return events[i].StartedAt < events[j].StartedAt
+		}
+		return bytes.Compare(events[i].ID[:], events[j].ID[:]) < 0
+	})
+
+	return events, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryEmailTokenRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryEmailTokenRepository(store *MemoryStore) EmailTokenRepository {
+	return &memoryEmailTokenRepository{
+		store: store,
+	}
+}
+
+func (r *memoryEmailTokenRepository) Create(ctx context.Context, token *domain.EmailToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	newObjectIDIfMissing(&token.ID)
+
+	if err := r.store.put("email_tokens", token.ID, token); err != nil {
+		return fmt.Errorf("failed to create email token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryEmailTokenRepository) Consume(ctx context.Context, id primitive.ObjectID, purpose domain.EmailTokenPurpose) (*domain.EmailToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, err := memoryGet[domain.EmailToken](r.store, "email_tokens", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume email token: %w", err)
+	}
+
+	now := time.Now()
+	if token == nil || token.Purpose != purpose || !token.IsUsable(now) {
+		return nil, domain.ErrEmailTokenNotFound
+	}
+
+	usedAt := primitive.NewDateTimeFromTime(now)
+	token.UsedAt = &usedAt
+
+	if err := r.store.put("email_tokens", token.ID, token); err != nil {
+		return nil, fmt.Errorf("failed to consume email token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (r *memoryEmailTokenRepository) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, purpose domain.EmailTokenPurpose) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tokens, err := memoryAll[domain.EmailToken](r.store, "email_tokens")
+	if err != nil {
+		return fmt.Errorf("failed to invalidate email tokens: %w", err)
+	}
+
+	usedAt := primitive.NewDateTimeFromTime(time.Now())
+	for _, token := range tokens {
+		if token.UserID != userID || token.Purpose != purpose || token.UsedAt != nil {
+			continue
+		}
+
+		token.UsedAt = &usedAt
+		if err := r.store.put("email_tokens", token.ID, token); err != nil {
+			return fmt.Errorf("failed to invalidate email tokens: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryFacilityRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryFacilityRepository(store *MemoryStore) FacilityRepository {
+	return &memoryFacilityRepository{
+		store: store,
+	}
+}
+
+func (r *memoryFacilityRepository) Create(ctx context.Context, facility *domain.Facility) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	facility.CreatedAt = primitive.NewDateTimeFromTime(now)
+	facility.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&facility.ID)
+
+	if err := r.store.put("facilities", facility.ID, facility); err != nil {
+		return fmt.Errorf("failed to create facility: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryFacilityRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Facility, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	facility, err := memoryGet[domain.Facility](r.store, "facilities", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get facility: %w", err)
+	}
+	if facility == nil || facility.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	return facility, nil
+}
+
+func (r *memoryFacilityRepository) Update(ctx context.Context, facility *domain.Facility) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.Facility](r.store, "facilities", facility.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update facility: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != facility.OrganizationID {
+		return fmt.Errorf("facility not found")
+	}
+
+	existing.FacilityNumber = facility.FacilityNumber
+	existing.Name = facility.Name
+	existing.Type = facility.Type
+	existing.Address = facility.Address
+	existing.Location = facility.Location
+	existing.Geofence = facility.Geofence
+	existing.ContactInfo = facility.ContactInfo
+	existing.ParkingCapacity = facility.ParkingCapacity
+	existing.ServicesAvailable = facility.ServicesAvailable
+	existing.AcceptsHazmat = facility.AcceptsHazmat
+	existing.HazardClasses = facility.HazardClasses
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("facilities", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update facility: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryFacilityRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	facility, err := memoryGet[domain.Facility](r.store, "facilities", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete facility: %w", err)
+	}
+	if facility == nil || facility.OrganizationID != orgID {
+		return domain.ErrFacilityNotFound
+	}
+
+	r.store.remove("facilities", id)
+
+	return nil
+}
+
+func (r *memoryFacilityRepository) ListWithFilter(ctx context.Context, filter domain.FacilityFilter) (*ListFacilitiesResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Facility](r.store, "facilities")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode facilities: %w", err)
+	}
+
+	matched := make([]*domain.Facility, 0, len(all))
+	for _, facility := range all {
+		if filter.OrganizationID != primitive.NilObjectID && facility.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.StateCode != "" && facility.Address.State != filter.StateCode {
+			continue
+		}
+		if filter.Type != "" && facility.Type != filter.Type {
+			continue
+		}
+		if !containsAllServices(facility.ServicesAvailable, filter.ServicesInclude) {
+			continue
+		}
+		if filter.MinCapacity != nil && facility.ParkingCapacity < *filter.MinCapacity {
+			continue
+		}
+		if filter.MaxCapacity != nil && facility.ParkingCapacity > *filter.MaxCapacity {
+			continue
+		}
+		if filter.Near != nil {
+			if facility.Location == nil {
+				continue
+			}
+			distance := filter.Near.DistanceMiles(facility.Location)
+			if filter.RadiusMiles > 0 && distance > filter.RadiusMiles {
+				continue
+			}
+			facility.DistanceMiles = &distance
+		}
+		matched = append(matched, facility)
+	}
+
+	if filter.Near != nil {
+		sort.SliceStable(matched, func(i, j int) bool {
+			return *matched[i].DistanceMiles < *matched[j].DistanceMiles
+		})
+	}
+
+	return &ListFacilitiesResult{
+		Facilities: paginate(matched, filter.Limit, filter.Offset),
+		Total:      int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryFacilityRepository) UpdateAvailableFacilityServices(ctx context.Context, id, orgID primitive.ObjectID, servicesAvailable []domain.FacilityService) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	facility, err := memoryGet[domain.Facility](r.store, "facilities", id)
+	if err != nil {
+		return fmt.Errorf("failed to update facility services: %w", err)
+	}
+	if facility == nil || facility.OrganizationID != orgID {
+		return domain.ErrFacilityNotFound
+	}
+
+	facility.ServicesAvailable = servicesAvailable
+	facility.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("facilities", facility.ID, facility); err != nil {
+		return fmt.Errorf("failed to update facility services: %w", err)
+	}
+
+	return nil
+}
+
+// containsAllServices mirrors the $all operator used by the mongo facility filter
+func containsAllServices(available, required []domain.FacilityService) bool {
+	for _, want := range required {
+		found := false
+		for _, have := range available {
+			if have == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryFuelAnalyticsRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryFuelAnalyticsRepository(store *MemoryStore) FuelAnalyticsRepository {
+	return &memoryFuelAnalyticsRepository{
+		store: store,
+	}
+}
+
+func (r *memoryFuelAnalyticsRepository) Efficiency(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelEfficiency, error) {
+	if !filter.GroupBy.IsValid() {
+		return nil, fmt.Errorf("invalid grouping: %s", filter.GroupBy)
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	trips, err := memoryAll[domain.Trip](r.store, "trips")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	fuelLogsByTrip, err := r.fuelLogsByTrip()
+	if err != nil {
+		return nil, err
+	}
+
+	// the same key the mongo $group uses: one id for most groupings, both facilities for a lane, or a truck field
+	type groupKey struct {
+		id, endID primitive.ObjectID
+		value     string
+	}
+
+	groups := make(map[groupKey]*domain.FuelEfficiency)
+	order := make([]groupKey, 0)
+	for _, trip := range trips {
+		if trip.OrganizationID != filter.OrganizationID || trip.Status != domain.TripStatusCompleted || trip.DistanceMiles <= 0 {
+			continue
+		}
+		if trip.ArrivalTime.Actual == nil || *trip.ArrivalTime.Actual < filter.From || *trip.ArrivalTime.Actual >= filter.To {
+			continue
+		}
+
+		var key groupKey
+		var group domain.FuelEfficiency
+
+		switch filter.GroupBy {
+		case domain.FuelGroupingTruck, domain.FuelGroupingFuelType, domain.FuelGroupingTrailerType:
+			truck, err := memoryLookup[domain.Truck](r.store, "trucks", trip.TruckID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up trip truck: %w", err)
+			}
+			if truck == nil {
+				continue
+			}
+
+			switch filter.GroupBy {
+			case domain.FuelGroupingTruck:
+				key = groupKey{id: truck.ID}
+				group = domain.FuelEfficiency{TruckID: &truck.ID, TruckNumber: truck.TruckNumber}
+			case domain.FuelGroupingFuelType:
+				key = groupKey{value: string(truck.FuelType)}
+				group = domain.FuelEfficiency{FuelType: truck.FuelType}
+			default:
+				key = groupKey{value: string(truck.TrailerType)}
+				group = domain.FuelEfficiency{TrailerType: truck.TrailerType}
+			}
+		case domain.FuelGroupingDriver:
+			if trip.DriverID == nil {
+				continue
+			}
+			driver, err := memoryLookup[domain.Driver](r.store, "drivers", trip.DriverID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up trip driver: %w", err)
+			}
+
+			key = groupKey{id: *trip.DriverID}
+			group = domain.FuelEfficiency{DriverID: trip.DriverID}
+			if driver != nil {
+				group.DriverName = driver.FirstName + " " + driver.LastName
+			}
+		case domain.FuelGroupingLane:
+			if trip.StartFacilityID == nil || trip.EndFacilityID == nil {
+				continue
+			}
+			start, err := memoryLookup[domain.Facility](r.store, "facilities", trip.StartFacilityID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up trip start facility: %w", err)
+			}
+			end, err := memoryLookup[domain.Facility](r.store, "facilities", trip.EndFacilityID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up trip end facility: %w", err)
+			}
+
+			key = groupKey{id: *trip.StartFacilityID, endID: *trip.EndFacilityID}
+			group = domain.FuelEfficiency{StartFacilityID: trip.StartFacilityID, EndFacilityID: trip.EndFacilityID}
+			if start != nil {
+				group.StartFacilityName = start.Name
+			}
+			if end != nil {
+				group.EndFacilityName = end.Name
+			}
+		}
+
+		if _, seen := groups[key]; !seen {
+			groups[key] = &group
+			order = append(order, key)
+		}
+
+		gallons, cost := domain.TripFuel(trip, fuelLogsByTrip[trip.ID])
+		groups[key].AddTrip(trip.DistanceMiles, gallons, cost)
+	}
+
+	efficiencies := make([]domain.FuelEfficiency, 0, len(order))
+	for _, key := range order {
+		groups[key].ComputeRatios()
+		efficiencies = append(efficiencies, *groups[key])
+	}
+	domain.SortFuelEfficiencies(efficiencies)
+
+	return efficiencies, nil
+}
+
+func (r *memoryFuelAnalyticsRepository) ListFillUps(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]*domain.FuelFillUp, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	fuelLogs, err := memoryAll[domain.FuelLog](r.store, "fuel_logs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fuel logs: %w", err)
+	}
+
+	// oldest first, as the mongo $setWindowFields sorts each truck's fill-ups
+	sort.SliceStable(fuelLogs, func(i, j int) bool {
+		if fuelLogs[i].Date != fuelLogs[j].Date {
+			return fuelLogs[i].Date < fuelLogs[j].Date
+		}
+		return fuelLogs[i].CreatedAt < fuelLogs[j].CreatedAt
+	})
+
+	previousReadings := make(map[primitive.ObjectID]int)
+	fillUps := make([]*domain.FuelFillUp, 0)
+	for _, fuelLog := range fuelLogs {
+		if fuelLog.Date >= filter.To {
+			continue
+		}
+		if fuelLog.OrganizationID != filter.OrganizationID {
+			continue
+		}
+
+		truckID := fuelLog.TruckID
+		previous, seen := previousReadings[truckID]
+		previousReadings[truckID] = fuelLog.OdometerReading
+
+		if fuelLog.Date < filter.From {
+			continue
+		}
+
+		truck, err := memoryGet[domain.Truck](r.store, "trucks", truckID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up fuel log truck: %w", err)
+		}
+
+		fillUp := &domain.FuelFillUp{
+			FuelLogID:        fuelLog.ID,
+			TripID:           fuelLog.TripID,
+			TruckID:          truckID,
+			Date:             fuelLog.Date,
+			Location:         fuelLog.Location,
+			GallonsPurchased: fuelLog.GallonsPurchased,
+			PricePerGallon:   fuelLog.PricePerGallon,
+			TotalCost:        fuelLog.TotalCost,
+			OdometerReading:  fuelLog.OdometerReading,
+		}
+		if truck != nil {
+			fillUp.TruckNumber = truck.TruckNumber
+		}
+		if seen {
+			fillUp.PreviousOdometerReading = &previous
+		}
+		fillUps = append(fillUps, fillUp)
+	}
+
+	return fillUps, nil
+}
+
+func (r *memoryFuelAnalyticsRepository) fuelLogsByTrip() (map[primitive.ObjectID][]*domain.FuelLog, error) {
+	fuelLogs, err := memoryAll[domain.FuelLog](r.store, "fuel_logs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fuel logs: %w", err)
+	}
+
+	byTrip := make(map[primitive.ObjectID][]*domain.FuelLog)
+	for _, fuelLog := range fuelLogs {
+		if fuelLog.TripID != nil {
+			byTrip[*fuelLog.TripID] = append(byTrip[*fuelLog.TripID], fuelLog)
+		}
+	}
+
+	return byTrip, nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryFuelLogRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryFuelLogRepository(store *MemoryStore) FuelLogRepository {
+	return &memoryFuelLogRepository{
+		store: store,
+	}
+}
+
+func (r *memoryFuelLogRepository) Create(ctx context.Context, fuelLog *domain.FuelLog) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	fuelLog.CreatedAt = primitive.NewDateTimeFromTime(now)
+	fuelLog.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&fuelLog.ID)
+
+	if err := r.store.put("fuel_logs", fuelLog.ID, fuelLog); err != nil {
+		return fmt.Errorf("failed to create fuelLog: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryFuelLogRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.FuelLog, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	fuelLog, err := memoryGet[domain.FuelLog](r.store, "fuel_logs", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fuel log: %w", err)
+	}
+	if fuelLog == nil || fuelLog.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	if err := r.expand(fuelLog); err != nil {
+		return nil, err
+	}
+
+	return fuelLog, nil
+}
+
+func (r *memoryFuelLogRepository) Update(ctx context.Context, fuelLog *domain.FuelLog) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.FuelLog](r.store, "fuel_logs", fuelLog.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update fuel log: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != fuelLog.OrganizationID {
+		return domain.ErrFuelLogNotFound
+	}
+
+	existing.TruckID = fuelLog.TruckID
+	existing.DriverID = fuelLog.DriverID
+	existing.FacilityID = fuelLog.FacilityID
+	existing.TripID = fuelLog.TripID
+	existing.Date = fuelLog.Date
+	existing.GallonsPurchased = fuelLog.GallonsPurchased
+	existing.PricePerGallon = fuelLog.PricePerGallon
+	existing.TotalCost = fuelLog.TotalCost
+	existing.Location = fuelLog.Location
+	existing.OdometerReading = fuelLog.OdometerReading
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("fuel_logs", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update fuel log: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryFuelLogRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	fuelLog, err := memoryGet[domain.FuelLog](r.store, "fuel_logs", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete fuel log: %w", err)
+	}
+	if fuelLog == nil || fuelLog.OrganizationID != orgID {
+		return domain.ErrFuelLogNotFound
+	}
+
+	r.store.remove("fuel_logs", id)
+
+	return nil
+}
+
+func (r *memoryFuelLogRepository) List(ctx context.Context, filter domain.FuelLogFilter) (*ListFuelLogsResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.FuelLog](r.store, "fuel_logs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fuel logs: %w", err)
+	}
+
+	matched := make([]*domain.FuelLog, 0, len(all))
+	location := strings.ToLower(filter.Location)
+	for _, fuelLog := range all {
+		if fuelLog.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.TripID != nil && !sameObjectID(fuelLog.TripID, filter.TripID) {
+			continue
+		}
+		if filter.TruckID != nil && fuelLog.TruckID != *filter.TruckID {
+			continue
+		}
+		if filter.DriverID != nil && !sameObjectID(fuelLog.DriverID, filter.DriverID) {
+			continue
+		}
+		if filter.FacilityID != nil && !sameObjectID(fuelLog.FacilityID, filter.FacilityID) {
+			continue
+		}
+		if filter.From != nil && fuelLog.Date < *filter.From {
+			continue
+		}
+		if filter.To != nil && fuelLog.Date >= *filter.To {
+			continue
+		}
+		if location != "" && !strings.Contains(strings.ToLower(fuelLog.Location), location) {
+			continue
+		}
+		matched = append(matched, fuelLog)
+	}
+
+	fuelLogs := paginate(matched, filter.Limit, filter.Offset)
+	for _, fuelLog := range fuelLogs {
+		if err := r.expand(fuelLog); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ListFuelLogsResult{
+		FuelLogs: fuelLogs,
+		Total:    int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryFuelLogRepository) ListByTrucks(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.FuelLog, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.FuelLog](r.store, "fuel_logs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fuel logs: %w", err)
+	}
+
+	fuelLogs := make([]*domain.FuelLog, 0)
+	for _, fuelLog := range all {
+		if fuelLog.OrganizationID != orgID {
+			continue
+		}
+		if truckID != nil && fuelLog.TruckID != *truckID {
+			continue
+		}
+		fuelLogs = append(fuelLogs, fuelLog)
+	}
+
+	return fuelLogs, nil
+}
+
+func (r *memoryFuelLogRepository) expand(fuelLog *domain.FuelLog) error {
+	truck, err := memoryGet[domain.Truck](r.store, "trucks", fuelLog.TruckID)
+	if err != nil {
+		return fmt.Errorf("failed to look up fuel log truck: %w", err)
+	}
+
+	driver, err := memoryLookup[domain.Driver](r.store, "drivers", fuelLog.DriverID)
+	if err != nil {
+		return fmt.Errorf("failed to look up fuel log driver: %w", err)
+	}
+
+	facility, err := memoryLookup[domain.Facility](r.store, "facilities", fuelLog.FacilityID)
+	if err != nil {
+		return fmt.Errorf("failed to look up fuel log facility: %w", err)
+	}
+
+	trip, err := memoryLookup[domain.Trip](r.store, "trips", fuelLog.TripID)
+	if err != nil {
+		return fmt.Errorf("failed to look up fuel log trip: %w", err)
+	}
+
+	fuelLog.Truck = truck
+	fuelLog.Driver = driver
+	fuelLog.Facility = facility
+	fuelLog.Trip = trip
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryIncidentReportRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryIncidentReportRepository(store *MemoryStore) IncidentReportRepository {
+	return &memoryIncidentReportRepository{
+		store: store,
+	}
+}
+
+func (r *memoryIncidentReportRepository) Create(ctx context.Context, incidentReport *domain.IncidentReport) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	incidentReport.CreatedAt = primitive.NewDateTimeFromTime(now)
+	incidentReport.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&incidentReport.ID)
+
+	if err := r.store.put("incident_reports", incidentReport.ID, incidentReport); err != nil {
+		return fmt.Errorf("failed to create incidentReport: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryIncidentReportRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.IncidentReport, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	incidentReport, err := memoryGet[domain.IncidentReport](r.store, "incident_reports", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode incident report: %w", err)
+	}
+	if incidentReport == nil || incidentReport.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	if err := r.expand(incidentReport); err != nil {
+		return nil, err
+	}
+
+	return incidentReport, nil
+}
+
+func (r *memoryIncidentReportRepository) Update(ctx context.Context, incidentReport *domain.IncidentReport) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.IncidentReport](r.store, "incident_reports", incidentReport.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update incidentReport: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != incidentReport.OrganizationID {
+		return fmt.Errorf("incident report not found")
+	}
+
+	existing.TruckID = incidentReport.TruckID
+	existing.DriverID = incidentReport.DriverID
+	existing.Type = incidentReport.Type
+	existing.Description = incidentReport.Description
+	existing.Date = incidentReport.Date
+	existing.Location = incidentReport.Location
+	existing.DamageEstimate = incidentReport.DamageEstimate
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("incident_reports", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update incidentReport: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryIncidentReportRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	incidentReport, err := memoryGet[domain.IncidentReport](r.store, "incident_reports", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete incidentReport: %w", err)
+	}
+	if incidentReport == nil || incidentReport.OrganizationID != orgID {
+		return domain.ErrIncidentReportNotFound
+	}
+
+	r.store.remove("incident_reports", id)
+
+	return nil
+}
+
+func (r *memoryIncidentReportRepository) List(ctx context.Context, filter domain.IncidentReportFilter) (*ListIncidentReportsResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.IncidentReport](r.store, "incident_reports")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode incident reports: %w", err)
+	}
+
+	matched := make([]*domain.IncidentReport, 0, len(all))
+	for _, incidentReport := range all {
+		if incidentReport.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.TripID != nil && !sameObjectID(incidentReport.TripID, filter.TripID) {
+			continue
+		}
+		if filter.TruckID != nil && !sameObjectID(incidentReport.TruckID, filter.TruckID) {
+			continue
+		}
+		if filter.DriverID != nil && !sameObjectID(incidentReport.DriverID, filter.DriverID) {
+			continue
+		}
+		if filter.Type != "" && incidentReport.Type != filter.Type {
+			continue
+		}
+		matched = append(matched, incidentReport)
+	}
+
+	incidentReports := paginate(matched, filter.Limit, filter.Offset)
+	for _, incidentReport := range incidentReports {
+		if err := r.expand(incidentReport); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ListIncidentReportsResult{
+		IncidentReports: incidentReports,
+		Total:           int64(len(matched)),
+	}, nil
+}
+
+// expand resolves the trip, truck and driver and then drops the raw ids, matching the $project in the mongo pipelines
+func (r *memoryIncidentReportRepository) expand(incidentReport *domain.IncidentReport) error {
+	var err error
+
+	if incidentReport.Trip, err = memoryLookup[domain.Trip](r.store, "trips", incidentReport.TripID); err != nil {
+		return fmt.Errorf("failed to look up incident report trip: %w", err)
+	}
+	if incidentReport.Truck, err = memoryLookup[domain.Truck](r.store, "trucks", incidentReport.TruckID); err != nil {
+		return fmt.Errorf("failed to look up incident report truck: %w", err)
+	}
+	if incidentReport.Driver, err = memoryLookup[domain.Driver](r.store, "drivers", incidentReport.DriverID); err != nil {
+		return fmt.Errorf("failed to look up incident report driver: %w", err)
+	}
+
+	incidentReport.TripID = nil
+	incidentReport.TruckID = nil
+	incidentReport.DriverID = nil
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryLoginAttemptRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryLoginAttemptRepository(store *MemoryStore) LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{
+		store: store,
+	}
+}
+
+func (r *memoryLoginAttemptRepository) Get(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	attempt, err := r.find(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) ListByKeyPrefix(ctx context.Context, prefix string) ([]*domain.LoginAttempt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	attempts, err := memoryAll[domain.LoginAttempt](r.store, "login_attempts")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+
+	var matched []*domain.LoginAttempt
+	for _, attempt := range attempts {
+		if strings.HasPrefix(attempt.Key, prefix) {
+			matched = append(matched, attempt)
+		}
+	}
+
+	return matched, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*domain.LoginAttempt, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	attempt, err := r.find(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if attempt == nil {
+		attempt = &domain.LoginAttempt{ID: primitive.NewObjectID(), Key: key}
+	}
+
+	lockExpired := attempt.LockedUntil != nil && !now.Before(attempt.LockedUntil.Time())
+	if attempt.LastFailureAt.Time().Before(now.Add(-window)) || lockExpired {
+		attempt.Failures = 0
+		attempt.LockedUntil = nil
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = primitive.NewDateTimeFromTime(now)
+	attempt.Extend(now.Add(window))
+
+	if err := r.store.put("login_attempts", attempt.ID, attempt); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) Block(ctx context.Context, key string, retryAt time.Time, lockedUntil *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	attempt, err := r.find(key)
+	if err != nil {
+		return fmt.Errorf("failed to block login attempts: %w", err)
+	}
+	if attempt == nil {
+		return nil
+	}
+
+	retry := primitive.NewDateTimeFromTime(retryAt)
+	attempt.RetryAt = &retry
+	attempt.Extend(retryAt)
+	if lockedUntil != nil {
+		locked := primitive.NewDateTimeFromTime(*lockedUntil)
+		attempt.LockedUntil = &locked
+		attempt.Extend(*lockedUntil)
+	}
+
+	if err := r.store.put("login_attempts", attempt.ID, attempt); err != nil {
+		return fmt.Errorf("failed to block login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Delete(ctx context.Context, key string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	attempt, err := r.find(key)
+	if err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+	if attempt != nil {
+		r.store.remove("login_attempts", attempt.ID)
+	}
+
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) find(key string) (*domain.LoginAttempt, error) {
+	attempts, err := memoryAll[domain.LoginAttempt](r.store, "login_attempts")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attempt := range attempts {
+		if attempt.Key == key {
+			return attempt, nil
+		}
+	}
+
+	return nil, nil
+}
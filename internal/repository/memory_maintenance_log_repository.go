@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryMaintenanceLogRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMaintenanceLogRepository(store *MemoryStore) MaintenanceLogRepository {
+	return &memoryMaintenanceLogRepository{
+		store: store,
+	}
+}
+
+func (r *memoryMaintenanceLogRepository) Create(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	maintenanceLog.CreatedAt = primitive.NewDateTimeFromTime(now)
+	maintenanceLog.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&maintenanceLog.ID)
+
+	if err := r.store.put("maintenance_logs", maintenanceLog.ID, maintenanceLog); err != nil {
+		return fmt.Errorf("failed to create maintenance log %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryMaintenanceLogRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceLog, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	maintenanceLog, err := memoryGet[domain.MaintenanceLog](r.store, "maintenance_logs", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance log: %w", err)
+	}
+	if maintenanceLog == nil || maintenanceLog.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	if err := r.expand(maintenanceLog); err != nil {
+		return nil, err
+	}
+
+	return maintenanceLog, nil
+}
+
+func (r *memoryMaintenanceLogRepository) Update(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.MaintenanceLog](r.store, "maintenance_logs", maintenanceLog.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance log %w", err)
+	}
+	if existing == nil || existing.OrganizationID != maintenanceLog.OrganizationID {
+		return fmt.Errorf("maintenance log not found")
+	}
+
+	existing.TruckID = maintenanceLog.TruckID
+	existing.ScheduleID = maintenanceLog.ScheduleID
+	existing.Date = maintenanceLog.Date
+	existing.Mileage = maintenanceLog.Mileage
+	existing.ServiceType = maintenanceLog.ServiceType
+	existing.Cost = maintenanceLog.Cost
+	existing.Notes = maintenanceLog.Notes
+	existing.Mechanic = maintenanceLog.Mechanic
+	existing.Location = maintenanceLog.Location
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("maintenance_logs", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update maintenance log %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryMaintenanceLogRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	maintenanceLog, err := memoryGet[domain.MaintenanceLog](r.store, "maintenance_logs", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance log %w", err)
+	}
+	if maintenanceLog == nil || maintenanceLog.OrganizationID != orgID {
+		return domain.ErrMaintenanceLogNotFound
+	}
+
+	r.store.remove("maintenance_logs", id)
+
+	return nil
+}
+
+func (r *memoryMaintenanceLogRepository) List(ctx context.Context, filter domain.MaintenanceLogFilter) (*ListMaintenanceLogsResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.MaintenanceLog](r.store, "maintenance_logs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance logs: %w", err)
+	}
+
+	matched := make([]*domain.MaintenanceLog, 0, len(all))
+	for _, maintenanceLog := range all {
+		if maintenanceLog.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.TruckID != nil && !sameObjectID(maintenanceLog.TruckID, filter.TruckID) {
+			continue
+		}
+		if filter.ScheduleID != nil && !sameObjectID(maintenanceLog.ScheduleID, filter.ScheduleID) {
+			continue
+		}
+		if filter.ServiceType != "" && maintenanceLog.ServiceType != filter.ServiceType {
+			continue
+		}
+		matched = append(matched, maintenanceLog)
+	}
+
+	maintenanceLogs := paginate(matched, filter.Limit, filter.Offset)
+	for _, maintenanceLog := range maintenanceLogs {
+		if err := r.expand(maintenanceLog); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ListMaintenanceLogsResult{
+		MaintenanceLogs: maintenanceLogs,
+		Total:           int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryMaintenanceLogRepository) ListLatestBySchedule(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.MaintenanceLog, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.MaintenanceLog](r.store, "maintenance_logs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance logs: %w", err)
+	}
+
+	type scheduledTruck struct {
+		scheduleID, truckID primitive.ObjectID
+	}
+
+	// logs come back newest first, so on a tie the first one seen stands, as it does after the mongo $sort
+	latest := make(map[scheduledTruck]*domain.MaintenanceLog)
+	order := make([]scheduledTruck, 0)
+	for _, maintenanceLog := range all {
+		if maintenanceLog.OrganizationID != orgID || maintenanceLog.ScheduleID == nil || maintenanceLog.TruckID == nil {
+			continue
+		}
+		if truckID != nil && *maintenanceLog.TruckID != *truckID {
+			continue
+		}
+
+		key := scheduledTruck{scheduleID: *maintenanceLog.ScheduleID, truckID: *maintenanceLog.TruckID}
+		current, seen := latest[key]
+		if !seen {
+			order = append(order, key)
+		}
+		if !seen || maintenanceLog.Date > current.Date {
+			latest[key] = maintenanceLog
+		}
+	}
+
+	maintenanceLogs := make([]*domain.MaintenanceLog, 0, len(order))
+	for _, key := range order {
+		maintenanceLogs = append(maintenanceLogs, latest[key])
+	}
+
+	return maintenanceLogs, nil
+}
+
+// expand resolves the truck and then drops the raw id, matching the $project in the mongo pipelines
+func (r *memoryMaintenanceLogRepository) expand(maintenanceLog *domain.MaintenanceLog) error {
+	truck, err := memoryLookup[domain.Truck](r.store, "trucks", maintenanceLog.TruckID)
+	if err != nil {
+		return fmt.Errorf("failed to look up maintenance log truck: %w", err)
+	}
+
+	maintenanceLog.Truck = truck
+	maintenanceLog.TruckID = nil
+
+	return nil
+}
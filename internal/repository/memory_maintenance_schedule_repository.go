@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryMaintenanceScheduleRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMaintenanceScheduleRepository(store *MemoryStore) MaintenanceScheduleRepository {
+	return &memoryMaintenanceScheduleRepository{
+		store: store,
+	}
+}
+
+func (r *memoryMaintenanceScheduleRepository) Create(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	schedule.CreatedAt = primitive.NewDateTimeFromTime(now)
+	schedule.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&schedule.ID)
+
+	if err := r.store.put("maintenance_schedules", schedule.ID, schedule); err != nil {
+		return fmt.Errorf("failed to create maintenance schedule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryMaintenanceScheduleRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceSchedule, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	schedule, err := memoryGet[domain.MaintenanceSchedule](r.store, "maintenance_schedules", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance schedule: %w", err)
+	}
+	if schedule == nil || schedule.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	return schedule, nil
+}
+
+func (r *memoryMaintenanceScheduleRepository) Update(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.MaintenanceSchedule](r.store, "maintenance_schedules", schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance schedule: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != schedule.OrganizationID {
+		return domain.ErrMaintenanceScheduleNotFound
+	}
+
+	existing.Name = schedule.Name
+	existing.Description = schedule.Description
+	existing.IntervalMiles = schedule.IntervalMiles
+	existing.IntervalDays = schedule.IntervalDays
+	existing.SafetyCritical = schedule.SafetyCritical
+	existing.TruckID = schedule.TruckID
+	existing.Make = schedule.Make
+	existing.Model = schedule.Model
+	existing.Baselines = schedule.Baselines
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("maintenance_schedules", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update maintenance schedule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryMaintenanceScheduleRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	schedule, err := memoryGet[domain.MaintenanceSchedule](r.store, "maintenance_schedules", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance schedule: %w", err)
+	}
+	if schedule == nil || schedule.OrganizationID != orgID {
+		return domain.ErrMaintenanceScheduleNotFound
+	}
+
+	r.store.remove("maintenance_schedules", id)
+
+	return nil
+}
+
+func (r *memoryMaintenanceScheduleRepository) List(ctx context.Context, filter domain.MaintenanceScheduleFilter) (*ListMaintenanceSchedulesResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.MaintenanceSchedule](r.store, "maintenance_schedules")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance schedules: %w", err)
+	}
+
+	matched := make([]*domain.MaintenanceSchedule, 0, len(all))
+	for _, schedule := range all {
+		if schedule.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.TruckID != nil && !sameObjectID(schedule.TruckID, filter.TruckID) {
+			continue
+		}
+		if filter.Make != "" && schedule.Make != filter.Make {
+			continue
+		}
+		matched = append(matched, schedule)
+	}
+
+	return &ListMaintenanceSchedulesResult{
+		Schedules: paginate(matched, filter.Limit, filter.Offset),
+		Total:     int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryMaintenanceScheduleRepository) ListAll(ctx context.Context, orgID primitive.ObjectID) ([]*domain.MaintenanceSchedule, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.MaintenanceSchedule](r.store, "maintenance_schedules")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance schedules: %w", err)
+	}
+
+	schedules := make([]*domain.MaintenanceSchedule, 0, len(all))
+	for _, schedule := range all {
+		if schedule.OrganizationID == orgID {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryMembershipRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMembershipRepository(store *MemoryStore) MembershipRepository {
+	return &memoryMembershipRepository{
+		store: store,
+	}
+}
+
+func (r *memoryMembershipRepository) Create(ctx context.Context, membership *domain.Membership) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	membership.CreatedAt = primitive.NewDateTimeFromTime(now)
+	membership.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&membership.ID)
+
+	if err := r.store.put("memberships", membership.ID, membership); err != nil {
+		return fmt.Errorf("failed to create membership: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryMembershipRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Membership, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	membership, err := memoryGet[domain.Membership](r.store, "memberships", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode membership: %w", err)
+	}
+	if membership == nil || membership.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	return membership, nil
+}
+
+func (r *memoryMembershipRepository) GetByUserAndOrganization(ctx context.Context, userID, orgID primitive.ObjectID) (*domain.Membership, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	memberships, err := r.matching(func(m *domain.Membership) bool {
+		return m.UserID == userID && m.OrganizationID == orgID
+	})
+	if err != nil || len(memberships) == 0 {
+		return nil, err
+	}
+
+	return memberships[0], nil
+}
+
+func (r *memoryMembershipRepository) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]*domain.Membership, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	memberships, err := r.matching(func(m *domain.Membership) bool {
+		return m.UserID == userID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		organization, err := memoryGet[domain.Organization](r.store, "organizations", membership.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up membership organization: %w", err)
+		}
+		membership.Organization = organization
+	}
+
+	return memberships, nil
+}
+
+func (r *memoryMembershipRepository) ListByOrganization(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Membership, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	memberships, err := r.matching(func(m *domain.Membership) bool {
+		return m.OrganizationID == orgID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		user, err := memoryGet[domain.User](r.store, "users", membership.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up membership user: %w", err)
+		}
+		if user != nil {
+			user.Password = ""
+		}
+		membership.User = user
+	}
+
+	return memberships, nil
+}
+
+func (r *memoryMembershipRepository) CountByRole(ctx context.Context, orgID primitive.ObjectID, role domain.Role) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	memberships, err := r.matching(func(m *domain.Membership) bool {
+		return m.OrganizationID == orgID && m.Role == role
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(memberships)), nil
+}
+
+func (r *memoryMembershipRepository) UpdateRole(ctx context.Context, id, orgID primitive.ObjectID, role domain.Role) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	membership, err := memoryGet[domain.Membership](r.store, "memberships", id)
+	if err != nil {
+		return fmt.Errorf("failed to update membership: %w", err)
+	}
+	if membership == nil || membership.OrganizationID != orgID {
+		return domain.ErrMembershipNotFound
+	}
+
+	membership.Role = role
+	membership.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("memberships", membership.ID, membership); err != nil {
+		return fmt.Errorf("failed to update membership: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryMembershipRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	membership, err := memoryGet[domain.Membership](r.store, "memberships", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete membership: %w", err)
+	}
+	if membership == nil || membership.OrganizationID != orgID {
+		return domain.ErrMembershipNotFound
+	}
+
+	r.store.remove("memberships", id)
+
+	return nil
+}
+
+// matching returns the memberships that satisfy match, oldest first
+func (r *memoryMembershipRepository) matching(match func(*domain.Membership) bool) ([]*domain.Membership, error) {
+	all, err := memoryAll[domain.Membership](r.store, "memberships")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode memberships: %w", err)
+	}
+
+	memberships := make([]*domain.Membership, 0)
+	for i := len(all) - 1; i >= 0; i-- {
+		if match(all[i]) {
+			memberships = append(memberships, all[i])
+		}
+	}
+
+	return memberships, nil
+}
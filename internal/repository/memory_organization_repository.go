@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryOrganizationRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryOrganizationRepository(store *MemoryStore) OrganizationRepository {
+	return &memoryOrganizationRepository{
+		store: store,
+	}
+}
+
+func (r *memoryOrganizationRepository) Create(ctx context.Context, organization *domain.Organization) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	organization.CreatedAt = primitive.NewDateTimeFromTime(now)
+	organization.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&organization.ID)
+
+	if err := r.store.put("organizations", organization.ID, organization); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryOrganizationRepository) GetById(ctx context.Context, id primitive.ObjectID) (*domain.Organization, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	organization, err := memoryGet[domain.Organization](r.store, "organizations", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode organization: %w", err)
+	}
+
+	return organization, nil
+}
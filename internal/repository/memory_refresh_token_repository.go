@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRefreshTokenRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryRefreshTokenRepository(store *MemoryStore) RefreshTokenRepository {
+	return &memoryRefreshTokenRepository{
+		store: store,
+	}
+}
+
+func (r *memoryRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	newObjectIDIfMissing(&token.ID)
+
+	if err := r.store.put("refresh_tokens", token.ID, token); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tokens, err := memoryAll[domain.RefreshToken](r.store, "refresh_tokens")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+
+	for _, token := range tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *memoryRefreshTokenRepository) MarkRotated(ctx context.Context, id, replacedBy primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, err := memoryGet[domain.RefreshToken](r.store, "refresh_tokens", id)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if token == nil || token.IsSpent() {
+		return domain.ErrRefreshTokenNotFound
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	token.RotatedAt = &now
+	token.ReplacedBy = &replacedBy
+
+	if err := r.store.put("refresh_tokens", token.ID, token); err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tokens, err := memoryAll[domain.RefreshToken](r.store, "refresh_tokens")
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, token := range tokens {
+		if token.FamilyID != familyID || token.RevokedAt != nil {
+			continue
+		}
+
+		token.RevokedAt = &now
+		if err := r.store.put("refresh_tokens", token.ID, token); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tokens, err := memoryAll[domain.RefreshToken](r.store, "refresh_tokens")
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, token := range tokens {
+		if token.UserID != userID || token.RevokedAt != nil {
+			continue
+		}
+
+		token.RevokedAt = &now
+		if err := r.store.put("refresh_tokens", token.ID, token); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRevokedTokenRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryRevokedTokenRepository(store *MemoryStore) RevokedTokenRepository {
+	return &memoryRevokedTokenRepository{
+		store: store,
+	}
+}
+
+func (r *memoryRevokedTokenRepository) Create(ctx context.Context, token *domain.RevokedToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	newObjectIDIfMissing(&token.ID)
+
+	if err := r.store.put("revoked_tokens", token.ID, token); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryRevokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tokens, err := memoryAll[domain.RevokedToken](r.store, "revoked_tokens")
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	for _, token := range tokens {
+		if token.JTI == jti {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
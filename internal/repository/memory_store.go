@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore is a process-local stand-in for the mongo database. Documents are kept bson-encoded so that
+// the in-memory repositories see the same field names, omitempty handling and copy semantics as the mongo
+// ones do (callers can never mutate a stored document through a pointer they were handed).
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]map[primitive.ObjectID][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		collections: make(map[string]map[primitive.ObjectID][]byte),
+	}
+}
+
+// the helpers below assume the caller already holds the store's lock. repositories take the lock once per
+// operation so that read-modify-write updates and cross-collection lookups see a consistent snapshot.
+
+func (s *MemoryStore) put(collection string, id primitive.ObjectID, doc any) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	if s.collections[collection] == nil {
+		s.collections[collection] = make(map[primitive.ObjectID][]byte)
+	}
+	s.collections[collection][id] = raw
+
+	return nil
+}
+
+func (s *MemoryStore) exists(collection string, id primitive.ObjectID) bool {
+	_, ok := s.collections[collection][id]
+	return ok
+}
+
+func (s *MemoryStore) remove(collection string, id primitive.ObjectID) bool {
+	if !s.exists(collection, id) {
+		return false
+	}
+
+	delete(s.collections[collection], id)
+	return true
+}
+
+// ids returns every document id in the collection, newest first (the same order as sorting on _id: -1)
+func (s *MemoryStore) ids(collection string) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(s.collections[collection]))
+	for id := range s.collections[collection] {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) > 0
+	})
+
+	return ids
+}
+
+// memoryGet decodes a single document, returning nil when it does not exist
+func memoryGet[T any](s *MemoryStore, collection string, id primitive.ObjectID) (*T, error) {
+	raw, ok := s.collections[collection][id]
+	if !ok {
+		return nil, nil
+	}
+
+	var doc T
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// memoryLookup mirrors a $lookup + $unwind with preserveNullAndEmptyArrays: a missing reference is not an error
+func memoryLookup[T any](s *MemoryStore, collection string, id *primitive.ObjectID) (*T, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	return memoryGet[T](s, collection, *id)
+}
+
+// memoryAll decodes every document in the collection, newest first
+func memoryAll[T any](s *MemoryStore, collection string) ([]*T, error) {
+	ids := s.ids(collection)
+	docs := make([]*T, 0, len(ids))
+
+	for _, id := range ids {
+		doc, err := memoryGet[T](s, collection, id)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// paginate applies the same bounds the mongo repositories put on limit/offset and returns the requested page
+func paginate[T any](docs []*T, limit, offset int64) []*T {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset >= int64(len(docs)) {
+		return make([]*T, 0)
+	}
+
+	end := offset + limit
+	if end > int64(len(docs)) {
+		end = int64(len(docs))
+	}
+
+	return docs[offset:end]
+}
+
+// sameObjectID compares optional references the way an equality match on a nullable field does in mongo
+func sameObjectID(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
+
+func newObjectIDIfMissing(id *primitive.ObjectID) {
+	if id.IsZero() {
+		*id = primitive.NewObjectID()
+	}
+}
@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryTripRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryTripRepository(store *MemoryStore) TripRepository {
+	return &memoryTripRepository{
+		store: store,
+	}
+}
+
+func (r *memoryTripRepository) Create(ctx context.Context, trip *domain.Trip) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	trip.CreatedAt = primitive.NewDateTimeFromTime(now)
+	trip.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&trip.ID)
+
+	if err := r.store.put("trips", trip.ID, trip); err != nil {
+		return fmt.Errorf("failed to create trip: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryTripRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Trip, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.getById(id, orgID)
+}
+
+func (r *memoryTripRepository) getById(id, orgID primitive.ObjectID) (*domain.Trip, error) {
+	trip, err := memoryGet[domain.Trip](r.store, "trips", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trip: %w", err)
+	}
+	if trip == nil || trip.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	if err := r.expand(trip); err != nil {
+		return nil, err
+	}
+
+	return trip, nil
+}
+
+func (r *memoryTripRepository) Update(ctx context.Context, trip *domain.Trip) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existingTrip, err := memoryGet[domain.Trip](r.store, "trips", trip.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch existing trip: %w", err)
+	}
+	if existingTrip == nil || existingTrip.OrganizationID != trip.OrganizationID {
+		return fmt.Errorf("trip not found")
+	}
+
+	// Preserve the IDs from the existing trip if they're not being updated
+	if trip.DriverID == nil {
+		trip.DriverID = existingTrip.DriverID
+	}
+	if trip.TruckID == nil {
+		trip.TruckID = existingTrip.TruckID
+	}
+	if trip.StartFacilityID == nil {
+		trip.StartFacilityID = existingTrip.StartFacilityID
+	}
+	if trip.EndFacilityID == nil {
+		trip.EndFacilityID = existingTrip.EndFacilityID
+	}
+	if trip.Stops == nil {
+		trip.Stops = existingTrip.Stops
+	}
+	if trip.StartOdometer == nil {
+		trip.StartOdometer = existingTrip.StartOdometer
+	}
+	if trip.EndOdometer == nil {
+		trip.EndOdometer = existingTrip.EndOdometer
+	}
+
+	existingTrip.TripNumber = trip.TripNumber
+	existingTrip.DriverID = trip.DriverID
+	existingTrip.TruckID = trip.TruckID
+	existingTrip.StartFacilityID = trip.StartFacilityID
+	existingTrip.EndFacilityID = trip.EndFacilityID
+	existingTrip.Stops = storedStops(trip.Stops)
+	existingTrip.DepartureTime = trip.DepartureTime
+	existingTrip.ArrivalTime = trip.ArrivalTime
+	existingTrip.Status = trip.Status
+	existingTrip.Cargo = trip.Cargo
+	existingTrip.FuelUsage = trip.FuelUsage
+	existingTrip.DistanceMiles = trip.DistanceMiles
+	existingTrip.StartOdometer = trip.StartOdometer
+	existingTrip.EndOdometer = trip.EndOdometer
+	existingTrip.Estimate = trip.Estimate
+	existingTrip.Notes = trip.Notes
+	existingTrip.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("trips", existingTrip.ID, existingTrip); err != nil {
+		return fmt.Errorf("failed to update trip: %w", err)
+	}
+
+	updatedTrip, err := r.getById(trip.ID, trip.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch updated trip: %w", err)
+	}
+
+	*trip = *updatedTrip
+
+	return nil
+}
+
+func (r *memoryTripRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	trip, err := memoryGet[domain.Trip](r.store, "trips", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete trip: %w", err)
+	}
+	if trip == nil || trip.OrganizationID != orgID {
+		return domain.ErrTripNotFound
+	}
+
+	r.store.remove("trips", id)
+
+	return nil
+}
+
+func (r *memoryTripRepository) List(ctx context.Context, filter domain.TripFilter) (*ListTripsResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Trip](r.store, "trips")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	matched := make([]*domain.Trip, 0, len(all))
+	for _, trip := range all {
+		if trip.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.DriverID != nil && !sameObjectID(trip.DriverID, filter.DriverID) {
+			continue
+		}
+		if filter.TruckID != nil && !sameObjectID(trip.TruckID, filter.TruckID) {
+			continue
+		}
+		if filter.StartFacilityID != nil && !sameObjectID(trip.StartFacilityID, filter.StartFacilityID) {
+			continue
+		}
+		if filter.EndFacilityID != nil && !sameObjectID(trip.EndFacilityID, filter.EndFacilityID) {
+			continue
+		}
+		if filter.Deviating && !trip.HasDeviations() {
+			continue
+		}
+		matched = append(matched, trip)
+	}
+
+	trips := paginate(matched, filter.Limit, filter.Offset)
+	for _, trip := range trips {
+		if err := r.expand(trip); err != nil {
+			return nil, err
+		}
+
+		// the list pipeline projects the raw reference ids away once they've been expanded
+		trip.DriverID = nil
+		trip.TruckID = nil
+		trip.StartFacilityID = nil
+		trip.EndFacilityID = nil
+	}
+
+	return &ListTripsResult{
+		Trips: trips,
+		Total: int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryTripRepository) ListActive(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Trip, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Trip](r.store, "trips")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	trips := make([]*domain.Trip, 0, len(all))
+	for _, trip := range all {
+		if trip.OrganizationID == orgID && trip.IsActive() {
+			trips = append(trips, trip)
+		}
+	}
+
+	sortTripsByDeparture(trips)
+
+	return trips, nil
+}
+
+func (r *memoryTripRepository) ListAssignedToTrucks(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.Trip, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Trip](r.store, "trips")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	trips := make([]*domain.Trip, 0, len(all))
+	for _, trip := range all {
+		if trip.OrganizationID != orgID || trip.TruckID == nil {
+			continue
+		}
+		if truckID != nil && *trip.TruckID != *truckID {
+			continue
+		}
+		trips = append(trips, trip)
+	}
+
+	return trips, nil
+}
+
+func (r *memoryTripRepository) FindOverlapping(ctx context.Context, query domain.TripOverlapQuery) ([]*domain.Trip, error) {
+	if query.DriverID == nil && query.TruckID == nil {
+		return []*domain.Trip{}, nil
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Trip](r.store, "trips")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	trips := make([]*domain.Trip, 0)
+	for _, trip := range all {
+		if trip.OrganizationID != query.OrganizationID || !trip.IsActive() {
+			continue
+		}
+		if query.ExcludeID != nil && trip.ID == *query.ExcludeID {
+			continue
+		}
+		if !(trip.DepartureTime.Scheduled < query.End && trip.ArrivalTime.Scheduled > query.Start) {
+			continue
+		}
+
+		sharesDriver := query.DriverID != nil && sameObjectID(trip.DriverID, query.DriverID)
+		sharesTruck := query.TruckID != nil && sameObjectID(trip.TruckID, query.TruckID)
+		if !sharesDriver && !sharesTruck {
+			continue
+		}
+
+		trips = append(trips, trip)
+	}
+
+	sortTripsByDeparture(trips)
+
+	return trips, nil
+}
+
+func sortTripsByDeparture(trips []*domain.Trip) {
+	sort.SliceStable(trips, func(i, j int) bool {
+		if trips[i].DepartureTime.Scheduled != trips[j].DepartureTime.Scheduled {
+			return trips[i].DepartureTime.Scheduled < trips[j].DepartureTime.Scheduled
+		}
+		return bytes.Compare(trips[i].ID[:], trips[j].ID[:]) < 0
+	})
+}
+
+func (r *memoryTripRepository) expand(trip *domain.Trip) error {
+	var err error
+
+	if trip.Driver, err = memoryLookup[domain.Driver](r.store, "drivers", trip.DriverID); err != nil {
+		return fmt.Errorf("failed to look up trip driver: %w", err)
+	}
+	if trip.Truck, err = memoryLookup[domain.Truck](r.store, "trucks", trip.TruckID); err != nil {
+		return fmt.Errorf("failed to look up trip truck: %w", err)
+	}
+	if trip.StartFacility, err = memoryLookup[domain.Facility](r.store, "facilities", trip.StartFacilityID); err != nil {
+		return fmt.Errorf("failed to look up trip start facility: %w", err)
+	}
+	if trip.EndFacility, err = memoryLookup[domain.Facility](r.store, "facilities", trip.EndFacilityID); err != nil {
+		return fmt.Errorf("failed to look up trip end facility: %w", err)
+	}
+
+	if trip.Stops == nil {
+		trip.Stops = make([]domain.TripStop, 0)
+	}
+	for i := range trip.Stops {
+		if trip.Stops[i].Facility, err = memoryLookup[domain.Facility](r.store, "facilities", &trip.Stops[i].FacilityID); err != nil {
+			return fmt.Errorf("failed to look up trip stop facility: %w", err)
+		}
+		if facility := trip.Stops[i].Facility; facility != nil && facility.OrganizationID != trip.OrganizationID {
+			trip.Stops[i].Facility = nil
+		}
+	}
+
+	return nil
+}
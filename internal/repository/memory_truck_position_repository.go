@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryTruckPositionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryTruckPositionRepository(store *MemoryStore) TruckPositionRepository {
+	return &memoryTruckPositionRepository{
+		store: store,
+	}
+}
+
+func (r *memoryTruckPositionRepository) CreateMany(ctx context.Context, positions []*domain.TruckPosition) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, position := range positions {
+		newObjectIDIfMissing(&position.ID)
+
+		if err := r.store.put("truck_positions", position.ID, position); err != nil {
+			return fmt.Errorf("failed to create truck positions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryTruckPositionRepository) ListForTruck(ctx context.Context, orgID, truckID primitive.ObjectID, from, to primitive.DateTime) ([]domain.Position, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.TruckPosition](r.store, "truck_positions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode truck positions: %w", err)
+	}
+
+	positions := make([]domain.Position, 0)
+	for _, position := range all {
+		if position.TruckID != truckID || position.OrganizationID != orgID {
+			continue
+		}
+		if position.RecordedAt < from || position.RecordedAt > to {
+			continue
+		}
+		positions = append(positions, position.Position)
+	}
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].RecordedAt < positions[j].RecordedAt
+	})
+
+	return positions, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryTruckRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryTruckRepository(store *MemoryStore) TruckRepository {
+	return &memoryTruckRepository{
+		store: store,
+	}
+}
+
+func (r *memoryTruckRepository) Create(ctx context.Context, truck *domain.Truck) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	truck.CreatedAt = primitive.NewDateTimeFromTime(now)
+	truck.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&truck.ID)
+
+	if err := r.store.put("trucks", truck.ID, truck); err != nil {
+		return fmt.Errorf("failed to create truck: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryTruckRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Truck, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	truck, err := memoryGet[domain.Truck](r.store, "trucks", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode truck: %w", err)
+	}
+	if truck == nil || truck.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	if err := r.expand(truck); err != nil {
+		return nil, err
+	}
+
+	return truck, nil
+}
+
+func (r *memoryTruckRepository) Update(ctx context.Context, truck *domain.Truck) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.Truck](r.store, "trucks", truck.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update truck: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != truck.OrganizationID {
+		return fmt.Errorf("truck not found")
+	}
+
+	existing.Mileage = truck.Mileage
+	existing.Status = truck.Status
+	existing.LastMaintenance = truck.LastMaintenance
+	if truck.AssignedDriverID != nil {
+		existing.AssignedDriverID = truck.AssignedDriverID
+	}
+	if truck.MileageRecordedAt != 0 {
+		existing.MileageRecordedAt = truck.MileageRecordedAt
+	}
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("trucks", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update truck: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryTruckRepository) UpdateLastPosition(ctx context.Context, id, orgID primitive.ObjectID, position domain.Position) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	truck, err := memoryGet[domain.Truck](r.store, "trucks", id)
+	if err != nil {
+		return fmt.Errorf("failed to update truck position: %w", err)
+	}
+	if truck == nil || truck.OrganizationID != orgID {
+		return nil
+	}
+	if truck.LastPosition != nil && truck.LastPosition.RecordedAt >= position.RecordedAt {
+		return nil
+	}
+
+	truck.LastPosition = &position
+
+	if err := r.store.put("trucks", truck.ID, truck); err != nil {
+		return fmt.Errorf("failed to update truck position: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryTruckRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	truck, err := memoryGet[domain.Truck](r.store, "trucks", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete truck: %w", err)
+	}
+	if truck == nil || truck.OrganizationID != orgID {
+		return domain.ErrTruckNotFound
+	}
+
+	r.store.remove("trucks", id)
+
+	return nil
+}
+
+func (r *memoryTruckRepository) List(ctx context.Context, filter domain.TruckFilter) (*ListTrucksResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Truck](r.store, "trucks")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trucks: %w", err)
+	}
+
+	matched := make([]*domain.Truck, 0, len(all))
+	for _, truck := range all {
+		if filter.OrganizationID != primitive.NilObjectID && truck.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.TrailerType != "" && truck.TrailerType != filter.TrailerType {
+			continue
+		}
+		if filter.FuelType != "" && truck.FuelType != filter.FuelType {
+			continue
+		}
+		if filter.Status != "" && truck.Status != filter.Status {
+			continue
+		}
+		// the mongo query always matches on assigned_driver_id, so an unset filter only matches unassigned trucks
+		if !sameObjectID(truck.AssignedDriverID, filter.AssignedDriverID) {
+			continue
+		}
+		matched = append(matched, truck)
+	}
+
+	trucks := paginate(matched, filter.Limit, filter.Offset)
+	for _, truck := range trucks {
+		if err := r.expand(truck); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ListTrucksResult{
+		Trucks: trucks,
+		Total:  int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryTruckRepository) ListInService(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Truck, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.Truck](r.store, "trucks")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trucks: %w", err)
+	}
+
+	trucks := make([]*domain.Truck, 0, len(all))
+	for _, truck := range all {
+		if truck.OrganizationID == orgID && truck.Status != domain.TruckStatusRetired {
+			trucks = append(trucks, truck)
+		}
+	}
+
+	return trucks, nil
+}
+
+// expand resolves the assigned driver and then drops the raw id, matching the $project in the mongo pipelines
+func (r *memoryTruckRepository) expand(truck *domain.Truck) error {
+	driver, err := memoryLookup[domain.Driver](r.store, "drivers", truck.AssignedDriverID)
+	if err != nil {
+		return fmt.Errorf("failed to look up assigned driver: %w", err)
+	}
+
+	truck.AssignedDriver = driver
+	truck.AssignedDriverID = nil
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepository{
+		store: store,
+	}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	users, err := memoryAll[domain.User](r.store, "users")
+	if err != nil {
+		return err
+	}
+	for _, existing := range users {
+		if existing.Email == user.Email {
+			return domain.ErrUserExists
+		}
+	}
+
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+	user.ID = primitive.NewObjectID()
+
+	return r.store.put("users", user.ID, user)
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users, err := memoryAll[domain.User](r.store, "users")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *memoryUserRepository) FindById(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return memoryGet[domain.User](r.store, "users", id)
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(ctx context.Context, id primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := memoryGet[domain.User](r.store, "users", id)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsEmailVerified() {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+
+	return r.store.put("users", user.ID, user)
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := memoryGet[domain.User](r.store, "users", id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	now := time.Now()
+	user.Password = passwordHash
+	user.PasswordChangedAt = &now
+	user.UpdatedAt = now
+
+	return r.store.put("users", user.ID, user)
+}
+
+func (r *memoryUserRepository) SetTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor *domain.TwoFactor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := memoryGet[domain.User](r.store, "users", id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	user.TwoFactor = twoFactor
+	user.UpdatedAt = time.Now()
+
+	return r.store.put("users", user.ID, user)
+}
+
+func (r *memoryUserRepository) ClaimTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := memoryGet[domain.User](r.store, "users", id)
+	if err != nil {
+		return false, err
+	}
+	if user == nil || user.TwoFactor == nil || user.TwoFactor.LastUsedStep >= step {
+		return false, nil
+	}
+
+	user.TwoFactor.LastUsedStep = step
+
+	return true, r.store.put("users", user.ID, user)
+}
+
+func (r *memoryUserRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := memoryGet[domain.User](r.store, "users", id)
+	if err != nil {
+		return false, err
+	}
+	if user == nil || user.TwoFactor == nil {
+		return false, nil
+	}
+
+	for i, hash := range user.TwoFactor.RecoveryCodes {
+		if hash == codeHash {
+			user.TwoFactor.RecoveryCodes = append(user.TwoFactor.RecoveryCodes[:i], user.TwoFactor.RecoveryCodes[i+1:]...)
+			return true, r.store.put("users", user.ID, user)
+		}
+	}
+
+	return false, nil
+}
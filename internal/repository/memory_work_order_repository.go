@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryWorkOrderRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryWorkOrderRepository(store *MemoryStore) WorkOrderRepository {
+	return &memoryWorkOrderRepository{
+		store: store,
+	}
+}
+
+func (r *memoryWorkOrderRepository) Create(ctx context.Context, workOrder *domain.WorkOrder) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	workOrder.CreatedAt = primitive.NewDateTimeFromTime(now)
+	workOrder.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	newObjectIDIfMissing(&workOrder.ID)
+
+	if err := r.store.put("work_orders", workOrder.ID, workOrder); err != nil {
+		return fmt.Errorf("failed to create work order: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryWorkOrderRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.WorkOrder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	workOrder, err := memoryGet[domain.WorkOrder](r.store, "work_orders", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work order: %w", err)
+	}
+	if workOrder == nil || workOrder.OrganizationID != orgID {
+		return nil, nil
+	}
+
+	return workOrder, nil
+}
+
+func (r *memoryWorkOrderRepository) Update(ctx context.Context, workOrder *domain.WorkOrder) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, err := memoryGet[domain.WorkOrder](r.store, "work_orders", workOrder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update work order: %w", err)
+	}
+	if existing == nil || existing.OrganizationID != workOrder.OrganizationID {
+		return domain.ErrWorkOrderNotFound
+	}
+
+	existing.ScheduleID = workOrder.ScheduleID
+	existing.Title = workOrder.Title
+	existing.Description = workOrder.Description
+	existing.ServiceType = workOrder.ServiceType
+	existing.Mechanic = workOrder.Mechanic
+	existing.Location = workOrder.Location
+	existing.Status = workOrder.Status
+	existing.Lines = workOrder.Lines
+	existing.Mileage = workOrder.Mileage
+	existing.StartedAt = workOrder.StartedAt
+	existing.ClosedAt = workOrder.ClosedAt
+	existing.MaintenanceLogID = workOrder.MaintenanceLogID
+	existing.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if err := r.store.put("work_orders", existing.ID, existing); err != nil {
+		return fmt.Errorf("failed to update work order: %w", err)
+	}
+
+	return nil
+}
+
+func (r *memoryWorkOrderRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	workOrder, err := memoryGet[domain.WorkOrder](r.store, "work_orders", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete work order: %w", err)
+	}
+	if workOrder == nil || workOrder.OrganizationID != orgID {
+		return domain.ErrWorkOrderNotFound
+	}
+
+	r.store.remove("work_orders", id)
+
+	return nil
+}
+
+func (r *memoryWorkOrderRepository) List(ctx context.Context, filter domain.WorkOrderFilter) (*ListWorkOrdersResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.WorkOrder](r.store, "work_orders")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode work orders: %w", err)
+	}
+
+	matched := make([]*domain.WorkOrder, 0, len(all))
+	for _, workOrder := range all {
+		if workOrder.OrganizationID != filter.OrganizationID {
+			continue
+		}
+		if filter.TruckID != nil && workOrder.TruckID != *filter.TruckID {
+			continue
+		}
+		if filter.Status != "" && workOrder.Status != filter.Status {
+			continue
+		}
+		matched = append(matched, workOrder)
+	}
+
+	return &ListWorkOrdersResult{
+		WorkOrders: paginate(matched, filter.Limit, filter.Offset),
+		Total:      int64(len(matched)),
+	}, nil
+}
+
+func (r *memoryWorkOrderRepository) CountHoldingTruck(ctx context.Context, truckID, orgID primitive.ObjectID) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	all, err := memoryAll[domain.WorkOrder](r.store, "work_orders")
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode work orders: %w", err)
+	}
+
+	var count int64
+	for _, workOrder := range all {
+		if workOrder.OrganizationID == orgID && workOrder.TruckID == truckID && workOrder.Status.HoldsTruck() {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrate brings documents written by earlier versions up to date. each step only matches documents still in the
+// old shape, so this runs on every start alongside EnsureIndexes.
+func Migrate(ctx context.Context, db *database.MongoDB) error {
+	// drivers' birth and license expiration dates, and maintenance and fuel logs' dates, used to be kept as
+	// whatever string was entered. ones that don't read as YYYY-MM-DD aren't guessed at: they show up as missing
+	// until they're entered again, and the original string is kept alongside in <field>_legacy.
+	dateStrings := []struct{ collection, field string }{
+		{"drivers", "dob"},
+		{"drivers", "license_expiration"},
+		{"maintenance_logs", "date"},
+		{"fuel_logs", "date"},
+	}
+	for _, d := range dateStrings {
+		if err := convertDateStrings(ctx, db, d.collection, d.field); err != nil {
+			return err
+		}
+	}
+
+	// fleet records belonged to the user who created them until organizations came along. each one moves into its
+	// owner's oldest organization, so it's still there after the upgrade, and has to happen before fuel logs are
+	// placed by their trips below.
+	for _, collection := range []string{"trucks", "drivers", "trips", "facilities", "maintenance_logs", "incident_reports"} {
+		if err := assignLegacyOrganizations(ctx, db, collection); err != nil {
+			return err
+		}
+	}
+
+	// fuel logs didn't belong to an organization, or point at a truck, until they were scoped like everything else.
+	// one bought on a trip takes its organization, owner, truck and driver from the trip; one without a trip has
+	// nothing to go on and stays out of every organization's results.
+	cursor, err := db.Database.Collection("fuel_logs").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"organization_id": bson.M{"$exists": false},
+			"trip_id":         bson.M{"$ne": nil},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "trips",
+			"localField":   "trip_id",
+			"foreignField": "_id",
+			"as":           "trip",
+		}}},
+		{{Key: "$unwind", Value: "$trip"}},
+		{{Key: "$project", Value: bson.M{
+			"organization_id": "$trip.organization_id",
+			"user_id":         "$trip.user_id",
+			"truck_id":        "$trip.truck_id",
+			"driver_id":       "$trip.driver_id",
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "fuel_logs",
+			"on":             "_id",
+			"whenMatched":    "merge",
+			"whenNotMatched": "discard",
+		}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to assign fuel logs to their trips' organizations: %w", err)
+	}
+	cursor.Close(ctx)
+
+	return nil
+}
+
+// convertDateStrings turns a field holding YYYY-MM-DD strings into dates. a string that doesn't parse is moved to
+// <field>_legacy rather than dropped, so it can still be read and entered again.
+func convertDateStrings(ctx context.Context, db *database.MongoDB, collection, field string) error {
+	legacy := field + "_legacy"
+
+	_, err := db.Database.Collection(collection).UpdateMany(ctx,
+		bson.M{field: bson.M{"$type": "string"}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				legacy: "$" + field,
+				field: bson.M{"$dateFromString": bson.M{
+					"dateString": "$" + field,
+					"format":     "%Y-%m-%d",
+					"onError":    nil,
+				}},
+			}}},
+			{{Key: "$set", Value: bson.M{
+				field:  bson.M{"$ifNull": bson.A{"$" + field, "$$REMOVE"}},
+				legacy: bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$type": "$" + field}, "date"}}, "$$REMOVE", "$" + legacy}},
+			}}},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s %s to a date: %w", collection, field, err)
+	}
+
+	return nil
+}
+
+// assignLegacyOrganizations files every document in the collection that has no organization under its owner's
+func assignLegacyOrganizations(ctx context.Context, db *database.MongoDB, collection string) error {
+	legacy := bson.M{"organization_id": bson.M{"$exists": false}}
+
+	userIDs, err := db.Database.Collection(collection).Distinct(ctx, "user_id", legacy)
+	if err != nil {
+		return fmt.Errorf("failed to find owners of legacy %s: %w", collection, err)
+	}
+
+	for _, value := range userIDs {
+		userID, ok := value.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+
+		orgID, err := personalOrganization(ctx, db, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.Database.Collection(collection).UpdateMany(ctx,
+			bson.M{"user_id": userID, "organization_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"organization_id": orgID}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to assign legacy %s to an organization: %w", collection, err)
+		}
+	}
+
+	return nil
+}
+
+// personalOrganization finds the oldest organization the user belongs to, which is the one they'd land in on their
+// next login. a user who hasn't logged in since organizations were added gets one made for them, as they would then.
+func personalOrganization(ctx context.Context, db *database.MongoDB, userID primitive.ObjectID) (primitive.ObjectID, error) {
+	var membership domain.Membership
+	err := db.Database.Collection("memberships").FindOne(ctx,
+		bson.M{"user_id": userID},
+		options.FindOne().SetSort(bson.M{"_id": 1}),
+	).Decode(&membership)
+	if err == nil {
+		return membership.OrganizationID, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return primitive.NilObjectID, fmt.Errorf("failed to look up memberships: %w", err)
+	}
+
+	var user domain.User
+	name := userID.Hex()
+	if err := db.Database.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err == nil {
+		name = user.Email
+	}
+
+	organization, err := domain.NewOrganization(name)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	result, err := db.Database.Collection("organizations").InsertOne(ctx, organization)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to create organization: %w", err)
+	}
+	orgID := result.InsertedID.(primitive.ObjectID)
+
+	owner, err := domain.NewMembership(orgID, userID, domain.RoleOwner)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if _, err := db.Database.Collection("memberships").InsertOne(ctx, owner); err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to create membership: %w", err)
+	}
+
+	return orgID, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type organizationRepository struct {
+	organizations *mongo.Collection
+}
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, organization *domain.Organization) error
+	GetById(ctx context.Context, id primitive.ObjectID) (*domain.Organization, error)
+}
+
+func NewOrganizationRepository(db *database.MongoDB) OrganizationRepository {
+	return &organizationRepository{
+		organizations: db.Database.Collection("organizations"),
+	}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, organization *domain.Organization) error {
+	now := time.Now()
+	organization.CreatedAt = primitive.NewDateTimeFromTime(now)
+	organization.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	result, err := r.organizations.InsertOne(ctx, organization)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	organization.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *organizationRepository) GetById(ctx context.Context, id primitive.ObjectID) (*domain.Organization, error) {
+	var organization domain.Organization
+	err := r.organizations.FindOne(ctx, bson.M{"_id": id}).Decode(&organization)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+
+	return &organization, nil
+}
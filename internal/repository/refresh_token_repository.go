@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type refreshTokenRepository struct {
+	refreshTokens *mongo.Collection
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *domain.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	MarkRotated(ctx context.Context, id, replacedBy primitive.ObjectID) error
+	RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error
+	RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+func NewRefreshTokenRepository(db *database.MongoDB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		refreshTokens: db.Database.Collection("refresh_tokens"),
+	}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	result, err := r.refreshTokens.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	token.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.refreshTokens.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// MarkRotated spends the token. it only matches a token that is still unspent, so two requests racing to
+// rotate the same token can't both succeed.
+func (r *refreshTokenRepository) MarkRotated(ctx context.Context, id, replacedBy primitive.ObjectID) error {
+	filter := bson.M{
+		"_id":        id,
+		"rotated_at": bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"rotated_at":  primitive.NewDateTimeFromTime(time.Now()),
+			"replaced_by": replacedBy,
+		},
+	}
+
+	result, err := r.refreshTokens.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrRefreshTokenNotFound
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	filter := bson.M{
+		"family_id":  familyID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"revoked_at": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+
+	if _, err := r.refreshTokens.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"revoked_at": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+
+	if _, err := r.refreshTokens.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
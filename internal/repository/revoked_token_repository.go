@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type revokedTokenRepository struct {
+	revokedTokens *mongo.Collection
+}
+
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, token *domain.RevokedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+func NewRevokedTokenRepository(db *database.MongoDB) RevokedTokenRepository {
+	return &revokedTokenRepository{
+		revokedTokens: db.Database.Collection("revoked_tokens"),
+	}
+}
+
+func (r *revokedTokenRepository) Create(ctx context.Context, token *domain.RevokedToken) error {
+	token.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.revokedTokens.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	token.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	count, err := r.revokedTokens.CountDocuments(ctx, bson.M{"jti": jti})
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	return count > 0, nil
+}
@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type tripRepository struct {
@@ -18,10 +19,13 @@ type tripRepository struct {
 
 type TripRepository interface {
 	Create(ctx context.Context, trip *domain.Trip) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Trip, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Trip, error)
 	Update(ctx context.Context, trip *domain.Trip) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.TripFilter) (*ListTripsResult, error)
+	ListActive(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Trip, error)
+	ListAssignedToTrucks(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.Trip, error)
+	FindOverlapping(ctx context.Context, query domain.TripOverlapQuery) ([]*domain.Trip, error)
 }
 
 type ListTripsResult struct {
@@ -40,19 +44,21 @@ func (r *tripRepository) Create(ctx context.Context, trip *domain.Trip) error {
 	trip.CreatedAt = primitive.NewDateTimeFromTime(now)
 	trip.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.trips.InsertOne(ctx, trip)
+	result, err := r.trips.InsertOne(ctx, trip)
 	if err != nil {
 		return fmt.Errorf("failed to create trip: %w", err)
 	}
 
+	trip.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *tripRepository) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Trip, error) {
+func (r *tripRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Trip, error) {
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.M{
-			"_id":     id,
-			"user_id": userID,
+			"_id":             id,
+			"organization_id": orgID,
 		}}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "drivers",
@@ -95,6 +101,7 @@ func (r *tripRepository) GetById(ctx context.Context, id, userID primitive.Objec
 			"preserveNullAndEmptyArrays": true,
 		}}},
 	}
+	pipeline = append(pipeline, stopFacilityLookup()...)
 
 	var result domain.Trip
 	cursor, err := r.trips.Aggregate(ctx, pipeline)
@@ -119,12 +126,12 @@ func (r *tripRepository) GetById(ctx context.Context, id, userID primitive.Objec
 
 func (r *tripRepository) Update(ctx context.Context, trip *domain.Trip) error {
 	filter := bson.M{
-		"_id":     trip.ID,
-		"user_id": trip.UserID,
+		"_id":             trip.ID,
+		"organization_id": trip.OrganizationID,
 	}
 
 	// First get the existing trip to preserve any fields we don't want to lose
-	existingTrip, err := r.GetById(ctx, trip.ID, trip.UserID)
+	existingTrip, err := r.GetById(ctx, trip.ID, trip.OrganizationID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch existing trip: %w", err)
 	}
@@ -142,6 +149,15 @@ func (r *tripRepository) Update(ctx context.Context, trip *domain.Trip) error {
 	if trip.EndFacilityID == nil {
 		trip.EndFacilityID = existingTrip.EndFacilityID
 	}
+	if trip.Stops == nil {
+		trip.Stops = existingTrip.Stops
+	}
+	if trip.StartOdometer == nil {
+		trip.StartOdometer = existingTrip.StartOdometer
+	}
+	if trip.EndOdometer == nil {
+		trip.EndOdometer = existingTrip.EndOdometer
+	}
 
 	update := bson.M{
 		"$set": bson.M{
@@ -150,15 +166,19 @@ func (r *tripRepository) Update(ctx context.Context, trip *domain.Trip) error {
 			"truck_id":           trip.TruckID,
 			"start_facility_id":  trip.StartFacilityID,
 			"end_facility_id":    trip.EndFacilityID,
+			"stops":              storedStops(trip.Stops),
 			"departure_time":     trip.DepartureTime,
 			"arrival_time":       trip.ArrivalTime,
 			"status":             trip.Status,
 			"cargo":              trip.Cargo,
 			"fuel_usage_gallons": trip.FuelUsage,
 			"distance_miles":     trip.DistanceMiles,
+			"start_odometer":     trip.StartOdometer,
+			"end_odometer":       trip.EndOdometer,
+			"estimate":           trip.Estimate,
 			"notes":              trip.Notes,
 			"updated_at":         primitive.NewDateTimeFromTime(time.Now()),
-			"user_id":            trip.UserID,
+			"organization_id":    trip.OrganizationID,
 		},
 	}
 
@@ -171,7 +191,7 @@ func (r *tripRepository) Update(ctx context.Context, trip *domain.Trip) error {
 		return fmt.Errorf("trip not found")
 	}
 
-	updatedTrip, err := r.GetById(ctx, trip.ID, trip.UserID)
+	updatedTrip, err := r.GetById(ctx, trip.ID, trip.OrganizationID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch updated trip: %w", err)
 	}
@@ -181,10 +201,10 @@ func (r *tripRepository) Update(ctx context.Context, trip *domain.Trip) error {
 	return nil
 }
 
-func (r *tripRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+func (r *tripRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
 	result, err := r.trips.DeleteOne(ctx, bson.M{
-		"_id":     id,
-		"user_id": userID,
+		"_id":             id,
+		"organization_id": orgID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete trip: %w", err)
@@ -208,7 +228,7 @@ func (r *tripRepository) List(ctx context.Context, filter domain.TripFilter) (*L
 		filter.Offset = 0
 	}
 
-	filterQuery := bson.M{"user_id": filter.UserID}
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
 
 	if filter.DriverID != nil {
 		filterQuery["driver_id"] = filter.DriverID
@@ -226,6 +246,10 @@ func (r *tripRepository) List(ctx context.Context, filter domain.TripFilter) (*L
 		filterQuery["end_facility_id"] = filter.EndFacilityID
 	}
 
+	if filter.Deviating {
+		filterQuery["estimate.deviations.0"] = bson.M{"$exists": true}
+	}
+
 	total, err := r.trips.CountDocuments(ctx, filterQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total count: %w", err)
@@ -276,13 +300,14 @@ func (r *tripRepository) List(ctx context.Context, filter domain.TripFilter) (*L
 			"path":                       "$end_facility",
 			"preserveNullAndEmptyArrays": true,
 		}}},
-		{{Key: "$project", Value: bson.M{
-			"driver_id":         0,
-			"truck_id":          0,
-			"start_facility_id": 0,
-			"end_facility_id":   0,
-		}}},
 	}
+	pipeline = append(pipeline, stopFacilityLookup()...)
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{
+		"driver_id":         0,
+		"truck_id":          0,
+		"start_facility_id": 0,
+		"end_facility_id":   0,
+	}}})
 
 	cursor, err := r.trips.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -300,3 +325,141 @@ func (r *tripRepository) List(ctx context.Context, filter domain.TripFilter) (*L
 		Total: total,
 	}, nil
 }
+
+// ListActive returns every trip that is still holding a driver or truck, ordered by scheduled departure
+func (r *tripRepository) ListActive(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Trip, error) {
+	filterQuery := bson.M{
+		"organization_id": orgID,
+		"status":          bson.M{"$in": domain.ActiveTripStatuses()},
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "departure_time.scheduled", Value: 1},
+		{Key: "_id", Value: 1},
+	})
+
+	cursor, err := r.trips.Find(ctx, filterQuery, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find active trips: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	trips := make([]*domain.Trip, 0)
+	if err := cursor.All(ctx, &trips); err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	return trips, nil
+}
+
+// ListAssignedToTrucks lists every trip with a truck assigned, or only the given truck's
+func (r *tripRepository) ListAssignedToTrucks(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]*domain.Trip, error) {
+	filterQuery := bson.M{
+		"organization_id": orgID,
+		"truck_id":        bson.M{"$ne": nil},
+	}
+	if truckID != nil {
+		filterQuery["truck_id"] = truckID
+	}
+
+	cursor, err := r.trips.Find(ctx, filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trips: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	trips := make([]*domain.Trip, 0)
+	if err := cursor.All(ctx, &trips); err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	return trips, nil
+}
+
+func (r *tripRepository) FindOverlapping(ctx context.Context, query domain.TripOverlapQuery) ([]*domain.Trip, error) {
+	resources := bson.A{}
+	if query.DriverID != nil {
+		resources = append(resources, bson.M{"driver_id": query.DriverID})
+	}
+	if query.TruckID != nil {
+		resources = append(resources, bson.M{"truck_id": query.TruckID})
+	}
+
+	// nothing is assigned, so there is nothing to double-book
+	if len(resources) == 0 {
+		return []*domain.Trip{}, nil
+	}
+
+	filterQuery := bson.M{
+		"organization_id":          query.OrganizationID,
+		"status":                   bson.M{"$in": domain.ActiveTripStatuses()},
+		"departure_time.scheduled": bson.M{"$lt": query.End},
+		"arrival_time.scheduled":   bson.M{"$gt": query.Start},
+		"$or":                      resources,
+	}
+
+	if query.ExcludeID != nil {
+		filterQuery["_id"] = bson.M{"$ne": query.ExcludeID}
+	}
+
+	opts := options.Find().SetSort(bson.M{"departure_time.scheduled": 1})
+
+	cursor, err := r.trips.Find(ctx, filterQuery, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find overlapping trips: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	trips := make([]*domain.Trip, 0)
+	if err := cursor.All(ctx, &trips); err != nil {
+		return nil, fmt.Errorf("failed to decode trips: %w", err)
+	}
+
+	return trips, nil
+}
+
+// stopFacilityLookup expands the facility of every stop in place. the facilities are looked up together and
+// matched back to their stops, since an $unwind would split the trip into a document per stop. only the trip's
+// own organization's facilities are expanded, whatever ID a stop names.
+func stopFacilityLookup() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "facilities",
+			"localField":   "stops.facility_id",
+			"foreignField": "_id",
+			"let":          bson.M{"organization_id": "$organization_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$organization_id", "$$organization_id"}}}}},
+			},
+			"as": "stop_facilities",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"stops": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$stops", bson.A{}}},
+				"as":    "stop",
+				"in": bson.M{"$mergeObjects": bson.A{"$$stop", bson.M{
+					"facility": bson.M{"$arrayElemAt": bson.A{
+						bson.M{"$filter": bson.M{
+							"input": "$stop_facilities",
+							"as":    "facility",
+							"cond":  bson.M{"$eq": bson.A{"$$facility._id", "$$stop.facility_id"}},
+						}},
+						0,
+					}},
+				}}},
+			}},
+		}}},
+		{{Key: "$project", Value: bson.M{"stop_facilities": 0}}},
+	}
+}
+
+// the expanded facilities are read back with the trip, so they're stripped before the stops are written
+func storedStops(stops []domain.TripStop) []domain.TripStop {
+	stored := make([]domain.TripStop, len(stops))
+	for i, stop := range stops {
+		stop.Facility = nil
+		stored[i] = stop
+	}
+
+	return stored
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type truckPositionRepository struct {
+	positions *mongo.Collection
+}
+
+type TruckPositionRepository interface {
+	CreateMany(ctx context.Context, positions []*domain.TruckPosition) error
+	ListForTruck(ctx context.Context, orgID, truckID primitive.ObjectID, from, to primitive.DateTime) ([]domain.Position, error)
+}
+
+func NewTruckPositionRepository(db *database.MongoDB) TruckPositionRepository {
+	return &truckPositionRepository{
+		positions: db.Database.Collection("truck_positions"),
+	}
+}
+
+func (r *truckPositionRepository) CreateMany(ctx context.Context, positions []*domain.TruckPosition) error {
+	docs := make([]interface{}, len(positions))
+	for i, position := range positions {
+		docs[i] = position
+	}
+
+	result, err := r.positions.InsertMany(ctx, docs)
+	if err != nil {
+		return fmt.Errorf("failed to create truck positions: %w", err)
+	}
+
+	for i, id := range result.InsertedIDs {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			positions[i].ID = oid
+		}
+	}
+
+	return nil
+}
+
+// ListForTruck returns the truck's positions recorded within the window, oldest first
+func (r *truckPositionRepository) ListForTruck(ctx context.Context, orgID, truckID primitive.ObjectID, from, to primitive.DateTime) ([]domain.Position, error) {
+	filter := bson.M{
+		"truck_id":        truckID,
+		"organization_id": orgID,
+		"recorded_at": bson.M{
+			"$gte": from,
+			"$lte": to,
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "recorded_at", Value: 1}})
+
+	cursor, err := r.positions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find truck positions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	positions := make([]domain.Position, 0)
+	for cursor.Next(ctx) {
+		var position domain.TruckPosition
+		if err := cursor.Decode(&position); err != nil {
+			return nil, fmt.Errorf("failed to decode truck position: %w", err)
+		}
+		positions = append(positions, position.Position)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return positions, nil
+}
@@ -18,10 +18,12 @@ type truckRepository struct {
 
 type TruckRepository interface {
 	Create(ctx context.Context, truck *domain.Truck) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Truck, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Truck, error)
 	Update(ctx context.Context, truck *domain.Truck) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	UpdateLastPosition(ctx context.Context, id, orgID primitive.ObjectID, position domain.Position) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.TruckFilter) (*ListTrucksResult, error)
+	ListInService(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Truck, error)
 }
 
 type ListTrucksResult struct {
@@ -40,20 +42,22 @@ func (r *truckRepository) Create(ctx context.Context, truck *domain.Truck) error
 	truck.CreatedAt = primitive.NewDateTimeFromTime(now)
 	truck.UpdatedAt = primitive.NewDateTimeFromTime(now)
 
-	_, err := r.trucks.InsertOne(ctx, truck)
+	result, err := r.trucks.InsertOne(ctx, truck)
 	if err != nil {
 		return fmt.Errorf("failed to create truck: %w", err)
 	}
 
+	truck.ID = result.InsertedID.(primitive.ObjectID)
+
 	return nil
 }
 
-func (r *truckRepository) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Truck, error) {
+func (r *truckRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Truck, error) {
 	pipeline := mongo.Pipeline{
 		{{
 			Key: "$match", Value: bson.M{
-				"_id":     id,
-				"user_id": userID,
+				"_id":             id,
+				"organization_id": orgID,
 			},
 		}},
 		{{
@@ -99,17 +103,24 @@ func (r *truckRepository) GetById(ctx context.Context, id, userID primitive.Obje
 }
 
 func (r *truckRepository) Update(ctx context.Context, truck *domain.Truck) error {
-	filter := bson.M{"_id": truck.ID}
-	update := bson.M{
-		"$set": bson.M{
-			"mileage":          truck.Mileage,
-			"status":           truck.Status,
-			"last_maintenance": truck.LastMaintenance,
-			"updated_at":       primitive.NewDateTimeFromTime(time.Now()),
-		},
+	filter := bson.M{"_id": truck.ID, "organization_id": truck.OrganizationID}
+	set := bson.M{
+		"mileage":          truck.Mileage,
+		"status":           truck.Status,
+		"last_maintenance": truck.LastMaintenance,
+		"updated_at":       primitive.NewDateTimeFromTime(time.Now()),
 	}
 
-	result, err := r.trucks.UpdateOne(ctx, filter, update)
+	// trucks are read back with their driver expanded and the id left off, so a truck saved without one keeps the
+	// driver it has
+	if truck.AssignedDriverID != nil {
+		set["assigned_driver_id"] = truck.AssignedDriverID
+	}
+	if truck.MileageRecordedAt != 0 {
+		set["mileage_recorded_at"] = truck.MileageRecordedAt
+	}
+
+	result, err := r.trucks.UpdateOne(ctx, filter, bson.M{"$set": set})
 	if err != nil {
 		return fmt.Errorf("failed to update truck: %w", err)
 	}
@@ -121,10 +132,34 @@ func (r *truckRepository) Update(ctx context.Context, truck *domain.Truck) error
 	return nil
 }
 
-func (r *truckRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+// UpdateLastPosition only moves the truck forward in time. pings can arrive out of order when a device catches up
+// after losing signal, and an old ping shouldn't replace a newer one.
+func (r *truckRepository) UpdateLastPosition(ctx context.Context, id, orgID primitive.ObjectID, position domain.Position) error {
+	filter := bson.M{
+		"_id":             id,
+		"organization_id": orgID,
+		"$or": bson.A{
+			bson.M{"last_position": bson.M{"$exists": false}},
+			bson.M{"last_position.recorded_at": bson.M{"$lt": position.RecordedAt}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"last_position": position,
+		},
+	}
+
+	if _, err := r.trucks.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to update truck position: %w", err)
+	}
+
+	return nil
+}
+
+func (r *truckRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
 	result, err := r.trucks.DeleteOne(ctx, bson.M{
-		"_id":     id,
-		"user_id": userID,
+		"_id":             id,
+		"organization_id": orgID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete truck: %w", err)
@@ -150,8 +185,8 @@ func (r *truckRepository) List(ctx context.Context, filter domain.TruckFilter) (
 
 	filterQuery := bson.M{}
 
-	if filter.UserID != primitive.NilObjectID {
-		filterQuery["user_id"] = filter.UserID
+	if filter.OrganizationID != primitive.NilObjectID {
+		filterQuery["organization_id"] = filter.OrganizationID
 	}
 
 	if filter.TrailerType != "" {
@@ -211,3 +246,22 @@ func (r *truckRepository) List(ctx context.Context, filter domain.TruckFilter) (
 		Total:  total,
 	}, nil
 }
+
+// ListInService returns every truck in the organization that hasn't been retired
+func (r *truckRepository) ListInService(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Truck, error) {
+	cursor, err := r.trucks.Find(ctx, bson.M{
+		"organization_id": orgID,
+		"status":          bson.M{"$ne": domain.TruckStatusRetired},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trucks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	trucks := make([]*domain.Truck, 0)
+	if err := cursor.All(ctx, &trucks); err != nil {
+		return nil, fmt.Errorf("failed to decode trucks: %w", err)
+	}
+
+	return trucks, nil
+}
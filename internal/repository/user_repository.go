@@ -11,23 +11,38 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-type UserRepository struct {
+type userRepository struct {
 	db         *database.MongoDB
 	collection *mongo.Collection
 }
 
-func NewUserRepository(db *database.MongoDB) *UserRepository {
-	return &UserRepository{
+type UserRepository interface {
+	Create(ctx context.Context, user *domain.User) error
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	FindById(ctx context.Context, id primitive.ObjectID) (*domain.User, error)
+	MarkEmailVerified(ctx context.Context, id primitive.ObjectID) error
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+	SetTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor *domain.TwoFactor) error
+	ClaimTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error)
+}
+
+func NewUserRepository(db *database.MongoDB) UserRepository {
+	return &userRepository{
 		db:         db,
 		collection: db.Database.Collection("users"),
 	}
 }
 
-func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
+	// the unique email index settles two registrations racing for the same address
 	result, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrUserExists
+	}
 	if err != nil {
 		return err
 	}
@@ -36,7 +51,7 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
-func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
 	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
 	if err == mongo.ErrNoDocuments {
@@ -47,3 +62,87 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain
 	}
 	return &user, nil
 }
+
+func (r *userRepository) FindById(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+	var user domain.User
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{"_id": id, "email_verified_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"email_verified_at": now, "updated_at": now}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"password": passwordHash, "password_changed_at": now, "updated_at": now}}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetTwoFactor replaces the user's enrollment, or removes it when twoFactor is nil
+func (r *userRepository) SetTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor *domain.TwoFactor) error {
+	update := bson.M{"$set": bson.M{"two_factor": twoFactor, "updated_at": time.Now()}}
+	if twoFactor == nil {
+		update = bson.M{"$unset": bson.M{"two_factor": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ClaimTOTPStep records that a code from the step was used. it fails when that step or a later one was already
+// used, so each code only works once even when two requests present it together.
+func (r *userRepository) ClaimTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{
+		"_id":                       id,
+		"two_factor":                bson.M{"$exists": true},
+		"two_factor.last_used_step": bson.M{"$lt": step},
+	}
+	update := bson.M{"$set": bson.M{"two_factor.last_used_step": step}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *userRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	filter := bson.M{"_id": id, "two_factor.recovery_codes": codeHash}
+	update := bson.M{"$pull": bson.M{"two_factor.recovery_codes": codeHash}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type workOrderRepository struct {
+	workOrders *mongo.Collection
+}
+
+type WorkOrderRepository interface {
+	Create(ctx context.Context, workOrder *domain.WorkOrder) error
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.WorkOrder, error)
+	Update(ctx context.Context, workOrder *domain.WorkOrder) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
+	List(ctx context.Context, filter domain.WorkOrderFilter) (*ListWorkOrdersResult, error)
+	CountHoldingTruck(ctx context.Context, truckID, orgID primitive.ObjectID) (int64, error)
+}
+
+type ListWorkOrdersResult struct {
+	WorkOrders []*domain.WorkOrder
+	Total      int64
+}
+
+func NewWorkOrderRepository(db *database.MongoDB) WorkOrderRepository {
+	return &workOrderRepository{
+		workOrders: db.Database.Collection("work_orders"),
+	}
+}
+
+func (r *workOrderRepository) Create(ctx context.Context, workOrder *domain.WorkOrder) error {
+	now := time.Now()
+	workOrder.CreatedAt = primitive.NewDateTimeFromTime(now)
+	workOrder.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	result, err := r.workOrders.InsertOne(ctx, workOrder)
+	if err != nil {
+		return fmt.Errorf("failed to create work order: %w", err)
+	}
+
+	workOrder.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *workOrderRepository) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.WorkOrder, error) {
+	var workOrder domain.WorkOrder
+	err := r.workOrders.FindOne(ctx, bson.M{"_id": id, "organization_id": orgID}).Decode(&workOrder)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work order: %w", err)
+	}
+
+	return &workOrder, nil
+}
+
+func (r *workOrderRepository) Update(ctx context.Context, workOrder *domain.WorkOrder) error {
+	filter := bson.M{"_id": workOrder.ID, "organization_id": workOrder.OrganizationID}
+	update := bson.M{
+		"$set": bson.M{
+			"schedule_id":        workOrder.ScheduleID,
+			"title":              workOrder.Title,
+			"description":        workOrder.Description,
+			"service_type":       workOrder.ServiceType,
+			"mechanic":           workOrder.Mechanic,
+			"location":           workOrder.Location,
+			"status":             workOrder.Status,
+			"lines":              workOrder.Lines,
+			"mileage":            workOrder.Mileage,
+			"started_at":         workOrder.StartedAt,
+			"closed_at":          workOrder.ClosedAt,
+			"maintenance_log_id": workOrder.MaintenanceLogID,
+			"updated_at":         primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+
+	result, err := r.workOrders.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update work order: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrWorkOrderNotFound
+	}
+
+	return nil
+}
+
+func (r *workOrderRepository) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	result, err := r.workOrders.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+	if err != nil {
+		return fmt.Errorf("failed to delete work order: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrWorkOrderNotFound
+	}
+
+	return nil
+}
+
+func (r *workOrderRepository) List(ctx context.Context, filter domain.WorkOrderFilter) (*ListWorkOrdersResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	filterQuery := bson.M{"organization_id": filter.OrganizationID}
+
+	if filter.TruckID != nil {
+		filterQuery["truck_id"] = filter.TruckID
+	}
+	if filter.Status != "" {
+		filterQuery["status"] = filter.Status
+	}
+
+	total, err := r.workOrders.CountDocuments(ctx, filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.workOrders.Find(ctx, filterQuery, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find work orders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	workOrders := make([]*domain.WorkOrder, 0, filter.Limit)
+	if err := cursor.All(ctx, &workOrders); err != nil {
+		return nil, fmt.Errorf("failed to decode work orders: %w", err)
+	}
+
+	return &ListWorkOrdersResult{
+		WorkOrders: workOrders,
+		Total:      total,
+	}, nil
+}
+
+// CountHoldingTruck counts the work orders that have the truck in the shop
+func (r *workOrderRepository) CountHoldingTruck(ctx context.Context, truckID, orgID primitive.ObjectID) (int64, error) {
+	count, err := r.workOrders.CountDocuments(ctx, bson.M{
+		"organization_id": orgID,
+		"truck_id":        truckID,
+		"status":          bson.M{"$in": bson.A{domain.WorkOrderStatusInProgress, domain.WorkOrderStatusAwaitingParts}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count work orders: %w", err)
+	}
+
+	return count, nil
+}
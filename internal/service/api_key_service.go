@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrScopeExceedsRole = errors.New("api key scope is not allowed by your role")
+)
+
+// last-used timestamps only need to be roughly right, so a busy key isn't written on every request
+const apiKeyUsageResolution = time.Minute
+
+type APIKeyService interface {
+	Create(ctx context.Context, membership *domain.Membership, name string, scopes []domain.Permission, expiresAt *time.Time) (*domain.APIKey, string, error)
+	List(ctx context.Context, filter domain.APIKeyFilter) (*repository.ListAPIKeysResult, error)
+	Revoke(ctx context.Context, id primitive.ObjectID, membership *domain.Membership) error
+	Authenticate(ctx context.Context, plaintext string) (*domain.APIKey, error)
+}
+
+type apiKeyService struct {
+	db         *database.MongoDB
+	apiKeyRepo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(db *database.MongoDB, apiKeyRepo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		db:         db,
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// Create issues a key in the member's organization. a key can't be given a scope its creator's role doesn't have.
+func (s *apiKeyService) Create(
+	ctx context.Context,
+	membership *domain.Membership,
+	name string,
+	scopes []domain.Permission,
+	expiresAt *time.Time) (*domain.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !membership.HasPermission(scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrScopeExceedsRole, scope)
+		}
+	}
+
+	key, plaintext, err := domain.NewAPIKey(membership.OrganizationID, membership.UserID, name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return key, plaintext, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, filter domain.APIKeyFilter) (*repository.ListAPIKeysResult, error) {
+	result, err := s.apiKeyRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	if result.APIKeys == nil {
+		result.APIKeys = []*domain.APIKey{}
+	}
+
+	return result, nil
+}
+
+// Revoke disables a key for good. members can revoke their own keys and anyone who manages members can revoke
+// any key in the organization; to everyone else other people's keys don't exist.
+func (s *apiKeyService) Revoke(ctx context.Context, id primitive.ObjectID, membership *domain.Membership) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		key, err := s.apiKeyRepo.GetById(ctx, id, membership.OrganizationID)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve api key: %w", err)
+		}
+		if key == nil {
+			return domain.ErrAPIKeyNotFound
+		}
+
+		if key.UserID != membership.UserID && !membership.HasPermission(domain.PermissionMembersManage) {
+			return domain.ErrAPIKeyNotFound
+		}
+
+		return s.apiKeyRepo.Revoke(ctx, id, membership.OrganizationID)
+	})
+}
+
+// Authenticate looks up the key a request was made with. unknown, revoked and expired keys are all reported
+// as not found so that callers can't tell them apart.
+func (s *apiKeyService) Authenticate(ctx context.Context, plaintext string) (*domain.APIKey, error) {
+	key, err := s.apiKeyRepo.FindByHash(ctx, domain.HashToken(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || !key.IsUsable() {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(key.LastUsedAt.Time()) >= apiKeyUsageResolution {
+		// failing to record usage shouldn't fail the request the key was used for
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, now); err == nil {
+			lastUsed := primitive.NewDateTimeFromTime(now)
+			key.LastUsedAt = &lastUsed
+		}
+	}
+
+	return key, nil
+}
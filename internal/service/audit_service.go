@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, entityType domain.AuditEntityType, entityID, orgID primitive.ObjectID, action domain.AuditAction, before, after any) error
+	History(ctx context.Context, filter domain.AuditEventFilter) (*repository.ListAuditEventsResult, error)
+}
+
+type auditService struct {
+	db             *database.MongoDB
+	auditEventRepo repository.AuditEventRepository
+}
+
+func NewAuditService(db *database.MongoDB, auditEventRepo repository.AuditEventRepository) AuditService {
+	return &auditService{
+		db:             db,
+		auditEventRepo: auditEventRepo,
+	}
+}
+
+// Record writes an audit event for a mutation to an entity owned by orgID. the event is attributed to the user
+// making the request, and is left unattributed when the mutation didn't come in through an authenticated request.
+// callers should record inside the same transaction as the mutation so that the two can't drift apart.
+func (s *auditService) Record(
+	ctx context.Context,
+	entityType domain.AuditEntityType,
+	entityID, orgID primitive.ObjectID,
+	action domain.AuditAction,
+	before, after any) error {
+	userID, _ := domain.ActorFromContext(ctx)
+
+	event, err := domain.NewAuditEvent(orgID, userID, entityType, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auditEventRepo.Create(ctx, event); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *auditService) History(ctx context.Context, filter domain.AuditEventFilter) (*repository.ListAuditEventsResult, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("context is required")
+	}
+
+	result, err := s.auditEventRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	if result.AuditEvents == nil {
+		result.AuditEvents = []*domain.AuditEvent{}
+	}
+
+	return result, nil
+}
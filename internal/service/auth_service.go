@@ -3,35 +3,117 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jwald3/waybill/internal/database"
 	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/mailer"
 	"github.com/jwald3/waybill/internal/repository"
+	"github.com/jwald3/waybill/internal/signing"
+	"github.com/jwald3/waybill/internal/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserExists         = errors.New("user already exists")
+	ErrUserExists         = domain.ErrUserExists
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
+	ErrEmailNotVerified   = errors.New("email address has not been verified")
+	ErrPasswordTooShort   = errors.New("password must be at least 8 characters")
+
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
 )
 
+const minPasswordLength = 8
+
+// how long mail sent after a request has been answered gets before it's given up on
+const backgroundTimeout = 30 * time.Second
+
+// AuthConfig holds the signing keys and token lifetimes. access tokens are kept short because they can only be
+// revoked by listing them; refresh tokens live longer but are single use.
+type AuthConfig struct {
+	Keys                     *signing.KeySet
+	AccessTokenTTL           time.Duration
+	RefreshTokenTTL          time.Duration
+	EmailVerificationTTL     time.Duration
+	PasswordResetTTL         time.Duration
+	RequireEmailVerification bool
+
+	// links in emails are built on this, e.g. <LinkBaseURL>/reset-password?token=...
+	LinkBaseURL string
+
+	TwoFactorIssuer       string
+	TwoFactorChallengeTTL time.Duration
+}
+
 type AuthService struct {
-	db       *database.MongoDB
-	userRepo *repository.UserRepository
-	jwtKey   []byte
+	db                       *database.MongoDB
+	userRepo                 repository.UserRepository
+	refreshTokenRepo         repository.RefreshTokenRepository
+	revokedTokenRepo         repository.RevokedTokenRepository
+	emailTokenRepo           repository.EmailTokenRepository
+	organizations            OrganizationService
+	logins                   LoginThrottleService
+	mailer                   mailer.Mailer
+	log                      *zap.Logger
+	keys                     *signing.KeySet
+	accessTokenTTL           time.Duration
+	refreshTokenTTL          time.Duration
+	emailVerificationTTL     time.Duration
+	passwordResetTTL         time.Duration
+	requireEmailVerification bool
+	linkBaseURL              string
+	twoFactorIssuer          string
+	twoFactorChallengeTTL    time.Duration
 }
 
-func NewAuthService(db *database.MongoDB, userRepo *repository.UserRepository, jwtKey string) *AuthService {
+func NewAuthService(
+	db *database.MongoDB,
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revokedTokenRepo repository.RevokedTokenRepository,
+	emailTokenRepo repository.EmailTokenRepository,
+	organizations OrganizationService,
+	logins LoginThrottleService,
+	mail mailer.Mailer,
+	log *zap.Logger,
+	cfg AuthConfig) *AuthService {
 	return &AuthService{
-		db:       db,
-		userRepo: userRepo,
-		jwtKey:   []byte(jwtKey),
+		db:                       db,
+		userRepo:                 userRepo,
+		refreshTokenRepo:         refreshTokenRepo,
+		revokedTokenRepo:         revokedTokenRepo,
+		emailTokenRepo:           emailTokenRepo,
+		organizations:            organizations,
+		logins:                   logins,
+		mailer:                   mail,
+		log:                      log,
+		keys:                     cfg.Keys,
+		accessTokenTTL:           cfg.AccessTokenTTL,
+		refreshTokenTTL:          cfg.RefreshTokenTTL,
+		emailVerificationTTL:     cfg.EmailVerificationTTL,
+		passwordResetTTL:         cfg.PasswordResetTTL,
+		requireEmailVerification: cfg.RequireEmailVerification,
+		linkBaseURL:              strings.TrimRight(cfg.LinkBaseURL, "/"),
+		twoFactorIssuer:          cfg.TwoFactorIssuer,
+		twoFactorChallengeTTL:    cfg.TwoFactorChallengeTTL,
 	}
 }
 
 func (s *AuthService) Register(ctx context.Context, req *domain.RegisterRequest) error {
+	if len(req.Password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
 	existing, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return err
@@ -50,32 +132,673 @@ func (s *AuthService) Register(ctx context.Context, req *domain.RegisterRequest)
 		Password: string(hashedPassword),
 	}
 
-	return s.userRepo.Create(ctx, user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.ensurePersonalOrganization(ctx, user); err != nil {
+		return err
+	}
+
+	// the account exists by now, so a mail that doesn't go out is left for ResendVerification rather than failing
+	// the registration
+	s.inBackground(ctx, "verification email", func(ctx context.Context) error {
+		return s.sendVerificationEmail(ctx, user)
+	})
+
+	return nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (string, error) {
+// Login checks the user's password. clientIP is the address the attempt came from, which failed attempts are
+// counted against as well as the account. accounts with two-factor authentication get a challenge instead of
+// tokens, to be redeemed with VerifyTwoFactor.
+func (s *AuthService) Login(ctx context.Context, req *domain.LoginRequest, clientIP string) (*domain.LoginResult, error) {
+	if err := s.logins.Check(ctx, req.Email, clientIP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if user == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		if err := s.logins.RecordFailure(ctx, req.Email, clientIP, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// with a second factor the login isn't over until VerifyTwoFactor, and clearing the failures here would let
+	// every correct password reset the count of wrong codes
+	if !user.TwoFactorEnabled() {
+		if err := s.logins.RecordSuccess(ctx, req.Email, clientIP); err != nil {
+			return nil, err
+		}
+	}
+
+	// only reported once the password checks out, so it doesn't reveal which addresses have accounts
+	if s.requireEmailVerification && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	if err := s.ensurePersonalOrganization(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled() {
+		challenge, err := s.signChallengeToken(user)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.LoginResult{
+			TwoFactorRequired:  true,
+			ChallengeToken:     challenge,
+			ChallengeExpiresIn: int64(s.twoFactorChallengeTTL.Seconds()),
+		}, nil
+	}
+
+	// each login starts a new refresh token family
+	pair, err := s.issueTokens(ctx, user, primitive.NewObjectID(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResult{TokenPair: pair}, nil
+}
+
+// VerifyTwoFactor finishes a login that was challenged for a second factor. the code can come from the user's
+// authenticator app or be one of their recovery codes. wrong codes count as failed logins, so guessing them runs
+// into the same lockout as guessing passwords.
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, challengeToken, code, clientIP string) (*domain.TokenPair, error) {
+	claims, err := s.parseSignedToken(challengeToken, domain.TokenUseTwoFactorChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	userID, err := primitive.ObjectIDFromHex(subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.TwoFactorEnabled() {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.logins.Check(ctx, user.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.logins.RecordFailure(ctx, user.Email, clientIP, user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.logins.RecordSuccess(ctx, user.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, primitive.NewObjectID(), nil)
+}
+
+// EnrollTwoFactor starts enrolling the user, replacing an enrollment that was never confirmed. it doesn't take
+// effect until ConfirmTwoFactor.
+func (s *AuthService) EnrollTwoFactor(ctx context.Context, userID primitive.ObjectID) (*domain.TwoFactorEnrollment, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTwoFactor(ctx, user.ID, &domain.TwoFactor{Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &domain.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor turns two-factor authentication on once the user shows a working code, and returns their
+// recovery codes. this is the only time the codes are shown.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactor == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := domain.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	enrollment := &domain.TwoFactor{
+		Secret:        user.TwoFactor.Secret,
+		ConfirmedAt:   &now,
+		LastUsedStep:  step,
+		RecoveryCodes: hashes,
+	}
+
+	if err := s.userRepo.SetTwoFactor(ctx, user.ID, enrollment); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off. it takes a current code so that someone who only has
+// the user's session can't remove it.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID, code string) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+
+	return s.userRepo.SetTwoFactor(ctx, user.ID, nil)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, for when they've used up or lost the old ones
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := domain.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// read the enrollment again, since checking the code moved its last used step on
+	user, err = s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment := *user.TwoFactor
+	enrollment.RecoveryCodes = hashes
+
+	if err := s.userRepo.SetTwoFactor(ctx, user.ID, &enrollment); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// checkSecondFactor accepts a code from the authenticator app, or spends a recovery code
+func (s *AuthService) checkSecondFactor(ctx context.Context, user *domain.User, code string) error {
+	if step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now()); ok {
+		claimed, err := s.userRepo.ClaimTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.ID, domain.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (s *AuthService) findUser(ctx context.Context, userID primitive.ObjectID) (*domain.User, error) {
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// Refresh trades a refresh token for a new access and refresh token. a token that was already traded in is
+// evidence that it leaked, so presenting one again revokes every token descended from the same login.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(ctx, domain.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.RevokedAt != nil || stored.IsExpired() {
+		return nil, ErrInvalidToken
+	}
+	if stored.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored.FamilyID)
+	}
+
+	user, err := s.userRepo.FindById(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidToken
+	}
+
+	pair, err := s.issueTokens(ctx, user, stored.FamilyID, &stored.ID)
+	if errors.Is(err, domain.ErrRefreshTokenNotFound) {
+		// another request rotated the token between our read and our write
+		return nil, s.revokeReusedFamily(ctx, stored.FamilyID)
+	}
+
+	return pair, err
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the access token the request was made with and, when one is given, the refresh token family
+// issued alongside it
+func (s *AuthService) Logout(ctx context.Context, claims jwt.MapClaims, refreshToken string) error {
+	userID, jti, expiresAt, err := accessTokenIdentity(claims)
+	if err != nil {
+		return err
+	}
+
+	var family *primitive.ObjectID
+	if refreshToken != "" {
+		stored, err := s.refreshTokenRepo.FindByHash(ctx, domain.HashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.UserID != userID {
+			return ErrInvalidToken
+		}
+		family = &stored.FamilyID
+	}
+
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		revoked := &domain.RevokedToken{
+			JTI:       jti,
+			UserID:    userID,
+			ExpiresAt: primitive.NewDateTimeFromTime(expiresAt),
+		}
+		if err := s.revokedTokenRepo.Create(ctx, revoked); err != nil {
+			return err
+		}
+
+		if family != nil {
+			return s.refreshTokenRepo.RevokeFamily(ctx, *family)
+		}
+
+		return nil
+	})
+}
+
+// IsRevoked reports whether an access token was revoked before it expired, either on its own or by its user's
+// password changing after it was issued. iat only has whole seconds, so the change is compared at that precision.
+func (s *AuthService) IsRevoked(ctx context.Context, jti string, userID primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	revoked, err := s.revokedTokenRepo.IsRevoked(ctx, jti)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return false, err
 	}
 	if user == nil {
-		return "", ErrInvalidCredentials
+		return true, nil
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return "", ErrInvalidCredentials
+	return user.PasswordChangedAt != nil && issuedAt.Before(user.PasswordChangedAt.Truncate(time.Second)), nil
+}
+
+// issueTokens signs a new access token and stores a new refresh token in the family. when the new refresh token
+// replaces an older one, the older one is spent in the same transaction.
+func (s *AuthService) issueTokens(ctx context.Context, user *domain.User, familyID primitive.ObjectID, replaces *primitive.ObjectID) (*domain.TokenPair, error) {
+	refreshToken, plaintext, err := domain.NewRefreshToken(user.ID, familyID, s.refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.refreshTokenRepo.Create(ctx, refreshToken); err != nil {
+			return err
+		}
+
+		if replaces != nil {
+			return s.refreshTokenRepo.MarkRotated(ctx, *replaces, refreshToken.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	accessToken, err := s.signAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: plaintext,
+		ExpiresIn:    int64(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *AuthService) signAccessToken(user *domain.User) (string, error) {
+	now := time.Now()
+
+	return s.sign(domain.TokenUseAccess, jwt.MapClaims{
 		"user_id": user.ID.Hex(),
 		"email":   user.Email,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"jti":     primitive.NewObjectID().Hex(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(s.accessTokenTTL).Unix(),
+	})
+}
+
+// the challenge only proves the password was right, so it lives just long enough to type in a code
+func (s *AuthService) signChallengeToken(user *domain.User) (string, error) {
+	now := time.Now()
+
+	return s.sign(domain.TokenUseTwoFactorChallenge, jwt.MapClaims{
+		"sub": user.ID.Hex(),
+		"jti": primitive.NewObjectID().Hex(),
+		"iat": now.Unix(),
+		"exp": now.Add(s.twoFactorChallengeTTL).Unix(),
+	})
+}
+
+// sign marks the claims with what the token is for, both in token_use and in an audience of its own, and signs them
+func (s *AuthService) sign(tokenUse string, claims jwt.MapClaims) (string, error) {
+	claims["token_use"] = tokenUse
+	claims["iss"] = domain.TokenIssuer
+	claims["aud"] = domain.TokenAudience(tokenUse)
+
+	return s.keys.Sign(claims)
+}
+
+// parseSignedToken checks a token we signed for something other than access, such as an email link or a login
+// challenge, and returns its claims
+func (s *AuthService) parseSignedToken(token, tokenUse string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(token, s.keys.Keyfunc,
+		jwt.WithValidMethods(s.keys.Algorithms()),
+		jwt.WithIssuer(domain.TokenIssuer),
+		jwt.WithAudience(domain.TokenAudience(tokenUse)))
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["token_use"] != tokenUse {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func accessTokenIdentity(claims jwt.MapClaims) (primitive.ObjectID, string, time.Time, error) {
+	userIDStr, _ := claims["user_id"].(string)
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return primitive.NilObjectID, "", time.Time{}, fmt.Errorf("%w: missing user id", ErrInvalidToken)
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return primitive.NilObjectID, "", time.Time{}, fmt.Errorf("%w: missing token id", ErrInvalidToken)
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return primitive.NilObjectID, "", time.Time{}, fmt.Errorf("%w: missing expiration", ErrInvalidToken)
+	}
+
+	return userID, jti, expiresAt.Time, nil
+}
+
+// VerifyEmail redeems the token from a verification email
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	stored, err := s.redeemEmailToken(ctx, token, domain.EmailTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.MarkEmailVerified(ctx, stored.UserID)
+}
+
+// ResendVerification sends a fresh verification email, replacing any sent before. nothing is sent when there's no
+// such account or it's already verified. the lookup and the mail both happen after it returns, so neither its
+// result nor how long it takes can be used to find out which is which.
+func (s *AuthService) ResendVerification(ctx context.Context, email string) {
+	s.inBackground(ctx, "verification email", func(ctx context.Context) error {
+		user, err := s.userRepo.FindByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		if user == nil || user.IsEmailVerified() {
+			return nil
+		}
+
+		return s.sendVerificationEmail(ctx, user)
+	})
+}
+
+// ForgotPassword emails a password reset link. like ResendVerification, it doesn't say whether the account exists.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) {
+	s.inBackground(ctx, "password reset email", func(ctx context.Context) error {
+		user, err := s.userRepo.FindByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return nil
+		}
+
+		link, err := s.issueEmailToken(ctx, user, domain.EmailTokenResetPassword, s.passwordResetTTL, "reset-password")
+		if err != nil {
+			return err
+		}
+
+		return s.mailer.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your Waybill password",
+			Body: fmt.Sprintf("Someone asked to reset the password for your Waybill account. To choose a new password, open this link:\n\n%s\n\n"+
+				"The link expires in %s. If you didn't ask for this, you can ignore this email.\n", link, s.passwordResetTTL),
+		})
+	})
+}
+
+// ResetPassword sets a new password with the token from a reset email. every session the account had is signed
+// out: its refresh tokens are revoked, and access tokens issued before the change stop being accepted. a lockout
+// from failed logins is lifted. following the link also proves the user owns the address.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var userID primitive.ObjectID
+	err = runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		stored, err := s.redeemEmailToken(ctx, token, domain.EmailTokenResetPassword)
+		if err != nil {
+			return err
+		}
+		userID = stored.UserID
+
+		if err := s.userRepo.UpdatePassword(ctx, userID, string(hashedPassword)); err != nil {
+			return err
+		}
+
+		if err := s.userRepo.MarkEmailVerified(ctx, userID); err != nil {
+			return err
+		}
+
+		return s.refreshTokenRepo.RevokeAllForUser(ctx, userID)
 	})
+	if err != nil {
+		return err
+	}
 
-	tokenString, err := token.SignedString(s.jwtKey)
+	return s.logins.Unlock(ctx, userID)
+}
+
+// inBackground runs work that the caller shouldn't wait on, or hear about, once the request that asked for it has
+// been answered. failures can only be logged.
+func (s *AuthService) inBackground(ctx context.Context, what string, work func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+
+	go func() {
+		defer cancel()
+
+		if err := work(ctx); err != nil {
+			s.log.Error("failed to send "+what, zap.Error(err))
+		}
+	}()
+}
+
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *domain.User) error {
+	link, err := s.issueEmailToken(ctx, user, domain.EmailTokenVerifyEmail, s.emailVerificationTTL, "verify-email")
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address for Waybill",
+		Body: fmt.Sprintf("Welcome to Waybill! Please confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s.\n", link, s.emailVerificationTTL),
+	})
+}
+
+// issueEmailToken signs a single use token for the purpose and returns the link to put in the email. tokens sent
+// earlier for the same purpose stop working, so only the latest email's link can be used.
+func (s *AuthService) issueEmailToken(
+	ctx context.Context,
+	user *domain.User,
+	purpose domain.EmailTokenPurpose,
+	ttl time.Duration,
+	path string) (string, error) {
+	stored := domain.NewEmailToken(user.ID, purpose, ttl)
+
+	err := runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.emailTokenRepo.InvalidateForUser(ctx, user.ID, purpose); err != nil {
+			return err
+		}
+
+		return s.emailTokenRepo.Create(ctx, stored)
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	token, err := s.sign(string(purpose), jwt.MapClaims{
+		"sub": user.ID.Hex(),
+		"jti": stored.ID.Hex(),
+		"iat": stored.CreatedAt.Time().Unix(),
+		"exp": stored.ExpiresAt.Time().Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s?token=%s", s.linkBaseURL, path, url.QueryEscape(token)), nil
+}
+
+// redeemEmailToken checks the token's signature and purpose, then spends it
+func (s *AuthService) redeemEmailToken(ctx context.Context, token string, purpose domain.EmailTokenPurpose) (*domain.EmailToken, error) {
+	claims, err := s.parseSignedToken(token, string(purpose))
+	if err != nil {
+		return nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	id, err := primitive.ObjectIDFromHex(jti)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	stored, err := s.emailTokenRepo.Consume(ctx, id, purpose)
+	if errors.Is(err, domain.ErrEmailTokenNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subject, _ := claims["sub"].(string); subject != stored.UserID.Hex() {
+		return nil, ErrInvalidToken
+	}
+
+	return stored, nil
+}
+
+// every user owns at least one organization so that they have somewhere to keep their fleet. accounts created
+// before organizations existed get theirs the next time they log in.
+func (s *AuthService) ensurePersonalOrganization(ctx context.Context, user *domain.User) error {
+	memberships, err := s.organizations.ListMemberships(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(memberships) > 0 {
+		return nil
+	}
+
+	_, err = s.organizations.CreateOrganization(ctx, user.Email, user.ID)
+	return err
 }
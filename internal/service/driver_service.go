@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jwald3/waybill/internal/database"
 	"github.com/jwald3/waybill/internal/domain"
@@ -16,37 +17,42 @@ var (
 
 type DriverService interface {
 	Create(ctx context.Context, driver *domain.Driver) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Driver, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Driver, error)
 	Update(ctx context.Context, driver *domain.Driver) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.DriverFilter) (*repository.ListDriversResult, error)
-	SuspendDriver(ctx context.Context, id, userID primitive.ObjectID) error
-	TerminateDriver(ctx context.Context, id, userID primitive.ObjectID) error
-	ActivateDriver(ctx context.Context, id, userID primitive.ObjectID) error
+	SuspendDriver(ctx context.Context, id, orgID primitive.ObjectID) error
+	TerminateDriver(ctx context.Context, id, orgID primitive.ObjectID) error
+	ActivateDriver(ctx context.Context, id, orgID primitive.ObjectID) error
+	ListExpiring(ctx context.Context, orgID primitive.ObjectID, withinDays int) ([]domain.CredentialExpiration, error)
 }
 
 type driverService struct {
 	db         *database.MongoDB
 	driverRepo repository.DriverRepository
+	audit      AuditService
 }
 
-func NewDriverService(db *database.MongoDB, driverRepo repository.DriverRepository) DriverService {
+func NewDriverService(db *database.MongoDB, driverRepo repository.DriverRepository, audit AuditService) DriverService {
 	return &driverService{
 		db:         db,
 		driverRepo: driverRepo,
+		audit:      audit,
 	}
 }
 
 func (s *driverService) Create(ctx context.Context, driver *domain.Driver) error {
-	if err := s.driverRepo.Create(ctx, driver); err != nil {
-		return fmt.Errorf("failed to create driver: %w", err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.driverRepo.Create(ctx, driver); err != nil {
+			return fmt.Errorf("failed to create driver: %w", err)
+		}
 
-	return nil
+		return s.audit.Record(ctx, domain.AuditEntityDriver, driver.ID, driver.OrganizationID, domain.AuditActionCreate, nil, driver)
+	})
 }
 
-func (s *driverService) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Driver, error) {
-	driver, err := s.driverRepo.GetById(ctx, id, userID)
+func (s *driverService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Driver, error) {
+	driver, err := s.driverRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(driverNotFound, err)
 	}
@@ -58,23 +64,39 @@ func (s *driverService) GetById(ctx context.Context, id, userID primitive.Object
 }
 
 func (s *driverService) Update(ctx context.Context, driver *domain.Driver) error {
-	err := s.driverRepo.Update(ctx, driver)
-	if err != nil {
-		return fmt.Errorf(driverNotFound, err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.driverRepo.GetById(ctx, driver.ID, driver.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(driverNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrDriverNotFound
+		}
+
+		if err := s.driverRepo.Update(ctx, driver); err != nil {
+			return fmt.Errorf(driverNotFound, err)
+		}
 
-	return nil
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
 }
 
-func (s *driverService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	if err := s.driverRepo.Delete(ctx, id, userID); err != nil {
-		if err == domain.ErrDriverNotFound {
-			return err
+func (s *driverService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.driverRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(driverNotFound, err)
+		}
+
+		if err := s.driverRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrDriverNotFound {
+				return err
+			}
+			return fmt.Errorf("failed to delete driver: %w", err)
 		}
-		return fmt.Errorf("failed to delete driver: %w", err)
-	}
 
-	return nil
+		return s.audit.Record(ctx, domain.AuditEntityDriver, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *driverService) List(ctx context.Context, filter domain.DriverFilter) (*repository.ListDriversResult, error) {
@@ -94,63 +116,118 @@ func (s *driverService) List(ctx context.Context, filter domain.DriverFilter) (*
 	return result, nil
 }
 
-// Atomic methods
-func (s *driverService) SuspendDriver(ctx context.Context, id, userID primitive.ObjectID) error {
-	driver, err := s.driverRepo.GetById(ctx, id, userID)
+// ListExpiring reports every license and credential that has expired or will within the given number of days,
+// soonest first
+func (s *driverService) ListExpiring(ctx context.Context, orgID primitive.ObjectID, withinDays int) ([]domain.CredentialExpiration, error) {
+	now := time.Now()
+	// everything expiring on the last day of the window is included, so the cutoff is the start of the day after
+	cutoff := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, withinDays+1)
+
+	drivers, err := s.driverRepo.ListExpiring(ctx, orgID, primitive.NewDateTimeFromTime(cutoff))
 	if err != nil {
-		return fmt.Errorf("failed to get driver: %w", err)
-	}
-	if driver == nil {
-		return domain.ErrDriverNotFound
+		return nil, fmt.Errorf("failed to list expiring credentials: %w", err)
 	}
 
-	if err := driver.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
+	expirations := make([]domain.CredentialExpiration, 0)
+	for _, driver := range drivers {
+		expirations = append(expirations, driver.Expirations(cutoff, now)...)
 	}
+	domain.SortCredentialExpirations(expirations)
 
-	if err := driver.SuspendDriver(); err != nil {
-		return err
-	}
+	return expirations, nil
+}
+
+// Atomic methods
+func (s *driverService) SuspendDriver(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		driver, err := s.driverRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to get driver: %w", err)
+		}
+		if driver == nil {
+			return domain.ErrDriverNotFound
+		}
+
+		if err := driver.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
+
+		before := *driver
+		if err := driver.SuspendDriver(); err != nil {
+			return err
+		}
 
-	return s.driverRepo.Update(ctx, driver)
+		if err := s.driverRepo.Update(ctx, driver); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
 }
 
-func (s *driverService) TerminateDriver(ctx context.Context, id, userID primitive.ObjectID) error {
-	driver, err := s.driverRepo.GetById(ctx, id, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get driver: %w", err)
-	}
-	if driver == nil {
-		return domain.ErrDriverNotFound
-	}
+func (s *driverService) TerminateDriver(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		driver, err := s.driverRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to get driver: %w", err)
+		}
+		if driver == nil {
+			return domain.ErrDriverNotFound
+		}
 
-	if err := driver.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
-	}
+		if err := driver.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
 
-	if err := driver.TerminateDriver(); err != nil {
-		return err
-	}
+		before := *driver
+		if err := driver.TerminateDriver(); err != nil {
+			return err
+		}
+
+		if err := s.driverRepo.Update(ctx, driver); err != nil {
+			return err
+		}
 
-	return s.driverRepo.Update(ctx, driver)
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
 }
 
-func (s *driverService) ActivateDriver(ctx context.Context, id, userID primitive.ObjectID) error {
-	driver, err := s.driverRepo.GetById(ctx, id, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get driver: %w", err)
-	}
-	if driver == nil {
-		return domain.ErrDriverNotFound
-	}
+func (s *driverService) ActivateDriver(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		driver, err := s.driverRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to get driver: %w", err)
+		}
+		if driver == nil {
+			return domain.ErrDriverNotFound
+		}
 
-	if err := driver.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
-	}
+		if err := driver.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
+
+		before := *driver
+		if err := driver.ActivateDriver(); err != nil {
+			return err
+		}
 
-	if err := driver.ActivateDriver(); err != nil {
-		return err
+		if err := s.driverRepo.Update(ctx, driver); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+// helpers
+
+// recordChange audits a mutation of an existing driver, reading the driver back so that the before and
+// after snapshots have the same shape
+func (s *driverService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.Driver) error {
+	after, err := s.driverRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(driverNotFound, err)
 	}
 
-	return s.driverRepo.Update(ctx, driver)
+	return s.audit.Record(ctx, domain.AuditEntityDriver, before.ID, before.OrganizationID, action, before, after)
 }
@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jwald3/waybill/internal/database"
 	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/geocode"
 	"github.com/jwald3/waybill/internal/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -16,35 +18,49 @@ var (
 
 type FacilityService interface {
 	Create(ctx context.Context, facility *domain.Facility) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Facility, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Facility, error)
 	Update(ctx context.Context, facility *domain.Facility) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	ListWithFilter(ctx context.Context, filter domain.FacilityFilter) (*repository.ListFacilitiesResult, error)
-	UpdateAvailableFacilityServices(ctx context.Context, id, userID primitive.ObjectID, servicesAvailable []domain.FacilityService) error
+	UpdateAvailableFacilityServices(ctx context.Context, id, orgID primitive.ObjectID, servicesAvailable []domain.FacilityService) error
 }
 
 type facilityService struct {
 	db           *database.MongoDB
 	facilityRepo repository.FacilityRepository
+	audit        AuditService
+	geocoder     geocode.Geocoder
 }
 
-func NewFacilityService(db *database.MongoDB, facilityRepo repository.FacilityRepository) FacilityService {
+func NewFacilityService(
+	db *database.MongoDB,
+	facilityRepo repository.FacilityRepository,
+	audit AuditService,
+	geocoder geocode.Geocoder) FacilityService {
 	return &facilityService{
 		db:           db,
 		facilityRepo: facilityRepo,
+		audit:        audit,
+		geocoder:     geocoder,
 	}
 }
 
 func (s *facilityService) Create(ctx context.Context, facility *domain.Facility) error {
-	if err := s.facilityRepo.Create(ctx, facility); err != nil {
-		return fmt.Errorf("failed to create facility: %w", err)
+	if err := s.locate(ctx, facility); err != nil {
+		return err
 	}
 
-	return nil
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.facilityRepo.Create(ctx, facility); err != nil {
+			return fmt.Errorf("failed to create facility: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityFacility, facility.ID, facility.OrganizationID, domain.AuditActionCreate, nil, facility)
+	})
 }
 
-func (s *facilityService) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Facility, error) {
-	facility, err := s.facilityRepo.GetById(ctx, id, userID)
+func (s *facilityService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Facility, error) {
+	facility, err := s.facilityRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(facilityNotFound, err)
 	}
@@ -56,23 +72,43 @@ func (s *facilityService) GetById(ctx context.Context, id, userID primitive.Obje
 }
 
 func (s *facilityService) Update(ctx context.Context, facility *domain.Facility) error {
-	err := s.facilityRepo.Update(ctx, facility)
-	if err != nil {
-		return fmt.Errorf(facilityNotFound, err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.facilityRepo.GetById(ctx, facility.ID, facility.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(facilityNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrFacilityNotFound
+		}
 
-	return nil
+		if err := s.relocate(ctx, facility, before); err != nil {
+			return err
+		}
+
+		if err := s.facilityRepo.Update(ctx, facility); err != nil {
+			return fmt.Errorf(facilityNotFound, err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
 }
 
-func (s *facilityService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	if err := s.facilityRepo.Delete(ctx, id, userID); err != nil {
-		if err == domain.ErrFacilityNotFound {
-			return err
+func (s *facilityService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.facilityRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(facilityNotFound, err)
 		}
-		return fmt.Errorf("failed to delete facility: %w", err)
-	}
 
-	return nil
+		if err := s.facilityRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrFacilityNotFound {
+				return err
+			}
+			return fmt.Errorf("failed to delete facility: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityFacility, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *facilityService) ListWithFilter(ctx context.Context, filter domain.FacilityFilter) (*repository.ListFacilitiesResult, error) {
@@ -93,7 +129,7 @@ func (s *facilityService) ListWithFilter(ctx context.Context, filter domain.Faci
 }
 
 // atomic methods
-func (s *facilityService) UpdateAvailableFacilityServices(ctx context.Context, id, userID primitive.ObjectID, servicesAvailable []domain.FacilityService) error {
+func (s *facilityService) UpdateAvailableFacilityServices(ctx context.Context, id, orgID primitive.ObjectID, servicesAvailable []domain.FacilityService) error {
 	// Validate services first
 	for _, service := range servicesAvailable {
 		if !service.IsValid() {
@@ -101,19 +137,72 @@ func (s *facilityService) UpdateAvailableFacilityServices(ctx context.Context, i
 		}
 	}
 
-	// Check if facility exists
-	facility, err := s.facilityRepo.GetById(ctx, id, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get facility: %w", err)
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		// Check if facility exists
+		facility, err := s.facilityRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to get facility: %w", err)
+		}
+		if facility == nil {
+			return domain.ErrFacilityNotFound
+		}
+
+		// Update the services
+		if err := s.facilityRepo.UpdateAvailableFacilityServices(ctx, id, orgID, servicesAvailable); err != nil {
+			return fmt.Errorf("failed to update available facility services: %w", err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, facility)
+	})
+}
+
+// helpers
+
+// relocate places an updated facility. coordinates sent with the update win; otherwise the stored ones are kept,
+// which may have been entered by hand, unless the address moved and the new one can be placed.
+func (s *facilityService) relocate(ctx context.Context, facility, before *domain.Facility) error {
+	if facility.Location != nil {
+		return nil
 	}
-	if facility == nil {
-		return domain.ErrFacilityNotFound
+
+	if facility.Address != before.Address {
+		if err := s.locate(ctx, facility); err != nil {
+			return err
+		}
+	}
+	if facility.Location == nil {
+		facility.Location = before.Location
 	}
 
-	// Update the services
-	if err := s.facilityRepo.UpdateAvailableFacilityServices(ctx, id, userID, servicesAvailable); err != nil {
-		return fmt.Errorf("failed to update available facility services: %w", err)
+	return nil
+}
+
+// locate geocodes the facility's address unless it was given coordinates. an address that can't be placed
+// leaves the facility without coordinates, which only keeps it out of proximity searches.
+func (s *facilityService) locate(ctx context.Context, facility *domain.Facility) error {
+	if facility.Location != nil {
+		return nil
+	}
+
+	location, err := s.geocoder.Geocode(ctx, facility.Address)
+	if errors.Is(err, geocode.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to geocode facility address: %w", err)
 	}
 
+	facility.Location = location
 	return nil
 }
+
+// recordChange audits a mutation of an existing facility, reading it back so that the before and after
+// snapshots have the same shape
+func (s *facilityService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.Facility) error {
+	after, err := s.facilityRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(facilityNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityFacility, before.ID, before.OrganizationID, action, before, after)
+}
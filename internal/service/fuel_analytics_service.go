@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+)
+
+type FuelAnalyticsService interface {
+	Efficiency(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelEfficiency, error)
+	Outliers(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelOutlier, error)
+}
+
+type fuelAnalyticsService struct {
+	analyticsRepo repository.FuelAnalyticsRepository
+}
+
+func NewFuelAnalyticsService(analyticsRepo repository.FuelAnalyticsRepository) FuelAnalyticsService {
+	return &fuelAnalyticsService{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+func (s *fuelAnalyticsService) Efficiency(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelEfficiency, error) {
+	efficiencies, err := s.analyticsRepo.Efficiency(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate fuel efficiency: %w", err)
+	}
+
+	return efficiencies, nil
+}
+
+// Outliers checks the fill-ups in the filter's range against each other. the typical fill-up is worked out from the
+// same range, so a wider range gives a steadier baseline.
+func (s *fuelAnalyticsService) Outliers(ctx context.Context, filter domain.FuelAnalyticsFilter) ([]domain.FuelOutlier, error) {
+	fillUps, err := s.analyticsRepo.ListFillUps(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fill-ups: %w", err)
+	}
+
+	return domain.FindFuelOutliers(fillUps), nil
+}
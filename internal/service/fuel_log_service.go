@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jwald3/waybill/internal/database"
 	"github.com/jwald3/waybill/internal/domain"
@@ -16,34 +17,57 @@ var (
 
 type FuelLogService interface {
 	Create(ctx context.Context, fuelLog *domain.FuelLog) error
-	GetById(ctx context.Context, id primitive.ObjectID) (*domain.FuelLog, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.FuelLog, error)
 	Update(ctx context.Context, fuelLog *domain.FuelLog) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.FuelLogFilter) (*repository.ListFuelLogsResult, error)
 }
 
 type fuelLogService struct {
-	db          *database.MongoDB
-	fuelLogRepo repository.FuelLogRepository
+	db           *database.MongoDB
+	fuelLogRepo  repository.FuelLogRepository
+	tripRepo     repository.TripRepository
+	truckRepo    repository.TruckRepository
+	driverRepo   repository.DriverRepository
+	facilityRepo repository.FacilityRepository
+	audit        AuditService
 }
 
-func NewFuelLogService(db *database.MongoDB, fuelLogRepo repository.FuelLogRepository) FuelLogService {
+func NewFuelLogService(
+	db *database.MongoDB,
+	fuelLogRepo repository.FuelLogRepository,
+	tripRepo repository.TripRepository,
+	truckRepo repository.TruckRepository,
+	driverRepo repository.DriverRepository,
+	facilityRepo repository.FacilityRepository,
+	audit AuditService) FuelLogService {
 	return &fuelLogService{
-		db:          db,
-		fuelLogRepo: fuelLogRepo,
+		db:           db,
+		fuelLogRepo:  fuelLogRepo,
+		tripRepo:     tripRepo,
+		truckRepo:    truckRepo,
+		driverRepo:   driverRepo,
+		facilityRepo: facilityRepo,
+		audit:        audit,
 	}
 }
 
 func (s *fuelLogService) Create(ctx context.Context, fuelLog *domain.FuelLog) error {
-	if err := s.fuelLogRepo.Create(ctx, fuelLog); err != nil {
-		return fmt.Errorf("failed to create fuel log: %w", err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.recordOdometer(ctx, fuelLog); err != nil {
+			return err
+		}
+
+		if err := s.fuelLogRepo.Create(ctx, fuelLog); err != nil {
+			return fmt.Errorf("failed to create fuel log: %w", err)
+		}
 
-	return nil
+		return s.audit.Record(ctx, domain.AuditEntityFuelLog, fuelLog.ID, fuelLog.OrganizationID, domain.AuditActionCreate, nil, fuelLog)
+	})
 }
 
-func (s *fuelLogService) GetById(ctx context.Context, id primitive.ObjectID) (*domain.FuelLog, error) {
-	fuelLog, err := s.fuelLogRepo.GetById(ctx, id)
+func (s *fuelLogService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.FuelLog, error) {
+	fuelLog, err := s.fuelLogRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(fuelLogNotFound, err)
 	}
@@ -55,23 +79,51 @@ func (s *fuelLogService) GetById(ctx context.Context, id primitive.ObjectID) (*d
 }
 
 func (s *fuelLogService) Update(ctx context.Context, fuelLog *domain.FuelLog) error {
-	err := s.fuelLogRepo.Update(ctx, fuelLog)
-	if err != nil {
-		return fmt.Errorf(fuelLogNotFound, err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.fuelLogRepo.GetById(ctx, fuelLog.ID, fuelLog.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(fuelLogNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrFuelLogNotFound
+		}
+
+		// a reading that's already on record was checked when it was logged, and the truck has likely moved on since
+		if before.TruckID == fuelLog.TruckID && before.OdometerReading == fuelLog.OdometerReading {
+			if _, err := s.checkReferences(ctx, fuelLog); err != nil {
+				return err
+			}
+		} else if err := s.recordOdometer(ctx, fuelLog); err != nil {
+			return err
+		}
 
-	return nil
+		if err := s.fuelLogRepo.Update(ctx, fuelLog); err != nil {
+			return fmt.Errorf(fuelLogNotFound, err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
 }
 
-func (s *fuelLogService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	if err := s.fuelLogRepo.Delete(ctx, id); err != nil {
-		if err == domain.ErrFuelLogNotFound {
-			return err
+func (s *fuelLogService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.fuelLogRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(fuelLogNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrFuelLogNotFound
+		}
+
+		if err := s.fuelLogRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrFuelLogNotFound {
+				return err
+			}
+			return fmt.Errorf("failed to delete fuel log: %w", err)
 		}
-		return fmt.Errorf("failed to delete fuel log: %w", err)
-	}
 
-	return nil
+		return s.audit.Record(ctx, domain.AuditEntityFuelLog, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *fuelLogService) List(ctx context.Context, filter domain.FuelLogFilter) (*repository.ListFuelLogsResult, error) {
@@ -90,3 +142,105 @@ func (s *fuelLogService) List(ctx context.Context, filter domain.FuelLogFilter)
 
 	return result, nil
 }
+
+// helpers
+
+// recordChange audits a mutation of an existing fuel log, reading it back so that the before and after
+// snapshots have the same shape
+func (s *fuelLogService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.FuelLog) error {
+	after, err := s.fuelLogRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(fuelLogNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityFuelLog, before.ID, before.OrganizationID, action, before, after)
+}
+
+// checkReferences makes sure everything a fuel log points at belongs to its organization, and that a log bought on
+// a trip names that trip's truck and driver. it hands back the trip, if there is one.
+func (s *fuelLogService) checkReferences(ctx context.Context, fuelLog *domain.FuelLog) (*domain.Trip, error) {
+	if fuelLog.DriverID != nil {
+		driver, err := s.driverRepo.GetById(ctx, *fuelLog.DriverID, fuelLog.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf(driverNotFound, err)
+		}
+		if driver == nil {
+			return nil, domain.ErrDriverNotFound
+		}
+	}
+
+	if fuelLog.FacilityID != nil {
+		facility, err := s.facilityRepo.GetById(ctx, *fuelLog.FacilityID, fuelLog.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf(facilityNotFound, err)
+		}
+		if facility == nil {
+			return nil, domain.ErrFacilityNotFound
+		}
+	}
+
+	if fuelLog.TripID == nil {
+		return nil, nil
+	}
+
+	trip, err := s.tripRepo.GetById(ctx, *fuelLog.TripID, fuelLog.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf(tripNotFound, err)
+	}
+	if trip == nil {
+		return nil, domain.ErrTripNotFound
+	}
+
+	if err := fuelLog.MatchTrip(trip); err != nil {
+		return nil, err
+	}
+
+	return trip, nil
+}
+
+// recordOdometer holds a fuel log's reading up against its truck's other readings, or against its trip's when it was
+// bought on one, and moves the truck's mileage up to it as long as the truck could have covered the distance
+func (s *fuelLogService) recordOdometer(ctx context.Context, fuelLog *domain.FuelLog) error {
+	truck, err := s.truckRepo.GetById(ctx, fuelLog.TruckID, fuelLog.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return domain.ErrTruckNotFound
+	}
+
+	trip, err := s.checkReferences(ctx, fuelLog)
+	if err != nil {
+		return err
+	}
+
+	var fuelLogs []*domain.FuelLog
+	if trip == nil {
+		if fuelLogs, err = s.fuelLogRepo.ListByTrucks(ctx, fuelLog.OrganizationID, &fuelLog.TruckID); err != nil {
+			return fmt.Errorf("failed to list fuel logs: %w", err)
+		}
+	}
+
+	if err := fuelLog.CheckOdometer(truck, trip, fuelLogs); err != nil {
+		return err
+	}
+	if fuelLog.OdometerReading <= truck.Mileage {
+		return nil
+	}
+
+	before := *truck
+	if err := truck.RecordOdometer(fuelLog.OdometerReading, fuelLog.ReadingTakenBy(time.Now())); err != nil {
+		return err
+	}
+
+	if err := s.truckRepo.Update(ctx, truck); err != nil {
+		return fmt.Errorf("failed to update truck mileage: %w", err)
+	}
+
+	after, err := s.truckRepo.GetById(ctx, truck.ID, truck.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(truckNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityTruck, truck.ID, truck.OrganizationID, domain.AuditActionUpdate, &before, after)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GeofenceService interface {
+	ProcessPositions(ctx context.Context, truckID, orgID primitive.ObjectID, previous *domain.Position, positions []domain.Position) ([]domain.TripAutomation, error)
+	ProcessEvent(ctx context.Context, truckID, orgID primitive.ObjectID, event domain.LocationEvent) ([]domain.TripAutomation, error)
+}
+
+type geofenceService struct {
+	tripRepo     repository.TripRepository
+	truckRepo    repository.TruckRepository
+	facilityRepo repository.FacilityRepository
+	tripService  TripService
+	policy       domain.GeofencePolicy
+}
+
+func NewGeofenceService(
+	tripRepo repository.TripRepository,
+	truckRepo repository.TruckRepository,
+	facilityRepo repository.FacilityRepository,
+	tripService TripService,
+	policy domain.GeofencePolicy,
+) GeofenceService {
+	return &geofenceService{
+		tripRepo:     tripRepo,
+		truckRepo:    truckRepo,
+		facilityRepo: facilityRepo,
+		tripService:  tripService,
+		policy:       policy,
+	}
+}
+
+// ProcessPositions works out where the truck crossed the geofences of the facilities its trips start and end at,
+// and moves the trips along. previous is where the truck was before these pings; pings older than it arrived out
+// of order and are too late to act on.
+func (s *geofenceService) ProcessPositions(ctx context.Context, truckID, orgID primitive.ObjectID, previous *domain.Position, positions []domain.Position) ([]domain.TripAutomation, error) {
+	recent := make([]domain.Position, 0, len(positions))
+	for _, position := range positions {
+		if previous == nil || position.RecordedAt > previous.RecordedAt {
+			recent = append(recent, position)
+		}
+	}
+	if len(recent) == 0 {
+		return []domain.TripAutomation{}, nil
+	}
+
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].RecordedAt < recent[j].RecordedAt
+	})
+
+	trips, err := s.truckTrips(ctx, truckID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	facilities, err := s.tripFacilities(ctx, orgID, trips)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]domain.LocationEvent, 0)
+	for _, facility := range facilities {
+		events = append(events, facility.GeofenceCrossings(s.policy, previous, recent)...)
+	}
+	domain.SortLocationEvents(events)
+
+	automations := make([]domain.TripAutomation, 0)
+	for _, event := range events {
+		automation, err := s.apply(ctx, truckID, orgID, facilities[event.FacilityID], event)
+		if err != nil {
+			return nil, err
+		}
+		if automation != nil {
+			automations = append(automations, *automation)
+		}
+	}
+
+	return automations, nil
+}
+
+// ProcessEvent acts on a crossing the truck's telematics unit reported itself
+func (s *geofenceService) ProcessEvent(ctx context.Context, truckID, orgID primitive.ObjectID, event domain.LocationEvent) ([]domain.TripAutomation, error) {
+	truck, err := s.truckRepo.GetById(ctx, truckID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return nil, domain.ErrTruckNotFound
+	}
+
+	facility, err := s.facilityRepo.GetById(ctx, event.FacilityID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve facility: %w", err)
+	}
+	if facility == nil {
+		return nil, domain.ErrFacilityNotFound
+	}
+
+	automations := make([]domain.TripAutomation, 0)
+	automation, err := s.apply(ctx, truckID, orgID, facility, event)
+	if err != nil {
+		return nil, err
+	}
+	if automation != nil {
+		automations = append(automations, *automation)
+	}
+
+	return automations, nil
+}
+
+// apply moves along the first of the truck's trips the event means something to. trips are reloaded for every
+// event, since the one before may have just begun or finished one of them.
+func (s *geofenceService) apply(ctx context.Context, truckID, orgID primitive.ObjectID, facility *domain.Facility, event domain.LocationEvent) (*domain.TripAutomation, error) {
+	trips, err := s.truckTrips(ctx, truckID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, trip := range trips {
+		automation, ok := trip.AutomationFor(s.policy, facility, event)
+		if !ok {
+			continue
+		}
+
+		if err := s.tripService.AutoTransition(ctx, orgID, automation); err != nil {
+			return nil, fmt.Errorf("failed to update trip %s from geofence: %w", trip.ID.Hex(), err)
+		}
+
+		return automation, nil
+	}
+
+	return nil, nil
+}
+
+// truckTrips are the truck's scheduled and in transit trips, soonest departure first
+func (s *geofenceService) truckTrips(ctx context.Context, truckID, orgID primitive.ObjectID) ([]*domain.Trip, error) {
+	active, err := s.tripRepo.ListActive(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve active trips: %w", err)
+	}
+
+	trips := make([]*domain.Trip, 0)
+	for _, trip := range active {
+		if trip.TruckID != nil && *trip.TruckID == truckID {
+			trips = append(trips, trip)
+		}
+	}
+
+	return trips, nil
+}
+
+func (s *geofenceService) tripFacilities(ctx context.Context, orgID primitive.ObjectID, trips []*domain.Trip) (map[primitive.ObjectID]*domain.Facility, error) {
+	facilities := make(map[primitive.ObjectID]*domain.Facility)
+
+	for _, trip := range trips {
+		for _, id := range []*primitive.ObjectID{trip.StartFacilityID, trip.EndFacilityID} {
+			if id == nil {
+				continue
+			}
+			if _, ok := facilities[*id]; ok {
+				continue
+			}
+
+			facility, err := s.facilityRepo.GetById(ctx, *id, orgID)
+			if err != nil {
+				return nil, fmt.Errorf("unable to retrieve facility: %w", err)
+			}
+			if facility != nil {
+				facilities[*id] = facility
+			}
+		}
+	}
+
+	return facilities, nil
+}
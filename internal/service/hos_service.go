@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HOSService interface {
+	RecordDutyStatus(ctx context.Context, event *domain.DutyStatusEvent) error
+	ListDutyStatus(ctx context.Context, filter domain.DutyStatusFilter) (*repository.ListDutyStatusEventsResult, error)
+	Summary(ctx context.Context, driverID, orgID primitive.ObjectID, asOf time.Time) (*domain.HOSSummary, error)
+}
+
+type hosService struct {
+	db             *database.MongoDB
+	dutyStatusRepo repository.DutyStatusRepository
+	driverRepo     repository.DriverRepository
+	rules          domain.HOSRules
+}
+
+func NewHOSService(
+	db *database.MongoDB,
+	dutyStatusRepo repository.DutyStatusRepository,
+	driverRepo repository.DriverRepository,
+	rules domain.HOSRules,
+) HOSService {
+	return &hosService{
+		db:             db,
+		dutyStatusRepo: dutyStatusRepo,
+		driverRepo:     driverRepo,
+		rules:          rules,
+	}
+}
+
+// RecordDutyStatus appends to the driver's log. a status can start at the same time as the latest one, which
+// corrects it, but not before it; the log is the driver's legal record and isn't rewritten.
+func (s *hosService) RecordDutyStatus(ctx context.Context, event *domain.DutyStatusEvent) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.ensureDriver(ctx, event.DriverID, event.OrganizationID); err != nil {
+			return err
+		}
+
+		latest, err := s.dutyStatusRepo.Latest(ctx, event.OrganizationID, event.DriverID)
+		if err != nil {
+			return err
+		}
+		if latest != nil && event.StartedAt < latest.StartedAt {
+			return domain.ErrDutyStatusOutOfOrder
+		}
+
+		return s.dutyStatusRepo.Create(ctx, event)
+	})
+}
+
+func (s *hosService) ListDutyStatus(ctx context.Context, filter domain.DutyStatusFilter) (*repository.ListDutyStatusEventsResult, error) {
+	if err := s.ensureDriver(ctx, filter.DriverID, filter.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.dutyStatusRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duty status events: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *hosService) Summary(ctx context.Context, driverID, orgID primitive.ObjectID, asOf time.Time) (*domain.HOSSummary, error) {
+	if err := s.ensureDriver(ctx, driverID, orgID); err != nil {
+		return nil, err
+	}
+
+	since := primitive.NewDateTimeFromTime(asOf.Add(-s.rules.Lookback()))
+	events, err := s.dutyStatusRepo.ListSince(ctx, orgID, driverID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read duty status log: %w", err)
+	}
+
+	summary := s.rules.Summarize(driverID, events, asOf)
+	return &summary, nil
+}
+
+func (s *hosService) ensureDriver(ctx context.Context, driverID, orgID primitive.ObjectID) error {
+	driver, err := s.driverRepo.GetById(ctx, driverID, orgID)
+	if err != nil {
+		return fmt.Errorf(driverNotFound, err)
+	}
+	if driver == nil {
+		return domain.ErrDriverNotFound
+	}
+
+	return nil
+}
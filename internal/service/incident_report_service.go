@@ -16,34 +16,38 @@ var (
 
 type IncidentReportService interface {
 	Create(ctx context.Context, incidentReport *domain.IncidentReport) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.IncidentReport, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.IncidentReport, error)
 	Update(ctx context.Context, incidentReport *domain.IncidentReport) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.IncidentReportFilter) (*repository.ListIncidentReportsResult, error)
 }
 
 type incidentReportService struct {
 	db                 *database.MongoDB
 	incidentReportRepo repository.IncidentReportRepository
+	audit              AuditService
 }
 
-func NewIncidentReportService(db *database.MongoDB, incidentReportRepo repository.IncidentReportRepository) IncidentReportService {
+func NewIncidentReportService(db *database.MongoDB, incidentReportRepo repository.IncidentReportRepository, audit AuditService) IncidentReportService {
 	return &incidentReportService{
 		db:                 db,
 		incidentReportRepo: incidentReportRepo,
+		audit:              audit,
 	}
 }
 
 func (s *incidentReportService) Create(ctx context.Context, incidentReport *domain.IncidentReport) error {
-	if err := s.incidentReportRepo.Create(ctx, incidentReport); err != nil {
-		return fmt.Errorf("failed to create incident report: %w", err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.incidentReportRepo.Create(ctx, incidentReport); err != nil {
+			return fmt.Errorf("failed to create incident report: %w", err)
+		}
 
-	return nil
+		return s.audit.Record(ctx, domain.AuditEntityIncidentReport, incidentReport.ID, incidentReport.OrganizationID, domain.AuditActionCreate, nil, incidentReport)
+	})
 }
 
-func (s *incidentReportService) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.IncidentReport, error) {
-	incidentReport, err := s.incidentReportRepo.GetById(ctx, id, userID)
+func (s *incidentReportService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.IncidentReport, error) {
+	incidentReport, err := s.incidentReportRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(incidentReportNotFound, err)
 	}
@@ -55,23 +59,39 @@ func (s *incidentReportService) GetById(ctx context.Context, id, userID primitiv
 }
 
 func (s *incidentReportService) Update(ctx context.Context, incidentReport *domain.IncidentReport) error {
-	err := s.incidentReportRepo.Update(ctx, incidentReport)
-	if err != nil {
-		return fmt.Errorf(incidentReportNotFound, err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.incidentReportRepo.GetById(ctx, incidentReport.ID, incidentReport.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(incidentReportNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrIncidentReportNotFound
+		}
 
-	return nil
+		if err := s.incidentReportRepo.Update(ctx, incidentReport); err != nil {
+			return fmt.Errorf(incidentReportNotFound, err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
 }
 
-func (s *incidentReportService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	if err := s.incidentReportRepo.Delete(ctx, id, userID); err != nil {
-		if err == domain.ErrIncidentReportNotFound {
-			return err
+func (s *incidentReportService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.incidentReportRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(incidentReportNotFound, err)
 		}
-		return fmt.Errorf("failed to delete incident report: %w", err)
-	}
 
-	return nil
+		if err := s.incidentReportRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrIncidentReportNotFound {
+				return err
+			}
+			return fmt.Errorf("failed to delete incident report: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityIncidentReport, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *incidentReportService) List(ctx context.Context, filter domain.IncidentReportFilter) (*repository.ListIncidentReportsResult, error) {
@@ -90,3 +110,16 @@ func (s *incidentReportService) List(ctx context.Context, filter domain.Incident
 
 	return result, nil
 }
+
+// helpers
+
+// recordChange audits a mutation of an existing incident report, reading it back so that the before and after
+// snapshots have the same shape
+func (s *incidentReportService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.IncidentReport) error {
+	after, err := s.incidentReportRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(incidentReportNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityIncidentReport, before.ID, before.OrganizationID, action, before, after)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginThrottleService tracks failed logins per account and per client address, slowing down and eventually
+// locking out whoever keeps guessing. an account is locked from the guessing address first, and only for everyone
+// once guesses come from more addresses than any one is allowed, see domain.LockoutPolicy.
+type LoginThrottleService interface {
+	Check(ctx context.Context, email, clientIP string) error
+	RecordFailure(ctx context.Context, email, clientIP string, user *domain.User) error
+	RecordSuccess(ctx context.Context, email, clientIP string) error
+	Unlock(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type loginThrottleService struct {
+	loginAttemptRepo repository.LoginAttemptRepository
+	membershipRepo   repository.MembershipRepository
+	userRepo         repository.UserRepository
+	audit            AuditService
+	policy           domain.LockoutPolicy
+}
+
+func NewLoginThrottleService(
+	loginAttemptRepo repository.LoginAttemptRepository,
+	membershipRepo repository.MembershipRepository,
+	userRepo repository.UserRepository,
+	audit AuditService,
+	policy domain.LockoutPolicy) LoginThrottleService {
+	return &loginThrottleService{
+		loginAttemptRepo: loginAttemptRepo,
+		membershipRepo:   membershipRepo,
+		userRepo:         userRepo,
+		audit:            audit,
+		policy:           policy,
+	}
+}
+
+// Check refuses a login while either the account or the address it comes from has to wait. it runs before the
+// password is looked at, so a locked account can't be used to test guesses, and it answers the same way whether
+// or not the account exists.
+func (s *loginThrottleService) Check(ctx context.Context, email, clientIP string) error {
+	if !s.policy.Enabled {
+		return nil
+	}
+
+	now := time.Now()
+	for _, key := range s.keys(email, clientIP) {
+		attempt, err := s.loginAttemptRepo.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempt == nil {
+			continue
+		}
+
+		if until, blocked := attempt.BlockedUntil(now); blocked {
+			return &domain.LoginThrottledError{RetryAt: until}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login against the account from the address, against the account as a whole and
+// against the address. user is nil when no account has the email; the guesses are counted all the same so that
+// probing for accounts is throttled too.
+func (s *loginThrottleService) RecordFailure(ctx context.Context, email, clientIP string, user *domain.User) error {
+	if !s.policy.Enabled {
+		return nil
+	}
+
+	now := time.Now()
+
+	attempt, err := s.loginAttemptRepo.RecordFailure(ctx, domain.AccountAddressAttemptKey(email, clientIP), now, s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	lockedUntil, err := s.block(ctx, attempt, s.policy.MaxAccountFailures, s.policy.Delay(attempt.Failures), now)
+	if err != nil {
+		return err
+	}
+
+	if err := s.recordLockout(ctx, user, attempt, s.policy.MaxAccountFailures, lockedUntil); err != nil {
+		return err
+	}
+
+	// guesses from other addresses add up here, but only lock the account for everyone well past what one address
+	// is allowed, so that its owner can't be kept out by someone who only knows the email
+	attempt, err = s.loginAttemptRepo.RecordFailure(ctx, domain.AccountAttemptKey(email), now, s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	lockedUntil, err = s.block(ctx, attempt, s.policy.MaxAccountWideFailures, 0, now)
+	if err != nil {
+		return err
+	}
+
+	if err := s.recordLockout(ctx, user, attempt, s.policy.MaxAccountWideFailures, lockedUntil); err != nil {
+		return err
+	}
+
+	if clientIP == "" {
+		return nil
+	}
+
+	attempt, err = s.loginAttemptRepo.RecordFailure(ctx, domain.IPAttemptKey(clientIP), now, s.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.block(ctx, attempt, s.policy.MaxIPFailures, 0, now)
+	return err
+}
+
+// RecordSuccess forgets the account's failures from the address and from everywhere. the failures other addresses
+// made against it are kept, as is the address's own count, since one good password says nothing about the other
+// guesses.
+func (s *loginThrottleService) RecordSuccess(ctx context.Context, email, clientIP string) error {
+	if !s.policy.Enabled {
+		return nil
+	}
+
+	if err := s.loginAttemptRepo.Delete(ctx, domain.AccountAddressAttemptKey(email, clientIP)); err != nil {
+		return err
+	}
+
+	return s.loginAttemptRepo.Delete(ctx, domain.AccountAttemptKey(email))
+}
+
+// Unlock lifts every lockout on the user's account before it runs out, from everywhere and from any one address
+func (s *loginThrottleService) Unlock(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve user: %w", err)
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	attempts, err := s.loginAttemptRepo.ListByKeyPrefix(ctx, domain.AccountAddressAttemptKey(user.Email, ""))
+	if err != nil {
+		return err
+	}
+
+	account, err := s.loginAttemptRepo.Get(ctx, domain.AccountAttemptKey(user.Email))
+	if err != nil {
+		return err
+	}
+	if account != nil {
+		attempts = append(attempts, account)
+	}
+
+	// the account-wide lockout comes last, so it's the one recorded when there are several
+	now := time.Now()
+	var locked *domain.LoginAttempt
+	for _, attempt := range attempts {
+		if err := s.loginAttemptRepo.Delete(ctx, attempt.Key); err != nil {
+			return err
+		}
+		if attempt.IsLocked(now) {
+			locked = attempt
+		}
+	}
+
+	if locked == nil {
+		return nil
+	}
+
+	return s.recordForUser(ctx, user.ID, domain.AuditActionUnlock, locked, nil)
+}
+
+// block makes the next attempt wait, locking the key outright once it reaches maxFailures. it returns when the
+// lockout ends, or nil when the key isn't locked.
+func (s *loginThrottleService) block(
+	ctx context.Context,
+	attempt *domain.LoginAttempt,
+	maxFailures int,
+	delay time.Duration,
+	now time.Time) (*time.Time, error) {
+	var lockedUntil *time.Time
+	if maxFailures > 0 && attempt.Failures >= maxFailures {
+		until := now.Add(s.policy.Duration)
+		lockedUntil = &until
+		delay = s.policy.Duration
+	}
+
+	if delay <= 0 {
+		return nil, nil
+	}
+
+	if err := s.loginAttemptRepo.Block(ctx, attempt.Key, now.Add(delay), lockedUntil); err != nil {
+		return nil, err
+	}
+
+	return lockedUntil, nil
+}
+
+// only the failure that crosses the threshold is audited, not every attempt made while locked
+func (s *loginThrottleService) recordLockout(
+	ctx context.Context,
+	user *domain.User,
+	attempt *domain.LoginAttempt,
+	maxFailures int,
+	lockedUntil *time.Time) error {
+	if lockedUntil == nil || attempt.Failures != maxFailures || user == nil {
+		return nil
+	}
+
+	locked := primitive.NewDateTimeFromTime(*lockedUntil)
+	attempt.LockedUntil = &locked
+	attempt.RetryAt = &locked
+
+	return s.recordForUser(ctx, user.ID, domain.AuditActionLockout, nil, attempt)
+}
+
+// a user isn't owned by any one organization, so lockouts are recorded in every organization they belong to
+func (s *loginThrottleService) recordForUser(ctx context.Context, userID primitive.ObjectID, action domain.AuditAction, before, after any) error {
+	memberships, err := s.membershipRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf(membershipNotFound, err)
+	}
+
+	for _, membership := range memberships {
+		if err := s.audit.Record(ctx, domain.AuditEntityUser, userID, membership.OrganizationID, action, before, after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *loginThrottleService) keys(email, clientIP string) []string {
+	keys := []string{domain.AccountAddressAttemptKey(email, clientIP), domain.AccountAttemptKey(email)}
+	if clientIP != "" {
+		keys = append(keys, domain.IPAttemptKey(clientIP))
+	}
+
+	return keys
+}
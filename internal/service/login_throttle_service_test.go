@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const throttledEmail = "driver@example.com"
+
+func newLoginThrottleFixture(t *testing.T) (LoginThrottleService, *domain.User) {
+	t.Helper()
+
+	store := repository.NewMemoryStore()
+	userRepo := repository.NewMemoryUserRepository(store)
+	user := &domain.User{ID: primitive.NewObjectID(), Email: throttledEmail}
+	if err := userRepo.Create(context.Background(), user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	logins := NewLoginThrottleService(repository.NewMemoryLoginAttemptRepository(store),
+		repository.NewMemoryMembershipRepository(store), userRepo,
+		NewAuditService(nil, repository.NewMemoryAuditEventRepository(store)),
+		domain.LockoutPolicy{
+			Enabled:                true,
+			MaxAccountFailures:     3,
+			MaxAccountWideFailures: 6,
+			MaxIPFailures:          50,
+			Duration:               time.Hour,
+			FailureWindow:          time.Hour,
+		})
+
+	return logins, user
+}
+
+func failLogins(t *testing.T, logins LoginThrottleService, user *domain.User, clientIP string, times int) {
+	t.Helper()
+
+	for i := 0; i < times; i++ {
+		if err := logins.RecordFailure(context.Background(), throttledEmail, clientIP, user); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+}
+
+func isThrottled(err error) bool {
+	var throttled *domain.LoginThrottledError
+	return errors.As(err, &throttled)
+}
+
+func TestLoginThrottleLocksTheGuessingAddressFirst(t *testing.T) {
+	ctx := context.Background()
+	logins, user := newLoginThrottleFixture(t)
+
+	failLogins(t, logins, user, "10.0.0.1", 3)
+
+	if err := logins.Check(ctx, throttledEmail, "10.0.0.1"); !isThrottled(err) {
+		t.Errorf("Check() from the guessing address error = %v, want it throttled", err)
+	}
+	if err := logins.Check(ctx, throttledEmail, "10.0.0.2"); err != nil {
+		t.Errorf("Check() from another address error = %v, want the owner let in", err)
+	}
+
+	failLogins(t, logins, user, "10.0.0.2", 3)
+
+	if err := logins.Check(ctx, throttledEmail, "10.0.0.3"); !isThrottled(err) {
+		t.Errorf("Check() once the account-wide limit is reached error = %v, want it throttled", err)
+	}
+
+	if err := logins.Unlock(ctx, user.ID); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		if err := logins.Check(ctx, throttledEmail, ip); err != nil {
+			t.Errorf("Check() from %s after Unlock() error = %v", ip, err)
+		}
+	}
+}
+
+func TestLoginThrottleSuccessKeepsOtherAddressesFailures(t *testing.T) {
+	ctx := context.Background()
+	logins, user := newLoginThrottleFixture(t)
+
+	failLogins(t, logins, user, "10.0.0.1", 3)
+	failLogins(t, logins, user, "10.0.0.2", 2)
+
+	if err := logins.RecordSuccess(ctx, throttledEmail, "10.0.0.2"); err != nil {
+		t.Fatalf("RecordSuccess() error = %v", err)
+	}
+
+	if err := logins.Check(ctx, throttledEmail, "10.0.0.1"); !isThrottled(err) {
+		t.Errorf("Check() from the guessing address error = %v, want it still throttled", err)
+	}
+
+	// the address that logged in starts over
+	failLogins(t, logins, user, "10.0.0.2", 2)
+	if err := logins.Check(ctx, throttledEmail, "10.0.0.2"); err != nil {
+		t.Errorf("Check() from the address that logged in error = %v", err)
+	}
+}
@@ -16,34 +16,51 @@ var (
 
 type MaintenanceLogService interface {
 	Create(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.MaintenanceLog, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceLog, error)
 	Update(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.MaintenanceLogFilter) (*repository.ListMaintenanceLogsResult, error)
 }
 
 type maintenanceLogService struct {
 	db                 *database.MongoDB
 	maintenanceLogRepo repository.MaintenanceLogRepository
+	scheduleRepo       repository.MaintenanceScheduleRepository
+	truckRepo          repository.TruckRepository
+	audit              AuditService
 }
 
-func NewMaintenanceLogService(db *database.MongoDB, maintenanceLogRepo repository.MaintenanceLogRepository) MaintenanceLogService {
+func NewMaintenanceLogService(
+	db *database.MongoDB,
+	maintenanceLogRepo repository.MaintenanceLogRepository,
+	scheduleRepo repository.MaintenanceScheduleRepository,
+	truckRepo repository.TruckRepository,
+	audit AuditService) MaintenanceLogService {
 	return &maintenanceLogService{
 		db:                 db,
 		maintenanceLogRepo: maintenanceLogRepo,
+		scheduleRepo:       scheduleRepo,
+		truckRepo:          truckRepo,
+		audit:              audit,
 	}
 }
 
 func (s *maintenanceLogService) Create(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error {
-	if err := s.maintenanceLogRepo.Create(ctx, maintenanceLog); err != nil {
-		return fmt.Errorf("failed to create maintenance log: %w", err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.checkSchedule(ctx, maintenanceLog); err != nil {
+			return err
+		}
 
-	return nil
+		if err := s.maintenanceLogRepo.Create(ctx, maintenanceLog); err != nil {
+			return fmt.Errorf("failed to create maintenance log: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityMaintenanceLog, maintenanceLog.ID, maintenanceLog.OrganizationID, domain.AuditActionCreate, nil, maintenanceLog)
+	})
 }
 
-func (s *maintenanceLogService) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.MaintenanceLog, error) {
-	maintenanceLog, err := s.maintenanceLogRepo.GetById(ctx, id, userID)
+func (s *maintenanceLogService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceLog, error) {
+	maintenanceLog, err := s.maintenanceLogRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(maintenanceLogNotFound, err)
 	}
@@ -55,24 +72,44 @@ func (s *maintenanceLogService) GetById(ctx context.Context, id, userID primitiv
 }
 
 func (s *maintenanceLogService) Update(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error {
-	err := s.maintenanceLogRepo.Update(ctx, maintenanceLog)
-	if err != nil {
-		return fmt.Errorf(maintenanceLogNotFound, err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.maintenanceLogRepo.GetById(ctx, maintenanceLog.ID, maintenanceLog.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(maintenanceLogNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrMaintenanceLogNotFound
+		}
 
-	return nil
+		if err := s.checkSchedule(ctx, maintenanceLog); err != nil {
+			return err
+		}
+
+		if err := s.maintenanceLogRepo.Update(ctx, maintenanceLog); err != nil {
+			return fmt.Errorf(maintenanceLogNotFound, err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
 }
 
-func (s *maintenanceLogService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	if err := s.maintenanceLogRepo.Delete(ctx, id, userID); err != nil {
-		if err == domain.ErrMaintenanceLogNotFound {
-			return err
+func (s *maintenanceLogService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.maintenanceLogRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(maintenanceLogNotFound, err)
 		}
 
-		return fmt.Errorf("failed to delete maintenance log: %w", err)
-	}
+		if err := s.maintenanceLogRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrMaintenanceLogNotFound {
+				return err
+			}
 
-	return nil
+			return fmt.Errorf("failed to delete maintenance log: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityMaintenanceLog, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *maintenanceLogService) List(ctx context.Context, filter domain.MaintenanceLogFilter) (*repository.ListMaintenanceLogsResult, error) {
@@ -91,3 +128,46 @@ func (s *maintenanceLogService) List(ctx context.Context, filter domain.Maintena
 
 	return result, nil
 }
+
+// helpers
+
+// recordChange audits a mutation of an existing maintenance log, reading it back so that the before and after
+// snapshots have the same shape
+func (s *maintenanceLogService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.MaintenanceLog) error {
+	after, err := s.maintenanceLogRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(maintenanceLogNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityMaintenanceLog, before.ID, before.OrganizationID, action, before, after)
+}
+
+// checkSchedule makes sure a log of a scheduled service names a schedule and truck in the organization. the
+// mileage next service is counted from defaults to the truck's current odometer when it isn't given.
+func (s *maintenanceLogService) checkSchedule(ctx context.Context, maintenanceLog *domain.MaintenanceLog) error {
+	if maintenanceLog.ScheduleID == nil {
+		return nil
+	}
+
+	schedule, err := s.scheduleRepo.GetById(ctx, *maintenanceLog.ScheduleID, maintenanceLog.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(maintenanceScheduleNotFound, err)
+	}
+	if schedule == nil {
+		return domain.ErrMaintenanceScheduleNotFound
+	}
+
+	truck, err := s.truckRepo.GetById(ctx, *maintenanceLog.TruckID, maintenanceLog.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return domain.ErrTruckNotFound
+	}
+
+	if maintenanceLog.Mileage == 0 {
+		maintenanceLog.Mileage = truck.Mileage
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	maintenanceScheduleNotFound = "unable to retrieve maintenance schedule: %w"
+)
+
+type MaintenanceScheduleService interface {
+	Create(ctx context.Context, schedule *domain.MaintenanceSchedule) error
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceSchedule, error)
+	Update(ctx context.Context, schedule *domain.MaintenanceSchedule) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
+	List(ctx context.Context, filter domain.MaintenanceScheduleFilter) (*repository.ListMaintenanceSchedulesResult, error)
+	Due(ctx context.Context, orgID primitive.ObjectID, withinMiles, withinDays int) ([]domain.MaintenanceDue, error)
+	EnsureRoadworthy(ctx context.Context, truck *domain.Truck, at time.Time) error
+}
+
+type maintenanceScheduleService struct {
+	db                 *database.MongoDB
+	scheduleRepo       repository.MaintenanceScheduleRepository
+	maintenanceLogRepo repository.MaintenanceLogRepository
+	truckRepo          repository.TruckRepository
+	audit              AuditService
+
+	// when set, trucks overdue on safety-critical maintenance can't be dispatched
+	blockOverdueDispatch bool
+}
+
+func NewMaintenanceScheduleService(
+	db *database.MongoDB,
+	scheduleRepo repository.MaintenanceScheduleRepository,
+	maintenanceLogRepo repository.MaintenanceLogRepository,
+	truckRepo repository.TruckRepository,
+	audit AuditService,
+	blockOverdueDispatch bool) MaintenanceScheduleService {
+	return &maintenanceScheduleService{
+		db:                   db,
+		scheduleRepo:         scheduleRepo,
+		maintenanceLogRepo:   maintenanceLogRepo,
+		truckRepo:            truckRepo,
+		audit:                audit,
+		blockOverdueDispatch: blockOverdueDispatch,
+	}
+}
+
+func (s *maintenanceScheduleService) Create(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.ensureTruck(ctx, schedule); err != nil {
+			return err
+		}
+		if err := s.baseline(ctx, schedule); err != nil {
+			return err
+		}
+
+		if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+			return fmt.Errorf("failed to create maintenance schedule: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityMaintenanceSchedule, schedule.ID, schedule.OrganizationID, domain.AuditActionCreate, nil, schedule)
+	})
+}
+
+func (s *maintenanceScheduleService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.MaintenanceSchedule, error) {
+	schedule, err := s.scheduleRepo.GetById(ctx, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(maintenanceScheduleNotFound, err)
+	}
+	if schedule == nil {
+		return nil, domain.ErrMaintenanceScheduleNotFound
+	}
+
+	return schedule, nil
+}
+
+func (s *maintenanceScheduleService) Update(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.scheduleRepo.GetById(ctx, schedule.ID, schedule.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(maintenanceScheduleNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrMaintenanceScheduleNotFound
+		}
+
+		if err := s.ensureTruck(ctx, schedule); err != nil {
+			return err
+		}
+
+		// trucks the schedule already covered keep the baseline they started from
+		schedule.Baselines = before.Baselines
+		if err := s.baseline(ctx, schedule); err != nil {
+			return err
+		}
+
+		if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+			return fmt.Errorf("failed to update maintenance schedule: %w", err)
+		}
+
+		after, err := s.scheduleRepo.GetById(ctx, schedule.ID, schedule.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(maintenanceScheduleNotFound, err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityMaintenanceSchedule, schedule.ID, schedule.OrganizationID, domain.AuditActionUpdate, before, after)
+	})
+}
+
+func (s *maintenanceScheduleService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.scheduleRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(maintenanceScheduleNotFound, err)
+		}
+
+		if err := s.scheduleRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrMaintenanceScheduleNotFound {
+				return err
+			}
+
+			return fmt.Errorf("failed to delete maintenance schedule: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityMaintenanceSchedule, id, orgID, domain.AuditActionDelete, before, nil)
+	})
+}
+
+func (s *maintenanceScheduleService) List(ctx context.Context, filter domain.MaintenanceScheduleFilter) (*repository.ListMaintenanceSchedulesResult, error) {
+	result, err := s.scheduleRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance schedules: %w", err)
+	}
+
+	if result.Schedules == nil {
+		result.Schedules = []*domain.MaintenanceSchedule{}
+	}
+
+	return result, nil
+}
+
+// Due lists the scheduled services, across every truck still in service, that are overdue or will come due within
+// the given miles or days
+func (s *maintenanceScheduleService) Due(ctx context.Context, orgID primitive.ObjectID, withinMiles, withinDays int) ([]domain.MaintenanceDue, error) {
+	schedules, err := s.scheduleRepo.ListAll(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance schedules: %w", err)
+	}
+
+	trucks, err := s.truckRepo.ListInService(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trucks: %w", err)
+	}
+
+	status, err := s.status(ctx, orgID, schedules, trucks, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	due := make([]domain.MaintenanceDue, 0, len(status))
+	for _, item := range status {
+		if item.DueWithin(withinMiles, withinDays) {
+			due = append(due, item)
+		}
+	}
+	domain.SortMaintenanceDue(due)
+
+	return due, nil
+}
+
+// EnsureRoadworthy refuses a truck that is overdue on any safety-critical schedule as of the given time. it lets
+// everything through unless blocking overdue trucks has been turned on.
+func (s *maintenanceScheduleService) EnsureRoadworthy(ctx context.Context, truck *domain.Truck, at time.Time) error {
+	if !s.blockOverdueDispatch {
+		return nil
+	}
+
+	schedules, err := s.scheduleRepo.ListAll(ctx, truck.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to list maintenance schedules: %w", err)
+	}
+
+	critical := make([]*domain.MaintenanceSchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		if schedule.SafetyCritical {
+			critical = append(critical, schedule)
+		}
+	}
+
+	status, err := s.status(ctx, truck.OrganizationID, critical, []*domain.Truck{truck}, &truck.ID, at)
+	if err != nil {
+		return err
+	}
+
+	overdue := make([]string, 0)
+	for _, item := range status {
+		if item.Overdue {
+			overdue = append(overdue, item.ScheduleName)
+		}
+	}
+	if len(overdue) > 0 {
+		return &domain.MaintenanceOverdueError{TruckID: truck.ID, Schedules: overdue}
+	}
+
+	return nil
+}
+
+// helpers
+
+// status works out where each truck stands on each schedule that applies to it, from the latest log of each
+func (s *maintenanceScheduleService) status(
+	ctx context.Context,
+	orgID primitive.ObjectID,
+	schedules []*domain.MaintenanceSchedule,
+	trucks []*domain.Truck,
+	truckID *primitive.ObjectID,
+	now time.Time) ([]domain.MaintenanceDue, error) {
+	if len(schedules) == 0 || len(trucks) == 0 {
+		return nil, nil
+	}
+
+	logs, err := s.maintenanceLogRepo.ListLatestBySchedule(ctx, orgID, truckID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance logs: %w", err)
+	}
+
+	type scheduledTruck struct {
+		scheduleID, truckID primitive.ObjectID
+	}
+
+	latest := make(map[scheduledTruck]*domain.MaintenanceLog, len(logs))
+	for _, log := range logs {
+		latest[scheduledTruck{scheduleID: *log.ScheduleID, truckID: *log.TruckID}] = log
+	}
+
+	status := make([]domain.MaintenanceDue, 0)
+	for _, truck := range trucks {
+		for _, schedule := range schedules {
+			if !schedule.AppliesTo(truck) {
+				continue
+			}
+			status = append(status, schedule.Due(truck, latest[scheduledTruck{scheduleID: schedule.ID, truckID: truck.ID}], now))
+		}
+	}
+
+	return status, nil
+}
+
+// baseline records where the trucks a schedule covers stand as it starts applying to them, so their first service
+// is counted from there rather than from the day they were bought
+func (s *maintenanceScheduleService) baseline(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	trucks, err := s.truckRepo.ListInService(ctx, schedule.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to list trucks: %w", err)
+	}
+
+	schedule.Baseline(trucks, time.Now())
+
+	return nil
+}
+
+// ensureTruck checks that a schedule tied to one truck is tied to one in the organization
+func (s *maintenanceScheduleService) ensureTruck(ctx context.Context, schedule *domain.MaintenanceSchedule) error {
+	if schedule.TruckID == nil {
+		return nil
+	}
+
+	truck, err := s.truckRepo.GetById(ctx, *schedule.TruckID, schedule.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return domain.ErrTruckNotFound
+	}
+
+	return nil
+}
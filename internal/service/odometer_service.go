@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OdometerService interface {
+	Discrepancies(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]domain.OdometerDiscrepancy, error)
+}
+
+type odometerService struct {
+	truckRepo   repository.TruckRepository
+	tripRepo    repository.TripRepository
+	fuelLogRepo repository.FuelLogRepository
+}
+
+func NewOdometerService(
+	truckRepo repository.TruckRepository,
+	tripRepo repository.TripRepository,
+	fuelLogRepo repository.FuelLogRepository) OdometerService {
+	return &odometerService{
+		truckRepo:   truckRepo,
+		tripRepo:    tripRepo,
+		fuelLogRepo: fuelLogRepo,
+	}
+}
+
+// Discrepancies checks the odometer readings on the trips and fuel logs of every truck still in service, or of just
+// the one truck
+func (s *odometerService) Discrepancies(ctx context.Context, orgID primitive.ObjectID, truckID *primitive.ObjectID) ([]domain.OdometerDiscrepancy, error) {
+	var trucks []*domain.Truck
+	if truckID != nil {
+		truck, err := s.truckRepo.GetById(ctx, *truckID, orgID)
+		if err != nil {
+			return nil, fmt.Errorf(truckNotFound, err)
+		}
+		if truck == nil {
+			return nil, domain.ErrTruckNotFound
+		}
+		trucks = []*domain.Truck{truck}
+	} else {
+		inService, err := s.truckRepo.ListInService(ctx, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list trucks: %w", err)
+		}
+		trucks = inService
+	}
+
+	trips, err := s.tripRepo.ListAssignedToTrucks(ctx, orgID, truckID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trips: %w", err)
+	}
+
+	tripsByTruck := make(map[primitive.ObjectID][]*domain.Trip)
+	for _, trip := range trips {
+		tripsByTruck[*trip.TruckID] = append(tripsByTruck[*trip.TruckID], trip)
+	}
+
+	fuelLogs, err := s.fuelLogRepo.ListByTrucks(ctx, orgID, truckID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fuel logs: %w", err)
+	}
+
+	fuelLogsByTruck := make(map[primitive.ObjectID][]*domain.FuelLog)
+	for _, fuelLog := range fuelLogs {
+		fuelLogsByTruck[fuelLog.TruckID] = append(fuelLogsByTruck[fuelLog.TruckID], fuelLog)
+	}
+
+	discrepancies := make([]domain.OdometerDiscrepancy, 0)
+	for _, truck := range trucks {
+		discrepancies = append(discrepancies, domain.FindOdometerDiscrepancies(truck, tripsByTruck[truck.ID], fuelLogsByTruck[truck.ID])...)
+	}
+	domain.SortOdometerDiscrepancies(discrepancies)
+
+	return discrepancies, nil
+}
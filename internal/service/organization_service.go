@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	membershipNotFound = "unable to retrieve membership: %w"
+)
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, name string, ownerID primitive.ObjectID) (*domain.Membership, error)
+	ListMemberships(ctx context.Context, userID primitive.ObjectID) ([]*domain.Membership, error)
+	ResolveMembership(ctx context.Context, userID primitive.ObjectID, orgID *primitive.ObjectID) (*domain.Membership, error)
+	ListMembers(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Membership, error)
+	AddMember(ctx context.Context, orgID primitive.ObjectID, email string, role domain.Role) (*domain.Membership, error)
+	UpdateMemberRole(ctx context.Context, id, orgID primitive.ObjectID, role domain.Role) (*domain.Membership, error)
+	RemoveMember(ctx context.Context, id, orgID primitive.ObjectID) error
+	UnlockMember(ctx context.Context, id, orgID primitive.ObjectID) error
+}
+
+type organizationService struct {
+	db               *database.MongoDB
+	organizationRepo repository.OrganizationRepository
+	membershipRepo   repository.MembershipRepository
+	userRepo         repository.UserRepository
+	logins           LoginThrottleService
+}
+
+func NewOrganizationService(
+	db *database.MongoDB,
+	organizationRepo repository.OrganizationRepository,
+	membershipRepo repository.MembershipRepository,
+	userRepo repository.UserRepository,
+	logins LoginThrottleService) OrganizationService {
+	return &organizationService{
+		db:               db,
+		organizationRepo: organizationRepo,
+		membershipRepo:   membershipRepo,
+		userRepo:         userRepo,
+		logins:           logins,
+	}
+}
+
+// CreateOrganization creates an organization and makes ownerID its first owner
+func (s *organizationService) CreateOrganization(ctx context.Context, name string, ownerID primitive.ObjectID) (*domain.Membership, error) {
+	organization, err := domain.NewOrganization(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var membership *domain.Membership
+	err = runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.organizationRepo.Create(ctx, organization); err != nil {
+			return fmt.Errorf("failed to create organization: %w", err)
+		}
+
+		membership, err = domain.NewMembership(organization.ID, ownerID, domain.RoleOwner)
+		if err != nil {
+			return err
+		}
+
+		if err := s.membershipRepo.Create(ctx, membership); err != nil {
+			return fmt.Errorf("failed to create membership: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	membership.Organization = organization
+
+	return membership, nil
+}
+
+func (s *organizationService) ListMemberships(ctx context.Context, userID primitive.ObjectID) ([]*domain.Membership, error) {
+	memberships, err := s.membershipRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+
+	return memberships, nil
+}
+
+// ResolveMembership finds the membership a request acts under. when the caller names an organization they must
+// belong to it; otherwise their oldest membership is used, which for most users is the one made at registration.
+func (s *organizationService) ResolveMembership(ctx context.Context, userID primitive.ObjectID, orgID *primitive.ObjectID) (*domain.Membership, error) {
+	if orgID != nil {
+		membership, err := s.membershipRepo.GetByUserAndOrganization(ctx, userID, *orgID)
+		if err != nil {
+			return nil, fmt.Errorf(membershipNotFound, err)
+		}
+		if membership == nil {
+			return nil, domain.ErrMembershipNotFound
+		}
+
+		return membership, nil
+	}
+
+	memberships, err := s.membershipRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf(membershipNotFound, err)
+	}
+	if len(memberships) == 0 {
+		return nil, domain.ErrMembershipNotFound
+	}
+
+	return memberships[0], nil
+}
+
+func (s *organizationService) ListMembers(ctx context.Context, orgID primitive.ObjectID) ([]*domain.Membership, error) {
+	memberships, err := s.membershipRepo.ListByOrganization(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	return memberships, nil
+}
+
+func (s *organizationService) AddMember(ctx context.Context, orgID primitive.ObjectID, email string, role domain.Role) (*domain.Membership, error) {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve user: %w", err)
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	membership, err := domain.NewMembership(orgID, user.ID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		existing, err := s.membershipRepo.GetByUserAndOrganization(ctx, user.ID, orgID)
+		if err != nil {
+			return fmt.Errorf(membershipNotFound, err)
+		}
+		if existing != nil {
+			return domain.ErrMembershipExists
+		}
+
+		if err := s.membershipRepo.Create(ctx, membership); err != nil {
+			return fmt.Errorf("failed to create membership: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	membership.User = user
+
+	return membership, nil
+}
+
+func (s *organizationService) UpdateMemberRole(ctx context.Context, id, orgID primitive.ObjectID, role domain.Role) (*domain.Membership, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("invalid role provided: %s", role)
+	}
+
+	var membership *domain.Membership
+	err := runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		var err error
+		membership, err = s.getMembership(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == domain.RoleOwner && role != domain.RoleOwner {
+			if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+				return err
+			}
+		}
+
+		if err := s.membershipRepo.UpdateRole(ctx, id, orgID, role); err != nil {
+			return fmt.Errorf(membershipNotFound, err)
+		}
+
+		membership.Role = role
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (s *organizationService) RemoveMember(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		membership, err := s.getMembership(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == domain.RoleOwner {
+			if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+				return err
+			}
+		}
+
+		return s.membershipRepo.Delete(ctx, id, orgID)
+	})
+}
+
+// UnlockMember lets a member who locked themselves out by mistyping their password back in without waiting
+func (s *organizationService) UnlockMember(ctx context.Context, id, orgID primitive.ObjectID) error {
+	membership, err := s.getMembership(ctx, id, orgID)
+	if err != nil {
+		return err
+	}
+
+	return s.logins.Unlock(ctx, membership.UserID)
+}
+
+func (s *organizationService) getMembership(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Membership, error) {
+	membership, err := s.membershipRepo.GetById(ctx, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(membershipNotFound, err)
+	}
+	if membership == nil {
+		return nil, domain.ErrMembershipNotFound
+	}
+
+	return membership, nil
+}
+
+// an organization with no owner would have nobody left who can manage its members
+func (s *organizationService) ensureAnotherOwner(ctx context.Context, orgID primitive.ObjectID) error {
+	owners, err := s.membershipRepo.CountByRole(ctx, orgID, domain.RoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return domain.ErrLastOwner
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jwald3/waybill/internal/database"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runInTransaction wraps fn in a mongo transaction so that changes spanning several aggregates either all land or
+// none do. the in-memory backend has no database handle and no transactions, so fn is run directly: a failure
+// part way through leaves whatever was written before it, and concurrent requests can see the changes half made.
+// that's acceptable only because the memory backend is for development, and it refuses to start in production.
+func runInTransaction(ctx context.Context, db *database.MongoDB, fn func(ctx context.Context) error) error {
+	if db == nil {
+		return fn(ctx)
+	}
+
+	return db.ExecuteTx(ctx, func(sessCtx mongo.SessionContext) error {
+		return fn(sessCtx)
+	})
+}
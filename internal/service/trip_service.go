@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jwald3/waybill/internal/database"
 	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/geocode"
 	"github.com/jwald3/waybill/internal/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -16,40 +18,106 @@ var (
 )
 
 type TripService interface {
-	Create(ctx context.Context, trip *domain.Trip) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Trip, error)
-	Update(ctx context.Context, trip *domain.Trip) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Create(ctx context.Context, trip *domain.Trip, force bool) ([]domain.TripConflict, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Trip, error)
+	Update(ctx context.Context, trip *domain.Trip, force bool) ([]domain.TripConflict, error)
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.TripFilter) (*repository.ListTripsResult, error)
-	AddNote(ctx context.Context, id, userID primitive.ObjectID, content string) error
-	BeginTrip(ctx context.Context, id, userID primitive.ObjectID, departureTime time.Time) error
-	CancelTrip(ctx context.Context, id, userID primitive.ObjectID) error
-	FinishTripSuccessfully(ctx context.Context, id, userID primitive.ObjectID, arrivalTime time.Time) error
-	FinishTripUnsuccessfully(ctx context.Context, id, userID primitive.ObjectID, arrivalTime time.Time) error
+	ListConflicts(ctx context.Context, orgID primitive.ObjectID) ([]domain.TripConflict, error)
+	AddNote(ctx context.Context, id, orgID primitive.ObjectID, content string) error
+	BeginTrip(ctx context.Context, id, orgID primitive.ObjectID, departureTime time.Time) error
+	CancelTrip(ctx context.Context, id, orgID primitive.ObjectID) error
+	FinishTripSuccessfully(ctx context.Context, id, orgID primitive.ObjectID, arrivalTime time.Time, endOdometer *int) error
+	FinishTripUnsuccessfully(ctx context.Context, id, orgID primitive.ObjectID, arrivalTime time.Time, endOdometer *int) error
+	ArriveAtStop(ctx context.Context, id, orgID, stopID primitive.ObjectID, arrivalTime time.Time) error
+	DepartStop(ctx context.Context, id, orgID, stopID primitive.ObjectID, departureTime time.Time) error
+	SkipStop(ctx context.Context, id, orgID, stopID primitive.ObjectID) error
+	AutoTransition(ctx context.Context, orgID primitive.ObjectID, automation *domain.TripAutomation) error
 }
 
 type tripService struct {
-	db       *database.MongoDB
-	tripRepo repository.TripRepository
+	db           *database.MongoDB
+	tripRepo     repository.TripRepository
+	truckRepo    repository.TruckRepository
+	driverRepo   repository.DriverRepository
+	facilityRepo repository.FacilityRepository
+	audit        AuditService
+	hos          HOSService
+	maintenance  MaintenanceScheduleService
+	geocoder     geocode.Geocoder
+	routing      domain.RoutePolicy
 }
 
-func NewTripService(db *database.MongoDB, tripRepo repository.TripRepository) TripService {
+func NewTripService(
+	db *database.MongoDB,
+	tripRepo repository.TripRepository,
+	truckRepo repository.TruckRepository,
+	driverRepo repository.DriverRepository,
+	facilityRepo repository.FacilityRepository,
+	audit AuditService,
+	hos HOSService,
+	maintenance MaintenanceScheduleService,
+	geocoder geocode.Geocoder,
+	routing domain.RoutePolicy) TripService {
 	return &tripService{
-		db:       db,
-		tripRepo: tripRepo,
+		db:           db,
+		tripRepo:     tripRepo,
+		truckRepo:    truckRepo,
+		driverRepo:   driverRepo,
+		facilityRepo: facilityRepo,
+		audit:        audit,
+		hos:          hos,
+		maintenance:  maintenance,
+		geocoder:     geocoder,
+		routing:      routing,
 	}
 }
 
-func (s *tripService) Create(ctx context.Context, trip *domain.Trip) error {
-	if err := s.tripRepo.Create(ctx, trip); err != nil {
-		return fmt.Errorf("failed to create trip: %w", err)
+// Create rejects a trip whose driver or truck is already booked during its scheduled window. passing force
+// creates the trip anyway and hands the conflicts back so the caller can surface them as warnings.
+func (s *tripService) Create(ctx context.Context, trip *domain.Trip, force bool) ([]domain.TripConflict, error) {
+	// assign the id up front so any conflicts reported back can point at the new trip
+	if trip.ID.IsZero() {
+		trip.ID = primitive.NewObjectID()
 	}
 
-	return nil
+	if err := s.checkFacilities(ctx, trip); err != nil {
+		return nil, err
+	}
+
+	// the estimate can fill in the scheduled arrival, so it has to come before the conflict check
+	if err := s.estimate(ctx, trip); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkDriverLicense(ctx, trip); err != nil {
+		return nil, err
+	}
+	if err := s.checkHazmat(ctx, trip, trip.DepartureTime.Scheduled.Time(), false); err != nil {
+		return nil, err
+	}
+
+	conflicts, err := s.checkScheduleConflicts(ctx, trip, force)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.tripRepo.Create(ctx, trip); err != nil {
+			return fmt.Errorf("failed to create trip: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityTrip, trip.ID, trip.OrganizationID, domain.AuditActionCreate, nil, trip)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
 }
 
-func (s *tripService) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Trip, error) {
-	trip, err := s.tripRepo.GetById(ctx, id, userID)
+func (s *tripService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Trip, error) {
+	trip, err := s.tripRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(tripNotFound, err)
 	}
@@ -60,6 +128,9 @@ func (s *tripService) GetById(ctx context.Context, id, userID primitive.ObjectID
 	if trip.Notes == nil {
 		trip.Notes = make([]domain.TripNote, 0)
 	}
+	if trip.Stops == nil {
+		trip.Stops = make([]domain.TripStop, 0)
+	}
 
 	// Initialize the state machine for the retrieved trip
 	if err := trip.InitializeStateMachine(); err != nil {
@@ -69,25 +140,110 @@ func (s *tripService) GetById(ctx context.Context, id, userID primitive.ObjectID
 	return trip, nil
 }
 
-func (s *tripService) Update(ctx context.Context, trip *domain.Trip) error {
-	err := s.tripRepo.Update(ctx, trip)
+func (s *tripService) Update(ctx context.Context, trip *domain.Trip, force bool) ([]domain.TripConflict, error) {
+	existingTrip, err := s.tripRepo.GetById(ctx, trip.ID, trip.OrganizationID)
 	if err != nil {
-		return fmt.Errorf(tripNotFound, err)
+		return nil, fmt.Errorf(tripNotFound, err)
+	}
+	if existingTrip == nil {
+		return nil, fmt.Errorf("trip with ID %v not found", trip.ID)
 	}
 
-	return nil
-}
+	// once a trip is under way its stops are being worked, and replacing them would throw away their progress
+	if trip.Stops != nil && existingTrip.Status != domain.TripStatusScheduled {
+		return nil, &domain.TripStateError{CurrentState: existingTrip.Status, DesiredState: domain.TripStatusScheduled}
+	}
 
-func (s *tripService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	if err := s.tripRepo.Delete(ctx, id, userID); err != nil {
-		if err == domain.ErrTripNotFound {
-			return err
+	// once a trip has left, its truck is on the road under its driver, and swapping either would leave the old ones
+	// dispatched on a trip that no longer has them and the new ones never dispatched at all
+	if existingTrip.Status != domain.TripStatusScheduled && (reassigned(trip.DriverID, existingTrip.DriverID) || reassigned(trip.TruckID, existingTrip.TruckID)) {
+		return nil, &domain.TripStateError{CurrentState: existingTrip.Status, DesiredState: domain.TripStatusScheduled}
+	}
+
+	// the route is estimated from the facilities the trip will have once it's stored, and the repository keeps
+	// the existing ones for any left off the update
+	if trip.StartFacilityID == nil {
+		trip.StartFacilityID = existingTrip.StartFacilityID
+	}
+	if trip.EndFacilityID == nil {
+		trip.EndFacilityID = existingTrip.EndFacilityID
+	}
+	if trip.Stops == nil {
+		trip.Stops = existingTrip.Stops
+	}
+	if err := s.checkFacilities(ctx, trip); err != nil {
+		return nil, err
+	}
+	if err := s.estimate(ctx, trip); err != nil {
+		return nil, err
+	}
+
+	// an update edits the trip's details, while its status only moves through transitions and its notes are only
+	// appended to, so both are carried over rather than overwritten by what was sent
+	trip.Status = existingTrip.Status
+	trip.Notes = existingTrip.Notes
+
+	// the same goes for the assignments, so the conflict check needs to look at the trip as it will be stored
+	// rather than as it was sent
+	candidate := *trip
+	if candidate.DriverID == nil {
+		candidate.DriverID = existingTrip.DriverID
+	}
+	if candidate.TruckID == nil {
+		candidate.TruckID = existingTrip.TruckID
+	}
+
+	// a trip already under way keeps its driver and truck, so there's nothing to check once it has left
+	if existingTrip.Status == domain.TripStatusScheduled {
+		if err := s.checkDriverLicense(ctx, &candidate); err != nil {
+			return nil, err
+		}
+		if err := s.checkHazmat(ctx, &candidate, candidate.DepartureTime.Scheduled.Time(), false); err != nil {
+			return nil, err
 		}
+	}
 
-		return fmt.Errorf("failed to delete trip: %w", err)
+	conflicts, err := s.checkScheduleConflicts(ctx, &candidate, force)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	err = runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return fmt.Errorf(tripNotFound, err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, existingTrip)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+func (s *tripService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.tripRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(tripNotFound, err)
+		}
+
+		// the truck of a trip under way is only released by finishing the trip
+		if before.Status == domain.TripStatusInTransit {
+			return domain.ErrTripInTransit
+		}
+
+		if err := s.tripRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrTripNotFound {
+				return err
+			}
+
+			return fmt.Errorf("failed to delete trip: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityTrip, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *tripService) List(ctx context.Context, filter domain.TripFilter) (*repository.ListTripsResult, error) {
@@ -107,106 +263,610 @@ func (s *tripService) List(ctx context.Context, filter domain.TripFilter) (*repo
 	return result, nil
 }
 
-func (s *tripService) AddNote(ctx context.Context, id, userID primitive.ObjectID, content string) error {
-	trip, err := s.tripRepo.GetById(ctx, id, userID)
+// ListConflicts finds every pair of active trips that have the same driver or truck booked at the same time
+func (s *tripService) ListConflicts(ctx context.Context, orgID primitive.ObjectID) ([]domain.TripConflict, error) {
+	trips, err := s.tripRepo.ListActive(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active trips: %w", err)
+	}
 
-	if trip == nil {
-		return fmt.Errorf("trip with ID %v not found", id)
+	// trips come back ordered by scheduled departure, so once a later trip departs after the current one
+	// arrives, no trip after it can overlap the current one either
+	conflicts := make([]domain.TripConflict, 0)
+	for i, trip := range trips {
+		for _, other := range trips[i+1:] {
+			if other.DepartureTime.Scheduled >= trip.ArrivalTime.Scheduled {
+				break
+			}
+			conflicts = append(conflicts, trip.ConflictsWith(other)...)
+		}
 	}
 
-	if err != nil {
-		return fmt.Errorf(tripNotFound, err)
+	return conflicts, nil
+}
+
+func (s *tripService) AddNote(ctx context.Context, id, orgID primitive.ObjectID, content string) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.tripRepo.GetById(ctx, id, orgID)
+
+		if trip == nil {
+			return fmt.Errorf("trip with ID %v not found", id)
+		}
+
+		if err != nil {
+			return fmt.Errorf(tripNotFound, err)
+		}
+
+		before := *trip
+		if err := trip.AddNote(content); err != nil {
+			return err
+		}
+
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, &before)
+	})
+}
+
+func (s *tripService) CancelTrip(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.getTripForTransition(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		// a trip can only be canceled before it departs, so its truck was never dispatched and has nothing to release
+		before := *trip
+		if err := trip.CancelTrip(); err != nil {
+			return err
+		}
+
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+func (s *tripService) BeginTrip(ctx context.Context, id, orgID primitive.ObjectID, departureTime time.Time) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.getTripForTransition(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *trip
+		if err := s.dispatch(ctx, trip, departureTime); err != nil {
+			return err
+		}
+
+		// Update with the full trip object that contains all references
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+func (s *tripService) FinishTripSuccessfully(ctx context.Context, id, orgID primitive.ObjectID, arrivalTime time.Time, endOdometer *int) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.getTripForTransition(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *trip
+		if err := trip.CompleteTripSuccessfully(arrivalTime); err != nil {
+			return fmt.Errorf("an error occurred when attempting to complete trip: %w", err)
+		}
+
+		if err := s.releaseTripTruck(ctx, trip, endOdometer, arrivalTime); err != nil {
+			return err
+		}
+
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+func (s *tripService) FinishTripUnsuccessfully(ctx context.Context, id, orgID primitive.ObjectID, arrivalTime time.Time, endOdometer *int) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.getTripForTransition(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *trip
+		if err := trip.CompleteTripUnsuccessfully(arrivalTime); err != nil {
+			return fmt.Errorf("an error occurred when attempting to complete trip: %w", err)
+		}
+
+		if err := s.releaseTripTruck(ctx, trip, endOdometer, arrivalTime); err != nil {
+			return err
+		}
+
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+func (s *tripService) ArriveAtStop(ctx context.Context, id, orgID, stopID primitive.ObjectID, arrivalTime time.Time) error {
+	return s.updateStop(ctx, id, orgID, func(trip *domain.Trip) error {
+		return trip.ArriveAtStop(stopID, arrivalTime)
+	})
+}
+
+func (s *tripService) DepartStop(ctx context.Context, id, orgID, stopID primitive.ObjectID, departureTime time.Time) error {
+	return s.updateStop(ctx, id, orgID, func(trip *domain.Trip) error {
+		return trip.DepartStop(stopID, departureTime)
+	})
+}
+
+func (s *tripService) SkipStop(ctx context.Context, id, orgID, stopID primitive.ObjectID) error {
+	return s.updateStop(ctx, id, orgID, func(trip *domain.Trip) error {
+		return trip.SkipStop(stopID)
+	})
+}
+
+// AutoTransition makes a transition a geofence decided on and adds the automation's note to the trip. when the trip,
+// its driver or its truck refuses the transition, the trip is left as it was and the note says why.
+func (s *tripService) AutoTransition(ctx context.Context, orgID primitive.ObjectID, automation *domain.TripAutomation) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.getTripForTransition(ctx, automation.TripID, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *trip
+		action := domain.AuditActionTransition
+
+		refusal := s.automate(ctx, trip, automation)
+		if refusal != nil {
+			if !isTransitionRefusal(refusal) {
+				return refusal
+			}
+
+			// nothing is written before a transition is refused, so reloading drops whatever was half applied
+			if trip, err = s.getTripForTransition(ctx, automation.TripID, orgID); err != nil {
+				return err
+			}
+			action = domain.AuditActionUpdate
+		}
+
+		automation.Resolve(refusal)
+		if err := trip.AddNote(automation.Note); err != nil {
+			return err
+		}
+
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, action, &before)
+	})
+}
+
+// helpers
+
+// dispatch begins the trip and puts its truck on the road, as long as the driver is still able to drive it
+func (s *tripService) dispatch(ctx context.Context, trip *domain.Trip, departureTime time.Time) error {
+	if err := trip.BeginTrip(departureTime); err != nil {
+		return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+	}
+
+	if trip.DriverID != nil {
+		driver, err := s.driverRepo.GetById(ctx, *trip.DriverID, trip.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(driverNotFound, err)
+		}
+		if driver == nil {
+			return domain.ErrDriverNotFound
+		}
+
+		if err := driver.EnsureDispatchable(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+		}
+		if err := driver.EnsureLicensed(departureTime); err != nil {
+			return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+		}
+
+		hours, err := s.hos.Summary(ctx, driver.ID, trip.OrganizationID, departureTime)
+		if err != nil {
+			return err
+		}
+		if err := hours.EnsureAvailable(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+		}
 	}
 
-	if err := trip.AddNote(content); err != nil {
+	if err := s.checkHazmat(ctx, trip, departureTime, true); err != nil {
+		return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+	}
+
+	truck, err := s.getTripTruck(ctx, trip)
+	if err != nil {
 		return err
 	}
 
-	return s.tripRepo.Update(ctx, trip)
+	if truck != nil {
+		if err := s.maintenance.EnsureRoadworthy(ctx, truck, departureTime); err != nil {
+			return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+		}
+
+		mileage := truck.Mileage
+		trip.StartOdometer = &mileage
+
+		truckBefore := *truck
+		if err := truck.SetTruckInTransit(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to dispatch truck: %w", err)
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return fmt.Errorf("failed to dispatch truck: %w", err)
+		}
+
+		if err := s.recordTruckChange(ctx, &truckBefore); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (s *tripService) CancelTrip(ctx context.Context, id, userID primitive.ObjectID) error {
-	trip, err := s.tripRepo.GetById(ctx, id, userID)
-	if err != nil {
-		return fmt.Errorf(tripNotFound, err)
+func (s *tripService) automate(ctx context.Context, trip *domain.Trip, automation *domain.TripAutomation) error {
+	switch automation.Action {
+	case domain.TripAutomationBegin:
+		return s.dispatch(ctx, trip, automation.OccurredAt)
+	case domain.TripAutomationArrive:
+		return trip.RecordArrival(automation.OccurredAt)
+	case domain.TripAutomationComplete:
+		if err := trip.CompleteTripSuccessfully(automation.OccurredAt); err != nil {
+			return err
+		}
+		return s.releaseTripTruck(ctx, trip, nil, automation.OccurredAt)
 	}
 
-	if trip == nil {
-		return fmt.Errorf("trip with ID %v not found", id)
+	return fmt.Errorf("unknown trip automation: %s", automation.Action)
+}
+
+// isTransitionRefusal tells a transition the trip, driver or truck won't make apart from a failure to make it
+func isTransitionRefusal(err error) bool {
+	var tripStateErr *domain.TripStateError
+	var truckStateErr *domain.TruckStateError
+	var driverStateErr *domain.DriverStateError
+	var tripStopErr *domain.TripStopError
+	var hosLimitErr *domain.HOSLimitError
+	var credentialErr *domain.CredentialExpiredError
+	var hazmatErr *domain.HazmatComplianceError
+	var maintenanceErr *domain.MaintenanceOverdueError
+
+	return errors.As(err, &tripStateErr) ||
+		errors.As(err, &truckStateErr) ||
+		errors.As(err, &driverStateErr) ||
+		errors.As(err, &tripStopErr) ||
+		errors.As(err, &hosLimitErr) ||
+		errors.As(err, &credentialErr) ||
+		errors.As(err, &hazmatErr) ||
+		errors.As(err, &maintenanceErr)
+}
+
+// checkFacilities makes sure every facility the trip names, at either end or as a stop, belongs to its organization
+func (s *tripService) checkFacilities(ctx context.Context, trip *domain.Trip) error {
+	ids := []*primitive.ObjectID{trip.StartFacilityID, trip.EndFacilityID}
+	for i := range trip.Stops {
+		ids = append(ids, &trip.Stops[i].FacilityID)
 	}
 
-	if err := trip.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
+	checked := make(map[primitive.ObjectID]bool)
+	for _, id := range ids {
+		if id == nil || checked[*id] {
+			continue
+		}
+
+		facility, err := s.facilityRepo.GetById(ctx, *id, trip.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(facilityNotFound, err)
+		}
+		if facility == nil {
+			return domain.ErrFacilityNotFound
+		}
+		checked[*id] = true
 	}
 
-	if err := trip.CancelTrip(); err != nil {
-		return err
+	return nil
+}
+
+// estimate works out the trip's route from its facilities. a trip whose facilities can't all be placed is left
+// without an estimate, and keeps whatever distance and arrival it was given.
+func (s *tripService) estimate(ctx context.Context, trip *domain.Trip) error {
+	trip.Estimate = nil
+	if trip.StartFacilityID == nil || trip.EndFacilityID == nil {
+		return nil
 	}
 
-	return s.tripRepo.Update(ctx, trip)
+	facilityIDs := make([]primitive.ObjectID, 0, len(trip.Stops)+2)
+	facilityIDs = append(facilityIDs, *trip.StartFacilityID)
+	for _, stop := range trip.Stops {
+		facilityIDs = append(facilityIDs, stop.FacilityID)
+	}
+	facilityIDs = append(facilityIDs, *trip.EndFacilityID)
+
+	located := make(map[primitive.ObjectID]*domain.GeoPoint)
+	route := make([]*domain.GeoPoint, 0, len(facilityIDs))
+	for _, id := range facilityIDs {
+		point, ok := located[id]
+		if !ok {
+			var err error
+			if point, err = s.locateFacility(ctx, id, trip.OrganizationID); err != nil {
+				return err
+			}
+			located[id] = point
+		}
+
+		if point == nil {
+			return nil
+		}
+		route = append(route, point)
+	}
+
+	trip.ApplyEstimate(s.routing, route)
+	return nil
 }
 
-func (s *tripService) BeginTrip(ctx context.Context, id, userID primitive.ObjectID, departureTime time.Time) error {
-	trip, err := s.tripRepo.GetById(ctx, id, userID)
+// a facility is placed by its stored coordinates, falling back to geocoding its address
+func (s *tripService) locateFacility(ctx context.Context, id, orgID primitive.ObjectID) (*domain.GeoPoint, error) {
+	facility, err := s.facilityRepo.GetById(ctx, id, orgID)
 	if err != nil {
-		return fmt.Errorf(tripNotFound, err)
+		return nil, fmt.Errorf(facilityNotFound, err)
+	}
+	if facility == nil {
+		return nil, nil
 	}
 
-	if trip == nil {
-		return fmt.Errorf("trip with ID %v not found", id)
+	if facility.Location != nil {
+		return facility.Location, nil
 	}
 
-	// Initialize the state machine for the retrieved trip
-	if err := trip.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
+	point, err := s.geocoder.Geocode(ctx, facility.Address)
+	if errors.Is(err, geocode.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode facility address: %w", err)
 	}
 
-	if err := trip.BeginTrip(departureTime); err != nil {
-		return fmt.Errorf("an error occurred when attempting to begin trip: %w", err)
+	return point, nil
+}
+
+// updateStop moves one of the trip's stops along, auditing it as a transition of the trip
+func (s *tripService) updateStop(ctx context.Context, id, orgID primitive.ObjectID, change func(trip *domain.Trip) error) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		trip, err := s.getTripForTransition(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *trip
+		if err := change(trip); err != nil {
+			return fmt.Errorf("an error occurred when attempting to update trip stop: %w", err)
+		}
+
+		if err := s.tripRepo.Update(ctx, trip); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+// checkDriverLicense refuses to put a driver on a trip that leaves after their license has expired
+func (s *tripService) checkDriverLicense(ctx context.Context, trip *domain.Trip) error {
+	if trip.DriverID == nil {
+		return nil
+	}
+
+	driver, err := s.driverRepo.GetById(ctx, *trip.DriverID, trip.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(driverNotFound, err)
+	}
+	if driver == nil {
+		return domain.ErrDriverNotFound
 	}
 
-	// Update with the full trip object that contains all references
-	return s.tripRepo.Update(ctx, trip)
+	return driver.EnsureLicensed(trip.DepartureTime.Scheduled.Time())
 }
 
-func (s *tripService) FinishTripSuccessfully(ctx context.Context, id, userID primitive.ObjectID, arrivalTime time.Time) error {
-	trip, err := s.tripRepo.GetById(ctx, id, userID)
+// checkHazmat makes sure the trip's driver, truck and facilities can all handle its hazardous cargo, if it has any.
+// at is when the driver's endorsement has to be good.
+func (s *tripService) checkHazmat(ctx context.Context, trip *domain.Trip, at time.Time, requireAssignments bool) error {
+	if len(trip.HazardClasses()) == 0 {
+		return nil
+	}
+
+	check := domain.HazmatCheck{
+		Facilities:         make(map[primitive.ObjectID]*domain.Facility),
+		At:                 at,
+		RequireAssignments: requireAssignments,
+	}
+
+	if trip.DriverID != nil {
+		driver, err := s.driverRepo.GetById(ctx, *trip.DriverID, trip.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(driverNotFound, err)
+		}
+		if driver == nil {
+			return domain.ErrDriverNotFound
+		}
+		check.Driver = driver
+	}
+
+	if trip.TruckID != nil {
+		truck, err := s.truckRepo.GetById(ctx, *trip.TruckID, trip.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
+		if truck == nil {
+			return domain.ErrTruckNotFound
+		}
+		check.Truck = truck
+	}
+
+	ids := []*primitive.ObjectID{trip.StartFacilityID, trip.EndFacilityID}
+	for i := range trip.Stops {
+		ids = append(ids, &trip.Stops[i].FacilityID)
+	}
+	for _, id := range ids {
+		if id == nil || check.Facilities[*id] != nil {
+			continue
+		}
+
+		facility, err := s.facilityRepo.GetById(ctx, *id, trip.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(facilityNotFound, err)
+		}
+		if facility == nil {
+			return domain.ErrFacilityNotFound
+		}
+		check.Facilities[*id] = facility
+	}
+
+	return trip.EnsureHazmatCompliant(check)
+}
+
+func (s *tripService) checkScheduleConflicts(ctx context.Context, trip *domain.Trip, force bool) ([]domain.TripConflict, error) {
+	if !trip.IsActive() {
+		return nil, nil
+	}
+
+	overlapping, err := s.tripRepo.FindOverlapping(ctx, trip.OverlapQuery())
 	if err != nil {
-		return fmt.Errorf(tripNotFound, err)
+		return nil, fmt.Errorf("failed to check for scheduling conflicts: %w", err)
+	}
+
+	conflicts := make([]domain.TripConflict, 0)
+	for _, other := range overlapping {
+		conflicts = append(conflicts, trip.ConflictsWith(other)...)
+	}
+
+	if len(conflicts) > 0 && !force {
+		return nil, &domain.ScheduleConflictError{Conflicts: conflicts}
+	}
+
+	return conflicts, nil
+}
+
+// reassigned tells whether an update sends a driver or truck other than the one the trip has. one left off the
+// update is kept by the repository, so it isn't a change.
+func reassigned(sent, current *primitive.ObjectID) bool {
+	return sent != nil && (current == nil || *sent != *current)
+}
+
+func (s *tripService) getTripForTransition(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Trip, error) {
+	trip, err := s.tripRepo.GetById(ctx, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(tripNotFound, err)
 	}
 
 	if trip == nil {
-		return fmt.Errorf("trip with ID %v not found", id)
+		return nil, fmt.Errorf("trip with ID %v not found", id)
 	}
 
+	// Initialize the state machine for the retrieved trip
 	if err := trip.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
+		return nil, fmt.Errorf("failed to initialize state machine: %w", err)
 	}
 
-	if err := trip.CompleteTripSuccessfully(arrivalTime); err != nil {
-		return fmt.Errorf("an error occurred when attempting to complete trip: %w", err)
+	return trip, nil
+}
+
+func (s *tripService) getTripTruck(ctx context.Context, trip *domain.Trip) (*domain.Truck, error) {
+	if trip.TruckID == nil {
+		return nil, nil
 	}
 
-	return s.tripRepo.Update(ctx, trip)
+	truck, err := s.truckRepo.GetById(ctx, *trip.TruckID, trip.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return nil, domain.ErrTruckNotFound
+	}
+
+	if err := truck.InitializeStateMachine(); err != nil {
+		return nil, fmt.Errorf("failed to initialize state machine: %w", err)
+	}
+
+	return truck, nil
 }
 
-func (s *tripService) FinishTripUnsuccessfully(ctx context.Context, id, userID primitive.ObjectID, arrivalTime time.Time) error {
-	trip, err := s.tripRepo.GetById(ctx, id, userID)
+// releaseTripTruck hands the truck back once the trip is over, moving its mileage up to the odometer reading taken
+// at the end of the trip when there is one and noting where the odometer ended up. the trip's distance is only ever
+// planned or estimated, so without a reading the truck stays at the last one on record. if the truck was pulled into
+// maintenance mid-trip it is left where it is rather than being made available out from under the mechanics.
+func (s *tripService) releaseTripTruck(ctx context.Context, trip *domain.Trip, endOdometer *int, at time.Time) error {
+	truck, err := s.getTripTruck(ctx, trip)
 	if err != nil {
-		return fmt.Errorf(tripNotFound, err)
+		return err
 	}
 
-	if trip == nil {
-		return fmt.Errorf("trip with ID %v not found", id)
+	if truck == nil {
+		return nil
 	}
 
-	if err := trip.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
+	before := *truck
+	if endOdometer != nil {
+		if err := truck.RecordOdometer(*endOdometer, at); err != nil {
+			return err
+		}
+	}
+
+	mileage := truck.Mileage
+	trip.EndOdometer = &mileage
+
+	if truck.Status == domain.TruckStatusInTransit {
+		if err := truck.MakeTruckAvailable(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to release truck: %w", err)
+		}
+	}
+
+	if truck.Status == before.Status && truck.Mileage == before.Mileage {
+		return nil
+	}
+
+	if err := s.truckRepo.Update(ctx, truck); err != nil {
+		return fmt.Errorf("failed to release truck: %w", err)
 	}
 
-	if err := trip.CompleteTripUnsuccessfully(arrivalTime); err != nil {
-		return fmt.Errorf("an error occurred when attempting to complete trip: %w", err)
+	return s.recordTruckChange(ctx, &before)
+}
+
+// recordChange audits a mutation of an existing trip, reading the trip back so that the before and
+// after snapshots have the same shape
+func (s *tripService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.Trip) error {
+	after, err := s.tripRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(tripNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityTrip, before.ID, before.OrganizationID, action, before, after)
+}
+
+// dispatching and finishing a trip moves its truck through its own lifecycle, which is audited against the truck
+func (s *tripService) recordTruckChange(ctx context.Context, before *domain.Truck) error {
+	after, err := s.truckRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(truckNotFound, err)
 	}
 
-	return s.tripRepo.Update(ctx, trip)
+	return s.audit.Record(ctx, domain.AuditEntityTruck, before.ID, before.OrganizationID, domain.AuditActionTransition, before, after)
 }
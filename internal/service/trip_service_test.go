@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/geocode"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type tripFixture struct {
+	service   TripService
+	trucks    TruckService
+	tripRepo  repository.TripRepository
+	truckRepo repository.TruckRepository
+	orgID     primitive.ObjectID
+	departure time.Time
+}
+
+// newTripFixture wires the trip service to the in-memory repositories, which is how it runs without a database
+func newTripFixture(t *testing.T) *tripFixture {
+	t.Helper()
+
+	store := repository.NewMemoryStore()
+	tripRepo := repository.NewMemoryTripRepository(store)
+	truckRepo := repository.NewMemoryTruckRepository(store)
+	driverRepo := repository.NewMemoryDriverRepository(store)
+	audit := NewAuditService(nil, repository.NewMemoryAuditEventRepository(store))
+	hos := NewHOSService(nil, repository.NewMemoryDutyStatusRepository(store), driverRepo, federalHOSRules)
+	maintenance := NewMaintenanceScheduleService(nil, repository.NewMemoryMaintenanceScheduleRepository(store),
+		repository.NewMemoryMaintenanceLogRepository(store), truckRepo, audit, false)
+
+	return &tripFixture{
+		service: NewTripService(nil, tripRepo, truckRepo, driverRepo, repository.NewMemoryFacilityRepository(store),
+			audit, hos, maintenance, geocode.Disabled{}, domain.RoutePolicy{}),
+		trucks:    NewTruckService(nil, truckRepo, driverRepo, tripRepo, audit),
+		tripRepo:  tripRepo,
+		truckRepo: truckRepo,
+		orgID:     primitive.NewObjectID(),
+		departure: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+}
+
+var federalHOSRules = domain.HOSRules{
+	MaxDriving:    11 * time.Hour,
+	DutyWindow:    14 * time.Hour,
+	ShiftReset:    10 * time.Hour,
+	BreakAfter:    8 * time.Hour,
+	BreakDuration: 30 * time.Minute,
+	CycleLimit:    70 * time.Hour,
+	CycleDays:     8,
+	CycleRestart:  34 * time.Hour,
+}
+
+func (f *tripFixture) truck(t *testing.T) *domain.Truck {
+	t.Helper()
+
+	truck, err := domain.NewTruck(f.orgID, primitive.NewObjectID(), "T1", "1XKAD49X0CJ123456", "Kenworth", "T680",
+		domain.TrailerTypeDryVan, domain.FuelTypeDiesel, "", 2020, 100_000, 20, domain.LicensePlate{Number: "ABC123", State: "OH"})
+	if err != nil {
+		t.Fatalf("NewTruck() error = %v", err)
+	}
+	if err := f.truckRepo.Create(context.Background(), truck); err != nil {
+		t.Fatalf("creating truck: %v", err)
+	}
+
+	return truck
+}
+
+func (f *tripFixture) trip(t *testing.T, truck *domain.Truck) *domain.Trip {
+	t.Helper()
+
+	trip, err := domain.NewTrip(f.orgID, primitive.NewObjectID(), "TR-1", nil, &truck.ID, nil, nil,
+		domain.TimeWindow{Scheduled: primitive.NewDateTimeFromTime(f.departure)},
+		domain.TimeWindow{Scheduled: primitive.NewDateTimeFromTime(f.departure.Add(8 * time.Hour))},
+		nil, domain.Cargo{Description: "paper"}, 0, 400)
+	if err != nil {
+		t.Fatalf("NewTrip() error = %v", err)
+	}
+	if _, err := f.service.Create(context.Background(), trip, false); err != nil {
+		t.Fatalf("creating trip: %v", err)
+	}
+	if err := f.service.AddNote(context.Background(), trip.ID, f.orgID, "call ahead"); err != nil {
+		t.Fatalf("adding note: %v", err)
+	}
+
+	return trip
+}
+
+// edit is what a PUT sends: the trip's details, without its status or notes
+func (f *tripFixture) edit(trip *domain.Trip) *domain.Trip {
+	return &domain.Trip{
+		ID:             trip.ID,
+		OrganizationID: f.orgID,
+		TripNumber:     "TR-1A",
+		DepartureTime:  trip.DepartureTime,
+		ArrivalTime:    trip.ArrivalTime,
+		Cargo:          trip.Cargo,
+		DistanceMiles:  trip.DistanceMiles,
+		Notes:          make([]domain.TripNote, 0),
+	}
+}
+
+func TestTripServiceUpdateKeepsStatusAndNotes(t *testing.T) {
+	tests := []struct {
+		name       string
+		begin      bool
+		wantStatus domain.TripStatus
+		// wantTruck is where the truck ends up once the trip has carried on to its next state
+		wantTruck domain.TruckStatus
+	}{
+		{name: "scheduled trip", wantStatus: domain.TripStatusScheduled, wantTruck: domain.TruckStatusInTransit},
+		{name: "trip in transit", begin: true, wantStatus: domain.TripStatusInTransit, wantTruck: domain.TruckStatusAvailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newTripFixture(t)
+			truck := f.truck(t)
+			trip := f.trip(t, truck)
+
+			if tt.begin {
+				if err := f.service.BeginTrip(ctx, trip.ID, f.orgID, f.departure); err != nil {
+					t.Fatalf("BeginTrip() error = %v", err)
+				}
+			}
+
+			if _, err := f.service.Update(ctx, f.edit(trip), false); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			got, err := f.service.GetById(ctx, trip.ID, f.orgID)
+			if err != nil {
+				t.Fatalf("GetById() error = %v", err)
+			}
+			if got.TripNumber != "TR-1A" {
+				t.Errorf("TripNumber = %s, want TR-1A", got.TripNumber)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if len(got.Notes) != 1 || got.Notes[0].Content != "call ahead" {
+				t.Errorf("Notes = %+v, want the note added before the update", got.Notes)
+			}
+
+			active, err := f.tripRepo.ListActive(ctx, f.orgID)
+			if err != nil {
+				t.Fatalf("ListActive() error = %v", err)
+			}
+			if len(active) != 1 || active[0].ID != trip.ID {
+				t.Errorf("ListActive() = %d trips, want the updated trip", len(active))
+			}
+
+			// the trip has to be able to carry on from where it was
+			if tt.begin {
+				err = f.service.FinishTripSuccessfully(ctx, trip.ID, f.orgID, f.departure.Add(6*time.Hour), nil)
+			} else {
+				err = f.service.BeginTrip(ctx, trip.ID, f.orgID, f.departure)
+			}
+			if err != nil {
+				t.Fatalf("next transition error = %v", err)
+			}
+
+			after, err := f.truckRepo.GetById(ctx, truck.ID, f.orgID)
+			if err != nil {
+				t.Fatalf("GetById() truck error = %v", err)
+			}
+			if after.Status != tt.wantTruck {
+				t.Errorf("truck Status = %s, want %s", after.Status, tt.wantTruck)
+			}
+		})
+	}
+}
+
+func TestTripServiceUnderwayTripKeepsItsTruck(t *testing.T) {
+	ctx := context.Background()
+	f := newTripFixture(t)
+	truck := f.truck(t)
+	trip := f.trip(t, truck)
+	other := f.truck(t)
+
+	if err := f.service.BeginTrip(ctx, trip.ID, f.orgID, f.departure); err != nil {
+		t.Fatalf("BeginTrip() error = %v", err)
+	}
+
+	edit := f.edit(trip)
+	edit.TruckID = &other.ID
+	var stateErr *domain.TripStateError
+	if _, err := f.service.Update(ctx, edit, false); !errors.As(err, &stateErr) {
+		t.Errorf("Update() with another truck error = %v, want a TripStateError", err)
+	}
+
+	edit = f.edit(trip)
+	edit.TruckID = &truck.ID
+	if _, err := f.service.Update(ctx, edit, false); err != nil {
+		t.Errorf("Update() with the same truck error = %v", err)
+	}
+
+	if err := f.service.Delete(ctx, trip.ID, f.orgID); !errors.Is(err, domain.ErrTripInTransit) {
+		t.Errorf("Delete() error = %v, want ErrTripInTransit", err)
+	}
+
+	got, err := f.service.GetById(ctx, trip.ID, f.orgID)
+	if err != nil {
+		t.Fatalf("GetById() error = %v", err)
+	}
+	if got.TruckID == nil || *got.TruckID != truck.ID {
+		t.Errorf("TruckID = %v, want %s", got.TruckID, truck.ID.Hex())
+	}
+}
+
+func TestTruckServiceStatusWhileOnTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		trip    bool
+		begin   bool
+		change  func(s TruckService, ctx context.Context, id, orgID primitive.ObjectID) error
+		wantErr bool
+	}{
+		{name: "put on the road without a trip", change: TruckService.SetTruckInTransit},
+		{name: "put on the road ahead of its trip", trip: true, change: TruckService.SetTruckInTransit, wantErr: true},
+		{name: "made available under its trip", trip: true, begin: true, change: TruckService.MakeTruckAvailable, wantErr: true},
+		{name: "retired under its trip", trip: true, begin: true, change: TruckService.RetireTruck, wantErr: true},
+		{name: "sent to maintenance while scheduled", trip: true, change: TruckService.SetTruckInMaintenance},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newTripFixture(t)
+			truck := f.truck(t)
+			if tt.trip {
+				trip := f.trip(t, truck)
+				if tt.begin {
+					if err := f.service.BeginTrip(ctx, trip.ID, f.orgID, f.departure); err != nil {
+						t.Fatalf("BeginTrip() error = %v", err)
+					}
+				}
+			}
+
+			err := tt.change(f.trucks, ctx, truck.ID, f.orgID)
+			var onTripErr *domain.TruckOnTripError
+			if tt.wantErr != errors.As(err, &onTripErr) {
+				t.Errorf("error = %v, want a TruckOnTripError: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTruckServiceUpdateKeepsStatus(t *testing.T) {
+	ctx := context.Background()
+	f := newTripFixture(t)
+	truck := f.truck(t)
+	trip := f.trip(t, truck)
+	if err := f.service.BeginTrip(ctx, trip.ID, f.orgID, f.departure); err != nil {
+		t.Fatalf("BeginTrip() error = %v", err)
+	}
+
+	// a PUT has no status to send
+	edit := *truck
+	edit.Status = ""
+	edit.LastMaintenance = "2024-05-01"
+	if err := f.trucks.Update(ctx, &edit); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := f.trucks.GetById(ctx, truck.ID, f.orgID)
+	if err != nil {
+		t.Fatalf("GetById() error = %v", err)
+	}
+	if got.Status != domain.TruckStatusInTransit {
+		t.Errorf("Status = %q, want %q", got.Status, domain.TruckStatusInTransit)
+	}
+	if got.LastMaintenance != "2024-05-01" {
+		t.Errorf("LastMaintenance = %q, want the updated one", got.LastMaintenance)
+	}
+}
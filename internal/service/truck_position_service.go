@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TruckPositionService interface {
+	Record(ctx context.Context, truckID, orgID primitive.ObjectID, positions []domain.Position) ([]*domain.TruckPosition, error)
+	TripTrack(ctx context.Context, tripID, orgID primitive.ObjectID) (*domain.TripTrack, error)
+}
+
+type truckPositionService struct {
+	positionRepo repository.TruckPositionRepository
+	truckRepo    repository.TruckRepository
+	tripRepo     repository.TripRepository
+	geofences    GeofenceService
+}
+
+func NewTruckPositionService(
+	positionRepo repository.TruckPositionRepository,
+	truckRepo repository.TruckRepository,
+	tripRepo repository.TripRepository,
+	geofences GeofenceService,
+) TruckPositionService {
+	return &truckPositionService{
+		positionRepo: positionRepo,
+		truckRepo:    truckRepo,
+		tripRepo:     tripRepo,
+		geofences:    geofences,
+	}
+}
+
+// Record stores a batch of pings for a truck, moves its last known position forward and lets the truck's trips react
+// to any geofences it crossed. pings aren't audited; they're data the truck reports, not changes anyone made. mongo
+// can't write to a time series collection inside a transaction, so this runs without one: a failure after the
+// insert leaves the history complete and only the latest position stale, which the next ping repairs.
+func (s *truckPositionService) Record(ctx context.Context, truckID, orgID primitive.ObjectID, positions []domain.Position) ([]*domain.TruckPosition, error) {
+	truck, err := s.truckRepo.GetById(ctx, truckID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return nil, domain.ErrTruckNotFound
+	}
+
+	records := make([]*domain.TruckPosition, 0, len(positions))
+	var latest *domain.Position
+	for i := range positions {
+		records = append(records, domain.NewTruckPosition(orgID, truckID, positions[i]))
+		if latest == nil || positions[i].RecordedAt > latest.RecordedAt {
+			latest = &positions[i]
+		}
+	}
+	if latest == nil {
+		return records, nil
+	}
+
+	if err := s.positionRepo.CreateMany(ctx, records); err != nil {
+		return nil, fmt.Errorf("failed to record truck positions: %w", err)
+	}
+
+	if err := s.truckRepo.UpdateLastPosition(ctx, truckID, orgID, *latest); err != nil {
+		return nil, fmt.Errorf("failed to update truck position: %w", err)
+	}
+
+	if _, err := s.geofences.ProcessPositions(ctx, truckID, orgID, truck.LastPosition, positions); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// TripTrack returns the positions the trip's truck reported between the trip's departure and arrival. a trip that
+// hasn't departed, or whose truck is gone, has an empty track.
+func (s *truckPositionService) TripTrack(ctx context.Context, tripID, orgID primitive.ObjectID) (*domain.TripTrack, error) {
+	trip, err := s.tripRepo.GetById(ctx, tripID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(tripNotFound, err)
+	}
+	if trip == nil {
+		return nil, domain.ErrTripNotFound
+	}
+
+	track := &domain.TripTrack{
+		TripID:    trip.ID,
+		Positions: make([]domain.Position, 0),
+	}
+
+	from, to, ok := trip.TrackWindow(time.Now())
+	if !ok || trip.Truck == nil {
+		return track, nil
+	}
+
+	track.TruckID = &trip.Truck.ID
+	track.From = &from
+	track.To = &to
+
+	positions, err := s.positionRepo.ListForTruck(ctx, orgID, trip.Truck.ID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve trip track: %w", err)
+	}
+	track.Positions = positions
+
+	return track, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jwald3/waybill/internal/database"
 	"github.com/jwald3/waybill/internal/domain"
@@ -16,40 +17,49 @@ var (
 
 type TruckService interface {
 	Create(ctx context.Context, truck *domain.Truck) error
-	GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Truck, error)
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Truck, error)
 	Update(ctx context.Context, truck *domain.Truck) error
-	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
 	List(ctx context.Context, filter domain.TruckFilter) (*repository.ListTrucksResult, error)
-	SetTruckInTransit(ctx context.Context, id, userID primitive.ObjectID) error
-	SetTruckInMaintenance(ctx context.Context, id, userID primitive.ObjectID) error
-	RetireTruck(ctx context.Context, id, userID primitive.ObjectID) error
-	MakeTruckAvailable(ctx context.Context, id, userID primitive.ObjectID) error
-	UpdateTruckMileage(ctx context.Context, id, userID primitive.ObjectID, newMileage int) error
-	UpdateTruckMaintenance(ctx context.Context, id, userID primitive.ObjectID, lastMaintenance string) error
+	SetTruckInTransit(ctx context.Context, id, orgID primitive.ObjectID) error
+	SetTruckInMaintenance(ctx context.Context, id, orgID primitive.ObjectID) error
+	RetireTruck(ctx context.Context, id, orgID primitive.ObjectID) error
+	MakeTruckAvailable(ctx context.Context, id, orgID primitive.ObjectID) error
+	UpdateTruckMileage(ctx context.Context, id, orgID primitive.ObjectID, newMileage int) error
+	CorrectTruckMileage(ctx context.Context, id, orgID primitive.ObjectID, mileage int) error
+	UpdateTruckMaintenance(ctx context.Context, id, orgID primitive.ObjectID, lastMaintenance string) error
 }
 
 type truckService struct {
-	db        *database.MongoDB
-	truckRepo repository.TruckRepository
+	db         *database.MongoDB
+	truckRepo  repository.TruckRepository
+	driverRepo repository.DriverRepository
+	tripRepo   repository.TripRepository
+	audit      AuditService
 }
 
-func NewTruckService(db *database.MongoDB, truckRepo repository.TruckRepository) TruckService {
+func NewTruckService(db *database.MongoDB, truckRepo repository.TruckRepository, driverRepo repository.DriverRepository, tripRepo repository.TripRepository, audit AuditService) TruckService {
 	return &truckService{
-		db:        db,
-		truckRepo: truckRepo,
+		db:         db,
+		truckRepo:  truckRepo,
+		driverRepo: driverRepo,
+		tripRepo:   tripRepo,
+		audit:      audit,
 	}
 }
 
 func (s *truckService) Create(ctx context.Context, truck *domain.Truck) error {
-	if err := s.truckRepo.Create(ctx, truck); err != nil {
-		return fmt.Errorf("failed to create truck: %w", err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.truckRepo.Create(ctx, truck); err != nil {
+			return fmt.Errorf("failed to create truck: %w", err)
+		}
 
-	return nil
+		return s.audit.Record(ctx, domain.AuditEntityTruck, truck.ID, truck.OrganizationID, domain.AuditActionCreate, nil, truck)
+	})
 }
 
-func (s *truckService) GetById(ctx context.Context, id, userID primitive.ObjectID) (*domain.Truck, error) {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+func (s *truckService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.Truck, error) {
+	truck, err := s.truckRepo.GetById(ctx, id, orgID)
 	if err != nil {
 		return nil, fmt.Errorf(truckNotFound, err)
 	}
@@ -61,24 +71,65 @@ func (s *truckService) GetById(ctx context.Context, id, userID primitive.ObjectI
 }
 
 func (s *truckService) Update(ctx context.Context, truck *domain.Truck) error {
-	err := s.truckRepo.Update(ctx, truck)
-	if err != nil {
-		return fmt.Errorf(truckNotFound, err)
-	}
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.truckRepo.GetById(ctx, truck.ID, truck.OrganizationID)
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
+		if before == nil {
+			return domain.ErrTruckNotFound
+		}
 
-	return nil
+		// status only moves through the truck's transitions, which keep it in step with the trips using the truck
+		truck.Status = before.Status
+
+		// a new mileage is a reading like any other, held to the last one on record
+		if truck.Mileage != before.Mileage {
+			reading := truck.Mileage
+			truck.Mileage, truck.MileageRecordedAt = before.Mileage, before.MileageRecordedAt
+			if err := truck.RecordOdometer(reading, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		if truck.AssignedDriverID != nil {
+			driver, err := s.driverRepo.GetById(ctx, *truck.AssignedDriverID, truck.OrganizationID)
+			if err != nil {
+				return fmt.Errorf(driverNotFound, err)
+			}
+			if driver == nil {
+				return domain.ErrDriverNotFound
+			}
+			if err := driver.EnsureLicensed(time.Now()); err != nil {
+				return err
+			}
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
 }
 
-func (s *truckService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-	if err := s.truckRepo.Delete(ctx, id, userID); err != nil {
-		if err == domain.ErrTruckNotFound {
-			return err
+func (s *truckService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.truckRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
 		}
 
-		return fmt.Errorf("failed to delete truck: %w", err)
-	}
+		if err := s.truckRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrTruckNotFound {
+				return err
+			}
 
-	return nil
+			return fmt.Errorf("failed to delete truck: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityTruck, id, orgID, domain.AuditActionDelete, before, nil)
+	})
 }
 
 func (s *truckService) List(ctx context.Context, filter domain.TruckFilter) (*repository.ListTrucksResult, error) {
@@ -100,112 +151,238 @@ func (s *truckService) List(ctx context.Context, filter domain.TruckFilter) (*re
 
 // atomic methods
 
-func (s *truckService) SetTruckInTransit(ctx context.Context, id, userID primitive.ObjectID) error {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+func (s *truckService) SetTruckInTransit(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
 
-	if err != nil {
-		return fmt.Errorf(truckNotFound, err)
-	}
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
 
-	if truck == nil {
-		return fmt.Errorf("truck with ID %v not found", id)
-	}
+		if truck == nil {
+			return fmt.Errorf("truck with ID %v not found", id)
+		}
 
-	if err := truck.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
-	}
+		if err := truck.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
 
-	if err := truck.SetTruckInTransit(); err != nil {
-		return fmt.Errorf("an error occurred when attempting to set truck in transit: %w", err)
-	}
+		if err := s.ensureNotOnTrip(ctx, truck, domain.TruckStatusInTransit); err != nil {
+			return err
+		}
+
+		before := *truck
+		if err := truck.SetTruckInTransit(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to set truck in transit: %w", err)
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
 
-	return s.truckRepo.Update(ctx, truck)
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
 }
 
-func (s *truckService) SetTruckInMaintenance(ctx context.Context, id, userID primitive.ObjectID) error {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+func (s *truckService) SetTruckInMaintenance(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
 
-	if err != nil {
-		return fmt.Errorf(truckNotFound, err)
-	}
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
 
-	if truck == nil {
-		return fmt.Errorf("truck with ID %v not found", id)
-	}
+		if truck == nil {
+			return fmt.Errorf("truck with ID %v not found", id)
+		}
 
-	if err := truck.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
-	}
+		if err := truck.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
 
-	if err := truck.SetTruckInMaintenance(); err != nil {
-		return fmt.Errorf("an error occurred when attempting to set truck in maintenance: %w", err)
-	}
+		if err := s.ensureNotOnTrip(ctx, truck, domain.TruckStatusUnderMaintenance); err != nil {
+			return err
+		}
+
+		before := *truck
+		if err := truck.SetTruckInMaintenance(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to set truck in maintenance: %w", err)
+		}
 
-	return s.truckRepo.Update(ctx, truck)
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
 }
 
-func (s *truckService) RetireTruck(ctx context.Context, id, userID primitive.ObjectID) error {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+func (s *truckService) RetireTruck(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
 
-	if err != nil {
-		return fmt.Errorf(truckNotFound, err)
-	}
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
 
-	if truck == nil {
-		return fmt.Errorf("truck with ID %v not found", id)
-	}
+		if truck == nil {
+			return fmt.Errorf("truck with ID %v not found", id)
+		}
 
-	if err := truck.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
-	}
+		if err := truck.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
 
-	if err := truck.RetireTruck(); err != nil {
-		return fmt.Errorf("an error occurred when attempting to retire truck: %w", err)
-	}
+		if err := s.ensureNotOnTrip(ctx, truck, domain.TruckStatusRetired); err != nil {
+			return err
+		}
+
+		before := *truck
+		if err := truck.RetireTruck(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to retire truck: %w", err)
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
 
-	return s.truckRepo.Update(ctx, truck)
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
 }
 
-func (s *truckService) MakeTruckAvailable(ctx context.Context, id, userID primitive.ObjectID) error {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+func (s *truckService) MakeTruckAvailable(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
 
-	if err != nil {
-		return fmt.Errorf(truckNotFound, err)
-	}
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
 
-	if truck == nil {
-		return fmt.Errorf("truck with ID %v not found", id)
-	}
+		if truck == nil {
+			return fmt.Errorf("truck with ID %v not found", id)
+		}
 
-	if err := truck.InitializeStateMachine(); err != nil {
-		return fmt.Errorf("failed to initialize state machine: %w", err)
-	}
+		if err := truck.InitializeStateMachine(); err != nil {
+			return fmt.Errorf("failed to initialize state machine: %w", err)
+		}
 
-	if err := truck.MakeTruckAvailable(); err != nil {
-		return fmt.Errorf("an error occurred when attempting to make truck available: %w", err)
-	}
+		if err := s.ensureNotOnTrip(ctx, truck, domain.TruckStatusAvailable); err != nil {
+			return err
+		}
+
+		before := *truck
+		if err := truck.MakeTruckAvailable(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to make truck available: %w", err)
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+func (s *truckService) UpdateTruckMileage(ctx context.Context, id, orgID primitive.ObjectID, newMileage int) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
+		if truck == nil {
+			return domain.ErrTruckNotFound
+		}
+
+		before := *truck
+		if err := truck.RecordOdometer(newMileage, time.Now()); err != nil {
+			return err
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, &before)
+	})
+}
+
+// CorrectTruckMileage overrides the truck's odometer, lower or higher, without the checks a reading goes through.
+// it's recorded as a correction so the override stands out in the truck's history.
+func (s *truckService) CorrectTruckMileage(ctx context.Context, id, orgID primitive.ObjectID, mileage int) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
+		if truck == nil {
+			return domain.ErrTruckNotFound
+		}
+
+		before := *truck
+		if err := truck.CorrectOdometer(mileage, time.Now()); err != nil {
+			return err
+		}
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
+
+		return s.recordChange(ctx, domain.AuditActionCorrection, &before)
+	})
+}
+
+func (s *truckService) UpdateTruckMaintenance(ctx context.Context, id, orgID primitive.ObjectID, lastMaintenance string) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		truck, err := s.truckRepo.GetById(ctx, id, orgID)
+		if err != nil {
+			return fmt.Errorf(truckNotFound, err)
+		}
+		if truck == nil {
+			return domain.ErrTruckNotFound
+		}
+
+		before := *truck
+		truck.LastMaintenance = lastMaintenance
+
+		if err := s.truckRepo.Update(ctx, truck); err != nil {
+			return err
+		}
 
-	return s.truckRepo.Update(ctx, truck)
+		return s.recordChange(ctx, domain.AuditActionUpdate, &before)
+	})
 }
 
-func (s *truckService) UpdateTruckMileage(ctx context.Context, id, userID primitive.ObjectID, newMileage int) error {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+// helpers
+
+// recordChange audits a mutation of an existing truck, reading the truck back so that the before and
+// after snapshots have the same shape
+// ensureNotOnTrip keeps a truck's trips in charge of moving it in and out of transit. it can't be put on the road by
+// hand while a trip is waiting to dispatch it, or taken off while one is under way; a truck that breaks down mid-trip
+// is pulled in by starting a work order on it.
+func (s *truckService) ensureNotOnTrip(ctx context.Context, truck *domain.Truck, desired domain.TruckStatus) error {
+	if truck.Status != domain.TruckStatusInTransit && desired != domain.TruckStatusInTransit {
+		return nil
+	}
+
+	trips, err := s.tripRepo.ListAssignedToTrucks(ctx, truck.OrganizationID, &truck.ID)
 	if err != nil {
-		return fmt.Errorf(truckNotFound, err)
+		return fmt.Errorf("failed to list the truck's trips: %w", err)
 	}
 
-	truck.Mileage = newMileage
+	for _, trip := range trips {
+		if trip.Status == domain.TripStatusInTransit || (desired == domain.TruckStatusInTransit && trip.IsActive()) {
+			return &domain.TruckOnTripError{TruckID: truck.ID, TripID: trip.ID, TripStatus: trip.Status}
+		}
+	}
 
-	return s.truckRepo.Update(ctx, truck)
+	return nil
 }
 
-func (s *truckService) UpdateTruckMaintenance(ctx context.Context, id, userID primitive.ObjectID, lastMaintenance string) error {
-	truck, err := s.truckRepo.GetById(ctx, id, userID)
+func (s *truckService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.Truck) error {
+	after, err := s.truckRepo.GetById(ctx, before.ID, before.OrganizationID)
 	if err != nil {
 		return fmt.Errorf(truckNotFound, err)
 	}
 
-	truck.LastMaintenance = lastMaintenance
-
-	return s.truckRepo.Update(ctx, truck)
+	return s.audit.Record(ctx, domain.AuditEntityTruck, before.ID, before.OrganizationID, action, before, after)
 }
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jwald3/waybill/internal/database"
+	"github.com/jwald3/waybill/internal/domain"
+	"github.com/jwald3/waybill/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	workOrderNotFound = "unable to retrieve work order: %w"
+)
+
+type WorkOrderService interface {
+	Create(ctx context.Context, workOrder *domain.WorkOrder) error
+	GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.WorkOrder, error)
+	Update(ctx context.Context, workOrder *domain.WorkOrder) error
+	Delete(ctx context.Context, id, orgID primitive.ObjectID) error
+	List(ctx context.Context, filter domain.WorkOrderFilter) (*repository.ListWorkOrdersResult, error)
+	Start(ctx context.Context, id, orgID primitive.ObjectID, at time.Time) error
+	AwaitParts(ctx context.Context, id, orgID primitive.ObjectID) error
+	Complete(ctx context.Context, id, orgID primitive.ObjectID, at time.Time, mileage int) error
+	Cancel(ctx context.Context, id, orgID primitive.ObjectID, at time.Time) error
+}
+
+type workOrderService struct {
+	db                 *database.MongoDB
+	workOrderRepo      repository.WorkOrderRepository
+	truckRepo          repository.TruckRepository
+	maintenanceLogRepo repository.MaintenanceLogRepository
+	scheduleRepo       repository.MaintenanceScheduleRepository
+	audit              AuditService
+}
+
+func NewWorkOrderService(
+	db *database.MongoDB,
+	workOrderRepo repository.WorkOrderRepository,
+	truckRepo repository.TruckRepository,
+	maintenanceLogRepo repository.MaintenanceLogRepository,
+	scheduleRepo repository.MaintenanceScheduleRepository,
+	audit AuditService) WorkOrderService {
+	return &workOrderService{
+		db:                 db,
+		workOrderRepo:      workOrderRepo,
+		truckRepo:          truckRepo,
+		maintenanceLogRepo: maintenanceLogRepo,
+		scheduleRepo:       scheduleRepo,
+		audit:              audit,
+	}
+}
+
+func (s *workOrderService) Create(ctx context.Context, workOrder *domain.WorkOrder) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		if _, err := s.getTruck(ctx, workOrder); err != nil {
+			return err
+		}
+
+		if err := s.ensureSchedule(ctx, workOrder); err != nil {
+			return err
+		}
+
+		if err := s.workOrderRepo.Create(ctx, workOrder); err != nil {
+			return fmt.Errorf("failed to create work order: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityWorkOrder, workOrder.ID, workOrder.OrganizationID, domain.AuditActionCreate, nil, workOrder)
+	})
+}
+
+func (s *workOrderService) GetById(ctx context.Context, id, orgID primitive.ObjectID) (*domain.WorkOrder, error) {
+	workOrder, err := s.workOrderRepo.GetById(ctx, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf(workOrderNotFound, err)
+	}
+	if workOrder == nil {
+		return nil, domain.ErrWorkOrderNotFound
+	}
+
+	if err := workOrder.InitializeStateMachine(); err != nil {
+		return nil, fmt.Errorf("failed to initialize state machine: %w", err)
+	}
+
+	return workOrder, nil
+}
+
+// Update rewrites the description and line items of a work order that is still open or underway. the truck and
+// the lifecycle fields stay as they are; those only change through the transitions.
+func (s *workOrderService) Update(ctx context.Context, workOrder *domain.WorkOrder) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.GetById(ctx, workOrder.ID, workOrder.OrganizationID)
+		if err != nil {
+			return err
+		}
+		if before.Status.IsClosed() {
+			return domain.ErrWorkOrderClosed
+		}
+
+		if err := s.ensureSchedule(ctx, workOrder); err != nil {
+			return err
+		}
+
+		workOrder.TruckID = before.TruckID
+		workOrder.Status = before.Status
+		workOrder.Mileage = before.Mileage
+		workOrder.StartedAt = before.StartedAt
+		workOrder.ClosedAt = before.ClosedAt
+		workOrder.MaintenanceLogID = before.MaintenanceLogID
+
+		if err := s.workOrderRepo.Update(ctx, workOrder); err != nil {
+			return fmt.Errorf("failed to update work order: %w", err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionUpdate, before)
+	})
+}
+
+func (s *workOrderService) Delete(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		before, err := s.GetById(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+		if before.Status.HoldsTruck() {
+			return domain.ErrWorkOrderActive
+		}
+
+		if err := s.workOrderRepo.Delete(ctx, id, orgID); err != nil {
+			if err == domain.ErrWorkOrderNotFound {
+				return err
+			}
+
+			return fmt.Errorf("failed to delete work order: %w", err)
+		}
+
+		return s.audit.Record(ctx, domain.AuditEntityWorkOrder, id, orgID, domain.AuditActionDelete, before, nil)
+	})
+}
+
+func (s *workOrderService) List(ctx context.Context, filter domain.WorkOrderFilter) (*repository.ListWorkOrdersResult, error) {
+	result, err := s.workOrderRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list work orders: %w", err)
+	}
+
+	if result.WorkOrders == nil {
+		result.WorkOrders = []*domain.WorkOrder{}
+	}
+
+	return result, nil
+}
+
+// Start begins work, or picks it back up once parts have come in, and takes the truck out of service for it
+func (s *workOrderService) Start(ctx context.Context, id, orgID primitive.ObjectID, at time.Time) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		workOrder, err := s.GetById(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *workOrder
+		if err := workOrder.Start(at); err != nil {
+			return fmt.Errorf("an error occurred when attempting to start work order: %w", err)
+		}
+
+		if err := s.holdTruck(ctx, workOrder); err != nil {
+			return err
+		}
+
+		if err := s.workOrderRepo.Update(ctx, workOrder); err != nil {
+			return fmt.Errorf("failed to start work order: %w", err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+func (s *workOrderService) AwaitParts(ctx context.Context, id, orgID primitive.ObjectID) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		workOrder, err := s.GetById(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *workOrder
+		if err := workOrder.AwaitParts(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to hold work order for parts: %w", err)
+		}
+
+		if err := s.workOrderRepo.Update(ctx, workOrder); err != nil {
+			return fmt.Errorf("failed to hold work order for parts: %w", err)
+		}
+
+		return s.recordChange(ctx, domain.AuditActionTransition, &before)
+	})
+}
+
+// Complete closes out the work order, writes the maintenance log it leaves behind and, once no other work order
+// has the truck in the shop, puts the truck back in service. a mileage of zero means the truck's current odometer;
+// any other is an odometer reading, so it can't be behind the truck's and moves the truck up to it.
+func (s *workOrderService) Complete(ctx context.Context, id, orgID primitive.ObjectID, at time.Time, mileage int) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		workOrder, err := s.GetById(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		truck, err := s.getTruck(ctx, workOrder)
+		if err != nil {
+			return err
+		}
+
+		if mileage == 0 {
+			mileage = truck.Mileage
+		}
+		if err := truck.RecordOdometer(mileage, at); err != nil {
+			return err
+		}
+
+		before := *workOrder
+		if err := workOrder.Complete(at, mileage); err != nil {
+			return fmt.Errorf("an error occurred when attempting to complete work order: %w", err)
+		}
+
+		maintenanceLog := workOrder.MaintenanceLog()
+		if err := s.maintenanceLogRepo.Create(ctx, maintenanceLog); err != nil {
+			return fmt.Errorf("failed to create maintenance log: %w", err)
+		}
+
+		if err := s.audit.Record(ctx, domain.AuditEntityMaintenanceLog, maintenanceLog.ID, maintenanceLog.OrganizationID, domain.AuditActionCreate, nil, maintenanceLog); err != nil {
+			return err
+		}
+
+		workOrder.MaintenanceLogID = &maintenanceLog.ID
+
+		if err := s.workOrderRepo.Update(ctx, workOrder); err != nil {
+			return fmt.Errorf("failed to complete work order: %w", err)
+		}
+
+		if err := s.recordChange(ctx, domain.AuditActionTransition, &before); err != nil {
+			return err
+		}
+
+		return s.releaseTruck(ctx, workOrder, domain.FormatDate(maintenanceLog.Date), mileage, at)
+	})
+}
+
+// Cancel abandons the work order. a truck that was in the shop for it goes back in service the same way it would
+// on completion, but no maintenance log is written.
+func (s *workOrderService) Cancel(ctx context.Context, id, orgID primitive.ObjectID, at time.Time) error {
+	return runInTransaction(ctx, s.db, func(ctx context.Context) error {
+		workOrder, err := s.GetById(ctx, id, orgID)
+		if err != nil {
+			return err
+		}
+
+		before := *workOrder
+		if err := workOrder.Cancel(at); err != nil {
+			return fmt.Errorf("an error occurred when attempting to cancel work order: %w", err)
+		}
+
+		if err := s.workOrderRepo.Update(ctx, workOrder); err != nil {
+			return fmt.Errorf("failed to cancel work order: %w", err)
+		}
+
+		if err := s.recordChange(ctx, domain.AuditActionTransition, &before); err != nil {
+			return err
+		}
+
+		if !before.Status.HoldsTruck() {
+			return nil
+		}
+
+		return s.releaseTruck(ctx, workOrder, "", 0, at)
+	})
+}
+
+// helpers
+
+// recordChange audits a mutation of an existing work order, reading it back so that the before and after
+// snapshots have the same shape
+func (s *workOrderService) recordChange(ctx context.Context, action domain.AuditAction, before *domain.WorkOrder) error {
+	after, err := s.workOrderRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(workOrderNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityWorkOrder, before.ID, before.OrganizationID, action, before, after)
+}
+
+// recordTruckChange audits a change the work order made to its truck
+func (s *workOrderService) recordTruckChange(ctx context.Context, before *domain.Truck) error {
+	after, err := s.truckRepo.GetById(ctx, before.ID, before.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(truckNotFound, err)
+	}
+
+	return s.audit.Record(ctx, domain.AuditEntityTruck, before.ID, before.OrganizationID, domain.AuditActionTransition, before, after)
+}
+
+func (s *workOrderService) getTruck(ctx context.Context, workOrder *domain.WorkOrder) (*domain.Truck, error) {
+	truck, err := s.truckRepo.GetById(ctx, workOrder.TruckID, workOrder.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf(truckNotFound, err)
+	}
+	if truck == nil {
+		return nil, domain.ErrTruckNotFound
+	}
+
+	if err := truck.InitializeStateMachine(); err != nil {
+		return nil, fmt.Errorf("failed to initialize state machine: %w", err)
+	}
+
+	return truck, nil
+}
+
+// holdTruck puts the work order's truck under maintenance, unless another work order already has it there
+func (s *workOrderService) holdTruck(ctx context.Context, workOrder *domain.WorkOrder) error {
+	truck, err := s.getTruck(ctx, workOrder)
+	if err != nil {
+		return err
+	}
+
+	if truck.Status == domain.TruckStatusUnderMaintenance {
+		return nil
+	}
+
+	before := *truck
+	if err := truck.SetTruckInMaintenance(); err != nil {
+		return fmt.Errorf("an error occurred when attempting to set truck in maintenance: %w", err)
+	}
+
+	if err := s.truckRepo.Update(ctx, truck); err != nil {
+		return fmt.Errorf("failed to set truck in maintenance: %w", err)
+	}
+
+	return s.recordTruckChange(ctx, &before)
+}
+
+// releaseTruck makes the truck available again once the last work order holding it is closed, noting the date of
+// the work and the odometer reading taken when there are ones. a truck someone else moved out of maintenance in the
+// meantime is left in service.
+func (s *workOrderService) releaseTruck(ctx context.Context, workOrder *domain.WorkOrder, lastMaintenance string, mileage int, at time.Time) error {
+	holding, err := s.workOrderRepo.CountHoldingTruck(ctx, workOrder.TruckID, workOrder.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	truck, err := s.getTruck(ctx, workOrder)
+	if err != nil {
+		return err
+	}
+
+	before := *truck
+	if lastMaintenance != "" {
+		truck.LastMaintenance = lastMaintenance
+	}
+	if mileage > truck.Mileage {
+		if err := truck.RecordOdometer(mileage, at); err != nil {
+			return err
+		}
+	}
+	if holding == 0 && truck.Status == domain.TruckStatusUnderMaintenance {
+		if err := truck.MakeTruckAvailable(); err != nil {
+			return fmt.Errorf("an error occurred when attempting to release truck: %w", err)
+		}
+	}
+
+	if truck.Status == before.Status && truck.LastMaintenance == before.LastMaintenance && truck.Mileage == before.Mileage {
+		return nil
+	}
+
+	if err := s.truckRepo.Update(ctx, truck); err != nil {
+		return fmt.Errorf("failed to release truck: %w", err)
+	}
+
+	return s.recordTruckChange(ctx, &before)
+}
+
+// ensureSchedule checks that a work order for a scheduled service names a schedule in the organization
+func (s *workOrderService) ensureSchedule(ctx context.Context, workOrder *domain.WorkOrder) error {
+	if workOrder.ScheduleID == nil {
+		return nil
+	}
+
+	schedule, err := s.scheduleRepo.GetById(ctx, *workOrder.ScheduleID, workOrder.OrganizationID)
+	if err != nil {
+		return fmt.Errorf(maintenanceScheduleNotFound, err)
+	}
+	if schedule == nil {
+		return domain.ErrMaintenanceScheduleNotFound
+	}
+
+	return nil
+}
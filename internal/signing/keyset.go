@@ -0,0 +1,267 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jwald3/waybill/internal/config"
+)
+
+// RSA keys shorter than this are considered breakable
+const minRSAKeyBits = 2048
+
+// a Key is one entry in the key set. verification-only keys (retired keys whose tokens may still be live, or
+// keys whose private half lives elsewhere) have no private key.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// a KeySet signs tokens with its active key and verifies tokens signed by any of its keys. rotating keys is a
+// matter of putting the new key first and keeping the old one around until the tokens it signed have expired.
+type KeySet struct {
+	active *Key
+	keys   []*Key
+	byID   map[string]*Key
+}
+
+// Load reads PEM encoded keys from disk. the first signing key is the one new tokens are signed with; every
+// other key, including the verification keys, is only used to check signatures.
+func Load(signingKeys, verificationKeys []config.KeyFile) (*KeySet, error) {
+	if len(signingKeys) == 0 {
+		return nil, fmt.Errorf("at least one signing key is required")
+	}
+
+	keys := make([]*Key, 0, len(signingKeys)+len(verificationKeys))
+
+	for _, file := range signingKeys {
+		key, err := loadPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	for _, file := range verificationKeys {
+		key, err := loadPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(keys...)
+}
+
+// Generate creates a key set holding a single fresh Ed25519 key. tokens signed with it stop verifying once the
+// process exits, so it's only suitable for local development.
+func Generate() (*KeySet, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	return NewKeySet(&Key{
+		ID:         "dev",
+		Method:     jwt.SigningMethodEdDSA,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	})
+}
+
+func NewKeySet(keys ...*Key) (*KeySet, error) {
+	if len(keys) == 0 || keys[0].PrivateKey == nil {
+		return nil, fmt.Errorf("the first key in a key set must be able to sign")
+	}
+
+	byID := make(map[string]*Key, len(keys))
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, fmt.Errorf("every key needs a key id")
+		}
+		if _, exists := byID[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		byID[key.ID] = key
+	}
+
+	return &KeySet{
+		active: keys[0],
+		keys:   keys,
+		byID:   byID,
+	}, nil
+}
+
+// Sign signs the claims with the active key, recording its id in the token header so verifiers know which
+// key to check against
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+
+	return token.SignedString(s.active.PrivateKey)
+}
+
+// Keyfunc picks the verification key for a token by its kid. the token's algorithm has to be the one that key
+// was issued for, so a token can't pick a weaker algorithm (or "none") and have it accepted.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, fmt.Errorf("token has no key id")
+	}
+
+	key, ok := s.byID[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if token.Method == nil || token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing algorithm for key %q", kid)
+	}
+
+	return key.PublicKey, nil
+}
+
+// Algorithms lists every algorithm used by a key in the set, for the parser's allow list
+func (s *KeySet) Algorithms() []string {
+	seen := make(map[string]bool)
+	algorithms := make([]string, 0, 2)
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algorithms = append(algorithms, alg)
+		}
+	}
+
+	return algorithms
+}
+
+// JWK is the public half of a key in RFC 7517 form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes every public key in the set so that other services can verify our tokens
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+
+	for _, key := range s.keys {
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func loadPrivateKey(file config.KeyFile) (*Key, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %q: %w", file.ID, err)
+	}
+
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		if privateKey.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("signing key %q is shorter than %d bits", file.ID, minRSAKeyBits)
+		}
+		return &Key{ID: file.ID, Method: jwt.SigningMethodRS256, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: file.ID, Method: jwt.SigningMethodEdDSA, PrivateKey: privateKey, PublicKey: privateKey.Public()}, nil
+	default:
+		return nil, fmt.Errorf("signing key %q must be an RSA or Ed25519 key", file.ID)
+	}
+}
+
+func loadPublicKey(file config.KeyFile) (*Key, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse verification key %q: %w", file.ID, err)
+	}
+
+	switch publicKey := parsed.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("verification key %q is shorter than %d bits", file.ID, minRSAKeyBits)
+		}
+		return &Key{ID: file.ID, Method: jwt.SigningMethodRS256, PublicKey: publicKey}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: file.ID, Method: jwt.SigningMethodEdDSA, PublicKey: publicKey}, nil
+	default:
+		return nil, fmt.Errorf("verification key %q must be an RSA or Ed25519 key", file.ID)
+	}
+}
+
+func readPEM(file config.KeyFile) (*pem.Block, error) {
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", file.ID, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", file.ID)
+	}
+
+	return block, nil
+}
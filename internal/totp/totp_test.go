@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// the SHA1 secret from the RFC 6238 test vectors, "12345678901234567890", base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "rfc vector at 59", secret: rfcSecret, code: "287082", now: time.Unix(59, 0), wantStep: 1, wantOK: true},
+		{name: "rfc vector at 1111111109", secret: rfcSecret, code: "081804", now: time.Unix(1111111109, 0), wantStep: 37037036, wantOK: true},
+		{name: "rfc vector at 1234567890", secret: rfcSecret, code: "005924", now: time.Unix(1234567890, 0), wantStep: 41152263, wantOK: true},
+		{name: "rfc vector at 2000000000", secret: rfcSecret, code: "279037", now: time.Unix(2000000000, 0), wantStep: 66666666, wantOK: true},
+		{name: "one step late", secret: rfcSecret, code: "287082", now: time.Unix(89, 0), wantStep: 1, wantOK: true},
+		{name: "one step early", secret: rfcSecret, code: "287082", now: time.Unix(29, 0), wantStep: 1, wantOK: true},
+		{name: "two steps late", secret: rfcSecret, code: "287082", now: time.Unix(119, 0)},
+		{name: "wrong code", secret: rfcSecret, code: "287083", now: time.Unix(59, 0)},
+		{name: "surrounding whitespace", secret: rfcSecret, code: " 287082\n", now: time.Unix(59, 0), wantStep: 1, wantOK: true},
+		{name: "lowercase secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "287082", now: time.Unix(59, 0), wantStep: 1, wantOK: true},
+		{name: "too short", secret: rfcSecret, code: "28708", now: time.Unix(59, 0)},
+		{name: "too long", secret: rfcSecret, code: "2870820", now: time.Unix(59, 0)},
+		{name: "empty", secret: rfcSecret, code: "", now: time.Unix(59, 0)},
+		{name: "secret isn't base32", secret: "not-base32!", code: "287082", now: time.Unix(59, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, tt.now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate(%q, %q, %d) = (%d, %v), want (%d, %v)", tt.secret, tt.code, tt.now.Unix(), step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateGeneratedSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("generated secret %q isn't base32: %v", secret, err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("generated secret is %d bytes, want %d", len(key), secretSize)
+	}
+
+	now := time.Now()
+	step := now.Unix() / int64(Period.Seconds())
+	if got, ok := Validate(secret, codeAt(key, step), now); !ok || got != step {
+		t.Errorf("Validate() = (%d, %v) for the current code, want (%d, true)", got, ok, step)
+	}
+}